@@ -36,7 +36,7 @@ func main() {
 		routes.GlobalWebSocketHandler.Broadcast(event, data)
 	}
 
-	zkSocket := handlers.SetupZKDevice(eventLogger, broadcastFunc)
+	zkSocket := handlers.SetupZKDevice(eventLogger, broadcastFunc, server.PreOrderPunchHook())
 
 	listener := mustListen(server.DefaultPort())
 	apiServer := server.NewServer(nil)
@@ -46,6 +46,7 @@ func main() {
 		func(getter func(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error)) {
 			routes.GlobalWebSocketHandler.RegisterQRChannelGetter(getter)
 		},
+		server.ReceiptMessageHook(),
 	)
 
 	whatsappInterface := handlers.NewWhatsAppClientInterface(whatsapp)
@@ -119,7 +119,9 @@ export default function SendAllBalance() {
 				<DialogClose />
 				<div className="space-y-4">
 					<div className="text-xs text-muted-foreground">
-						You can use <code>{"{name}"}</code>, <code>{"{balance}"}</code>,{" "}
+						You can use <code>{"{name}"}</code>, <code>{"{employee_id}"}</code>,{" "}
+						<code>{"{balance}"}</code>,{" "}
+						<code>{"{installment}"}</code>,{" "}
 						<code>{"{month}"}</code> and <code>{"{year}"}</code> as
 						placeholders.
 					</div>
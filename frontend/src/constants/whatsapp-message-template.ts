@@ -1,6 +1,6 @@
 // Centralized WhatsApp message template for notifications
 
-export const DEFAULT_WHATSAPP_MESSAGE_TEMPLATE = `**Balance Update** \n\nDear {name},\nYour current canteen balance is: *PKR {balance}*\n\nPlease pay online via Jazz Cash 03422949447 (Syed Kazim Raza) {duration} of Canteen bill for {month} {year}\n\nThis is an automated message from Maya Canteen Management System.\n\nAfter the payment share the screenshot.\n\n{transactions}`;
+export const DEFAULT_WHATSAPP_MESSAGE_TEMPLATE = `**Balance Update** \n\nDear {name},\nYour current canteen balance is: *PKR {balance}*{installment}\n\nPlease pay online via Jazz Cash 03422949447 (Syed Kazim Raza) {duration} of Canteen bill for {month} {year}\n\nThis is an automated message from Maya Canteen Management System.\n\nAfter the payment share the screenshot. Please write your employee ID {employee_id} in the payment reference so that the payment is matched to your account.\n\n{transactions}`;
 
 export const months = [
 	"January",
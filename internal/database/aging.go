@@ -0,0 +1,230 @@
+package database
+
+import (
+	"math"
+	"maya-canteen/internal/models"
+	"sort"
+	"time"
+)
+
+// Aging and escalation operations
+func (s *service) InitEscalationTable() error {
+	return s.escalationRepository.InitTable()
+}
+
+// GetAgingReport returns how long the users who owe money have owed it. Each user's credits are
+// matched against their oldest charges first, and the charges left unpaid are bucketed by age.
+// Users are listed from the oldest debt to the most recent, with the escalation level they reached.
+func (s *service) GetAgingReport() (*models.AgingReport, error) {
+	balances, err := s.transactionRepository.GetUsersBalances()
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := s.escalationRepository.GetLedger()
+	if err != nil {
+		return nil, err
+	}
+	levels, err := s.escalationRepository.GetLevels(true)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[int64][]models.LedgerEntry)
+	for _, entry := range ledger {
+		entries[entry.UserID] = append(entries[entry.UserID], entry)
+	}
+
+	report := &models.AgingReport{AsOf: time.Now(), Users: []models.UserAging{}}
+	for _, balance := range balances {
+		buckets, oldest := ageLedger(entries[balance.UserID], report.AsOf)
+		if buckets.Total <= 0 {
+			continue
+		}
+		aging := models.UserAging{
+			UserID:           balance.UserID,
+			UserName:         balance.UserName,
+			EmployeeID:       balance.EmployeeID,
+			Department:       balance.Department,
+			Phone:            balance.Phone,
+			UserActive:       balance.UserActive,
+			Balance:          roundAmount(balance.Balance),
+			AgingBuckets:     buckets,
+			OldestUnpaidDate: oldest,
+			AgeDays:          ageDays(*oldest, report.AsOf),
+		}
+		aging.EscalationLevel = escalationLevelFor(levels, &aging)
+		report.Users = append(report.Users, aging)
+
+		report.Totals.Current += buckets.Current
+		report.Totals.Days30 += buckets.Days30
+		report.Totals.Days60 += buckets.Days60
+		report.Totals.Days90Plus += buckets.Days90Plus
+		report.Totals.Total += buckets.Total
+	}
+	report.Totals.Current = roundAmount(report.Totals.Current)
+	report.Totals.Days30 = roundAmount(report.Totals.Days30)
+	report.Totals.Days60 = roundAmount(report.Totals.Days60)
+	report.Totals.Days90Plus = roundAmount(report.Totals.Days90Plus)
+	report.Totals.Total = roundAmount(report.Totals.Total)
+
+	sort.SliceStable(report.Users, func(i, j int) bool {
+		if report.Users[i].AgeDays != report.Users[j].AgeDays {
+			return report.Users[i].AgeDays > report.Users[j].AgeDays
+		}
+		return report.Users[i].Total > report.Users[j].Total
+	})
+	return report, nil
+}
+
+// unpaidCharge is what remains unpaid of a charge to a user's balance, in cents
+type unpaidCharge struct {
+	date  time.Time
+	cents int64
+}
+
+// ageLedger matches the credits of a user's ledger entries against the oldest charges first
+// and buckets what remains unpaid by its age at asOf. It returns the date of the oldest unpaid
+// charge, or nil if nothing is unpaid. Amounts are matched in cents to avoid rounding drift.
+func ageLedger(entries []models.LedgerEntry, asOf time.Time) (models.AgingBuckets, *time.Time) {
+	var charges []unpaidCharge
+	var credit int64
+	for _, entry := range entries {
+		cents := int64(math.Round(entry.Amount * 100))
+		if cents > 0 {
+			credit += cents
+		} else {
+			charges = append(charges, unpaidCharge{date: entry.CreatedAt, cents: -cents})
+		}
+		for credit > 0 && len(charges) > 0 {
+			paid := min(credit, charges[0].cents)
+			charges[0].cents -= paid
+			credit -= paid
+			if charges[0].cents == 0 {
+				charges = charges[1:]
+			}
+		}
+	}
+
+	var buckets models.AgingBuckets
+	if len(charges) == 0 {
+		return buckets, nil
+	}
+	for _, charge := range charges {
+		amount := float64(charge.cents) / 100
+		switch days := ageDays(charge.date, asOf); {
+		case days < 30:
+			buckets.Current += amount
+		case days < 60:
+			buckets.Days30 += amount
+		case days < 90:
+			buckets.Days60 += amount
+		default:
+			buckets.Days90Plus += amount
+		}
+		buckets.Total += amount
+	}
+	buckets.Current = roundAmount(buckets.Current)
+	buckets.Days30 = roundAmount(buckets.Days30)
+	buckets.Days60 = roundAmount(buckets.Days60)
+	buckets.Days90Plus = roundAmount(buckets.Days90Plus)
+	buckets.Total = roundAmount(buckets.Total)
+	oldest := charges[0].date
+	return buckets, &oldest
+}
+
+// ageDays returns the number of whole days from date to asOf
+func ageDays(date, asOf time.Time) int {
+	return int(asOf.Sub(date).Hours() / 24)
+}
+
+// escalationLevelFor returns the latest of levels, ordered from the earliest to the latest,
+// that the user's unpaid amount reached, or nil if it reached none
+func escalationLevelFor(levels []models.EscalationLevel, aging *models.UserAging) *models.EscalationLevel {
+	var reached *models.EscalationLevel
+	for i := range levels {
+		if aging.AgeDays >= levels[i].MinAgeDays && aging.Total >= levels[i].MinAmount {
+			reached = &levels[i]
+		}
+	}
+	return reached
+}
+
+// GetDueEscalations returns the users of the aging report who reached an escalation level and
+// were not escalated at that level within its repeat days. Users on an active installment plan
+// are left out unless they missed an installment.
+func (s *service) GetDueEscalations() ([]models.UserAging, error) {
+	report, err := s.GetAgingReport()
+	if err != nil {
+		return nil, err
+	}
+
+	maxRepeatDays := 0
+	for _, aging := range report.Users {
+		if aging.EscalationLevel != nil {
+			maxRepeatDays = max(maxRepeatDays, aging.EscalationLevel.RepeatDays)
+		}
+	}
+	notices, err := s.escalationRepository.GetNotices(report.AsOf.AddDate(0, 0, -maxRepeatDays))
+	if err != nil {
+		return nil, err
+	}
+	type userLevel struct{ userID, levelID int64 }
+	lastSent := make(map[userLevel]time.Time)
+	for _, notice := range notices {
+		key := userLevel{notice.UserID, 0}
+		if notice.LevelID != nil {
+			key.levelID = *notice.LevelID
+		}
+		if _, ok := lastSent[key]; !ok {
+			lastSent[key] = notice.CreatedAt
+		}
+	}
+
+	due := make([]models.UserAging, 0)
+	for _, aging := range report.Users {
+		level := aging.EscalationLevel
+		if level == nil {
+			continue
+		}
+		if sent, ok := lastSent[userLevel{aging.UserID, level.ID}]; ok && report.AsOf.Sub(sent) < time.Duration(level.RepeatDays)*24*time.Hour {
+			continue
+		}
+		plan, err := s.GetActiveInstallmentPlan(aging.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil && plan.MissedCount == 0 {
+			continue
+		}
+		due = append(due, aging)
+	}
+	return due, nil
+}
+
+func (s *service) RecordEscalationNotice(notice *models.EscalationNotice) error {
+	return s.escalationRepository.CreateNotice(notice)
+}
+
+func (s *service) GetEscalationNotices(since time.Time) ([]models.EscalationNotice, error) {
+	return s.escalationRepository.GetNotices(since)
+}
+
+func (s *service) CreateEscalationLevel(level *models.EscalationLevel) error {
+	return s.escalationRepository.CreateLevel(level)
+}
+
+func (s *service) GetEscalationLevels() ([]models.EscalationLevel, error) {
+	return s.escalationRepository.GetLevels(false)
+}
+
+func (s *service) GetEscalationLevel(id int64) (*models.EscalationLevel, error) {
+	return s.escalationRepository.GetLevel(id)
+}
+
+func (s *service) UpdateEscalationLevel(level *models.EscalationLevel) error {
+	return s.escalationRepository.UpdateLevel(level)
+}
+
+func (s *service) DeleteEscalationLevel(id int64) error {
+	return s.escalationRepository.DeleteLevel(id)
+}
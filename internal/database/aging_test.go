@@ -0,0 +1,114 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createAgedTransaction creates an account transaction of the user made daysAgo days ago
+func createAgedTransaction(t *testing.T, s *service, userID int64, transactionType string, amount float64, daysAgo int) {
+	t.Helper()
+	transaction := &models.Transaction{
+		UserID:          userID,
+		Amount:          amount,
+		TransactionType: transactionType,
+		PaymentMethod:   models.PaymentMethodAccount,
+		CreatedAt:       time.Now().Add(-time.Duration(daysAgo) * 24 * time.Hour),
+	}
+	require.NoError(t, s.CreateTransaction(transaction))
+}
+
+// agingOf returns the aging of the user in the report, or nil if the user is not listed
+func agingOf(report *models.AgingReport, userID int64) *models.UserAging {
+	for i := range report.Users {
+		if report.Users[i].UserID == userID {
+			return &report.Users[i]
+		}
+	}
+	return nil
+}
+
+func TestAgingReport(t *testing.T) {
+	s := newTestService(t)
+	recent := createTestUser(t, s, "9001")
+	createAgedTransaction(t, s, recent.ID, models.TransactionTypePurchase, 100, 95)
+	createAgedTransaction(t, s, recent.ID, models.TransactionTypePurchase, 200, 45)
+	createAgedTransaction(t, s, recent.ID, models.TransactionTypePurchase, 300, 5)
+	createTestTransaction(t, s, recent.ID, models.TransactionTypeDeposit, 150)
+
+	old := createTestUser(t, s, "9002")
+	createAgedTransaction(t, s, old.ID, models.TransactionTypePurchase, 50, 100)
+
+	prepaid := createTestUser(t, s, "9003")
+	createAgedTransaction(t, s, prepaid.ID, models.TransactionTypeDeposit, 100, 70)
+	createAgedTransaction(t, s, prepaid.ID, models.TransactionTypePurchase, 150, 65)
+
+	settled := createTestUser(t, s, "9004")
+	createAgedTransaction(t, s, settled.ID, models.TransactionTypePurchase, 80, 120)
+	createTestTransaction(t, s, settled.ID, models.TransactionTypeDeposit, 100)
+
+	report, err := s.GetAgingReport()
+	require.NoError(t, err)
+	require.Len(t, report.Users, 3, "users who owe nothing are left out")
+	assert.Equal(t, old.ID, report.Users[0].UserID, "the oldest debt comes first")
+	assert.Equal(t, prepaid.ID, report.Users[1].UserID)
+	assert.Equal(t, recent.ID, report.Users[2].UserID)
+
+	aging := agingOf(report, recent.ID)
+	assert.Equal(t, -450.0, aging.Balance)
+	assert.Equal(t, 450.0, aging.Total, "what is unpaid adds up to the balance")
+	assert.Equal(t, 300.0, aging.Current)
+	assert.Equal(t, 150.0, aging.Days30, "the deposit paid the oldest purchase and part of the next")
+	assert.Zero(t, aging.Days90Plus)
+	assert.Equal(t, 45, aging.AgeDays)
+
+	aging = agingOf(report, prepaid.ID)
+	assert.Equal(t, 50.0, aging.Days60, "an earlier deposit pays part of a later purchase")
+	assert.Equal(t, 65, aging.AgeDays)
+	assert.Equal(t, 50.0, agingOf(report, old.ID).Days90Plus)
+
+	assert.Equal(t, 300.0, report.Totals.Current)
+	assert.Equal(t, 150.0, report.Totals.Days30)
+	assert.Equal(t, 50.0, report.Totals.Days60)
+	assert.Equal(t, 50.0, report.Totals.Days90Plus)
+	assert.Equal(t, 550.0, report.Totals.Total)
+}
+
+func TestDueEscalations(t *testing.T) {
+	s := newTestService(t)
+	reminder := &models.EscalationLevel{Name: "Reminder", MinAgeDays: 30, MinAmount: 100, RepeatDays: 7, Active: true}
+	require.NoError(t, s.CreateEscalationLevel(reminder))
+	manager := &models.EscalationLevel{Name: "Manager", MinAgeDays: 90, MinAmount: 40, RepeatDays: 7, Active: true}
+	require.NoError(t, s.CreateEscalationLevel(manager))
+
+	overdue := createTestUser(t, s, "9011")
+	createAgedTransaction(t, s, overdue.ID, models.TransactionTypePurchase, 200, 45)
+	longOverdue := createTestUser(t, s, "9012")
+	createAgedTransaction(t, s, longOverdue.ID, models.TransactionTypePurchase, 50, 100)
+	small := createTestUser(t, s, "9013")
+	createAgedTransaction(t, s, small.ID, models.TransactionTypePurchase, 50, 45)
+
+	due, err := s.GetDueEscalations()
+	require.NoError(t, err)
+	require.Len(t, due, 2, "the small debt reaches no level")
+	assert.Equal(t, longOverdue.ID, due[0].UserID)
+	assert.Equal(t, manager.ID, due[0].EscalationLevel.ID, "the latest level reached applies")
+	assert.Equal(t, overdue.ID, due[1].UserID)
+	assert.Equal(t, reminder.ID, due[1].EscalationLevel.ID)
+
+	// A user escalated at the level within its repeat days is not escalated again
+	require.NoError(t, s.RecordEscalationNotice(&models.EscalationNotice{
+		UserID: overdue.ID, LevelID: &reminder.ID, LevelName: reminder.Name, Amount: 200, AgeDays: 45, SentBy: "admin",
+	}))
+	// Nor is a user keeping up with an installment plan
+	require.NoError(t, s.CreateInstallmentPlan(&models.InstallmentPlan{UserID: longOverdue.ID, InstallmentCount: 2, CreatedBy: "admin"}, time.Now().AddDate(0, 0, 7)))
+
+	due, err = s.GetDueEscalations()
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"maya-canteen/internal/models"
+	"sync"
+	"time"
+)
+
+// analyticsCacheTTL is how long computed analytics are served before they are computed again
+const analyticsCacheTTL = 5 * time.Minute
+
+// analyticsCache keeps computed analytics by query, so dashboards that refresh often do not
+// aggregate the same transactions over and over
+type analyticsCache struct {
+	mu      sync.Mutex
+	entries map[string]analyticsCacheEntry
+}
+
+type analyticsCacheEntry struct {
+	value     any
+	expiresAt time.Time
+}
+
+// cachedAnalytics returns the cached result of key if it has not expired, or computes and caches it
+func cachedAnalytics[T any](cache *analyticsCache, key string, compute func() (T, error)) (T, error) {
+	now := time.Now()
+	cache.mu.Lock()
+	if entry, ok := cache.entries[key]; ok && now.Before(entry.expiresAt) {
+		cache.mu.Unlock()
+		return entry.value.(T), nil
+	}
+	cache.mu.Unlock()
+
+	value, err := compute()
+	if err != nil {
+		return value, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for k, entry := range cache.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(cache.entries, k)
+		}
+	}
+	cache.entries[key] = analyticsCacheEntry{value: value, expiresAt: now.Add(analyticsCacheTTL)}
+	return value, nil
+}
+
+// analyticsKey builds the cache key of an analytics query
+func analyticsKey(name string, startDate, endDate time.Time, extra ...any) string {
+	return fmt.Sprint(name, "|", startDate.Format("2006-01-02"), "|", endDate.Format("2006-01-02"), "|", extra)
+}
+
+// previousPeriodStart returns the start of the period of the same length just before the
+// period from startDate to endDate, both inclusive
+func previousPeriodStart(startDate, endDate time.Time) time.Time {
+	days := int(endDate.Sub(startDate).Hours()/24) + 1
+	return startDate.AddDate(0, 0, -days)
+}
+
+// percentChange returns the change from previous to current in percent, or nil if previous is zero
+func percentChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round((current-previous)/math.Abs(previous)*10000) / 100
+	return &change
+}
+
+// Analytics operations
+func (s *service) GetAnalyticsSummary(startDate, endDate time.Time) (*models.AnalyticsSummary, error) {
+	return cachedAnalytics(s.analyticsCache, analyticsKey("summary", startDate, endDate), func() (*models.AnalyticsSummary, error) {
+		previousStart := previousPeriodStart(startDate, endDate)
+		current, previous, err := s.analyticsRepository.GetPeriods(previousStart, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		current.StartDate, current.EndDate = startDate.Format("2006-01-02"), endDate.Format("2006-01-02")
+		previous.StartDate, previous.EndDate = previousStart.Format("2006-01-02"), startDate.AddDate(0, 0, -1).Format("2006-01-02")
+		for _, period := range []*models.AnalyticsPeriod{&current, &previous} {
+			period.Revenue = roundAmount(period.Revenue)
+			period.AverageBasket = roundAmount(period.AverageBasket)
+			period.AverageItems = roundAmount(period.AverageItems)
+			period.AverageDailyCustomers = roundAmount(period.AverageDailyCustomers)
+		}
+
+		return &models.AnalyticsSummary{
+			Current:               current,
+			Previous:              previous,
+			RevenueChange:         percentChange(current.Revenue, previous.Revenue),
+			PurchaseCountChange:   percentChange(float64(current.PurchaseCount), float64(previous.PurchaseCount)),
+			AverageBasketChange:   percentChange(current.AverageBasket, previous.AverageBasket),
+			UniqueCustomersChange: percentChange(float64(current.UniqueCustomers), float64(previous.UniqueCustomers)),
+			GeneratedAt:           time.Now(),
+		}, nil
+	})
+}
+
+func (s *service) GetRevenueSeries(startDate, endDate time.Time, granularity string) (*models.RevenueSeries, error) {
+	return cachedAnalytics(s.analyticsCache, analyticsKey("revenue", startDate, endDate, granularity), func() (*models.RevenueSeries, error) {
+		points, err := s.analyticsRepository.GetRevenue(startDate, endDate, granularity)
+		if err != nil {
+			return nil, err
+		}
+		current, previous, err := s.analyticsRepository.GetPeriods(previousPeriodStart(startDate, endDate), startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		for i := range points {
+			points[i].Revenue = roundAmount(points[i].Revenue)
+			points[i].RefundTotal = roundAmount(points[i].RefundTotal)
+		}
+
+		return &models.RevenueSeries{
+			Granularity:   granularity,
+			Points:        points,
+			Total:         roundAmount(current.Revenue),
+			PreviousTotal: roundAmount(previous.Revenue),
+			Change:        percentChange(current.Revenue, previous.Revenue),
+			GeneratedAt:   time.Now(),
+		}, nil
+	})
+}
+
+func (s *service) GetSalesHeatmap(startDate, endDate time.Time) ([]models.HeatmapCell, error) {
+	return cachedAnalytics(s.analyticsCache, analyticsKey("heatmap", startDate, endDate), func() ([]models.HeatmapCell, error) {
+		cells, err := s.analyticsRepository.GetHeatmap(startDate, endDate)
+		for i := range cells {
+			cells[i].Revenue = roundAmount(cells[i].Revenue)
+		}
+		return cells, err
+	})
+}
+
+func (s *service) GetDailyCustomers(startDate, endDate time.Time) ([]models.DailyCustomers, error) {
+	return cachedAnalytics(s.analyticsCache, analyticsKey("customers", startDate, endDate), func() ([]models.DailyCustomers, error) {
+		return s.analyticsRepository.GetDailyCustomers(startDate, endDate)
+	})
+}
+
+func (s *service) GetTopProducts(startDate, endDate time.Time, limit int) ([]models.TopProduct, error) {
+	return cachedAnalytics(s.analyticsCache, analyticsKey("top-products", startDate, endDate, limit), func() ([]models.TopProduct, error) {
+		products, err := s.analyticsRepository.GetTopProducts(previousPeriodStart(startDate, endDate), startDate, endDate, limit)
+		for i := range products {
+			products[i].Revenue = roundAmount(products[i].Revenue)
+			products[i].PreviousRevenue = roundAmount(products[i].PreviousRevenue)
+			products[i].Change = percentChange(products[i].Revenue, products[i].PreviousRevenue)
+		}
+		return products, err
+	})
+}
+
+func (s *service) GetTopUsers(startDate, endDate time.Time, limit int) ([]models.TopUser, error) {
+	return cachedAnalytics(s.analyticsCache, analyticsKey("top-users", startDate, endDate, limit), func() ([]models.TopUser, error) {
+		users, err := s.analyticsRepository.GetTopUsers(previousPeriodStart(startDate, endDate), startDate, endDate, limit)
+		for i := range users {
+			users[i].Spent = roundAmount(users[i].Spent)
+			users[i].PreviousSpent = roundAmount(users[i].PreviousSpent)
+			users[i].Change = percentChange(users[i].Spent, users[i].PreviousSpent)
+		}
+		return users, err
+	})
+}
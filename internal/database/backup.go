@@ -0,0 +1,99 @@
+package database
+
+import (
+	"fmt"
+	"maya-canteen/internal/models"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// backupFilePrefix and backupFileExt name the scheduled backup files, e.g. canteen-20240131-020000.db
+const (
+	backupFilePrefix = "canteen-"
+	backupFileExt    = ".db"
+)
+
+// BackupTo writes a consistent SQLite snapshot of the database to path, which must not exist yet
+func (s *service) BackupTo(path string) error {
+	return s.backupRepository.BackupTo(path)
+}
+
+// ExportBackup returns all canteen data as a JSON bundle
+func (s *service) ExportBackup() (*models.BackupBundle, error) {
+	return s.backupRepository.Export()
+}
+
+// RestoreBackup imports a JSON bundle into an empty database, or replaces all canteen data
+// with it if overwrite is set
+func (s *service) RestoreBackup(bundle *models.BackupBundle, overwrite bool) (*models.RestoreResult, error) {
+	result, err := s.backupRepository.Restore(bundle, overwrite)
+	if err != nil {
+		return nil, err
+	}
+	// Backups taken before payer splits existed charged transactions fully to the user
+	if err := s.transactionRepository.InitTable(); err != nil {
+		return nil, err
+	}
+	// Backups taken before product units existed only have single unit pricing and no price history
+	if err := s.productUnitRepository.InitTable(); err != nil {
+		return nil, err
+	}
+	if err := s.productPriceRepository.InitTable(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RunScheduledBackup writes a timestamped backup into dir and removes the oldest
+// backups so that at most retention files are kept. A retention of 0 keeps all backups.
+func (s *service) RunScheduledBackup(dir string, retention int) (string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, backupFilePrefix+time.Now().Format("20060102-150405")+backupFileExt)
+	if err := s.BackupTo(path); err != nil {
+		return "", err
+	}
+
+	if retention > 0 {
+		if err := pruneBackups(dir, retention); err != nil {
+			return path, fmt.Errorf("pruning old backups: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// pruneBackups removes the oldest backup files in dir beyond the retention count
+func pruneBackups(dir string, retention int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, backupFilePrefix) && strings.HasSuffix(name, backupFileExt) {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) <= retention {
+		return nil
+	}
+
+	// Timestamped names sort chronologically
+	slices.Sort(backups)
+	for _, name := range backups[:len(backups)-retention] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		log.Infof("Removed old backup %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+
+	"maya-canteen/internal/database/repository"
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportTestBackup exports the data of s as a bundle read back from its JSON, as it is uploaded
+func exportTestBackup(t *testing.T, s *service) *models.BackupBundle {
+	t.Helper()
+	bundle, err := s.ExportBackup()
+	require.NoError(t, err)
+	data, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	var uploaded models.BackupBundle
+	require.NoError(t, json.Unmarshal(data, &uploaded))
+	return &uploaded
+}
+
+func TestRestoreBackup(t *testing.T) {
+	source := newTestService(t)
+	user := createTestUser(t, source, "2001")
+	createTestTransaction(t, source, user.ID, models.TransactionTypePurchase, 120)
+	bundle := exportTestBackup(t, source)
+
+	// A new database only holds the default users, which the backup replaces
+	s := newTestService(t)
+	result, err := s.RestoreBackup(bundle, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Tables["users"], "the default users and the new one")
+	assert.Equal(t, 1, result.Tables["transactions"])
+	assert.Equal(t, -120.0, balanceOf(t, s, user.ID))
+}
+
+func TestRestoreBackupIntoDatabaseWithUsers(t *testing.T) {
+	source := newTestService(t)
+	createTestUser(t, source, "2002")
+	bundle := exportTestBackup(t, source)
+
+	// No sales yet, but the users and their audit history are real
+	s := newTestService(t)
+	existing := createTestUser(t, s, "2003")
+	require.NoError(t, s.CreateAuditLog(&models.AuditLog{Actor: "admin", Action: models.AuditActionCreate, EntityType: models.AuditEntityUser, EntityID: existing.ID}))
+
+	_, err := s.RestoreBackup(bundle, false)
+	assert.ErrorIs(t, err, repository.ErrInvalidBackup)
+	saved, err := s.GetUserByEmployeeID("2003")
+	require.NoError(t, err)
+	assert.NotNil(t, saved, "the users are kept")
+
+	_, err = s.RestoreBackup(bundle, true)
+	require.NoError(t, err)
+	saved, err = s.GetUserByEmployeeID("2003")
+	require.NoError(t, err)
+	assert.Nil(t, saved, "overwriting replaces the users")
+	saved, err = s.GetUserByEmployeeID("2002")
+	require.NoError(t, err)
+	assert.NotNil(t, saved)
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// ErrInvalidTransfer is returned when a balance transfer cannot be created or reviewed as requested
+var ErrInvalidTransfer = errors.New("invalid balance transfer")
+
+// transferBatchPrefix starts the batch reference of the transactions of a balance transfer,
+// followed by the transfer ID
+const transferBatchPrefix = "TRANSFER-"
+
+// Balance transfer operations
+func (s *service) InitBalanceTransferTable() error {
+	return s.balanceTransferRepository.InitTable()
+}
+
+// CreateBalanceTransfer records a transfer between two users. Unless it requires the
+// payer's approval, the transfer is executed right away and only recorded if it succeeds.
+func (s *service) CreateBalanceTransfer(transfer *models.BalanceTransfer, requireApproval bool) error {
+	if transfer.FromUserID == transfer.ToUserID {
+		return fmt.Errorf("%w: cannot transfer to the same user", ErrInvalidTransfer)
+	}
+	if transfer.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be greater than zero", ErrInvalidTransfer)
+	}
+	for _, userID := range []int64{transfer.FromUserID, transfer.ToUserID} {
+		user, err := s.userRepository.GetByID(userID)
+		if err != nil {
+			return err
+		}
+		if user == nil || user.DeletedAt != nil {
+			return fmt.Errorf("%w: user %d not found", ErrInvalidTransfer, userID)
+		}
+		if err := s.offboardedError(userID, ErrInvalidTransfer); err != nil {
+			return err
+		}
+	}
+
+	return s.withTx(func(tx *sql.Tx) error {
+		transfers := s.balanceTransferRepository.WithTx(tx)
+		if err := transfers.Create(transfer); err != nil {
+			return err
+		}
+		if !requireApproval {
+			return s.executeBalanceTransfer(tx, transfer.ID, transfer.RequestedBy, transfer)
+		}
+		created, err := transfers.Get(transfer.ID)
+		if err != nil {
+			return err
+		}
+		*transfer = *created
+		return nil
+	})
+}
+
+func (s *service) GetBalanceTransfers(status string) ([]models.BalanceTransfer, error) {
+	return s.balanceTransferRepository.GetAll(status)
+}
+
+func (s *service) GetBalanceTransfer(id int64) (*models.BalanceTransfer, error) {
+	return s.balanceTransferRepository.Get(id)
+}
+
+func (s *service) GetUserBalanceTransfers(userID int64) ([]models.BalanceTransfer, error) {
+	return s.balanceTransferRepository.GetByUser(userID)
+}
+
+// ApproveBalanceTransfer executes a transfer awaiting the payer's approval. It returns nil
+// if the transfer does not exist.
+func (s *service) ApproveBalanceTransfer(id int64, approvedBy string) (*models.BalanceTransfer, error) {
+	transfer, err := s.balanceTransferRepository.Get(id)
+	if err != nil || transfer == nil {
+		return nil, err
+	}
+	if transfer.Status != models.BalanceTransferStatusPending {
+		return nil, fmt.Errorf("%w: transfer %d is %s", ErrInvalidTransfer, id, transfer.Status)
+	}
+	for _, userID := range []int64{transfer.FromUserID, transfer.ToUserID} {
+		if err := s.offboardedError(userID, ErrInvalidTransfer); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.withTx(func(tx *sql.Tx) error {
+		return s.executeBalanceTransfer(tx, id, approvedBy, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// RejectBalanceTransfer declines a transfer awaiting the payer's approval and reports whether
+// a pending transfer was found
+func (s *service) RejectBalanceTransfer(id int64, rejectedBy, note string) (bool, error) {
+	return s.balanceTransferRepository.Reject(id, rejectedBy, note)
+}
+
+// executeBalanceTransfer debits the payer and credits the payee of a pending transfer with a
+// pair of linked transfer transactions, and marks the transfer completed. The payer's balance
+// must cover the amount, or the payer's credit limit the part it does not cover, so a transfer
+// never moves debt. Unlike purchases, a payer without a credit limit can only transfer credit.
+// transfer is updated with the refreshed transfer.
+func (s *service) executeBalanceTransfer(tx *sql.Tx, id int64, reviewedBy string, transfer *models.BalanceTransfer) error {
+	transfers := s.balanceTransferRepository.WithTx(tx)
+	pending, err := transfers.Get(id)
+	if err != nil {
+		return err
+	}
+	if pending == nil {
+		return fmt.Errorf("%w: transfer %d not found", ErrInvalidTransfer, id)
+	}
+
+	transactions := s.transactionRepository.WithTx(tx)
+	balance, err := transactions.GetUserBalanceByID(pending.FromUserID)
+	if err != nil {
+		return err
+	}
+	payer, err := s.userRepository.WithTx(tx).GetByID(pending.FromUserID)
+	if err != nil {
+		return err
+	}
+	creditLimit := 0.0
+	if payer != nil {
+		creditLimit = payer.CreditLimit
+	}
+	if available := roundAmount(balance.Balance + creditLimit); available < pending.Amount {
+		return fmt.Errorf("%w: %s can transfer at most %.2f, less than %.2f", ErrInvalidTransfer, pending.FromUserName, max(available, 0), pending.Amount)
+	}
+
+	now := time.Now()
+	batchReference := fmt.Sprintf("%s%d", transferBatchPrefix, pending.ID)
+	debit := models.Transaction{
+		UserID:          pending.FromUserID,
+		Amount:          -pending.Amount,
+		Description:     transferDescription("Transfer to "+pending.ToUserName, pending.Note),
+		TransactionType: models.TransactionTypeTransfer,
+		BatchReference:  batchReference,
+		CreatedAt:       now,
+	}
+	credit := models.Transaction{
+		UserID:          pending.ToUserID,
+		Amount:          pending.Amount,
+		Description:     transferDescription("Transfer from "+pending.FromUserName, pending.Note),
+		TransactionType: models.TransactionTypeTransfer,
+		BatchReference:  batchReference,
+		CreatedAt:       now,
+	}
+	for _, transaction := range []*models.Transaction{&debit, &credit} {
+		if err := transactions.Create(transaction); err != nil {
+			return err
+		}
+	}
+
+	pending.ReviewedBy = reviewedBy
+	pending.ReviewedAt = &now
+	pending.FromTransactionID = &debit.ID
+	pending.ToTransactionID = &credit.ID
+	completed, err := transfers.MarkCompleted(pending)
+	if err != nil {
+		return err
+	}
+	if !completed {
+		return fmt.Errorf("%w: transfer %d was already reviewed", ErrInvalidTransfer, id)
+	}
+	*transfer = *pending
+	return nil
+}
+
+// transferDescription describes one side of a transfer, followed by the transfer's note
+func transferDescription(description, note string) string {
+	if note == "" {
+		return description
+	}
+	return description + ": " + note
+}
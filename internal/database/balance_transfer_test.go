@@ -0,0 +1,81 @@
+package database
+
+import (
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceTransferApproval(t *testing.T) {
+	s := newTestService(t)
+	payer := createTestUser(t, s, "8001")
+	payee := createTestUser(t, s, "8002")
+	createTestTransaction(t, s, payer.ID, models.TransactionTypeDeposit, 500)
+
+	transfer := &models.BalanceTransfer{FromUserID: payer.ID, ToUserID: payee.ID, Amount: 200, RequestedBy: "admin"}
+	require.NoError(t, s.CreateBalanceTransfer(transfer, true))
+	assert.Equal(t, models.BalanceTransferStatusPending, transfer.Status)
+	assert.Equal(t, 500.0, balanceOf(t, s, payer.ID), "nothing moves before the approval")
+
+	approved, err := s.ApproveBalanceTransfer(transfer.ID, "payer")
+	require.NoError(t, err)
+	assert.Equal(t, models.BalanceTransferStatusCompleted, approved.Status)
+	assert.Equal(t, 300.0, balanceOf(t, s, payer.ID))
+	assert.Equal(t, 200.0, balanceOf(t, s, payee.ID))
+
+	_, err = s.ApproveBalanceTransfer(transfer.ID, "payer")
+	assert.ErrorIs(t, err, ErrInvalidTransfer, "a transfer is executed once")
+	assert.Equal(t, 300.0, balanceOf(t, s, payer.ID))
+}
+
+func TestBalanceTransferRejection(t *testing.T) {
+	s := newTestService(t)
+	payer := createTestUser(t, s, "8003")
+	payee := createTestUser(t, s, "8004")
+	createTestTransaction(t, s, payer.ID, models.TransactionTypeDeposit, 500)
+
+	transfer := &models.BalanceTransfer{FromUserID: payer.ID, ToUserID: payee.ID, Amount: 200, RequestedBy: "admin"}
+	require.NoError(t, s.CreateBalanceTransfer(transfer, true))
+	rejected, err := s.RejectBalanceTransfer(transfer.ID, "payer", "Not mine")
+	require.NoError(t, err)
+	assert.True(t, rejected)
+
+	_, err = s.ApproveBalanceTransfer(transfer.ID, "payer")
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+	assert.Equal(t, 500.0, balanceOf(t, s, payer.ID))
+	assert.Zero(t, balanceOf(t, s, payee.ID))
+}
+
+func TestBalanceTransferInsufficientBalance(t *testing.T) {
+	s := newTestService(t)
+	payer := createTestUser(t, s, "8005")
+	payee := createTestUser(t, s, "8006")
+	createTestTransaction(t, s, payer.ID, models.TransactionTypeDeposit, 100)
+
+	err := s.CreateBalanceTransfer(&models.BalanceTransfer{FromUserID: payer.ID, ToUserID: payee.ID, Amount: 150, RequestedBy: "admin"}, false)
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+	transfers, err := s.GetUserBalanceTransfers(payer.ID)
+	require.NoError(t, err)
+	assert.Empty(t, transfers, "a failed transfer is not recorded")
+	assert.Equal(t, 100.0, balanceOf(t, s, payer.ID))
+	assert.Zero(t, balanceOf(t, s, payee.ID))
+
+	// A credit limit covers the part the balance does not, up to the limit
+	payer.CreditLimit = 100
+	require.NoError(t, s.UpdateUser(payer))
+	require.NoError(t, s.CreateBalanceTransfer(&models.BalanceTransfer{FromUserID: payer.ID, ToUserID: payee.ID, Amount: 150, RequestedBy: "admin"}, false))
+	assert.Equal(t, -50.0, balanceOf(t, s, payer.ID))
+	assert.Equal(t, 150.0, balanceOf(t, s, payee.ID))
+
+	pending := &models.BalanceTransfer{FromUserID: payer.ID, ToUserID: payee.ID, Amount: 60, RequestedBy: "admin"}
+	require.NoError(t, s.CreateBalanceTransfer(pending, true))
+	_, err = s.ApproveBalanceTransfer(pending.ID, "payer")
+	assert.ErrorIs(t, err, ErrInvalidTransfer, "the payer can only go 50 further into debt")
+	assert.Equal(t, -50.0, balanceOf(t, s, payer.ID))
+	pending, err = s.GetBalanceTransfer(pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BalanceTransferStatusPending, pending.Status)
+}
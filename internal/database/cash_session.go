@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maya-canteen/internal/database/repository"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// ErrInvalidCashSession is returned when a cash session cannot be opened or closed as requested
+var ErrInvalidCashSession = errors.New("invalid cash session")
+
+// Cash session operations
+func (s *service) InitCashSessionTable() error {
+	return s.cashSessionRepository.InitTable()
+}
+
+// OpenCashSession opens a session for the cashier with an opening float. A cashier can only
+// have one open session at a time.
+func (s *service) OpenCashSession(session *models.CashSession) error {
+	open, err := s.cashSessionRepository.GetOpen(session.Cashier)
+	if err != nil {
+		return err
+	}
+	if open != nil {
+		return fmt.Errorf("%w: %s already has open session %d", ErrInvalidCashSession, session.Cashier, open.ID)
+	}
+	return s.cashSessionRepository.Create(session)
+}
+
+func (s *service) GetCashSessions() ([]models.CashSession, error) {
+	sessions, err := s.cashSessionRepository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		if err := addCashSessionTotals(s.cashSessionRepository, &sessions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+// GetCashSession returns a session with its transactions, or nil if it does not exist. The
+// totals of an open session are those of its transactions so far.
+func (s *service) GetCashSession(id int64) (*models.CashSession, error) {
+	session, err := s.cashSessionRepository.Get(id)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	if err := addCashSessionTotals(s.cashSessionRepository, session); err != nil {
+		return nil, err
+	}
+	session.Transactions, err = s.cashSessionRepository.GetTransactions(id)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *service) GetOpenCashSession(cashier string) (*models.CashSession, error) {
+	session, err := s.cashSessionRepository.GetOpen(cashier)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	if err := addCashSessionTotals(s.cashSessionRepository, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CloseCashSession closes an open session with the amount counted in the drawer and returns
+// its over/short report, or nil if the session does not exist. The totals are fixed at close.
+func (s *service) CloseCashSession(id int64, countedAmount float64, closedBy, note string) (*models.CashSession, error) {
+	var session *models.CashSession
+	err := s.withTx(func(tx *sql.Tx) error {
+		cashSessionRepository := s.cashSessionRepository.WithTx(tx)
+		var err error
+		session, err = cashSessionRepository.Get(id)
+		if err != nil || session == nil {
+			return err
+		}
+		if session.Status != models.CashSessionStatusOpen {
+			return fmt.Errorf("%w: session %d is already closed", ErrInvalidCashSession, id)
+		}
+		if err := addCashSessionTotals(cashSessionRepository, session); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		difference := countedAmount - session.ExpectedAmount
+		session.Status = models.CashSessionStatusClosed
+		session.CountedAmount = &countedAmount
+		session.Difference = &difference
+		session.ClosedAt = &now
+		session.ClosedBy = closedBy
+		if note != "" {
+			session.Note = note
+		}
+		closed, err := cashSessionRepository.Close(session)
+		if err != nil {
+			return err
+		}
+		if !closed {
+			return fmt.Errorf("%w: session %d is already closed", ErrInvalidCashSession, id)
+		}
+		session.Transactions, err = cashSessionRepository.GetTransactions(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// addCashSessionTotals sets the cash totals and expected amount of an open session from its
+// transactions. Closed sessions keep the totals recorded when they were closed.
+func addCashSessionTotals(cashSessionRepository repository.CashSessionRepositoryInterface, session *models.CashSession) error {
+	if session.Status != models.CashSessionStatusOpen {
+		return nil
+	}
+	totals, err := cashSessionRepository.GetTotals(session.ID)
+	if err != nil {
+		return err
+	}
+	session.CashIn = totals.CashIn
+	session.CashSales = totals.CashSales
+	session.CashOut = totals.CashOut
+	session.ExpectedAmount = session.OpeningFloat + totals.CashIn + totals.CashSales - totals.CashOut
+	return nil
+}
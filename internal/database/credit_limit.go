@@ -0,0 +1,47 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"maya-canteen/internal/database/repository"
+	"maya-canteen/internal/models"
+)
+
+// ErrCreditLimitExceeded is returned when a purchase would take a user's debt past the user's
+// credit limit
+var ErrCreditLimitExceeded = errors.New("credit limit exceeded")
+
+// accountCharge returns the amount a transaction charges to the user's account, which is the
+// employee share of purchases not paid in cash
+func accountCharge(transaction *models.Transaction) float64 {
+	if transaction.TransactionType != models.TransactionTypePurchase || transaction.PaymentMethod == models.PaymentMethodCash {
+		return 0
+	}
+	return transaction.Amount - transaction.CompanyShare
+}
+
+// creditLimitError returns err wrapped with an explanation if charging amount to the user's
+// account takes the debt past the user's credit limit, and nil otherwise. Users without a
+// credit limit and guests can owe any amount. It must be called with repositories bound to
+// the database transaction that creates the charge, so that concurrent charges cannot both
+// pass the check.
+func creditLimitError(users repository.UserRepositoryInterface, transactions repository.TransactionRepositoryInterface, userID int64, amount float64, err error) error {
+	if userID == 0 || amount <= 0 {
+		return nil
+	}
+	user, lookupErr := users.GetByID(userID)
+	if lookupErr != nil {
+		return lookupErr
+	}
+	if user == nil || user.CreditLimit <= 0 {
+		return nil
+	}
+	balance, lookupErr := transactions.GetUserBalanceByID(userID)
+	if lookupErr != nil {
+		return lookupErr
+	}
+	if owed := roundAmount(amount - balance.Balance); owed > user.CreditLimit {
+		return fmt.Errorf("%w: %s would owe %.2f, more than the credit limit of %.2f", err, user.Name, owed, user.CreditLimit)
+	}
+	return nil
+}
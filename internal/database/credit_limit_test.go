@@ -0,0 +1,100 @@
+package database
+
+import (
+	"sync"
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreditLimitOnPurchases(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "5001")
+	user.CreditLimit = 100
+	require.NoError(t, s.UpdateUser(user))
+
+	createTestTransaction(t, s, user.ID, models.TransactionTypePurchase, 80)
+	over := &models.Transaction{UserID: user.ID, Amount: 30, TransactionType: models.TransactionTypePurchase}
+	assert.ErrorIs(t, s.CreateTransaction(over), ErrCreditLimitExceeded)
+	assert.ErrorIs(t, s.CreateTransactionWithProducts(over, []models.TransactionProduct{{ProductName: "Tea", Quantity: 1, UnitPrice: 30}}), ErrCreditLimitExceeded)
+
+	// Only the employee share is charged to the account, and cash is paid on the spot
+	require.NoError(t, s.CreateTransaction(&models.Transaction{UserID: user.ID, Amount: 30, CompanyShare: 10, TransactionType: models.TransactionTypePurchase}))
+	require.NoError(t, s.CreateTransaction(&models.Transaction{UserID: user.ID, Amount: 500, TransactionType: models.TransactionTypePurchase, PaymentMethod: models.PaymentMethodCash}))
+	assert.Equal(t, -100.0, balanceOf(t, s, user.ID))
+
+	createTestTransaction(t, s, user.ID, models.TransactionTypeDeposit, 50)
+	rows := []models.TransactionImportRow{
+		{Line: 2, Transaction: models.Transaction{UserID: user.ID, Amount: 30, TransactionType: models.TransactionTypePurchase}},
+		{Line: 3, Transaction: models.Transaction{UserID: user.ID, Amount: 30, TransactionType: models.TransactionTypePurchase}},
+	}
+	err := s.ImportTransactions(rows, "IMPORT-1")
+	assert.ErrorIs(t, err, ErrCreditLimitExceeded)
+	assert.ErrorContains(t, err, "line 3")
+	assert.Equal(t, -50.0, balanceOf(t, s, user.ID))
+
+	unlimited := createTestUser(t, s, "5002")
+	createTestTransaction(t, s, unlimited.ID, models.TransactionTypePurchase, 5000)
+	assert.Equal(t, -5000.0, balanceOf(t, s, unlimited.ID), "users without a credit limit can owe any amount")
+}
+
+func TestImportUsersIsAtomic(t *testing.T) {
+	s := newTestService(t)
+	existing := createTestUser(t, s, "5003")
+
+	created := &models.User{Name: "New user", EmployeeId: "5004", Department: "Kitchen", Phone: "03001111111", Active: false}
+	existing.Name = "Renamed"
+	duplicate := &models.User{Name: "Duplicate", EmployeeId: "5003", Department: "Finance"}
+	assert.Error(t, s.ImportUsers([]*models.User{created, existing, duplicate}))
+
+	saved, err := s.GetUserByEmployeeID("5004")
+	require.NoError(t, err)
+	assert.Nil(t, saved, "the new user is rolled back")
+	saved, err = s.GetUserByID(existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "User 5003", saved.Name, "the update is rolled back")
+	departments, err := s.GetAllDepartments()
+	require.NoError(t, err)
+	for _, department := range departments {
+		assert.NotEqual(t, "Kitchen", department.Name, "the new department is rolled back")
+	}
+
+	created.ID, created.DepartmentID = 0, nil
+	require.NoError(t, s.ImportUsers([]*models.User{created, existing}))
+	saved, err = s.GetUserByEmployeeID("5004")
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.False(t, saved.Active)
+	assert.Equal(t, "Kitchen", saved.Department)
+}
+
+func TestCreditLimitOnConcurrentPurchases(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "5005")
+	user.CreditLimit = 100
+	require.NoError(t, s.UpdateUser(user))
+
+	// Each purchase fits the limit on its own, but not together
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.CreateTransaction(&models.Transaction{UserID: user.ID, Amount: 80, TransactionType: models.TransactionTypePurchase})
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, -80.0, balanceOf(t, s, user.ID), "the purchases together stay within the limit")
+}
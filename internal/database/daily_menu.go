@@ -0,0 +1,267 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// ErrInvalidMenu is returned when a daily menu cannot be published as requested
+var ErrInvalidMenu = errors.New("invalid menu")
+
+// ErrInvalidPreOrder is returned when a pre-order cannot be placed as requested
+var ErrInvalidPreOrder = errors.New("invalid pre-order")
+
+// Daily menu operations
+func (s *service) InitDailyMenuTable() error {
+	return s.dailyMenuRepository.InitTable()
+}
+
+func (s *service) GetDailyMenu(date string) (*models.DailyMenu, error) {
+	items, err := s.dailyMenuRepository.GetItems(date)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DailyMenu{Date: date, Items: items}, nil
+}
+
+// PublishDailyMenu sets the items of the menu of a date. Items without a unit use the
+// default unit of their product. Items left off the menu are removed, which is refused
+// for items that were already pre-ordered.
+func (s *service) PublishDailyMenu(date string, items []models.MenuItem) (*models.DailyMenu, error) {
+	listed := make(map[int64]bool, len(items))
+	for i := range items {
+		item := &items[i]
+		item.MenuDate = date
+
+		var unit *models.ProductUnit
+		var err error
+		if item.UnitID == 0 {
+			unit, err = s.productUnitRepository.GetDefault(item.ProductID)
+		} else {
+			unit, err = s.productUnitRepository.Get(item.UnitID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if unit == nil || unit.ProductID != item.ProductID {
+			return nil, fmt.Errorf("%w: product %d has no such unit", ErrInvalidMenu, item.ProductID)
+		}
+		if listed[unit.ID] {
+			return nil, fmt.Errorf("%w: unit %s of product %d is listed more than once", ErrInvalidMenu, unit.Name, item.ProductID)
+		}
+		item.UnitID = unit.ID
+		listed[unit.ID] = true
+	}
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		menu := s.dailyMenuRepository.WithTx(tx)
+		existing, err := menu.GetItems(date)
+		if err != nil {
+			return err
+		}
+		for _, item := range existing {
+			if listed[item.UnitID] {
+				continue
+			}
+			orders, err := menu.CountActiveOrders(item.ID)
+			if err != nil {
+				return err
+			}
+			if orders > 0 {
+				return fmt.Errorf("%w: %s (%s) already has %d pre-orders and cannot be removed", ErrInvalidMenu, item.ProductName, item.UnitName, orders)
+			}
+			if err := menu.DeleteItem(item.ID); err != nil {
+				return err
+			}
+		}
+
+		for i := range items {
+			if err := menu.SaveItem(&items[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDailyMenu(date)
+}
+
+// PlacePreOrders sets the quantities a user pre-orders from the menu of a date and returns
+// the user's pending pre-orders for that date. Quantities replace earlier pre-orders of the
+// same item, and a quantity of zero cancels them.
+func (s *service) PlacePreOrders(userID int64, date string, lines []models.PreOrderLine) ([]models.PreOrder, error) {
+	if err := s.offboardedError(userID, ErrInvalidPreOrder); err != nil {
+		return nil, err
+	}
+	err := s.withTx(func(tx *sql.Tx) error {
+		menu := s.dailyMenuRepository.WithTx(tx)
+		for _, line := range lines {
+			item, err := menu.GetItem(line.MenuItemID)
+			if err != nil {
+				return err
+			}
+			if item == nil || item.MenuDate != date {
+				return fmt.Errorf("%w: item %d is not on the menu of %s", ErrInvalidPreOrder, line.MenuItemID, date)
+			}
+			if line.Quantity < 0 {
+				return fmt.Errorf("%w: quantity of %s must not be negative", ErrInvalidPreOrder, item.ProductName)
+			}
+
+			existing, err := menu.GetPendingOrder(item.ID, userID)
+			if err != nil {
+				return err
+			}
+			if line.Quantity == 0 {
+				if existing != nil {
+					if _, err := menu.CancelOrder(existing.ID); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			if item.Remaining != nil {
+				available := *item.Remaining
+				if existing != nil {
+					available += existing.Quantity
+				}
+				if line.Quantity > available {
+					return fmt.Errorf("%w: only %d of %s (%s) left", ErrInvalidPreOrder, available, item.ProductName, item.UnitName)
+				}
+			}
+
+			if existing != nil {
+				err = menu.UpdateOrderQuantity(existing.ID, line.Quantity)
+			} else {
+				err = menu.CreateOrder(&models.PreOrder{MenuItemID: item.ID, UserID: userID, Quantity: line.Quantity})
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.dailyMenuRepository.GetPendingOrdersForUser(userID, date)
+}
+
+func (s *service) GetPreOrder(id int64) (*models.PreOrder, error) {
+	return s.dailyMenuRepository.GetOrder(id)
+}
+
+func (s *service) CancelPreOrder(id int64) (bool, error) {
+	return s.dailyMenuRepository.CancelOrder(id)
+}
+
+// GetMenuOrderTally returns the quantities pre-ordered of each item on the menu of a date,
+// with the pending and collected pre-orders
+func (s *service) GetMenuOrderTally(date string) (*models.MenuOrderTally, error) {
+	items, err := s.dailyMenuRepository.GetItems(date)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.dailyMenuRepository.GetOrders(date)
+	if err != nil {
+		return nil, err
+	}
+
+	tally := &models.MenuOrderTally{
+		Date:   date,
+		Items:  make([]models.MenuItemTally, 0, len(items)),
+		Orders: orders,
+	}
+	index := make(map[int64]int, len(items))
+	for _, item := range items {
+		index[item.ID] = len(tally.Items)
+		tally.Items = append(tally.Items, models.MenuItemTally{
+			MenuItemID:    item.ID,
+			ProductID:     item.ProductID,
+			ProductName:   item.ProductName,
+			UnitName:      item.UnitName,
+			QuantityLimit: item.QuantityLimit,
+			Remaining:     item.Remaining,
+		})
+	}
+	for _, order := range orders {
+		item := &tally.Items[index[order.MenuItemID]]
+		if order.Status == models.PreOrderStatusCollected {
+			item.Collected += order.Quantity
+		} else {
+			item.Pending += order.Quantity
+		}
+		tally.TotalQuantity += order.Quantity
+	}
+	return tally, nil
+}
+
+// CollectPreOrders turns the pending pre-orders of a user for a date into a single purchase
+// at the current unit prices and pricing rules. It returns nil if the user has no pending pre-orders.
+func (s *service) CollectPreOrders(userID int64, date string) (*models.PreOrderCollection, error) {
+	orders, err := s.dailyMenuRepository.GetPendingOrdersForUser(userID, date)
+	if err != nil || len(orders) == 0 {
+		return nil, err
+	}
+
+	products := make([]models.TransactionProduct, 0, len(orders))
+	for _, order := range orders {
+		products = append(products, models.TransactionProduct{
+			ProductID:   order.ProductID,
+			ProductName: order.ProductName,
+			Quantity:    order.Quantity,
+			UnitID:      &order.UnitID,
+		})
+	}
+	if err := s.resolveSaleUnits(products); err != nil {
+		return nil, err
+	}
+
+	transaction := models.Transaction{
+		UserID:          userID,
+		Description:     "Pre-order for " + date,
+		TransactionType: models.TransactionTypePurchase,
+	}
+	for _, product := range products {
+		transaction.Amount += product.UnitPrice * float64(product.Quantity)
+	}
+	if err := s.applyPricingRules(&transaction, products); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = s.withTx(func(tx *sql.Tx) error {
+		transactions := s.transactionRepository.WithTx(tx)
+		if err := userOffboardedError(s.offboardingRepository.WithTx(tx), userID, ErrInvalidPreOrder); err != nil {
+			return err
+		}
+		if err := creditLimitError(s.userRepository.WithTx(tx), transactions, userID, accountCharge(&transaction), ErrInvalidPreOrder); err != nil {
+			return err
+		}
+		if err := createTransactionWithProducts(transactions, s.transactionProductRepository.WithTx(tx), &transaction, products); err != nil {
+			return err
+		}
+		menu := s.dailyMenuRepository.WithTx(tx)
+		for _, order := range orders {
+			if err := menu.MarkCollected(order.ID, transaction.ID, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range orders {
+		orders[i].Status = models.PreOrderStatusCollected
+		orders[i].TransactionID = &transaction.ID
+		orders[i].CollectedAt = &now
+	}
+	return &models.PreOrderCollection{Transaction: transaction, Orders: orders}, nil
+}
@@ -33,20 +33,26 @@ type Service interface {
 	// User-related operations
 	InitUserTable() error
 	CreateUser(user *models.User) error
-	GetAllUsers() ([]models.User, error)
+	GetAllUsers(includeDeleted bool) ([]models.User, error)
 	GetUser(id int64) (*models.User, error)
+	GetUserByID(id int64) (*models.User, error)
 	UpdateUser(user *models.User) error
+	ImportUsers(users []*models.User) error
 	DeleteUser(id int64) error
+	RestoreUser(id int64) (bool, error)
 	UpdateLastNotificationTime(id string) error
+	GetUserByEmployeeID(employeeID string) (*models.User, error)
 
 	// Transaction-related operations
 	InitTransactionTable() error
 	CreateTransaction(transaction *models.Transaction) error
-	GetAllTransactions() ([]models.Transaction, error)
+	GetAllTransactions(includeDeleted bool) ([]models.Transaction, error)
 	GetLatestTransactions(limit int) ([]models.Transaction, error)
-	GetTransaction(id int64) (*models.Transaction, error)
+	GetTransaction(id int64, includeDeleted bool) (*models.Transaction, error)
 	UpdateTransaction(transaction *models.Transaction) error
 	DeleteTransaction(id int64) error
+	RestoreTransaction(id int64) (bool, error)
+	RefundTransaction(id int64, lines []models.RefundLine, reason string, cashSessionID *int64) (*models.Refund, error)
 	GetTransactionsByUserID(userID int64, limit int) ([]models.EmployeeTransaction, error)
 	GetTransactionsByDateRange(startDate, endDate time.Time) ([]models.Transaction, error)
 	GetUsersBalances() ([]models.UserBalance, error)
@@ -55,10 +61,143 @@ type Service interface {
 	// Product-related operations
 	InitProductTable() error
 	CreateProduct(product *models.Product) error
-	GetAllProducts() ([]models.Product, error)
-	GetProduct(id int64) (*models.Product, error)
+	GetAllProducts(includeDeleted bool) ([]models.Product, error)
+	GetProduct(id int64, includeDeleted bool) (*models.Product, error)
 	UpdateProduct(product *models.Product) error
 	DeleteProduct(id int64) error
+	RestoreProduct(id int64) (bool, error)
+	GetProductByBarcode(barcode string) (*models.Product, error)
+	SaveProductImage(image *models.ProductImage) error
+	GetProductImage(productID int64) (*models.ProductImage, error)
+	DeleteProductImage(productID int64) (bool, error)
+
+	// Product unit operations
+	InitProductUnitTable() error
+	GetProductUnits(productID int64) ([]models.ProductUnit, error)
+	GetProductUnit(id int64) (*models.ProductUnit, error)
+	CreateProductUnit(unit *models.ProductUnit) error
+	UpdateProductUnit(unit *models.ProductUnit) error
+	DeleteProductUnit(id int64) error
+
+	// Daily menu operations
+	InitDailyMenuTable() error
+	GetDailyMenu(date string) (*models.DailyMenu, error)
+	PublishDailyMenu(date string, items []models.MenuItem) (*models.DailyMenu, error)
+	PlacePreOrders(userID int64, date string, lines []models.PreOrderLine) ([]models.PreOrder, error)
+	GetPreOrder(id int64) (*models.PreOrder, error)
+	CancelPreOrder(id int64) (bool, error)
+	GetMenuOrderTally(date string) (*models.MenuOrderTally, error)
+	CollectPreOrders(userID int64, date string) (*models.PreOrderCollection, error)
+
+	// Product price history operations
+	InitProductPriceTable() error
+	GetProductPrice(id int64) (*models.ProductPrice, error)
+	GetProductPriceTimeline(productID int64) ([]models.ProductPrice, error)
+	ScheduleProductPrice(price *models.ProductPrice) error
+	CancelScheduledProductPrice(id int64) (bool, error)
+	ApplyDueProductPrices(now time.Time) ([]models.ProductPrice, error)
+	GetPriceImpactReport(startDate, endDate time.Time) (*models.PriceImpactReport, error)
+
+	// Pricing rule operations
+	InitPricingRuleTable() error
+	CreatePricingRule(rule *models.PricingRule) error
+	GetAllPricingRules(activeOnly bool) ([]models.PricingRule, error)
+	GetPricingRule(id int64) (*models.PricingRule, error)
+	UpdatePricingRule(rule *models.PricingRule) error
+	DeletePricingRule(id int64) error
+
+	// Payment reconciliation operations
+	InitPaymentTable() error
+	ImportPaymentStatement(statement *models.PaymentStatement) error
+	GetPaymentStatements() ([]models.PaymentStatement, error)
+	GetPaymentStatement(id int64) (*models.PaymentStatement, error)
+	GetPaymentReviewQueue(status string) ([]models.PaymentLine, error)
+	GetPaymentLine(id int64) (*models.PaymentLine, error)
+	ApprovePaymentLine(id int64, userID *int64, reviewedBy string) (*models.PaymentApproval, error)
+	RejectPaymentLine(id int64, reviewedBy, note string) (bool, error)
+
+	// Pending deposit operations
+	InitPendingDepositTable() error
+	GetUserByPhone(number string) (*models.User, error)
+	CreatePendingDeposit(deposit *models.PendingDeposit) (bool, error)
+	GetPendingDeposits(status string) ([]models.PendingDeposit, error)
+	GetPendingDeposit(id int64) (*models.PendingDeposit, error)
+	ApprovePendingDeposit(id int64, amount float64, reviewedBy, note string) (*models.PendingDepositApproval, error)
+	RejectPendingDeposit(id int64, reviewedBy, note string) (bool, error)
+
+	// Cash session operations
+	InitCashSessionTable() error
+	OpenCashSession(session *models.CashSession) error
+	GetCashSessions() ([]models.CashSession, error)
+	GetCashSession(id int64) (*models.CashSession, error)
+	GetOpenCashSession(cashier string) (*models.CashSession, error)
+	CloseCashSession(id int64, countedAmount float64, closedBy, note string) (*models.CashSession, error)
+
+	// Balance transfer operations
+	InitBalanceTransferTable() error
+	CreateBalanceTransfer(transfer *models.BalanceTransfer, requireApproval bool) error
+	GetBalanceTransfers(status string) ([]models.BalanceTransfer, error)
+	GetBalanceTransfer(id int64) (*models.BalanceTransfer, error)
+	GetUserBalanceTransfers(userID int64) ([]models.BalanceTransfer, error)
+	ApproveBalanceTransfer(id int64, approvedBy string) (*models.BalanceTransfer, error)
+	RejectBalanceTransfer(id int64, rejectedBy, note string) (bool, error)
+
+	// Offboarding operations
+	InitOffboardingTable() error
+	GetFinalStatement(userID int64) (*models.FinalStatement, error)
+	OffboardUser(userID int64, method, reason, processedBy string, cashSessionID *int64) (*models.FinalStatement, error)
+	GetOffboardings() ([]models.Offboarding, error)
+	GetWriteOffReport(startDate, endDate time.Time) (*models.WriteOffReport, error)
+
+	// Wallet operations
+	InitWalletTable() error
+	CreateTopUpBonusRule(rule *models.TopUpBonusRule) error
+	GetTopUpBonusRules(activeOnly bool) ([]models.TopUpBonusRule, error)
+	GetTopUpBonusRule(id int64) (*models.TopUpBonusRule, error)
+	UpdateTopUpBonusRule(rule *models.TopUpBonusRule) error
+	DeleteTopUpBonusRule(id int64) error
+	TopUpWallet(topUp *models.TopUp, cashSessionID *int64) error
+	GetTopUps(userID int64) ([]models.TopUp, error)
+	GetPrepaidLiability() (*models.PrepaidLiability, error)
+
+	// Installment plan operations
+	InitInstallmentPlanTable() error
+	CreateInstallmentPlan(plan *models.InstallmentPlan, firstDueDate time.Time) error
+	GetInstallmentPlans(status string, missedOnly bool) ([]models.InstallmentPlan, error)
+	GetUserInstallmentPlans(userID int64) ([]models.InstallmentPlan, error)
+	GetInstallmentPlan(id int64) (*models.InstallmentPlan, error)
+	GetActiveInstallmentPlan(userID int64) (*models.InstallmentPlan, error)
+	PayInstallmentPlan(id int64, amount float64, paymentMethod, note string, cashSessionID *int64) (*models.InstallmentPlan, error)
+	CancelInstallmentPlan(id int64) (bool, error)
+
+	// Aging and escalation operations
+	InitEscalationTable() error
+	GetAgingReport() (*models.AgingReport, error)
+	GetDueEscalations() ([]models.UserAging, error)
+	RecordEscalationNotice(notice *models.EscalationNotice) error
+	GetEscalationNotices(since time.Time) ([]models.EscalationNotice, error)
+	CreateEscalationLevel(level *models.EscalationLevel) error
+	GetEscalationLevels() ([]models.EscalationLevel, error)
+	GetEscalationLevel(id int64) (*models.EscalationLevel, error)
+	UpdateEscalationLevel(level *models.EscalationLevel) error
+	DeleteEscalationLevel(id int64) error
+
+	// Analytics operations, cached for a few minutes
+	GetAnalyticsSummary(startDate, endDate time.Time) (*models.AnalyticsSummary, error)
+	GetRevenueSeries(startDate, endDate time.Time, granularity string) (*models.RevenueSeries, error)
+	GetSalesHeatmap(startDate, endDate time.Time) ([]models.HeatmapCell, error)
+	GetDailyCustomers(startDate, endDate time.Time) ([]models.DailyCustomers, error)
+	GetTopProducts(startDate, endDate time.Time, limit int) ([]models.TopProduct, error)
+	GetTopUsers(startDate, endDate time.Time, limit int) ([]models.TopUser, error)
+
+	// Category and menu operations
+	InitCategoryTable() error
+	CreateCategory(category *models.Category) error
+	GetAllCategories() ([]models.Category, error)
+	GetCategory(id int64) (*models.Category, error)
+	UpdateCategory(category *models.Category) error
+	DeleteCategory(id int64) error
+	GetMenu() ([]models.MenuCategory, error)
 
 	// Transaction product operations
 	InitTransactionProductTable() error
@@ -69,6 +208,39 @@ type Service interface {
 
 	// Transaction creation with products
 	CreateTransactionWithProducts(transaction *models.Transaction, products []models.TransactionProduct) error
+	ImportTransactions(rows []models.TransactionImportRow, batchReference string) error
+
+	// Department-related operations
+	InitDepartmentTable() error
+	CreateDepartment(department *models.Department) error
+	GetAllDepartments() ([]models.Department, error)
+	GetDepartment(id int64) (*models.Department, error)
+	UpdateDepartment(department *models.Department) error
+	DeleteDepartment(id int64) error
+	GetDepartmentMonthlySpend(startDate, endDate time.Time) ([]models.DepartmentSpend, error)
+	GetDepartmentTopConsumers(startDate, endDate time.Time, limit int) ([]models.DepartmentTopConsumer, error)
+	GetDepartmentProductConsumption(startDate, endDate time.Time) ([]models.DepartmentProductConsumption, error)
+	GetDepartmentBalances() ([]models.DepartmentBalance, error)
+
+	// Payroll deduction operations
+	GetPayrollDeductions(period time.Time) ([]models.PayrollDeduction, error)
+	SettlePayrollDeductions(period time.Time, userIDs []int64) (*models.PayrollSettlement, error)
+	GetEmployerInvoice(period time.Time) (*models.EmployerInvoice, error)
+
+	// Backup and restore operations
+	BackupTo(path string) error
+	ExportBackup() (*models.BackupBundle, error)
+	RestoreBackup(bundle *models.BackupBundle, overwrite bool) (*models.RestoreResult, error)
+	RunScheduledBackup(dir string, retention int) (string, error)
+
+	// Audit log operations
+	InitAuditTable() error
+	CreateAuditLog(entry *models.AuditLog) error
+	GetAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, error)
+	PurgeAuditLogs(before time.Time) (int64, error)
+
+	// Soft delete operations
+	PurgeDeleted(before time.Time) (*models.PurgeResult, error)
 }
 
 type service struct {
@@ -77,7 +249,25 @@ type service struct {
 	userRepository               repository.UserRepositoryInterface
 	transactionRepository        repository.TransactionRepositoryInterface
 	productRepository            repository.ProductRepositoryInterface
+	categoryRepository           repository.CategoryRepositoryInterface
 	transactionProductRepository repository.TransactionProductRepositoryInterface
+	productUnitRepository        repository.ProductUnitRepositoryInterface
+	productPriceRepository       repository.ProductPriceRepositoryInterface
+	dailyMenuRepository          repository.DailyMenuRepositoryInterface
+	pricingRuleRepository        repository.PricingRuleRepositoryInterface
+	paymentRepository            repository.PaymentRepositoryInterface
+	pendingDepositRepository     repository.PendingDepositRepositoryInterface
+	cashSessionRepository        repository.CashSessionRepositoryInterface
+	balanceTransferRepository    repository.BalanceTransferRepositoryInterface
+	offboardingRepository        repository.OffboardingRepositoryInterface
+	walletRepository             repository.WalletRepositoryInterface
+	installmentPlanRepository    repository.InstallmentPlanRepositoryInterface
+	escalationRepository         repository.EscalationRepositoryInterface
+	analyticsRepository          repository.AnalyticsRepositoryInterface
+	departmentRepository         repository.DepartmentRepositoryInterface
+	backupRepository             repository.BackupRepositoryInterface
+	auditRepository              repository.AuditRepositoryInterface
+	analyticsCache               *analyticsCache
 }
 
 var (
@@ -122,19 +312,42 @@ func New() Service {
 		log.Fatalf("Error opening database: %v", err)
 	}
 
+	dbInstance = newService(db)
+	log.Info("Connected to database:", dburl)
+	return dbInstance
+}
+
+// newService wires the repositories of the service to an open database
+func newService(db *sql.DB) *service {
 	// Create repository factory
 	repoFactory := repository.NewRepositoryFactory(db)
 
-	dbInstance = &service{
+	return &service{
 		db:                           db,
 		repositoryFactory:            repoFactory,
 		userRepository:               repoFactory.NewUserRepository(),
 		transactionRepository:        repoFactory.NewTransactionRepository(),
 		productRepository:            repoFactory.NewProductRepository(),
+		categoryRepository:           repoFactory.NewCategoryRepository(),
 		transactionProductRepository: repoFactory.NewTransactionProductRepository(),
+		productUnitRepository:        repoFactory.NewProductUnitRepository(),
+		productPriceRepository:       repoFactory.NewProductPriceRepository(),
+		dailyMenuRepository:          repoFactory.NewDailyMenuRepository(),
+		pricingRuleRepository:        repoFactory.NewPricingRuleRepository(),
+		paymentRepository:            repoFactory.NewPaymentRepository(),
+		pendingDepositRepository:     repoFactory.NewPendingDepositRepository(),
+		cashSessionRepository:        repoFactory.NewCashSessionRepository(),
+		balanceTransferRepository:    repoFactory.NewBalanceTransferRepository(),
+		offboardingRepository:        repoFactory.NewOffboardingRepository(),
+		walletRepository:             repoFactory.NewWalletRepository(),
+		installmentPlanRepository:    repoFactory.NewInstallmentPlanRepository(),
+		escalationRepository:         repoFactory.NewEscalationRepository(),
+		analyticsRepository:          repoFactory.NewAnalyticsRepository(),
+		departmentRepository:         repoFactory.NewDepartmentRepository(),
+		backupRepository:             repoFactory.NewBackupRepository(),
+		auditRepository:              repoFactory.NewAuditRepository(),
+		analyticsCache:               &analyticsCache{entries: make(map[string]analyticsCacheEntry)},
 	}
-	log.Info("Connected to database:", dburl)
-	return dbInstance
 }
 
 // Health checks the health of the database connection by pinging the database.
@@ -202,17 +415,38 @@ func (s *service) GetDB() *sql.DB {
 	return s.db
 }
 
+// withTx runs fn inside a database transaction. The transaction is rolled back
+// if fn returns an error and committed otherwise.
+func (s *service) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Errorf("Error rolling back transaction: %v", rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // User-related operations
 func (s *service) InitUserTable() error {
 	return s.userRepository.InitTable()
 }
 
 func (s *service) CreateUser(user *models.User) error {
+	if err := resolveUserDepartment(s.departmentRepository, user); err != nil {
+		return err
+	}
 	return s.userRepository.Create(user)
 }
 
-func (s *service) GetAllUsers() ([]models.User, error) {
-	return s.userRepository.GetAll()
+func (s *service) GetAllUsers(includeDeleted bool) ([]models.User, error) {
+	return s.userRepository.GetAll(includeDeleted)
 }
 
 func (s *service) GetUser(id int64) (*models.User, error) {
@@ -220,46 +454,185 @@ func (s *service) GetUser(id int64) (*models.User, error) {
 }
 
 func (s *service) UpdateUser(user *models.User) error {
+	if err := resolveUserDepartment(s.departmentRepository, user); err != nil {
+		return err
+	}
 	return s.userRepository.Update(user)
 }
 
+// ImportUsers creates the users without an ID and updates the others in a single database
+// transaction, so that nothing is saved if any of them fails
+func (s *service) ImportUsers(users []*models.User) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		userRepository := s.userRepository.WithTx(tx)
+		departmentRepository := s.departmentRepository.WithTx(tx)
+		for _, user := range users {
+			if err := resolveUserDepartment(departmentRepository, user); err != nil {
+				return err
+			}
+			if user.ID != 0 {
+				if err := userRepository.Update(user); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Create always makes users active
+			active := user.Active
+			if err := userRepository.Create(user); err != nil {
+				return err
+			}
+			if !active {
+				user.Active = false
+				if err := userRepository.Update(user); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// resolveUserDepartment keeps the user's department name and department_id in sync.
+// A known department_id wins; otherwise the department is looked up (or created) by name.
+func resolveUserDepartment(departmentRepository repository.DepartmentRepositoryInterface, user *models.User) error {
+	if user.DepartmentID != nil {
+		department, err := departmentRepository.Get(*user.DepartmentID)
+		if err != nil {
+			return err
+		}
+		if department != nil {
+			user.Department = department.Name
+			return nil
+		}
+		user.DepartmentID = nil
+	}
+
+	name := strings.TrimSpace(user.Department)
+	if name == "" {
+		return nil
+	}
+	department, err := departmentRepository.GetByName(name)
+	if err != nil {
+		return err
+	}
+	if department == nil {
+		department = &models.Department{Name: name}
+		if err := departmentRepository.Create(department); err != nil {
+			return err
+		}
+	}
+	user.Department = department.Name
+	user.DepartmentID = &department.ID
+	return nil
+}
+
 func (s *service) DeleteUser(id int64) error {
 	return s.userRepository.Delete(id)
 }
 
+func (s *service) RestoreUser(id int64) (bool, error) {
+	return s.userRepository.Restore(id)
+}
+
 func (s *service) UpdateLastNotificationTime(id string) error {
 	return s.userRepository.UpdateLastNotificationTime(id)
 }
 
+func (s *service) GetUserByEmployeeID(employeeID string) (*models.User, error) {
+	return s.userRepository.GetByEmployeeID(employeeID)
+}
+
+func (s *service) GetUserByID(id int64) (*models.User, error) {
+	return s.userRepository.GetByID(id)
+}
+
 // Transaction-related operations
 func (s *service) InitTransactionTable() error {
 	return s.transactionRepository.InitTable()
 }
 
+// CreateTransaction creates a transaction. The balance of an offboarded user is settled with
+// the final statement, so no transactions can be created for them. Purchases charged to the
+// account must stay within the user's credit limit.
 func (s *service) CreateTransaction(transaction *models.Transaction) error {
-	return s.transactionRepository.Create(transaction)
+	return s.withTx(func(tx *sql.Tx) error {
+		transactions := s.transactionRepository.WithTx(tx)
+		if err := userOffboardedError(s.offboardingRepository.WithTx(tx), transaction.UserID, ErrUserOffboarded); err != nil {
+			return err
+		}
+		if err := creditLimitError(s.userRepository.WithTx(tx), transactions, transaction.UserID, accountCharge(transaction), ErrCreditLimitExceeded); err != nil {
+			return err
+		}
+		return transactions.Create(transaction)
+	})
 }
 
-func (s *service) GetAllTransactions() ([]models.Transaction, error) {
-	return s.transactionRepository.GetAll()
+func (s *service) GetAllTransactions(includeDeleted bool) ([]models.Transaction, error) {
+	return s.transactionRepository.GetAll(includeDeleted)
 }
 
 func (s *service) GetLatestTransactions(limit int) ([]models.Transaction, error) {
 	return s.transactionRepository.GetLatest(limit)
 }
 
-func (s *service) GetTransaction(id int64) (*models.Transaction, error) {
-	return s.transactionRepository.Get(id)
+func (s *service) GetTransaction(id int64, includeDeleted bool) (*models.Transaction, error) {
+	return s.transactionRepository.Get(id, includeDeleted)
 }
 
+// UpdateTransaction updates a transaction, unless it belongs or is moved to an offboarded user
 func (s *service) UpdateTransaction(transaction *models.Transaction) error {
+	existing, err := s.transactionRepository.Get(transaction.ID, false)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if err := s.offboardedError(existing.UserID, ErrUserOffboarded); err != nil {
+			return err
+		}
+	}
+	if err := s.offboardedError(transaction.UserID, ErrUserOffboarded); err != nil {
+		return err
+	}
 	return s.transactionRepository.Update(transaction)
 }
 
+// transactionOffboardedError returns ErrUserOffboarded if the transaction with the given ID
+// belongs to an offboarded user, whose balance must not change any more
+func (s *service) transactionOffboardedError(id int64) error {
+	transaction, err := s.transactionRepository.Get(id, true)
+	if err != nil || transaction == nil {
+		return err
+	}
+	return s.offboardedError(transaction.UserID, ErrUserOffboarded)
+}
+
+// DeleteTransaction soft deletes a transaction. Purchases cannot be deleted while refunds of
+// them exist, as that would leave the refunds crediting a purchase that no longer counts.
+// Transactions of offboarded users cannot be deleted either.
 func (s *service) DeleteTransaction(id int64) error {
+	if err := s.transactionOffboardedError(id); err != nil {
+		return err
+	}
+	refunded, err := s.transactionProductRepository.GetRefundedQuantities(id)
+	if err != nil {
+		return err
+	}
+	if len(refunded) > 0 {
+		return fmt.Errorf("%w: delete the refunds of transaction %d first", ErrPurchaseRefunded, id)
+	}
 	return s.transactionRepository.Delete(id)
 }
 
+// RestoreTransaction undoes the soft delete of a transaction, unless it belongs to an
+// offboarded user
+func (s *service) RestoreTransaction(id int64) (bool, error) {
+	if err := s.transactionOffboardedError(id); err != nil {
+		return false, err
+	}
+	return s.transactionRepository.Restore(id)
+}
+
 func (s *service) GetTransactionsByUserID(userID int64, limit int) ([]models.EmployeeTransaction, error) {
 	return s.transactionRepository.GetByUserID(userID, limit)
 }
@@ -281,26 +654,81 @@ func (s *service) InitProductTable() error {
 	return s.productRepository.InitTable()
 }
 
+// CreateProduct creates a product and its units. Products created without units get the
+// default units, and the default unit's price becomes the product price.
 func (s *service) CreateProduct(product *models.Product) error {
-	return s.productRepository.Create(product)
+	if err := s.productRepository.Create(product); err != nil {
+		return err
+	}
+	if err := s.createProductUnits(product); err != nil {
+		return err
+	}
+	return s.recordPriceChanges(product.ID)
 }
 
-func (s *service) GetAllProducts() ([]models.Product, error) {
-	return s.productRepository.GetAll()
+func (s *service) GetAllProducts(includeDeleted bool) ([]models.Product, error) {
+	products, err := s.productRepository.GetAll(includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return products, s.attachProductUnits(products)
 }
 
-func (s *service) GetProduct(id int64) (*models.Product, error) {
-	return s.productRepository.Get(id)
+func (s *service) GetProduct(id int64, includeDeleted bool) (*models.Product, error) {
+	product, err := s.productRepository.Get(id, includeDeleted)
+	if err != nil || product == nil {
+		return product, err
+	}
+	product.Units, err = s.productUnitRepository.GetByProductID(id)
+	return product, err
 }
 
+// UpdateProduct updates a product and sets the price of its default unit to the product
+// price. The units themselves are changed through the product unit operations.
 func (s *service) UpdateProduct(product *models.Product) error {
-	return s.productRepository.Update(product)
+	if err := s.productRepository.Update(product); err != nil {
+		return err
+	}
+	if err := s.productUnitRepository.SetDefaultPrice(product.ID, product.Price); err != nil {
+		return err
+	}
+	if err := s.recordPriceChanges(product.ID); err != nil {
+		return err
+	}
+	units, err := s.productUnitRepository.GetByProductID(product.ID)
+	product.Units = units
+	return err
 }
 
 func (s *service) DeleteProduct(id int64) error {
 	return s.productRepository.Delete(id)
 }
 
+func (s *service) RestoreProduct(id int64) (bool, error) {
+	return s.productRepository.Restore(id)
+}
+
+func (s *service) GetProductByBarcode(barcode string) (*models.Product, error) {
+	product, err := s.productRepository.GetByBarcode(barcode)
+	if err != nil || product == nil {
+		return product, err
+	}
+	product.Units, err = s.productUnitRepository.GetByProductID(product.ID)
+	return product, err
+}
+
+func (s *service) SaveProductImage(image *models.ProductImage) error {
+	return s.productRepository.SaveImage(image)
+}
+
+func (s *service) GetProductImage(productID int64) (*models.ProductImage, error) {
+	return s.productRepository.GetImage(productID)
+}
+
+func (s *service) DeleteProductImage(productID int64) (bool, error) {
+	return s.productRepository.DeleteImage(productID)
+}
+
 // Transaction product operations
 func (s *service) InitTransactionProductTable() error {
 	return s.transactionProductRepository.InitTable()
@@ -322,29 +750,152 @@ func (s *service) GetTransactionProductDetails(startDate, endDate time.Time) ([]
 	return s.transactionProductRepository.GetTransactionProductDetails(startDate, endDate)
 }
 
-// CreateTransactionWithProducts creates a transaction and its associated products in a single transaction
+// Department-related operations
+func (s *service) InitDepartmentTable() error {
+	return s.departmentRepository.InitTable()
+}
+
+func (s *service) CreateDepartment(department *models.Department) error {
+	return s.departmentRepository.Create(department)
+}
+
+func (s *service) GetAllDepartments() ([]models.Department, error) {
+	return s.departmentRepository.GetAll()
+}
+
+func (s *service) GetDepartment(id int64) (*models.Department, error) {
+	return s.departmentRepository.Get(id)
+}
+
+func (s *service) UpdateDepartment(department *models.Department) error {
+	return s.departmentRepository.Update(department)
+}
+
+// DeleteDepartment deletes a department, removing its users from it and deactivating its
+// pricing rules, which would otherwise apply to every user
+func (s *service) DeleteDepartment(id int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		departments := s.departmentRepository.WithTx(tx)
+		department, err := departments.Get(id)
+		if err != nil || department == nil {
+			return err
+		}
+		if err := s.userRepository.WithTx(tx).ClearDepartment(department); err != nil {
+			return err
+		}
+		if err := s.pricingRuleRepository.WithTx(tx).DeactivateDepartment(id); err != nil {
+			return err
+		}
+		return departments.Delete(id)
+	})
+}
+
+func (s *service) GetDepartmentMonthlySpend(startDate, endDate time.Time) ([]models.DepartmentSpend, error) {
+	return s.departmentRepository.GetMonthlySpend(startDate, endDate)
+}
+
+func (s *service) GetDepartmentTopConsumers(startDate, endDate time.Time, limit int) ([]models.DepartmentTopConsumer, error) {
+	return s.departmentRepository.GetTopConsumers(startDate, endDate, limit)
+}
+
+func (s *service) GetDepartmentProductConsumption(startDate, endDate time.Time) ([]models.DepartmentProductConsumption, error) {
+	return s.departmentRepository.GetProductConsumption(startDate, endDate)
+}
+
+func (s *service) GetDepartmentBalances() ([]models.DepartmentBalance, error) {
+	return s.departmentRepository.GetBalances()
+}
+
+// CreateTransactionWithProducts creates a transaction and its associated products in a single
+// transaction, after applying the pricing rules to the products of purchases. Purchases
+// charged to the account must stay within the user's credit limit.
 func (s *service) CreateTransactionWithProducts(transaction *models.Transaction, products []models.TransactionProduct) error {
-	// Start a database transaction
-	tx, err := s.db.Begin()
-	if err != nil {
+	if err := s.resolveSaleUnits(products); err != nil {
+		return err
+	}
+	if err := s.applyPricingRules(transaction, products); err != nil {
 		return err
 	}
+	return s.withTx(func(tx *sql.Tx) error {
+		transactions := s.transactionRepository.WithTx(tx)
+		if err := userOffboardedError(s.offboardingRepository.WithTx(tx), transaction.UserID, ErrUserOffboarded); err != nil {
+			return err
+		}
+		if err := creditLimitError(s.userRepository.WithTx(tx), transactions, transaction.UserID, accountCharge(transaction), ErrCreditLimitExceeded); err != nil {
+			return err
+		}
+		return createTransactionWithProducts(transactions, s.transactionProductRepository.WithTx(tx), transaction, products)
+	})
+}
+
+// ImportTransactions creates all imported transactions and their products in a single
+// database transaction. Every transaction is tagged with batchReference. Pricing rules are
+// not applied, since imported amounts are what was actually charged. Nothing is imported if
+// any row belongs to an offboarded user, or takes a user past the credit limit.
+func (s *service) ImportTransactions(rows []models.TransactionImportRow, batchReference string) error {
+	for i := range rows {
+		if err := s.resolveSaleUnits(rows[i].Products); err != nil {
+			return fmt.Errorf("line %d: %w", rows[i].Line, err)
+		}
+	}
+	return s.withTx(func(tx *sql.Tx) error {
+		users := s.userRepository.WithTx(tx)
+		offboardings := s.offboardingRepository.WithTx(tx)
+		transactionRepository := s.transactionRepository.WithTx(tx)
+		transactionProductRepository := s.transactionProductRepository.WithTx(tx)
+		for i := range rows {
+			// The balance read includes the rows imported before, so limits apply cumulatively
+			userID := rows[i].Transaction.UserID
+			if err := userOffboardedError(offboardings, userID, ErrUserOffboarded); err != nil {
+				return fmt.Errorf("line %d: %w", rows[i].Line, err)
+			}
+			if err := creditLimitError(users, transactionRepository, userID, accountCharge(&rows[i].Transaction), ErrCreditLimitExceeded); err != nil {
+				return fmt.Errorf("line %d: %w", rows[i].Line, err)
+			}
+			rows[i].Transaction.BatchReference = batchReference
+			if err := createTransactionWithProducts(transactionRepository, transactionProductRepository, &rows[i].Transaction, rows[i].Products); err != nil {
+				return fmt.Errorf("line %d: %w", rows[i].Line, err)
+			}
+		}
+		return nil
+	})
+}
 
+// createTransactionWithProducts creates a transaction and associates the products with it
+func createTransactionWithProducts(
+	transactionRepository repository.TransactionRepositoryInterface,
+	transactionProductRepository repository.TransactionProductRepositoryInterface,
+	transaction *models.Transaction,
+	products []models.TransactionProduct,
+) error {
 	// Create the transaction first
-	if err := s.transactionRepository.Create(transaction); err != nil {
-		tx.Rollback()
+	if err := transactionRepository.Create(transaction); err != nil {
 		return err
 	}
 
 	// Associate products with the transaction
 	for i := range products {
 		products[i].TransactionID = transaction.ID
-		if err := s.transactionProductRepository.Create(&products[i]); err != nil {
-			tx.Rollback()
+		if err := transactionProductRepository.Create(&products[i]); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	// Commit the transaction
-	return tx.Commit()
+// Audit log operations
+func (s *service) InitAuditTable() error {
+	return s.auditRepository.InitTable()
+}
+
+func (s *service) CreateAuditLog(entry *models.AuditLog) error {
+	return s.auditRepository.Create(entry)
+}
+
+func (s *service) GetAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, error) {
+	return s.auditRepository.List(filter)
+}
+
+func (s *service) PurgeAuditLogs(before time.Time) (int64, error) {
+	return s.auditRepository.DeleteBefore(before)
 }
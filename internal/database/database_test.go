@@ -0,0 +1,68 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService returns a service backed by a fresh SQLite database with every table created
+func newTestService(t *testing.T) *service {
+	t.Helper()
+	db, err := sql.Open("sqlite3", t.TempDir()+"/canteen.db")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	s := newService(db)
+	for _, initTable := range []func() error{
+		s.InitUserTable,
+		s.InitDepartmentTable,
+		s.InitTransactionTable,
+		s.InitCategoryTable,
+		s.InitProductTable,
+		s.InitTransactionProductTable,
+		s.InitProductUnitTable,
+		s.InitProductPriceTable,
+		s.InitPricingRuleTable,
+		s.InitDailyMenuTable,
+		s.InitPaymentTable,
+		s.InitPendingDepositTable,
+		s.InitCashSessionTable,
+		s.InitBalanceTransferTable,
+		s.InitOffboardingTable,
+		s.InitWalletTable,
+		s.InitInstallmentPlanTable,
+		s.InitEscalationTable,
+		s.InitAuditTable,
+	} {
+		require.NoError(t, initTable())
+	}
+	return s
+}
+
+// createTestUser creates an active user with the given employee ID
+func createTestUser(t *testing.T, s *service, employeeID string) *models.User {
+	t.Helper()
+	user := &models.User{Name: "User " + employeeID, EmployeeId: employeeID, Department: "Finance", Phone: "03001234567", Active: true}
+	require.NoError(t, s.CreateUser(user))
+	return user
+}
+
+// createTestTransaction creates an account transaction of the user
+func createTestTransaction(t *testing.T, s *service, userID int64, transactionType string, amount float64) *models.Transaction {
+	t.Helper()
+	transaction := &models.Transaction{UserID: userID, Amount: amount, TransactionType: transactionType, PaymentMethod: models.PaymentMethodAccount}
+	require.NoError(t, s.CreateTransaction(transaction))
+	return transaction
+}
+
+// balanceOf returns the current balance of the user
+func balanceOf(t *testing.T, s *service, userID int64) float64 {
+	t.Helper()
+	balance, err := s.GetUserBalanceByUserID(userID)
+	require.NoError(t, err)
+	return roundAmount(balance.Balance)
+}
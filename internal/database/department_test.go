@@ -0,0 +1,98 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepartmentReports(t *testing.T) {
+	s := newTestService(t)
+	finance := createTestUser(t, s, "4001")
+	sales := &models.User{Name: "Sales user", EmployeeId: "4002", Department: "Sales", Phone: "03007654321", Active: true}
+	require.NoError(t, s.CreateUser(sales))
+	require.NotNil(t, sales.DepartmentID)
+	purchase, products := createRefundPurchase(t, s, finance.ID)
+	tea := &models.Product{Name: "Tea", Price: 40, Active: true}
+	require.NoError(t, s.CreateProduct(tea))
+	require.NoError(t, s.CreateTransactionWithProducts(
+		&models.Transaction{UserID: sales.ID, Amount: 80, TransactionType: models.TransactionTypePurchase},
+		[]models.TransactionProduct{{ProductID: tea.ID, ProductName: tea.Name, Quantity: 2, UnitPrice: 40}},
+	))
+	createTestTransaction(t, s, sales.ID, models.TransactionTypeDeposit, 50)
+	_, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 1}}, "", nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	consumption, err := s.GetDepartmentProductConsumption(today, today)
+	require.NoError(t, err)
+	require.Len(t, consumption, 3)
+	assert.Equal(t, "Finance", consumption[0].DepartmentName)
+	assert.Equal(t, "Drink", consumption[0].ProductName)
+	assert.Equal(t, 200.0, consumption[0].TotalSales)
+	assert.Equal(t, "Meal", consumption[1].ProductName)
+	assert.Equal(t, 2, consumption[1].Quantity, "the refunded meal is taken off")
+	assert.Equal(t, 200.0, consumption[1].TotalSales)
+	assert.Equal(t, 100.0, consumption[1].CompanyShare)
+	assert.Equal(t, "Sales", consumption[2].DepartmentName)
+	assert.Equal(t, 2, consumption[2].Quantity)
+	assert.Equal(t, 80.0, consumption[2].TotalSales)
+
+	spend, err := s.GetDepartmentMonthlySpend(today, today)
+	require.NoError(t, err)
+	require.Len(t, spend, 2)
+	assert.Equal(t, "Finance", spend[0].DepartmentName)
+	assert.Equal(t, 400.0, spend[0].PurchaseTotal)
+	assert.Equal(t, 80.0, spend[1].PurchaseTotal)
+	assert.Equal(t, 50.0, spend[1].DepositTotal)
+
+	balances, err := s.GetDepartmentBalances()
+	require.NoError(t, err)
+	byName := make(map[string]models.DepartmentBalance)
+	for _, balance := range balances {
+		byName[balance.DepartmentName] = balance
+	}
+	assert.Equal(t, 300.0, byName["Finance"].OutstandingBalance, "the employee share of the purchase less the refund")
+	assert.Equal(t, 30.0, byName["Sales"].OutstandingBalance)
+	assert.Equal(t, 1, byName["Sales"].UsersInDebt)
+}
+
+func TestDeleteDepartment(t *testing.T) {
+	s := newTestService(t)
+	user := &models.User{Name: "Kitchen user", EmployeeId: "4003", Department: "Kitchen", Phone: "03001112222", Active: true}
+	require.NoError(t, s.CreateUser(user))
+	require.NotNil(t, user.DepartmentID)
+	rule := &models.PricingRule{
+		Name: "Kitchen subsidy", Kind: models.PricingRuleKindSubsidy, Type: models.PricingRuleTypePercentage,
+		Value: 50, DepartmentID: user.DepartmentID, Active: true,
+	}
+	require.NoError(t, s.CreatePricingRule(rule))
+
+	require.NoError(t, s.DeleteDepartment(*user.DepartmentID))
+	saved, err := s.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, saved.Department)
+	assert.Nil(t, saved.DepartmentID)
+	rule, err = s.GetPricingRule(rule.ID)
+	require.NoError(t, err)
+	assert.False(t, rule.Active, "the subsidy would otherwise apply to every user")
+	assert.Nil(t, rule.DepartmentID)
+
+	// Updating the user does not bring the department back
+	saved.Phone = "03003334444"
+	require.NoError(t, s.UpdateUser(saved))
+	departments, err := s.GetAllDepartments()
+	require.NoError(t, err)
+	for _, department := range departments {
+		assert.NotEqual(t, "Kitchen", department.Name)
+	}
+	saved, err = s.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, saved.Department)
+	assert.Equal(t, "03003334444", saved.Phone)
+}
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// ErrInvalidInstallmentPlan is returned when an installment plan cannot be created or paid as requested
+var ErrInvalidInstallmentPlan = errors.New("invalid installment plan")
+
+// installmentBatchPrefix starts the batch reference of the deposits paying an installment plan,
+// followed by the plan ID
+const installmentBatchPrefix = "INSTALLMENT-"
+
+// maxInstallments caps how many months an installment plan may run
+const maxInstallments = 24
+
+// Installment plan operations
+func (s *service) InitInstallmentPlanTable() error {
+	return s.installmentPlanRepository.InitTable()
+}
+
+// CreateInstallmentPlan splits an amount the user owes into plan.InstallmentCount monthly
+// installments, the first due on firstDueDate. If plan.TotalAmount is zero the plan covers the
+// user's whole outstanding balance. A user can only have one active plan at a time.
+func (s *service) CreateInstallmentPlan(plan *models.InstallmentPlan, firstDueDate time.Time) error {
+	if plan.InstallmentCount < 2 || plan.InstallmentCount > maxInstallments {
+		return fmt.Errorf("%w: installments must be between 2 and %d", ErrInvalidInstallmentPlan, maxInstallments)
+	}
+	if plan.TotalAmount < 0 {
+		return fmt.Errorf("%w: total amount cannot be negative", ErrInvalidInstallmentPlan)
+	}
+	firstDueDate = dateOf(firstDueDate)
+	if firstDueDate.Before(dateOf(time.Now())) {
+		return fmt.Errorf("%w: first due date cannot be in the past", ErrInvalidInstallmentPlan)
+	}
+	user, err := s.userRepository.GetByID(plan.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.DeletedAt != nil {
+		return fmt.Errorf("%w: user %d not found", ErrInvalidInstallmentPlan, plan.UserID)
+	}
+	if err := s.offboardedError(user.ID, ErrInvalidInstallmentPlan); err != nil {
+		return err
+	}
+	active, err := s.installmentPlanRepository.GetActiveByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		return fmt.Errorf("%w: %s already has active plan %d", ErrInvalidInstallmentPlan, user.Name, active.ID)
+	}
+
+	balance, err := s.transactionRepository.GetUserBalanceByID(user.ID)
+	if err != nil {
+		return err
+	}
+	owed := roundAmount(-balance.Balance)
+	if owed <= 0 {
+		return fmt.Errorf("%w: %s has no outstanding balance", ErrInvalidInstallmentPlan, user.Name)
+	}
+	if plan.TotalAmount == 0 {
+		plan.TotalAmount = owed
+	}
+	plan.TotalAmount = roundAmount(plan.TotalAmount)
+	if plan.TotalAmount > owed {
+		return fmt.Errorf("%w: total amount %.2f exceeds the outstanding balance of %.2f", ErrInvalidInstallmentPlan, plan.TotalAmount, owed)
+	}
+
+	plan.Installments = splitInstallments(plan.TotalAmount, plan.InstallmentCount, firstDueDate)
+	if err := s.installmentPlanRepository.Create(plan); err != nil {
+		return err
+	}
+	plan.UserName = user.Name
+	plan.EmployeeID = user.EmployeeId
+	return s.allocateInstallmentPayments(plan)
+}
+
+// splitInstallments divides total into count monthly installments in cents, the last
+// installment taking the remainder so that they add up to total exactly
+func splitInstallments(total float64, count int, firstDueDate time.Time) []models.Installment {
+	cents := int64(total*100 + 0.5)
+	share := cents / int64(count)
+	installments := make([]models.Installment, count)
+	for i := range installments {
+		amount := share
+		if i == count-1 {
+			amount = cents - share*int64(count-1)
+		}
+		installments[i] = models.Installment{
+			Sequence: i + 1,
+			DueDate:  firstDueDate.AddDate(0, i, 0),
+			Amount:   float64(amount) / 100,
+			Status:   models.InstallmentStatusPending,
+		}
+	}
+	return installments
+}
+
+// dateOf returns midnight UTC of t's calendar date, matching how due dates are stored
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// allocateInstallmentPayments loads the installments of a plan and allocates the payments made
+// towards it in the order the installments fall due. Installments that are past their due date
+// without being fully paid are flagged as missed.
+func (s *service) allocateInstallmentPayments(plan *models.InstallmentPlan) error {
+	installments, err := s.installmentPlanRepository.GetInstallments(plan.ID)
+	if err != nil {
+		return err
+	}
+	paid, err := s.installmentPlanRepository.GetPaidAmount(fmt.Sprintf("%s%d", installmentBatchPrefix, plan.ID))
+	if err != nil {
+		return err
+	}
+
+	plan.PaidAmount = roundAmount(paid)
+	plan.RemainingAmount = roundAmount(max(plan.TotalAmount-paid, 0))
+	plan.OverdueAmount, plan.MissedCount, plan.NextInstallment = 0, 0, nil
+	today := dateOf(time.Now())
+	remaining := paid
+	for i := range installments {
+		installment := &installments[i]
+		installment.PaidAmount = roundAmount(min(remaining, installment.Amount))
+		remaining = max(remaining-installment.Amount, 0)
+
+		switch {
+		case installment.PaidAmount >= installment.Amount:
+			installment.Status = models.InstallmentStatusPaid
+		case plan.Status == models.InstallmentPlanStatusActive && installment.DueDate.Before(today):
+			installment.Status = models.InstallmentStatusMissed
+			plan.MissedCount++
+			plan.OverdueAmount += installment.Amount - installment.PaidAmount
+		default:
+			installment.Status = models.InstallmentStatusPending
+		}
+	}
+	plan.OverdueAmount = roundAmount(plan.OverdueAmount)
+	plan.Installments = installments
+	if plan.Status == models.InstallmentPlanStatusActive {
+		for i := range installments {
+			if installments[i].Status == models.InstallmentStatusPending {
+				plan.NextInstallment = &installments[i]
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// GetInstallmentPlans returns the installment plans with a status, or all plans if status is
+// empty. With missedOnly, only plans that have missed installments are returned.
+func (s *service) GetInstallmentPlans(status string, missedOnly bool) ([]models.InstallmentPlan, error) {
+	plans, err := s.installmentPlanRepository.GetAll(status)
+	if err != nil {
+		return nil, err
+	}
+	return s.allocatePlans(plans, missedOnly)
+}
+
+func (s *service) GetUserInstallmentPlans(userID int64) ([]models.InstallmentPlan, error) {
+	plans, err := s.installmentPlanRepository.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.allocatePlans(plans, false)
+}
+
+// allocatePlans allocates the payments of each plan, keeping only plans with missed
+// installments if missedOnly is set
+func (s *service) allocatePlans(plans []models.InstallmentPlan, missedOnly bool) ([]models.InstallmentPlan, error) {
+	result := make([]models.InstallmentPlan, 0, len(plans))
+	for i := range plans {
+		if err := s.allocateInstallmentPayments(&plans[i]); err != nil {
+			return nil, err
+		}
+		if missedOnly && plans[i].MissedCount == 0 {
+			continue
+		}
+		result = append(result, plans[i])
+	}
+	return result, nil
+}
+
+// GetInstallmentPlan returns an installment plan with its installments, or nil if it does not exist
+func (s *service) GetInstallmentPlan(id int64) (*models.InstallmentPlan, error) {
+	plan, err := s.installmentPlanRepository.Get(id)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	return plan, s.allocateInstallmentPayments(plan)
+}
+
+// GetActiveInstallmentPlan returns the active installment plan of a user, or nil if the user has none
+func (s *service) GetActiveInstallmentPlan(userID int64) (*models.InstallmentPlan, error) {
+	plan, err := s.installmentPlanRepository.GetActiveByUser(userID)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	return plan, s.allocateInstallmentPayments(plan)
+}
+
+// PayInstallmentPlan records a payment towards an active installment plan as a deposit
+// carrying the plan's batch reference. Payments in cash go through the cash session
+// cashSessionID. The plan is completed once it is paid in full. It returns the updated plan,
+// or nil if the plan does not exist.
+func (s *service) PayInstallmentPlan(id int64, amount float64, paymentMethod, note string, cashSessionID *int64) (*models.InstallmentPlan, error) {
+	plan, err := s.GetInstallmentPlan(id)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	if err := s.offboardedError(plan.UserID, ErrInvalidInstallmentPlan); err != nil {
+		return nil, err
+	}
+	if plan.Status != models.InstallmentPlanStatusActive {
+		return nil, fmt.Errorf("%w: plan %d is %s", ErrInvalidInstallmentPlan, plan.ID, plan.Status)
+	}
+	amount = roundAmount(amount)
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be greater than zero", ErrInvalidInstallmentPlan)
+	}
+	if amount > plan.RemainingAmount {
+		return nil, fmt.Errorf("%w: amount %.2f exceeds the remaining %.2f", ErrInvalidInstallmentPlan, amount, plan.RemainingAmount)
+	}
+	if paymentMethod == "" {
+		paymentMethod = models.PaymentMethodAccount
+	}
+	if paymentMethod == models.PaymentMethodCash && cashSessionID == nil {
+		return nil, fmt.Errorf("%w: open a cash session before taking payments in cash", ErrInvalidInstallmentPlan)
+	}
+
+	description := fmt.Sprintf("Installment plan %d payment", plan.ID)
+	if note != "" {
+		description += ": " + note
+	}
+	err = s.withTx(func(tx *sql.Tx) error {
+		deposit := models.Transaction{
+			UserID:          plan.UserID,
+			Amount:          amount,
+			Description:     description,
+			TransactionType: models.TransactionTypeDeposit,
+			PaymentMethod:   paymentMethod,
+			CashSessionID:   cashSessionID,
+			BatchReference:  fmt.Sprintf("%s%d", installmentBatchPrefix, plan.ID),
+			CreatedAt:       time.Now(),
+		}
+		if err := s.transactionRepository.WithTx(tx).Create(&deposit); err != nil {
+			return err
+		}
+		if amount < plan.RemainingAmount {
+			return nil
+		}
+		_, err := s.installmentPlanRepository.WithTx(tx).SetStatus(plan.ID, models.InstallmentPlanStatusActive, models.InstallmentPlanStatusCompleted)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetInstallmentPlan(plan.ID)
+}
+
+// CancelInstallmentPlan cancels an active installment plan and reports whether it was active.
+// Payments already made stay on the user's balance.
+func (s *service) CancelInstallmentPlan(id int64) (bool, error) {
+	return s.installmentPlanRepository.SetStatus(id, models.InstallmentPlanStatusActive, models.InstallmentPlanStatusCancelled)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"cmp"
+	"maya-canteen/internal/models"
+	"slices"
+)
+
+// uncategorizedMenuName is the menu section for active products without a category
+const uncategorizedMenuName = "Other"
+
+// Category operations
+func (s *service) InitCategoryTable() error {
+	return s.categoryRepository.InitTable()
+}
+
+func (s *service) CreateCategory(category *models.Category) error {
+	return s.categoryRepository.Create(category)
+}
+
+func (s *service) GetAllCategories() ([]models.Category, error) {
+	return s.categoryRepository.GetAll()
+}
+
+func (s *service) GetCategory(id int64) (*models.Category, error) {
+	return s.categoryRepository.Get(id)
+}
+
+func (s *service) UpdateCategory(category *models.Category) error {
+	return s.categoryRepository.Update(category)
+}
+
+func (s *service) DeleteCategory(id int64) error {
+	return s.categoryRepository.Delete(id)
+}
+
+// GetMenu returns the active products grouped by category, in menu order. Categories without
+// active products are left out, and products without a category are listed last.
+func (s *service) GetMenu() ([]models.MenuCategory, error) {
+	categories, err := s.categoryRepository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	products, err := s.GetAllProducts(false)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := make(map[int64][]models.Product)
+	var uncategorized []models.Product
+	for _, product := range products {
+		if !product.Active {
+			continue
+		}
+		if product.CategoryID == nil {
+			uncategorized = append(uncategorized, product)
+			continue
+		}
+		byCategory[*product.CategoryID] = append(byCategory[*product.CategoryID], product)
+	}
+
+	menu := make([]models.MenuCategory, 0, len(categories)+1)
+	for _, category := range categories {
+		if len(byCategory[category.ID]) == 0 {
+			continue
+		}
+		menu = append(menu, models.MenuCategory{
+			CategoryID: &category.ID,
+			Name:       category.Name,
+			SortOrder:  category.SortOrder,
+			Products:   sortMenuProducts(byCategory[category.ID]),
+		})
+	}
+	if len(uncategorized) > 0 {
+		menu = append(menu, models.MenuCategory{
+			Name:     uncategorizedMenuName,
+			Products: sortMenuProducts(uncategorized),
+		})
+	}
+	return menu, nil
+}
+
+// sortMenuProducts orders products by sort order; products arrive sorted by name,
+// which the stable sort keeps as the tie breaker
+func sortMenuProducts(products []models.Product) []models.Product {
+	slices.SortStableFunc(products, func(a, b models.Product) int {
+		return cmp.Compare(a.SortOrder, b.SortOrder)
+	})
+	return products
+}
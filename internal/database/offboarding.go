@@ -0,0 +1,208 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"maya-canteen/internal/database/repository"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// ErrInvalidOffboarding is returned when a user cannot be offboarded as requested
+var ErrInvalidOffboarding = errors.New("invalid offboarding")
+
+// ErrUserOffboarded is returned when a transaction would change the balance of an offboarded user
+var ErrUserOffboarded = errors.New("user offboarded")
+
+// offboardingBatchPrefix starts the batch reference of the transaction settling an offboarded
+// user's final balance, followed by the user ID
+const offboardingBatchPrefix = "OFFBOARDING-"
+
+// Offboarding operations
+func (s *service) InitOffboardingTable() error {
+	return s.offboardingRepository.InitTable()
+}
+
+// GetFinalStatement returns the statement of a user's account, or nil if the user does not
+// exist. It can be generated before offboarding the user to review the final balance.
+func (s *service) GetFinalStatement(userID int64) (*models.FinalStatement, error) {
+	user, err := s.userRepository.GetByID(userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	balance, err := s.transactionRepository.GetUserBalanceByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.offboardingRepository.GetTransactions(userID)
+	if err != nil {
+		return nil, err
+	}
+	offboarding, err := s.offboardingRepository.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FinalStatement{
+		User:         *user,
+		Balance:      roundAmount(balance.Balance),
+		Transactions: transactions,
+		Offboarding:  offboarding,
+		GeneratedAt:  time.Now(),
+	}, nil
+}
+
+// OffboardUser settles the final balance of a departing user with method and deactivates the
+// user, whose balance can then no longer change. Debts can be deducted from the final salary,
+// collected in cash or written off, and credit can be paid with the final salary or in cash.
+// Cash settlements go through the cash session cashSessionID. It returns the final statement,
+// or nil if the user does not exist.
+func (s *service) OffboardUser(userID int64, method, reason, processedBy string, cashSessionID *int64) (*models.FinalStatement, error) {
+	user, err := s.userRepository.GetByID(userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: %s has been deleted", ErrInvalidOffboarding, user.Name)
+	}
+
+	err = s.withTx(func(tx *sql.Tx) error {
+		offboardings := s.offboardingRepository.WithTx(tx)
+		existing, err := offboardings.GetByUser(userID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%w: %s was already offboarded", ErrInvalidOffboarding, user.Name)
+		}
+
+		transactions := s.transactionRepository.WithTx(tx)
+		balance, err := transactions.GetUserBalanceByID(userID)
+		if err != nil {
+			return err
+		}
+		offboarding := models.Offboarding{
+			UserID:       userID,
+			FinalBalance: roundAmount(balance.Balance),
+			Reason:       reason,
+			ProcessedBy:  processedBy,
+		}
+
+		if offboarding.FinalBalance != 0 {
+			settlement, err := settlementTransaction(user, offboarding.FinalBalance, method, reason, cashSessionID)
+			if err != nil {
+				return err
+			}
+			if err := transactions.Create(settlement); err != nil {
+				return err
+			}
+			offboarding.SettlementMethod = method
+			offboarding.TransactionID = &settlement.ID
+		}
+		return offboardings.Create(&offboarding)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetFinalStatement(userID)
+}
+
+// settlementTransaction returns the transaction that brings a final balance to zero
+func settlementTransaction(user *models.User, balance float64, method, reason string, cashSessionID *int64) (*models.Transaction, error) {
+	transaction := &models.Transaction{
+		UserID:         user.ID,
+		Amount:         math.Abs(balance),
+		PaymentMethod:  models.PaymentMethodAccount,
+		CashSessionID:  cashSessionID,
+		BatchReference: fmt.Sprintf("%s%d", offboardingBatchPrefix, user.ID),
+	}
+
+	owes := balance < 0
+	switch method {
+	case models.SettlementMethodPayroll:
+		transaction.Description = "Final balance paid with the final salary"
+		if owes {
+			transaction.Description = "Final balance deducted from the final salary"
+		}
+	case models.SettlementMethodCash:
+		if cashSessionID == nil {
+			return nil, fmt.Errorf("%w: open a cash session before settling in cash", ErrInvalidOffboarding)
+		}
+		transaction.PaymentMethod = models.PaymentMethodCash
+		transaction.Description = "Final balance paid out in cash"
+		if owes {
+			transaction.Description = "Final balance collected in cash"
+		}
+	case models.SettlementMethodWriteOff:
+		if !owes {
+			return nil, fmt.Errorf("%w: only balances %s owes can be written off", ErrInvalidOffboarding, user.Name)
+		}
+		if reason == "" {
+			return nil, fmt.Errorf("%w: a reason is required to write off a balance", ErrInvalidOffboarding)
+		}
+		transaction.TransactionType = models.TransactionTypeWriteOff
+		transaction.Description = "Final balance written off"
+	default:
+		return nil, fmt.Errorf("%w: %s has a balance of %.2f, expected a settlement method of payroll, cash or write-off", ErrInvalidOffboarding, user.Name, balance)
+	}
+
+	// Debts are paid like deposits, while credit is taken off with an adjustment
+	if transaction.TransactionType == "" {
+		transaction.TransactionType = models.TransactionTypeAdjustment
+		if owes {
+			transaction.TransactionType = models.TransactionTypeDeposit
+		}
+	}
+	if reason != "" {
+		transaction.Description += ": " + reason
+	}
+	return transaction, nil
+}
+
+func (s *service) GetOffboardings() ([]models.Offboarding, error) {
+	return s.offboardingRepository.GetAll()
+}
+
+// GetWriteOffReport returns the balances written off from startDate to the end of endDate,
+// whether when offboarding users or directly
+func (s *service) GetWriteOffReport(startDate, endDate time.Time) (*models.WriteOffReport, error) {
+	// Include the entire end day
+	writeOffs, err := s.offboardingRepository.GetWriteOffs(startDate, endDate.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.WriteOffReport{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		WriteOffs: writeOffs,
+	}
+	for _, writeOff := range writeOffs {
+		report.TotalAmount += writeOff.Amount
+	}
+	return report, nil
+}
+
+// offboardedError returns err wrapped with an explanation if the user has been offboarded, and
+// nil otherwise. Guests without a user are never offboarded.
+func (s *service) offboardedError(userID int64, err error) error {
+	return userOffboardedError(s.offboardingRepository, userID, err)
+}
+
+// userOffboardedError is offboardedError for the offboardings of a repository, which can be
+// bound to the database transaction that changes the user's balance
+func userOffboardedError(offboardings repository.OffboardingRepositoryInterface, userID int64, err error) error {
+	if userID == 0 {
+		return nil
+	}
+	offboarding, lookupErr := offboardings.GetByUser(userID)
+	if lookupErr != nil {
+		return lookupErr
+	}
+	if offboarding == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %s was offboarded on %s", err, offboarding.UserName, offboarding.CreatedAt.Format("2006-01-02"))
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffboardUserSettlesFinalBalance(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "2001")
+	createTestTransaction(t, s, user.ID, models.TransactionTypePurchase, 300)
+	createTestTransaction(t, s, user.ID, models.TransactionTypeDeposit, 100)
+
+	statement, err := s.OffboardUser(user.ID, models.SettlementMethodWriteOff, "Left without notice", "admin", nil)
+	require.NoError(t, err)
+	assert.Equal(t, -200.0, statement.Offboarding.FinalBalance)
+	assert.Equal(t, 0.0, statement.Balance)
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID))
+
+	_, err = s.OffboardUser(user.ID, models.SettlementMethodPayroll, "", "admin", nil)
+	assert.ErrorIs(t, err, ErrInvalidOffboarding, "a user is offboarded once")
+}
+
+func TestOffboardedUserBalanceIsLocked(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "2002")
+	purchase := createTestTransaction(t, s, user.ID, models.TransactionTypePurchase, 300)
+	deposit := createTestTransaction(t, s, user.ID, models.TransactionTypeDeposit, 50)
+	require.NoError(t, s.DeleteTransaction(deposit.ID))
+
+	_, err := s.OffboardUser(user.ID, models.SettlementMethodPayroll, "", "admin", nil)
+	require.NoError(t, err)
+	require.Equal(t, 0.0, balanceOf(t, s, user.ID))
+
+	for _, transactionType := range models.TransactionTypes {
+		transaction := &models.Transaction{UserID: user.ID, Amount: 40, TransactionType: transactionType, PaymentMethod: models.PaymentMethodAccount}
+		assert.ErrorIs(t, s.CreateTransaction(transaction), ErrUserOffboarded, transactionType)
+	}
+	assert.ErrorIs(t, s.CreateTransactionWithProducts(
+		&models.Transaction{UserID: user.ID, Amount: 40, TransactionType: models.TransactionTypePurchase},
+		[]models.TransactionProduct{{ProductName: "Tea", Quantity: 1, UnitPrice: 40}},
+	), ErrUserOffboarded)
+
+	edited := *purchase
+	edited.Amount = 30
+	assert.ErrorIs(t, s.UpdateTransaction(&edited), ErrUserOffboarded)
+	assert.ErrorIs(t, s.DeleteTransaction(purchase.ID), ErrUserOffboarded)
+	_, err = s.RestoreTransaction(deposit.ID)
+	assert.ErrorIs(t, err, ErrUserOffboarded)
+
+	other := createTestUser(t, s, "2003")
+	rows := []models.TransactionImportRow{
+		{Line: 2, Transaction: models.Transaction{UserID: other.ID, Amount: 70, TransactionType: models.TransactionTypePurchase, PaymentMethod: models.PaymentMethodAccount}},
+		{Line: 3, Transaction: models.Transaction{UserID: user.ID, Amount: 70, TransactionType: models.TransactionTypeAdjustment, PaymentMethod: models.PaymentMethodAccount}},
+	}
+	assert.ErrorIs(t, s.ImportTransactions(rows, "IMPORT-1"), ErrUserOffboarded)
+
+	// Moving a transaction of another user onto the offboarded user is refused as well
+	moved := createTestTransaction(t, s, other.ID, models.TransactionTypePurchase, 20)
+	moved.UserID = user.ID
+	assert.ErrorIs(t, s.UpdateTransaction(moved), ErrUserOffboarded)
+
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID))
+	assert.Equal(t, -20.0, balanceOf(t, s, other.ID), "the import is all or nothing")
+}
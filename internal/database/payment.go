@@ -0,0 +1,268 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"maya-canteen/internal/models"
+	"maya-canteen/internal/phone"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidPaymentLine is returned when a payment line cannot be approved or rejected as requested
+var ErrInvalidPaymentLine = errors.New("invalid payment line")
+
+// paymentBatchPrefix starts the batch reference of deposits posted from payment lines,
+// followed by the line ID
+const paymentBatchPrefix = "PAYMENT-"
+
+// Payment reconciliation operations
+func (s *service) InitPaymentTable() error {
+	return s.paymentRepository.InitTable()
+}
+
+// paymentMatcher matches statement lines to users by phone, employee ID reference or
+// outstanding balance
+type paymentMatcher struct {
+	byPhone      map[string][]int64
+	byEmployeeID map[string]int64
+	byOwed       map[int64][]int64 // Outstanding balances in paisa
+}
+
+func (s *service) newPaymentMatcher() (*paymentMatcher, error) {
+	users, err := s.userRepository.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := s.transactionRepository.GetUsersBalances()
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := &paymentMatcher{
+		byPhone:      make(map[string][]int64),
+		byEmployeeID: make(map[string]int64, len(users)),
+		byOwed:       make(map[int64][]int64),
+	}
+	for _, user := range users {
+		matcher.byEmployeeID[strings.ToLower(user.EmployeeId)] = user.ID
+		if number, err := phone.Normalize(user.Phone); err == nil && number != "" {
+			matcher.byPhone[number] = append(matcher.byPhone[number], user.ID)
+		}
+	}
+	for _, balance := range balances {
+		if balance.Balance < 0 {
+			owed := int64(math.Round(-balance.Balance * 100))
+			matcher.byOwed[owed] = append(matcher.byOwed[owed], balance.UserID)
+		}
+	}
+	return matcher, nil
+}
+
+// match returns the user a payment line belongs to and how it was matched. Matches must
+// be unambiguous: a phone shared by several users or an amount owed by several users
+// does not match.
+func (m *paymentMatcher) match(line *models.PaymentLine) (*int64, string) {
+	if number, err := phone.Normalize(line.Phone); err == nil && number != "" {
+		if users := m.byPhone[number]; len(users) == 1 {
+			return &users[0], models.PaymentMatchPhone
+		}
+	}
+
+	// Staff are asked to put their employee ID in the payment reference
+	var referenced *int64
+	tokens := strings.FieldsFunc(strings.ToLower(line.Reference+" "+line.Description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, token := range tokens {
+		userID, ok := m.byEmployeeID[token]
+		if !ok {
+			continue
+		}
+		if referenced != nil && *referenced != userID {
+			referenced = nil
+			break
+		}
+		referenced = &userID
+	}
+	if referenced != nil {
+		return referenced, models.PaymentMatchReference
+	}
+
+	if users := m.byOwed[int64(math.Round(line.Amount*100))]; len(users) == 1 {
+		return &users[0], models.PaymentMatchAmount
+	}
+	return nil, ""
+}
+
+// paymentFingerprint identifies a payment across statements by its reference, or by its
+// time, amount and phone when the statement has no references
+func paymentFingerprint(source string, line *models.PaymentLine) string {
+	if reference := strings.ToLower(strings.TrimSpace(line.Reference)); reference != "" {
+		return fmt.Sprintf("%s|ref|%s", strings.ToLower(source), reference)
+	}
+	return fmt.Sprintf("%s|%s|%.2f|%s", strings.ToLower(source), line.PaidAt.Format(time.RFC3339), line.Amount, phone.Digits(line.Phone))
+}
+
+// ImportPaymentStatement saves a statement with its lines and matches each line to a user.
+// Lines that were imported before are saved as duplicates. Matched lines still need to be
+// approved before a deposit is posted.
+func (s *service) ImportPaymentStatement(statement *models.PaymentStatement) error {
+	matcher, err := s.newPaymentMatcher()
+	if err != nil {
+		return err
+	}
+
+	err = s.withTx(func(tx *sql.Tx) error {
+		payments := s.paymentRepository.WithTx(tx)
+		if err := payments.CreateStatement(statement); err != nil {
+			return err
+		}
+
+		for i := range statement.Lines {
+			line := &statement.Lines[i]
+			line.StatementID = statement.ID
+			line.Fingerprint = paymentFingerprint(statement.Source, line)
+
+			duplicate, err := payments.HasFingerprint(line.Fingerprint)
+			if err != nil {
+				return err
+			}
+			if duplicate {
+				line.Status = models.PaymentLineStatusDuplicate
+			} else if line.UserID, line.MatchMethod = matcher.match(line); line.UserID != nil {
+				line.Status = models.PaymentLineStatusMatched
+			} else {
+				line.Status = models.PaymentLineStatusUnmatched
+			}
+			if err := payments.CreateLine(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	saved, err := s.GetPaymentStatement(statement.ID)
+	if err != nil {
+		return err
+	}
+	*statement = *saved
+	return nil
+}
+
+func (s *service) GetPaymentStatements() ([]models.PaymentStatement, error) {
+	return s.paymentRepository.GetStatements()
+}
+
+// GetPaymentStatement returns a statement with its lines, or nil if it does not exist
+func (s *service) GetPaymentStatement(id int64) (*models.PaymentStatement, error) {
+	statement, err := s.paymentRepository.GetStatement(id)
+	if err != nil || statement == nil {
+		return statement, err
+	}
+	statement.Lines, err = s.paymentRepository.GetLines(id)
+	if err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+// GetPaymentReviewQueue returns the lines awaiting review with the given status, or both
+// the matched and unmatched lines if status is empty
+func (s *service) GetPaymentReviewQueue(status string) ([]models.PaymentLine, error) {
+	if status != "" {
+		return s.paymentRepository.GetLinesByStatus(status)
+	}
+	return s.paymentRepository.GetLinesByStatus(models.PaymentLineStatusUnmatched, models.PaymentLineStatusMatched)
+}
+
+func (s *service) GetPaymentLine(id int64) (*models.PaymentLine, error) {
+	return s.paymentRepository.GetLine(id)
+}
+
+// ApprovePaymentLine posts a deposit for a payment line awaiting review, dated at the time
+// of the payment. userID overrides the matched user and is required for unmatched lines.
+// It returns nil if the line does not exist.
+func (s *service) ApprovePaymentLine(id int64, userID *int64, reviewedBy string) (*models.PaymentApproval, error) {
+	line, err := s.paymentRepository.GetLine(id)
+	if err != nil || line == nil {
+		return nil, err
+	}
+	if line.Status != models.PaymentLineStatusMatched && line.Status != models.PaymentLineStatusUnmatched {
+		return nil, fmt.Errorf("%w: line %d is %s", ErrInvalidPaymentLine, id, line.Status)
+	}
+
+	if userID != nil && (line.UserID == nil || *line.UserID != *userID) {
+		line.UserID = userID
+		line.MatchMethod = models.PaymentMatchManual
+	}
+	if line.UserID == nil {
+		return nil, fmt.Errorf("%w: line %d is not matched to a user", ErrInvalidPaymentLine, id)
+	}
+	user, err := s.userRepository.GetByID(*line.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: user %d does not exist", ErrInvalidPaymentLine, *line.UserID)
+	}
+	if err := s.offboardedError(user.ID, ErrInvalidPaymentLine); err != nil {
+		return nil, err
+	}
+	statement, err := s.paymentRepository.GetStatement(line.StatementID)
+	if err != nil {
+		return nil, err
+	}
+
+	description := statement.Source + " payment"
+	if line.Reference != "" {
+		description += " " + line.Reference
+	}
+	transaction := models.Transaction{
+		UserID:          user.ID,
+		Amount:          line.Amount,
+		Description:     description,
+		TransactionType: models.TransactionTypeDeposit,
+		BatchReference:  fmt.Sprintf("%s%d", paymentBatchPrefix, line.ID),
+		CreatedAt:       line.PaidAt,
+	}
+
+	now := time.Now()
+	err = s.withTx(func(tx *sql.Tx) error {
+		if err := s.transactionRepository.WithTx(tx).Create(&transaction); err != nil {
+			return err
+		}
+		line.TransactionID = &transaction.ID
+		line.ReviewedBy = reviewedBy
+		line.ReviewedAt = &now
+		posted, err := s.paymentRepository.WithTx(tx).MarkPosted(line)
+		if err != nil {
+			return err
+		}
+		if !posted {
+			return fmt.Errorf("%w: line %d was already reviewed", ErrInvalidPaymentLine, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.paymentRepository.GetLine(id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PaymentApproval{Line: *saved, Transaction: transaction}, nil
+}
+
+// RejectPaymentLine marks a payment line awaiting review as rejected and reports whether
+// such a line was found
+func (s *service) RejectPaymentLine(id int64, reviewedBy, note string) (bool, error) {
+	return s.paymentRepository.Reject(id, reviewedBy, note)
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"maya-canteen/internal/database/repository"
+	"maya-canteen/internal/models"
+	"slices"
+	"time"
+)
+
+// payrollSettlementPrefix returns the batch reference prefix shared by all settlements of a period
+func payrollSettlementPrefix(period time.Time) string {
+	return "PAYROLL-" + period.Format("2006-01") + "-"
+}
+
+// payrollPeriodEnd returns the last instant of the month period belongs to
+func payrollPeriodEnd(period time.Time) time.Time {
+	start := time.Date(period.Year(), period.Month(), 1, 0, 0, 0, 0, period.Location())
+	return start.AddDate(0, 1, 0).Add(-time.Nanosecond)
+}
+
+// GetPayrollDeductions returns the active users that owe money for the period month
+func (s *service) GetPayrollDeductions(period time.Time) ([]models.PayrollDeduction, error) {
+	return payrollDeductions(s.transactionRepository, period)
+}
+
+// payrollDeductions returns the outstanding deductions of the period month. Payments made
+// since the period, including the settlement of this or a later period, are taken off it.
+func payrollDeductions(transactionRepository repository.TransactionRepositoryInterface, period time.Time) ([]models.PayrollDeduction, error) {
+	balances, err := transactionRepository.GetPeriodBalances(payrollPeriodEnd(period))
+	if err != nil {
+		return nil, err
+	}
+
+	deductions := make([]models.PayrollDeduction, 0, len(balances))
+	for _, balance := range balances {
+		if balance.AmountToDeduct > 0 {
+			deductions = append(deductions, balance)
+		}
+	}
+	return deductions, nil
+}
+
+// SettlePayrollDeductions posts a deposit for every outstanding payroll deduction of the period
+// in a single database transaction. The deductions are worked out in the same database
+// transaction, so payments posted meanwhile are never deducted again. All deposits share one
+// batch reference, numbered after the earlier settlements of the period. If userIDs is not
+// empty, only those users are settled.
+func (s *service) SettlePayrollDeductions(period time.Time, userIDs []int64) (*models.PayrollSettlement, error) {
+	settlement := &models.PayrollSettlement{
+		Period:       period.Format("2006-01"),
+		Transactions: []models.Transaction{},
+	}
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		transactionRepository := s.transactionRepository.WithTx(tx)
+		deductions, err := payrollDeductions(transactionRepository, period)
+		if err != nil {
+			return err
+		}
+		settled, err := transactionRepository.CountBatchReferences(payrollSettlementPrefix(period))
+		if err != nil {
+			return err
+		}
+		settlement.BatchReference = fmt.Sprintf("%s%d", payrollSettlementPrefix(period), settled+1)
+
+		for _, deduction := range deductions {
+			if len(userIDs) > 0 && !slices.Contains(userIDs, deduction.UserID) {
+				continue
+			}
+
+			transaction := models.Transaction{
+				UserID:          deduction.UserID,
+				Amount:          deduction.AmountToDeduct,
+				Description:     "Payroll deduction for " + period.Format("January 2006"),
+				TransactionType: models.TransactionTypeDeposit,
+				BatchReference:  settlement.BatchReference,
+			}
+			if err := transactionRepository.Create(&transaction); err != nil {
+				return err
+			}
+
+			settlement.Transactions = append(settlement.Transactions, transaction)
+			settlement.TotalAmount += transaction.Amount
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	settlement.UserCount = len(settlement.Transactions)
+	return settlement, nil
+}
+
+// GetEmployerInvoice returns the company shares of the transactions of the period month per
+// department, for billing the employer
+func (s *service) GetEmployerInvoice(period time.Time) (*models.EmployerInvoice, error) {
+	start := time.Date(period.Year(), period.Month(), 1, 0, 0, 0, 0, period.Location())
+	departments, err := s.departmentRepository.GetCompanyShares(start, payrollPeriodEnd(period))
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &models.EmployerInvoice{
+		Month:       period.Format("2006-01"),
+		Departments: departments,
+	}
+	for _, department := range departments {
+		invoice.TotalAmount += department.TotalAmount
+		invoice.EmployeeShare += department.EmployeeShare
+		invoice.CompanyShare += department.CompanyShare
+	}
+	return invoice, nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	march = time.Date(2026, time.March, 1, 0, 0, 0, 0, time.Local)
+	april = time.Date(2026, time.April, 1, 0, 0, 0, 0, time.Local)
+)
+
+// createPayrollTransaction creates an account transaction of the user on day of the month of period
+func createPayrollTransaction(t *testing.T, s *service, userID int64, transactionType string, amount float64, period time.Time, day int) {
+	t.Helper()
+	transaction := &models.Transaction{
+		UserID:          userID,
+		Amount:          amount,
+		TransactionType: transactionType,
+		PaymentMethod:   models.PaymentMethodAccount,
+		CreatedAt:       period.AddDate(0, 0, day-1).Add(12 * time.Hour),
+	}
+	require.NoError(t, s.CreateTransaction(transaction))
+}
+
+func TestSettlePayrollDeductionsTwice(t *testing.T) {
+	s := newTestService(t)
+	first := createTestUser(t, s, "3001")
+	second := createTestUser(t, s, "3002")
+	createPayrollTransaction(t, s, first.ID, models.TransactionTypePurchase, 300, march, 10)
+	createPayrollTransaction(t, s, second.ID, models.TransactionTypePurchase, 120, march, 12)
+
+	settlement, err := s.SettlePayrollDeductions(march, []int64{first.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, settlement.UserCount)
+	assert.Equal(t, 300.0, settlement.TotalAmount)
+	assert.Equal(t, "PAYROLL-2026-03-1", settlement.BatchReference)
+
+	settlement, err = s.SettlePayrollDeductions(march, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, settlement.UserCount, "the first user is settled already")
+	assert.Equal(t, 120.0, settlement.TotalAmount)
+	assert.Equal(t, "PAYROLL-2026-03-2", settlement.BatchReference, "every settlement has its own batch reference")
+
+	settlement, err = s.SettlePayrollDeductions(march, nil)
+	require.NoError(t, err)
+	assert.Zero(t, settlement.UserCount)
+	assert.Equal(t, 0.0, balanceOf(t, s, first.ID))
+	assert.Equal(t, 0.0, balanceOf(t, s, second.ID))
+}
+
+func TestSettlePayrollDeductionsOutOfOrder(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "3003")
+	createPayrollTransaction(t, s, user.ID, models.TransactionTypePurchase, 300, march, 10)
+	createPayrollTransaction(t, s, user.ID, models.TransactionTypePurchase, 200, april, 10)
+
+	settlement, err := s.SettlePayrollDeductions(april, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, settlement.TotalAmount)
+
+	// April's settlement paid the March purchases as well
+	deductions, err := s.GetPayrollDeductions(march)
+	require.NoError(t, err)
+	assert.Empty(t, deductions)
+	settlement, err = s.SettlePayrollDeductions(march, nil)
+	require.NoError(t, err)
+	assert.Zero(t, settlement.UserCount)
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID))
+}
+
+func TestPayrollDeductionsCountLaterPayments(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "3004")
+	prepaid := createTestUser(t, s, "3005")
+	createPayrollTransaction(t, s, user.ID, models.TransactionTypePurchase, 300, march, 10)
+	createPayrollTransaction(t, s, user.ID, models.TransactionTypeDeposit, 100, april, 5)
+	createPayrollTransaction(t, s, user.ID, models.TransactionTypePurchase, 50, april, 6)
+	createPayrollTransaction(t, s, prepaid.ID, models.TransactionTypePurchase, 300, march, 10)
+	createPayrollTransaction(t, s, prepaid.ID, models.TransactionTypeDeposit, 400, april, 5)
+
+	deductions, err := s.GetPayrollDeductions(march)
+	require.NoError(t, err)
+	require.Len(t, deductions, 1, "the prepaid user owes nothing any more")
+	assert.Equal(t, user.ID, deductions[0].UserID)
+	assert.Equal(t, 200.0, deductions[0].AmountToDeduct, "the April deposit pays off March, the April purchase is not deducted")
+	assert.Equal(t, -250.0, deductions[0].CurrentBalance)
+
+	_, err = s.SettlePayrollDeductions(march, nil)
+	require.NoError(t, err)
+	assert.Equal(t, -50.0, balanceOf(t, s, user.ID))
+	assert.Equal(t, 100.0, balanceOf(t, s, prepaid.ID))
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maya-canteen/internal/models"
+	"maya-canteen/internal/phone"
+	"time"
+)
+
+// ErrInvalidPendingDeposit is returned when a pending deposit cannot be approved as requested
+var ErrInvalidPendingDeposit = errors.New("invalid pending deposit")
+
+// receiptBatchPrefix starts the batch reference of deposits posted from WhatsApp receipts,
+// followed by the pending deposit ID
+const receiptBatchPrefix = "RECEIPT-"
+
+// Pending deposit operations
+func (s *service) InitPendingDepositTable() error {
+	return s.pendingDepositRepository.InitTable()
+}
+
+// GetUserByPhone returns the user with the phone number, compared in E.164 form, or nil
+// if no user or more than one user has the number
+func (s *service) GetUserByPhone(number string) (*models.User, error) {
+	normalized, err := phone.Normalize(number)
+	if err != nil || normalized == "" {
+		return nil, nil
+	}
+	users, err := s.userRepository.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *models.User
+	for i := range users {
+		if userPhone, err := phone.Normalize(users[i].Phone); err != nil || userPhone != normalized {
+			continue
+		}
+		if found != nil {
+			return nil, nil
+		}
+		found = &users[i]
+	}
+	return found, nil
+}
+
+func (s *service) CreatePendingDeposit(deposit *models.PendingDeposit) (bool, error) {
+	return s.pendingDepositRepository.Create(deposit)
+}
+
+func (s *service) GetPendingDeposits(status string) ([]models.PendingDeposit, error) {
+	return s.pendingDepositRepository.GetByStatus(status)
+}
+
+func (s *service) GetPendingDeposit(id int64) (*models.PendingDeposit, error) {
+	return s.pendingDepositRepository.Get(id)
+}
+
+// ApprovePendingDeposit posts a deposit of amount for a pending receipt, dated at the time
+// the receipt was received. It returns nil if the pending deposit does not exist.
+func (s *service) ApprovePendingDeposit(id int64, amount float64, reviewedBy, note string) (*models.PendingDepositApproval, error) {
+	deposit, err := s.pendingDepositRepository.Get(id)
+	if err != nil || deposit == nil {
+		return nil, err
+	}
+	if deposit.Status != models.PendingDepositStatusPending {
+		return nil, fmt.Errorf("%w: receipt %d is %s", ErrInvalidPendingDeposit, id, deposit.Status)
+	}
+	if err := s.offboardedError(deposit.UserID, ErrInvalidPendingDeposit); err != nil {
+		return nil, err
+	}
+
+	description := "WhatsApp payment receipt"
+	if deposit.Caption != "" {
+		description += ": " + deposit.Caption
+	}
+	transaction := models.Transaction{
+		UserID:          deposit.UserID,
+		Amount:          amount,
+		Description:     description,
+		TransactionType: models.TransactionTypeDeposit,
+		BatchReference:  fmt.Sprintf("%s%d", receiptBatchPrefix, deposit.ID),
+		CreatedAt:       deposit.ReceivedAt,
+	}
+
+	now := time.Now()
+	err = s.withTx(func(tx *sql.Tx) error {
+		if err := s.transactionRepository.WithTx(tx).Create(&transaction); err != nil {
+			return err
+		}
+		deposit.Amount = &amount
+		deposit.TransactionID = &transaction.ID
+		deposit.ReviewedBy = reviewedBy
+		deposit.ReviewedAt = &now
+		deposit.Note = note
+		approved, err := s.pendingDepositRepository.WithTx(tx).MarkApproved(deposit)
+		if err != nil {
+			return err
+		}
+		if !approved {
+			return fmt.Errorf("%w: receipt %d was already reviewed", ErrInvalidPendingDeposit, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deposit.Status = models.PendingDepositStatusApproved
+	deposit.UpdatedAt = now
+	return &models.PendingDepositApproval{PendingDeposit: *deposit, Transaction: transaction}, nil
+}
+
+// RejectPendingDeposit marks a pending receipt as rejected and reports whether a pending
+// receipt was found
+func (s *service) RejectPendingDeposit(id int64, reviewedBy, note string) (bool, error) {
+	return s.pendingDepositRepository.Reject(id, reviewedBy, note)
+}
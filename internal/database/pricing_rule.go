@@ -0,0 +1,185 @@
+package database
+
+import (
+	"math"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// Pricing rule operations
+func (s *service) InitPricingRuleTable() error {
+	return s.pricingRuleRepository.InitTable()
+}
+
+func (s *service) CreatePricingRule(rule *models.PricingRule) error {
+	return s.pricingRuleRepository.Create(rule)
+}
+
+func (s *service) GetAllPricingRules(activeOnly bool) ([]models.PricingRule, error) {
+	return s.pricingRuleRepository.GetAll(activeOnly)
+}
+
+func (s *service) GetPricingRule(id int64) (*models.PricingRule, error) {
+	return s.pricingRuleRepository.Get(id)
+}
+
+func (s *service) UpdatePricingRule(rule *models.PricingRule) error {
+	return s.pricingRuleRepository.Update(rule)
+}
+
+func (s *service) DeletePricingRule(id int64) error {
+	return s.pricingRuleRepository.Delete(id)
+}
+
+// applyPricingRules applies the active pricing rules to the products of a purchase. Each line
+// gets at most one discount, the one that takes the most off, and then at most one subsidy,
+// the one that pays the most of the discounted price. Discounts are taken off the transaction
+// amount, and subsidies are added to the company share of it.
+func (s *service) applyPricingRules(transaction *models.Transaction, products []models.TransactionProduct) error {
+	if transaction.TransactionType != models.TransactionTypePurchase || len(products) == 0 {
+		return nil
+	}
+	rules, err := s.pricingRuleRepository.GetAll(true)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+
+	user, err := s.userRepository.GetByID(transaction.UserID)
+	if err != nil {
+		return err
+	}
+	at := transaction.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	var subsidies float64
+	categories := make(map[int64]*int64)
+	for i := range products {
+		line := &products[i]
+		categoryID, ok := categories[line.ProductID]
+		if !ok {
+			product, err := s.productRepository.Get(line.ProductID, true)
+			if err != nil {
+				return err
+			}
+			if product != nil {
+				categoryID = product.CategoryID
+			}
+			categories[line.ProductID] = categoryID
+		}
+
+		sale := pricingSale{user: user, categoryID: categoryID, line: line, at: at}
+		amount := line.UnitPrice * float64(line.Quantity)
+		if rule, discount := bestPricingRule(rules, models.PricingRuleKindDiscount, sale, amount); rule != nil {
+			line.DiscountRuleID = &rule.ID
+			line.DiscountName = rule.Name
+			line.DiscountAmount = discount
+			amount -= discount
+		}
+		// The employer only subsidises its staff, not walk-in guests
+		if transaction.UserID != 0 {
+			if rule, subsidy := bestPricingRule(rules, models.PricingRuleKindSubsidy, sale, amount); rule != nil {
+				line.SubsidyRuleID = &rule.ID
+				line.SubsidyName = rule.Name
+				line.SubsidyAmount = subsidy
+			}
+		}
+		transaction.DiscountAmount += line.DiscountAmount
+		subsidies += line.SubsidyAmount
+	}
+
+	transaction.DiscountAmount = roundAmount(transaction.DiscountAmount)
+	transaction.Amount = math.Max(0, roundAmount(transaction.Amount-transaction.DiscountAmount))
+	transaction.CompanyShare = math.Min(roundAmount(transaction.CompanyShare+subsidies), transaction.Amount)
+	return nil
+}
+
+// pricingSale is a purchase line together with what pricing rules are matched against
+type pricingSale struct {
+	user       *models.User
+	categoryID *int64
+	line       *models.TransactionProduct
+	at         time.Time
+}
+
+// bestPricingRule returns the rule of the given kind that takes the most off amount, the
+// remaining price of the line, and how much it takes off. It returns nil if no rule applies.
+func bestPricingRule(rules []models.PricingRule, kind string, sale pricingSale, amount float64) (*models.PricingRule, float64) {
+	var best *models.PricingRule
+	var bestReduction float64
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Kind != kind || !pricingRuleMatches(rule, sale) {
+			continue
+		}
+		if reduction := pricingRuleReduction(rule, sale.line, amount); reduction > bestReduction {
+			best, bestReduction = rule, reduction
+		}
+	}
+	return best, bestReduction
+}
+
+// pricingRuleMatches reports whether a rule applies to a sale
+func pricingRuleMatches(rule *models.PricingRule, sale pricingSale) bool {
+	switch {
+	case !rule.Active:
+		return false
+	case rule.ProductID != nil && *rule.ProductID != sale.line.ProductID:
+		return false
+	case rule.CategoryID != nil && (sale.categoryID == nil || *rule.CategoryID != *sale.categoryID):
+		return false
+	case rule.UserID != nil && (sale.user == nil || *rule.UserID != sale.user.ID):
+		return false
+	case rule.DepartmentID != nil && (sale.user == nil || sale.user.DepartmentID == nil || *rule.DepartmentID != *sale.user.DepartmentID):
+		return false
+	case rule.MinQuantity > 0 && sale.line.Quantity < rule.MinQuantity:
+		return false
+	case rule.ValidFrom != nil && sale.at.Before(*rule.ValidFrom):
+		return false
+	case rule.ValidUntil != nil && sale.at.After(*rule.ValidUntil):
+		return false
+	}
+	return inDailyWindow(rule.StartTime, rule.EndTime, sale.at)
+}
+
+// inDailyWindow reports whether the local time of day of at is within the "HH:MM" window
+// from start up to end. Windows that end before they start run past midnight, and an empty
+// start or end leaves that side of the window open.
+func inDailyWindow(start, end string, at time.Time) bool {
+	clock := at.Local().Format("15:04")
+	switch {
+	case start == "" && end == "":
+		return true
+	case start == "":
+		return clock < end
+	case end == "":
+		return clock >= start
+	case start <= end:
+		return clock >= start && clock < end
+	default:
+		return clock >= start || clock < end
+	}
+}
+
+// pricingRuleReduction returns how much a rule takes off amount, the remaining price of the line
+func pricingRuleReduction(rule *models.PricingRule, line *models.TransactionProduct, amount float64) float64 {
+	var reduction float64
+	switch rule.Type {
+	case models.PricingRuleTypePercentage:
+		reduction = amount * rule.Value / 100
+	case models.PricingRuleTypeFixed:
+		reduction = rule.Value * float64(line.Quantity)
+	case models.PricingRuleTypeBuyXGetY:
+		if group := rule.BuyQuantity + rule.FreeQuantity; group > 0 {
+			free := line.Quantity / group * rule.FreeQuantity
+			reduction = float64(free) * line.UnitPrice
+		}
+	}
+	return roundAmount(math.Min(math.Max(reduction, 0), amount))
+}
+
+// roundAmount rounds an amount of money to two decimals
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
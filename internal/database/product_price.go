@@ -0,0 +1,246 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"maya-canteen/internal/database/repository"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// Reasons recorded for price history entries created when units are saved
+const (
+	priceReasonInitial = "Initial price"
+	priceReasonUpdated = "Price updated"
+)
+
+// Product price history operations
+func (s *service) InitProductPriceTable() error {
+	return s.productPriceRepository.InitTable()
+}
+
+func (s *service) GetProductPrice(id int64) (*models.ProductPrice, error) {
+	return s.productPriceRepository.Get(id)
+}
+
+// GetProductPriceTimeline returns the price history and scheduled prices of a product, oldest
+// first. The latest applied price of each unit is its current price.
+func (s *service) GetProductPriceTimeline(productID int64) ([]models.ProductPrice, error) {
+	prices, err := s.productPriceRepository.GetByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	setPriceStatuses(prices)
+	return prices, nil
+}
+
+// setPriceStatuses sets the status of each price of a timeline ordered oldest first
+func setPriceStatuses(prices []models.ProductPrice) {
+	current := make(map[string]int)
+	for i := range prices {
+		if prices[i].AppliedAt == nil {
+			prices[i].Status = models.ProductPriceStatusScheduled
+			continue
+		}
+		prices[i].Status = models.ProductPriceStatusPast
+		current[priceUnitKey(&prices[i])] = i
+	}
+	for _, i := range current {
+		prices[i].Status = models.ProductPriceStatusCurrent
+	}
+}
+
+// priceUnitKey identifies the unit of a price, by name once the unit is deleted
+func priceUnitKey(price *models.ProductPrice) string {
+	if price.UnitID != nil {
+		return fmt.Sprintf("%d:%d", price.ProductID, *price.UnitID)
+	}
+	return fmt.Sprintf("%d:%s", price.ProductID, price.UnitName)
+}
+
+// ScheduleProductPrice records a new price of a product unit. A price without an effective
+// time is applied right away, a price effective in the future once it is due.
+func (s *service) ScheduleProductPrice(price *models.ProductPrice) error {
+	now := time.Now()
+	if price.EffectiveFrom.IsZero() || !price.EffectiveFrom.After(now) {
+		price.EffectiveFrom = now
+	}
+	if price.EffectiveFrom.After(now) {
+		price.Status = models.ProductPriceStatusScheduled
+		return s.productPriceRepository.Create(price)
+	}
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		price.AppliedAt = &now
+		if err := s.productPriceRepository.WithTx(tx).Create(price); err != nil {
+			return err
+		}
+		return applyProductPrice(s.productUnitRepository.WithTx(tx), price)
+	})
+	if err != nil {
+		return err
+	}
+	price.Status = models.ProductPriceStatusCurrent
+	return nil
+}
+
+func (s *service) CancelScheduledProductPrice(id int64) (bool, error) {
+	return s.productPriceRepository.DeleteScheduled(id)
+}
+
+// ApplyDueProductPrices applies the scheduled prices effective at or before now and returns
+// the prices applied, also when applying a later price fails
+func (s *service) ApplyDueProductPrices(now time.Time) ([]models.ProductPrice, error) {
+	due, err := s.productPriceRepository.GetDue(now)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]models.ProductPrice, 0, len(due))
+	for _, price := range due {
+		err := s.withTx(func(tx *sql.Tx) error {
+			if err := s.productPriceRepository.WithTx(tx).MarkApplied(price.ID, now); err != nil {
+				return err
+			}
+			return applyProductPrice(s.productUnitRepository.WithTx(tx), &price)
+		})
+		if err != nil {
+			return applied, fmt.Errorf("applying price %d: %w", price.ID, err)
+		}
+		price.AppliedAt = &now
+		price.Status = models.ProductPriceStatusCurrent
+		applied = append(applied, price)
+	}
+	return applied, nil
+}
+
+// applyProductPrice sets the price of the unit of price, and the product price if the unit
+// is the default unit
+func applyProductPrice(units repository.ProductUnitRepositoryInterface, price *models.ProductPrice) error {
+	if price.UnitID == nil {
+		return nil
+	}
+	unit, err := units.Get(*price.UnitID)
+	if err != nil || unit == nil {
+		return err
+	}
+
+	unit.Price = price.Price
+	if err := units.Update(unit); err != nil {
+		return err
+	}
+	if unit.IsDefault {
+		return units.MakeDefault(unit)
+	}
+	return nil
+}
+
+// recordPriceChanges adds a price history entry for each unit of a product whose price
+// differs from its latest recorded price
+func (s *service) recordPriceChanges(productID int64) error {
+	units, err := s.productUnitRepository.GetByProductID(productID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, unit := range units {
+		latest, err := s.productPriceRepository.GetLatestApplied(unit.ID)
+		if err != nil {
+			return err
+		}
+		if latest != nil && latest.Price == unit.Price {
+			continue
+		}
+
+		reason := priceReasonUpdated
+		if latest == nil {
+			reason = priceReasonInitial
+		}
+		err = s.productPriceRepository.Create(&models.ProductPrice{
+			ProductID:     productID,
+			UnitID:        &unit.ID,
+			UnitName:      unit.Name,
+			Price:         unit.Price,
+			EffectiveFrom: now,
+			AppliedAt:     &now,
+			Reason:        reason,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPriceImpactReport compares the revenue of each price change made between startDate and
+// endDate with the revenue the same sales would have made at the old price. Each change is
+// measured until the next change of the unit or the end of the report.
+func (s *service) GetPriceImpactReport(startDate, endDate time.Time) (*models.PriceImpactReport, error) {
+	history, err := s.productPriceRepository.GetApplied()
+	if err != nil {
+		return nil, err
+	}
+	products, err := s.productRepository.GetAll(true)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(products))
+	for _, product := range products {
+		names[product.ID] = product.Name
+	}
+
+	report := &models.PriceImpactReport{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Changes:   make([]models.PriceChangeImpact, 0),
+	}
+	// Include the entire end day
+	reportEnd := endDate.Add(24 * time.Hour)
+	for _, change := range priceChanges(history, startDate, reportEnd) {
+		change.ProductName = names[change.ProductID]
+		quantity, revenue, err := s.productPriceRepository.GetUnitSales(change.ProductID, change.UnitID, change.UnitName, change.EffectiveFrom, change.EffectiveUntil)
+		if err != nil {
+			return nil, err
+		}
+		change.QuantitySold = quantity
+		change.Revenue = revenue
+		change.RevenueAtOldPrice = float64(quantity) * change.OldPrice
+		change.Impact = change.Revenue - change.RevenueAtOldPrice
+
+		report.Changes = append(report.Changes, change)
+		report.TotalImpact += change.Impact
+	}
+	return report, nil
+}
+
+// priceChanges returns the changes in history effective from start until before end.
+// The history must be grouped by unit and ordered oldest first within each unit.
+func priceChanges(history []models.ProductPrice, start, end time.Time) []models.PriceChangeImpact {
+	var changes []models.PriceChangeImpact
+	for i := 1; i < len(history); i++ {
+		previous, price := &history[i-1], &history[i]
+		if priceUnitKey(previous) != priceUnitKey(price) || previous.Price == price.Price {
+			continue
+		}
+		if price.EffectiveFrom.Before(start) || !price.EffectiveFrom.Before(end) {
+			continue
+		}
+
+		until := end
+		if i+1 < len(history) && priceUnitKey(&history[i+1]) == priceUnitKey(price) && history[i+1].EffectiveFrom.Before(end) {
+			until = history[i+1].EffectiveFrom
+		}
+		changes = append(changes, models.PriceChangeImpact{
+			PriceID:        price.ID,
+			ProductID:      price.ProductID,
+			UnitID:         price.UnitID,
+			UnitName:       price.UnitName,
+			OldPrice:       previous.Price,
+			NewPrice:       price.Price,
+			EffectiveFrom:  price.EffectiveFrom,
+			EffectiveUntil: until,
+		})
+	}
+	return changes
+}
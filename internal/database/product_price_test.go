@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDueProductPrices(t *testing.T) {
+	s := newTestService(t)
+	product := &models.Product{Name: "Tea", Price: 40, Active: true}
+	require.NoError(t, s.CreateProduct(product))
+	product, err := s.GetProduct(product.ID, false)
+	require.NoError(t, err)
+	require.NotEmpty(t, product.Units)
+
+	price := &models.ProductPrice{ProductID: product.ID, UnitID: &product.Units[0].ID, UnitName: product.Units[0].Name, Price: 50, EffectiveFrom: time.Now().Add(time.Hour)}
+	require.NoError(t, s.ScheduleProductPrice(price))
+
+	applied, err := s.ApplyDueProductPrices(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, applied, "the price is not due yet")
+
+	applied, err = s.ApplyDueProductPrices(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, price.ID, applied[0].ID)
+	assert.Equal(t, models.ProductPriceStatusCurrent, applied[0].Status)
+	assert.NotNil(t, applied[0].AppliedAt)
+	product, err = s.GetProduct(product.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, product.Price)
+}
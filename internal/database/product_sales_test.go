@@ -0,0 +1,39 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductSalesSummaryAfterDiscounts(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "7001")
+	meal := &models.Product{Name: "Meal", Price: 100, Active: true}
+	require.NoError(t, s.CreateProduct(meal))
+	require.NoError(t, s.CreatePricingRule(&models.PricingRule{
+		Name: "Lunch deal", Kind: models.PricingRuleKindDiscount, Type: models.PricingRuleTypePercentage,
+		Value: 10, ProductID: &meal.ID, Active: true,
+	}))
+
+	purchase := &models.Transaction{UserID: user.ID, Amount: 400, TransactionType: models.TransactionTypePurchase}
+	products := []models.TransactionProduct{{ProductID: meal.ID, ProductName: meal.Name, Quantity: 4, UnitPrice: 100}}
+	require.NoError(t, s.CreateTransactionWithProducts(purchase, products))
+	require.Equal(t, 360.0, purchase.Amount)
+	_, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 1}}, "", nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	summary, err := s.GetProductSalesSummary(today, today)
+	require.NoError(t, err)
+	require.Len(t, summary, 1)
+	assert.Equal(t, 3, summary[0].TotalQuantity)
+	assert.Equal(t, 270.0, summary[0].TotalSales, "three meals at the discounted price")
+	require.Len(t, summary[0].Variants, 1)
+	assert.Equal(t, 270.0, summary[0].Variants[0].TotalSales)
+}
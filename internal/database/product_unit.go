@@ -0,0 +1,161 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maya-canteen/internal/models"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidProductUnit is returned when a sold product refers to a unit it cannot be sold in
+var ErrInvalidProductUnit = errors.New("invalid product unit")
+
+// Product unit operations
+func (s *service) InitProductUnitTable() error {
+	return s.productUnitRepository.InitTable()
+}
+
+func (s *service) GetProductUnits(productID int64) ([]models.ProductUnit, error) {
+	return s.productUnitRepository.GetByProductID(productID)
+}
+
+func (s *service) GetProductUnit(id int64) (*models.ProductUnit, error) {
+	return s.productUnitRepository.Get(id)
+}
+
+// CreateProductUnit adds a unit to a product. A new default unit replaces the previous
+// default, and its price becomes the product price.
+func (s *service) CreateProductUnit(unit *models.ProductUnit) error {
+	err := s.withTx(func(tx *sql.Tx) error {
+		units := s.productUnitRepository.WithTx(tx)
+		if err := units.Create(unit); err != nil {
+			return err
+		}
+		if unit.IsDefault {
+			return units.MakeDefault(unit)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.recordPriceChanges(unit.ProductID)
+}
+
+// UpdateProductUnit updates a unit of a product, keeping the product price in sync with
+// the default unit
+func (s *service) UpdateProductUnit(unit *models.ProductUnit) error {
+	err := s.withTx(func(tx *sql.Tx) error {
+		units := s.productUnitRepository.WithTx(tx)
+		if err := units.Update(unit); err != nil {
+			return err
+		}
+		if unit.IsDefault {
+			return units.MakeDefault(unit)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.recordPriceChanges(unit.ProductID)
+}
+
+func (s *service) DeleteProductUnit(id int64) error {
+	return s.productUnitRepository.Delete(id)
+}
+
+// createProductUnits creates the units of a new product, or its default units when the
+// product has none. The first unit is the default unless another one is marked as such.
+func (s *service) createProductUnits(product *models.Product) error {
+	units := product.Units
+	if len(units) == 0 {
+		units = models.DefaultProductUnits(product)
+	}
+	if !slices.ContainsFunc(units, func(unit models.ProductUnit) bool { return unit.IsDefault }) {
+		units[0].IsDefault = true
+	}
+
+	for i := range units {
+		units[i].ProductID = product.ID
+		if units[i].StockFactor <= 0 {
+			units[i].StockFactor = 1
+		}
+		if err := s.productUnitRepository.Create(&units[i]); err != nil {
+			return err
+		}
+		if units[i].IsDefault {
+			if err := s.productUnitRepository.MakeDefault(&units[i]); err != nil {
+				return err
+			}
+			product.Price = units[i].Price
+		}
+	}
+
+	units, err := s.productUnitRepository.GetByProductID(product.ID)
+	if err != nil {
+		return err
+	}
+	product.Units = units
+	return nil
+}
+
+// attachProductUnits sets the units of each product
+func (s *service) attachProductUnits(products []models.Product) error {
+	units, err := s.productUnitRepository.GetAll()
+	if err != nil {
+		return err
+	}
+	byProduct := make(map[int64][]models.ProductUnit)
+	for _, unit := range units {
+		byProduct[unit.ProductID] = append(byProduct[unit.ProductID], unit)
+	}
+	for i := range products {
+		products[i].Units = byProduct[products[i].ID]
+	}
+	return nil
+}
+
+// resolveSaleUnits sets the unit, unit name and stock factor of each sold product. Products
+// without a unit ID are sold in their Single unit when IsSingleUnit is set, and in their
+// default unit otherwise. A unit price of zero is replaced by the price of the unit.
+func (s *service) resolveSaleUnits(products []models.TransactionProduct) error {
+	for i := range products {
+		sold := &products[i]
+
+		var unit *models.ProductUnit
+		var err error
+		switch {
+		case sold.UnitID != nil:
+			unit, err = s.productUnitRepository.Get(*sold.UnitID)
+			if err == nil && (unit == nil || unit.ProductID != sold.ProductID) {
+				return fmt.Errorf("%w: unit %d is not a unit of product %d", ErrInvalidProductUnit, *sold.UnitID, sold.ProductID)
+			}
+		case sold.IsSingleUnit:
+			unit, err = s.productUnitRepository.GetByName(sold.ProductID, models.ProductUnitSingle)
+			if err == nil && unit == nil {
+				return fmt.Errorf("%w: product %d is not sold as single units", ErrInvalidProductUnit, sold.ProductID)
+			}
+		default:
+			unit, err = s.productUnitRepository.GetDefault(sold.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if unit == nil {
+			sold.StockFactor = 1
+			continue
+		}
+		sold.UnitID = &unit.ID
+		sold.UnitName = unit.Name
+		sold.StockFactor = unit.StockFactor
+		sold.IsSingleUnit = strings.EqualFold(unit.Name, models.ProductUnitSingle)
+		if sold.UnitPrice == 0 {
+			sold.UnitPrice = unit.Price
+		}
+	}
+	return nil
+}
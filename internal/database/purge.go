@@ -0,0 +1,35 @@
+package database
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// PurgeDeleted permanently removes the users, products and transactions that were soft
+// deleted before the given time. Transactions go first, together with their products, so
+// that users and products they referenced can be purged too. Users that still have
+// transactions and products that were sold are kept, since reports refer to them.
+func (s *service) PurgeDeleted(before time.Time) (*models.PurgeResult, error) {
+	result := &models.PurgeResult{DeletedBefore: before}
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		transactions, err := s.transactionRepository.WithTx(tx).PurgeDeleted(before)
+		if err != nil {
+			return err
+		}
+		result.Transactions = transactions
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Users, err = s.userRepository.PurgeDeleted(before); err != nil {
+		return nil, err
+	}
+	if result.Products, err = s.productRepository.PurgeDeleted(before); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"maya-canteen/internal/models"
+)
+
+// ErrInvalidRefund is returned when a refund does not match the purchase it returns products of
+var ErrInvalidRefund = errors.New("invalid refund")
+
+// ErrPurchaseRefunded is returned when a purchase is deleted while refunds of it still exist
+var ErrPurchaseRefunded = errors.New("purchase has refunds")
+
+// RefundTransaction returns quantities of product lines of a purchase with a linked refund
+// transaction, which has the returned lines as its products. Returned products are taken off
+// sales reports, which restocks them. The refund credits the user's balance with the
+// employee share, or is paid out of the cash drawer for cash purchases, and takes the
+// subsidies of the returned lines and their part of the company share set on the purchase off
+// the employer invoice as its company share. It returns nil if the purchase does not exist.
+func (s *service) RefundTransaction(id int64, lines []models.RefundLine, reason string, cashSessionID *int64) (*models.Refund, error) {
+	var refund *models.Refund
+	err := s.withTx(func(tx *sql.Tx) error {
+		transactionRepository := s.transactionRepository.WithTx(tx)
+		transactionProductRepository := s.transactionProductRepository.WithTx(tx)
+
+		purchase, err := transactionRepository.Get(id, false)
+		if err != nil || purchase == nil {
+			return err
+		}
+		if purchase.TransactionType != models.TransactionTypePurchase {
+			return fmt.Errorf("%w: transaction %d is a %s, only purchases can be refunded", ErrInvalidRefund, id, purchase.TransactionType)
+		}
+		if err := s.offboardedError(purchase.UserID, ErrInvalidRefund); err != nil {
+			return err
+		}
+		products, err := transactionProductRepository.GetByTransactionID(id)
+		if err != nil {
+			return err
+		}
+		refunded, err := transactionProductRepository.GetRefundedQuantities(id)
+		if err != nil {
+			return err
+		}
+		returned, err := refundProducts(products, refunded, lines)
+		if err != nil {
+			return err
+		}
+
+		// Subsidies are granted per product line, so the company gets back exactly the
+		// subsidies of the lines returned, together with their part of any company share set
+		// on the purchase itself
+		manualShares := manualCompanyShares(purchase, products)
+		quantities := make(map[int64]int, len(products))
+		for _, product := range products {
+			quantities[product.ID] = product.Quantity
+		}
+		var amount, discount, companyShare float64
+		for _, line := range returned {
+			amount += line.UnitPrice*float64(line.Quantity) - line.DiscountAmount
+			discount += line.DiscountAmount
+			companyShare += line.SubsidyAmount
+			lineID := *line.RefundedLineID
+			companyShare += refundShare(manualShares[lineID], refunded[lineID], line.Quantity, quantities[lineID])
+		}
+
+		// Together the refunds of a purchase never return more than it cost, neither to the
+		// employee nor to the company
+		refundedAmount, refundedShare, err := transactionRepository.GetRefundedTotals(id)
+		if err != nil {
+			return err
+		}
+		amount = math.Min(roundAmount(amount), roundAmount(purchase.Amount-refundedAmount))
+		if amount <= 0 {
+			return fmt.Errorf("%w: transaction %d has already been refunded in full", ErrInvalidRefund, id)
+		}
+		remainingShare := math.Max(roundAmount(purchase.CompanyShare-refundedShare), 0)
+		remainingEmployeeShare := roundAmount(purchase.Amount - purchase.CompanyShare - (refundedAmount - refundedShare))
+		companyShare = math.Min(roundAmount(companyShare), remainingShare)
+		companyShare = math.Min(math.Max(companyShare, roundAmount(amount-remainingEmployeeShare)), amount)
+
+		description := fmt.Sprintf("Refund of transaction #%d", id)
+		if reason != "" {
+			description += ": " + reason
+		}
+		transaction := models.Transaction{
+			UserID:          purchase.UserID,
+			Amount:          amount,
+			Description:     description,
+			TransactionType: models.TransactionTypeRefund,
+			DiscountAmount:  roundAmount(discount),
+			CompanyShare:    companyShare,
+			PaymentMethod:   purchase.PaymentMethod,
+			CashSessionID:   cashSessionID,
+			RefundOfID:      &purchase.ID,
+		}
+		if err := createTransactionWithProducts(transactionRepository, transactionProductRepository, &transaction, returned); err != nil {
+			return err
+		}
+		refund = &models.Refund{Transaction: transaction, Products: returned}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// refundProducts returns the refund lines for the requested quantities of the products of a
+// purchase, given the quantities of each line already refunded. Discounts and subsidies are
+// refunded in proportion to the quantity returned, rounded so that refunding a line in
+// several parts returns exactly its discount and subsidy.
+func refundProducts(products []models.TransactionProduct, refunded map[int64]int, lines []models.RefundLine) ([]models.TransactionProduct, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: no lines to refund", ErrInvalidRefund)
+	}
+
+	requested := make(map[int64]int)
+	var order []int64
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity of line %d must be greater than zero", ErrInvalidRefund, line.TransactionProductID)
+		}
+		if _, ok := requested[line.TransactionProductID]; !ok {
+			order = append(order, line.TransactionProductID)
+		}
+		requested[line.TransactionProductID] += line.Quantity
+	}
+
+	byID := make(map[int64]models.TransactionProduct, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	returned := make([]models.TransactionProduct, 0, len(order))
+	for _, lineID := range order {
+		product, ok := byID[lineID]
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d is not part of the purchase", ErrInvalidRefund, lineID)
+		}
+		quantity := requested[lineID]
+		if remaining := product.Quantity - refunded[lineID]; quantity > remaining {
+			return nil, fmt.Errorf("%w: only %d of %s on line %d can still be refunded", ErrInvalidRefund, remaining, product.ProductName, lineID)
+		}
+
+		before := refunded[lineID]
+		refundedLineID := product.ID
+		returned = append(returned, models.TransactionProduct{
+			ProductID:      product.ProductID,
+			ProductName:    product.ProductName,
+			Quantity:       quantity,
+			UnitPrice:      product.UnitPrice,
+			IsSingleUnit:   product.IsSingleUnit,
+			UnitID:         product.UnitID,
+			UnitName:       product.UnitName,
+			StockFactor:    product.StockFactor,
+			DiscountRuleID: product.DiscountRuleID,
+			DiscountName:   product.DiscountName,
+			DiscountAmount: refundShare(product.DiscountAmount, before, quantity, product.Quantity),
+			SubsidyRuleID:  product.SubsidyRuleID,
+			SubsidyName:    product.SubsidyName,
+			SubsidyAmount:  refundShare(product.SubsidyAmount, before, quantity, product.Quantity),
+			RefundedLineID: &refundedLineID,
+		})
+	}
+	return returned, nil
+}
+
+// refundShare returns the part of a line amount that belongs to quantity units returned after
+// before units of the total were already refunded
+func refundShare(amount float64, before, quantity, total int) float64 {
+	refunded := roundAmount(amount * float64(before) / float64(total))
+	return roundAmount(roundAmount(amount*float64(before+quantity)/float64(total)) - refunded)
+}
+
+// manualCompanyShares spreads the part of a purchase's company share that is not a line
+// subsidy, such as a share set when the purchase was made, across its product lines in
+// proportion to their amounts, by line ID. The shares are rounded cumulatively so that they
+// add up to exactly that part.
+func manualCompanyShares(purchase *models.Transaction, products []models.TransactionProduct) map[int64]float64 {
+	shares := make(map[int64]float64, len(products))
+	var subsidies, total float64
+	for _, product := range products {
+		subsidies += product.SubsidyAmount
+		total += product.UnitPrice*float64(product.Quantity) - product.DiscountAmount
+	}
+	manual := roundAmount(purchase.CompanyShare - subsidies)
+	if manual <= 0 || total <= 0 {
+		return shares
+	}
+	var cumulative, allocated float64
+	for _, product := range products {
+		cumulative += product.UnitPrice*float64(product.Quantity) - product.DiscountAmount
+		upTo := roundAmount(manual * cumulative / total)
+		shares[product.ID] = roundAmount(upTo - allocated)
+		allocated = upTo
+	}
+	return shares
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createRefundPurchase buys three subsidised meals at 100 and a drink at 200 that is not
+// subsidised. Half of each meal is paid by the company.
+func createRefundPurchase(t *testing.T, s *service, userID int64) (*models.Transaction, []models.TransactionProduct) {
+	t.Helper()
+	meal := &models.Product{Name: "Meal", Price: 100, Active: true}
+	require.NoError(t, s.CreateProduct(meal))
+	drink := &models.Product{Name: "Drink", Price: 200, Active: true}
+	require.NoError(t, s.CreateProduct(drink))
+	require.NoError(t, s.CreatePricingRule(&models.PricingRule{
+		Name: "Meal subsidy", Kind: models.PricingRuleKindSubsidy, Type: models.PricingRuleTypePercentage,
+		Value: 50, ProductID: &meal.ID, Active: true,
+	}))
+
+	purchase := &models.Transaction{UserID: userID, Amount: 500, TransactionType: models.TransactionTypePurchase, PaymentMethod: models.PaymentMethodAccount}
+	products := []models.TransactionProduct{
+		{ProductID: meal.ID, ProductName: meal.Name, Quantity: 3, UnitPrice: 100},
+		{ProductID: drink.ID, ProductName: drink.Name, Quantity: 1, UnitPrice: 200},
+	}
+	require.NoError(t, s.CreateTransactionWithProducts(purchase, products))
+	require.Equal(t, 150.0, purchase.CompanyShare)
+	return purchase, products
+}
+
+func TestRefundTransactionReturnsSubsidyOfReturnedLines(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "1001")
+	purchase, products := createRefundPurchase(t, s, user.ID)
+	assert.Equal(t, -350.0, balanceOf(t, s, user.ID))
+
+	// A subsidised meal returns its own subsidy, not a share of the whole basket
+	refund, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 1}}, "cold", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, refund.Transaction.Amount)
+	assert.Equal(t, 50.0, refund.Transaction.CompanyShare)
+	assert.Equal(t, -300.0, balanceOf(t, s, user.ID))
+
+	// The drink is not subsidised, so the company gets nothing back
+	refund, err = s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[1].ID, Quantity: 1}}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, refund.Transaction.Amount)
+	assert.Equal(t, 0.0, refund.Transaction.CompanyShare)
+	assert.Equal(t, -100.0, balanceOf(t, s, user.ID))
+}
+
+func TestRefundTransactionReturnsCompanyShareOfPurchase(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "1005")
+	tea := &models.Product{Name: "Tea", Price: 100, Active: true}
+	require.NoError(t, s.CreateProduct(tea))
+	purchase := &models.Transaction{UserID: user.ID, Amount: 100, CompanyShare: 100, TransactionType: models.TransactionTypePurchase}
+	products := []models.TransactionProduct{{ProductID: tea.ID, ProductName: tea.Name, Quantity: 1, UnitPrice: 100}}
+	require.NoError(t, s.CreateTransactionWithProducts(purchase, products))
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID))
+
+	// The company paid for the tea, so the employee gets nothing back
+	refund, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 1}}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, refund.Transaction.CompanyShare)
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID))
+}
+
+func TestRefundTransactionSpreadsCompanyShareOfPurchase(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "1006")
+	meal := &models.Product{Name: "Meal", Price: 100, Active: true}
+	require.NoError(t, s.CreateProduct(meal))
+	drink := &models.Product{Name: "Drink", Price: 200, Active: true}
+	require.NoError(t, s.CreateProduct(drink))
+	require.NoError(t, s.CreatePricingRule(&models.PricingRule{
+		Name: "Meal subsidy", Kind: models.PricingRuleKindSubsidy, Type: models.PricingRuleTypePercentage,
+		Value: 50, ProductID: &meal.ID, Active: true,
+	}))
+	purchase := &models.Transaction{UserID: user.ID, Amount: 500, CompanyShare: 50, TransactionType: models.TransactionTypePurchase}
+	products := []models.TransactionProduct{
+		{ProductID: meal.ID, ProductName: meal.Name, Quantity: 3, UnitPrice: 100},
+		{ProductID: drink.ID, ProductName: drink.Name, Quantity: 1, UnitPrice: 200},
+	}
+	require.NoError(t, s.CreateTransactionWithProducts(purchase, products))
+	require.Equal(t, 200.0, purchase.CompanyShare, "the meal subsidies on top of the share set on the purchase")
+	assert.Equal(t, -300.0, balanceOf(t, s, user.ID))
+
+	// The 50 set on the purchase is spread 30 to the meals and 20 to the drink
+	refund, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[1].ID, Quantity: 1}}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, refund.Transaction.CompanyShare)
+	refund, err = s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 1}}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, refund.Transaction.CompanyShare)
+	refund, err = s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 2}}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 120.0, refund.Transaction.CompanyShare)
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID), "a full refund returns exactly what the employee paid")
+}
+
+func TestRefundTransactionCapsTotalAtPurchaseAmount(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "1007")
+	purchase, products := createRefundPurchase(t, s, user.ID)
+	purchase.Amount = 300
+	require.NoError(t, s.UpdateTransaction(purchase))
+
+	refund, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[1].ID, Quantity: 1}}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, refund.Transaction.Amount)
+	refund, err = s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 2}}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, refund.Transaction.Amount, "only what is left of the purchase amount is refunded")
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID))
+
+	_, err = s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 1}}, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidRefund)
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID))
+}
+
+func TestRefundTransactionRepeatedRefunds(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "1002")
+	purchase, products := createRefundPurchase(t, s, user.ID)
+	meal := products[0].ID
+
+	var companyShare float64
+	for range 3 {
+		refund, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: meal, Quantity: 1}}, "", nil)
+		require.NoError(t, err)
+		companyShare += refund.Transaction.CompanyShare
+	}
+	assert.Equal(t, 150.0, companyShare, "refunding every meal returns the whole subsidy")
+	assert.Equal(t, -200.0, balanceOf(t, s, user.ID), "only the drink is still owed")
+
+	_, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: meal, Quantity: 1}}, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidRefund, "no meals are left to refund")
+}
+
+func TestRefundTransactionPartialQuantity(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "1003")
+	purchase, products := createRefundPurchase(t, s, user.ID)
+
+	refund, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 2}}, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, refund.Transaction.Amount)
+	assert.Equal(t, 100.0, refund.Transaction.CompanyShare)
+	assert.Equal(t, -250.0, balanceOf(t, s, user.ID))
+
+	_, err = s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[0].ID, Quantity: 2}}, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidRefund, "only one meal is left to refund")
+}
+
+func TestDeleteRefundedPurchase(t *testing.T) {
+	s := newTestService(t)
+	user := createTestUser(t, s, "1004")
+	purchase, products := createRefundPurchase(t, s, user.ID)
+
+	refund, err := s.RefundTransaction(purchase.ID, []models.RefundLine{{TransactionProductID: products[1].ID, Quantity: 1}}, "", nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.DeleteTransaction(purchase.ID), ErrPurchaseRefunded)
+
+	require.NoError(t, s.DeleteTransaction(refund.Transaction.ID))
+	require.NoError(t, s.DeleteTransaction(purchase.ID))
+	assert.Equal(t, 0.0, balanceOf(t, s, user.ID))
+}
+
+func TestRefundShare(t *testing.T) {
+	// A third of 10.00 cannot be refunded exactly, so the parts are rounded to add up
+	assert.Equal(t, 3.33, refundShare(10, 0, 1, 3))
+	assert.Equal(t, 3.34, refundShare(10, 1, 1, 3))
+	assert.Equal(t, 3.33, refundShare(10, 2, 1, 3))
+	assert.Equal(t, 10.0, refundShare(10, 0, 3, 3))
+}
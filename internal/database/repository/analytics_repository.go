@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// created_at is stored as "YYYY-MM-DD HH:MM:SS..." in local time. Analytics group by the local
+// date and hour taken from the text, as SQLite's date functions would convert them to UTC.
+const (
+	localDateSQL = "substr(t.created_at, 1, 10)"
+	localHourSQL = "CAST(substr(t.created_at, 12, 2) AS INTEGER)"
+)
+
+// revenuePeriodSQL returns the SQL expression for the date the day, week or month of a sale starts on
+func revenuePeriodSQL(granularity string) (string, error) {
+	switch granularity {
+	case models.AnalyticsGranularityDay:
+		return localDateSQL, nil
+	case models.AnalyticsGranularityWeek:
+		// Moving to the coming Sunday and back six days gives the Monday of the week
+		return "date(" + localDateSQL + ", 'weekday 0', '-6 days')", nil
+	case models.AnalyticsGranularityMonth:
+		return "substr(t.created_at, 1, 7) || '-01'", nil
+	default:
+		return "", fmt.Errorf("unknown granularity %q", granularity)
+	}
+}
+
+// AnalyticsRepository aggregates sales for the analytics dashboards. It reads the transactions
+// and transaction_products tables and has no tables of its own.
+type AnalyticsRepository struct {
+	db DBTX
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// GetPeriods summarizes the sales from startDate to endDate and of the previous period from
+// previousStart up to startDate, in a single pass over both periods
+func (r *AnalyticsRepository) GetPeriods(previousStart, startDate, endDate time.Time) (models.AnalyticsPeriod, models.AnalyticsPeriod, error) {
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	var periods [2]models.AnalyticsPeriod
+	var activeDays [2]int
+	var customerDays [2]int
+	rows, err := r.db.Query(`
+		SELECT
+			CASE WHEN t.created_at >= ? THEN 0 ELSE 1 END AS period,
+			COALESCE(SUM(`+saleSignSQL("t")+` * t.amount), 0) AS revenue,
+			COUNT(CASE WHEN t.transaction_type = 'purchase' THEN 1 END) AS purchase_count,
+			COUNT(DISTINCT CASE WHEN t.transaction_type = 'purchase' THEN t.user_id END) AS unique_customers,
+			COUNT(DISTINCT CASE WHEN t.transaction_type = 'purchase' AND t.user_id IS NOT NULL
+				THEN `+localDateSQL+` || '#' || t.user_id END) AS customer_days,
+			COUNT(DISTINCT CASE WHEN t.transaction_type = 'purchase' THEN `+localDateSQL+` END) AS active_days
+		FROM transactions t
+		WHERE t.transaction_type IN ('purchase', 'refund')
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY period
+	`, startDate, previousStart, endDate)
+	if err != nil {
+		log.Errorf("Error getting analytics periods: %v", err)
+		return periods[0], periods[1], err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var index, customers, days int
+		var period models.AnalyticsPeriod
+		if err := rows.Scan(&index, &period.Revenue, &period.PurchaseCount, &period.UniqueCustomers, &customers, &days); err != nil {
+			log.Errorf("Error scanning analytics period row: %v", err)
+			return periods[0], periods[1], err
+		}
+		periods[index] = period
+		customerDays[index], activeDays[index] = customers, days
+	}
+	if err := rows.Err(); err != nil {
+		return periods[0], periods[1], err
+	}
+
+	itemRows, err := r.db.Query(`
+		SELECT
+			CASE WHEN t.created_at >= ? THEN 0 ELSE 1 END AS period,
+			COALESCE(SUM(`+saleSignSQL("t")+` * tp.quantity), 0) AS items
+		FROM transaction_products tp
+		JOIN transactions t ON tp.transaction_id = t.id
+		WHERE t.transaction_type IN ('purchase', 'refund')
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY period
+	`, startDate, previousStart, endDate)
+	if err != nil {
+		log.Errorf("Error getting analytics items: %v", err)
+		return periods[0], periods[1], err
+	}
+	defer itemRows.Close()
+	var items [2]float64
+	for itemRows.Next() {
+		var index int
+		var count float64
+		if err := itemRows.Scan(&index, &count); err != nil {
+			log.Errorf("Error scanning analytics items row: %v", err)
+			return periods[0], periods[1], err
+		}
+		items[index] = count
+	}
+	if err := itemRows.Err(); err != nil {
+		return periods[0], periods[1], err
+	}
+
+	for i := range periods {
+		if periods[i].PurchaseCount > 0 {
+			periods[i].AverageBasket = periods[i].Revenue / float64(periods[i].PurchaseCount)
+			periods[i].AverageItems = items[i] / float64(periods[i].PurchaseCount)
+		}
+		if activeDays[i] > 0 {
+			periods[i].AverageDailyCustomers = float64(customerDays[i]) / float64(activeDays[i])
+		}
+	}
+	return periods[0], periods[1], nil
+}
+
+// GetRevenue retrieves the revenue from startDate to endDate by day, week or month
+func (r *AnalyticsRepository) GetRevenue(startDate, endDate time.Time, granularity string) ([]models.RevenuePoint, error) {
+	periodSQL, err := revenuePeriodSQL(granularity)
+	if err != nil {
+		return nil, err
+	}
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	rows, err := r.db.Query(`
+		SELECT
+			`+periodSQL+` AS period,
+			COALESCE(SUM(`+saleSignSQL("t")+` * t.amount), 0) AS revenue,
+			COUNT(CASE WHEN t.transaction_type = 'purchase' THEN 1 END) AS purchase_count,
+			COALESCE(SUM(CASE WHEN t.transaction_type = 'refund' THEN t.amount ELSE 0 END), 0) AS refund_total
+		FROM transactions t
+		WHERE t.transaction_type IN ('purchase', 'refund')
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY period
+		ORDER BY period
+	`, startDate, endDate)
+	if err != nil {
+		log.Errorf("Error getting revenue: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]models.RevenuePoint, 0)
+	for rows.Next() {
+		var point models.RevenuePoint
+		if err := rows.Scan(&point.Period, &point.Revenue, &point.PurchaseCount, &point.RefundTotal); err != nil {
+			log.Errorf("Error scanning revenue row: %v", err)
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+// GetHeatmap retrieves the purchases from startDate to endDate by weekday and hour
+func (r *AnalyticsRepository) GetHeatmap(startDate, endDate time.Time) ([]models.HeatmapCell, error) {
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	rows, err := r.db.Query(`
+		SELECT
+			CAST(strftime('%w', `+localDateSQL+`) AS INTEGER) AS weekday,
+			`+localHourSQL+` AS hour,
+			COUNT(*) AS purchase_count,
+			COALESCE(SUM(t.amount), 0) AS revenue
+		FROM transactions t
+		WHERE t.transaction_type = 'purchase'
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY weekday, hour
+		ORDER BY weekday, hour
+	`, startDate, endDate)
+	if err != nil {
+		log.Errorf("Error getting sales heatmap: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cells := make([]models.HeatmapCell, 0)
+	for rows.Next() {
+		var cell models.HeatmapCell
+		if err := rows.Scan(&cell.Weekday, &cell.Hour, &cell.PurchaseCount, &cell.Revenue); err != nil {
+			log.Errorf("Error scanning sales heatmap row: %v", err)
+			return nil, err
+		}
+		cells = append(cells, cell)
+	}
+	return cells, rows.Err()
+}
+
+// GetDailyCustomers retrieves the number of users who made purchases on each day from
+// startDate to endDate with sales
+func (r *AnalyticsRepository) GetDailyCustomers(startDate, endDate time.Time) ([]models.DailyCustomers, error) {
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	rows, err := r.db.Query(`
+		SELECT
+			`+localDateSQL+` AS day,
+			COUNT(DISTINCT t.user_id) AS unique_customers,
+			COUNT(*) AS purchase_count
+		FROM transactions t
+		WHERE t.transaction_type = 'purchase'
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY day
+		ORDER BY day
+	`, startDate, endDate)
+	if err != nil {
+		log.Errorf("Error getting daily customers: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := make([]models.DailyCustomers, 0)
+	for rows.Next() {
+		var day models.DailyCustomers
+		if err := rows.Scan(&day.Date, &day.UniqueCustomers, &day.PurchaseCount); err != nil {
+			log.Errorf("Error scanning daily customers row: %v", err)
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// GetTopProducts retrieves the limit products with the highest revenue from startDate to
+// endDate, with their revenue in the previous period from previousStart up to startDate
+func (r *AnalyticsRepository) GetTopProducts(previousStart, startDate, endDate time.Time, limit int) ([]models.TopProduct, error) {
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	rows, err := r.db.Query(`
+		SELECT
+			tp.product_id,
+			MAX(tp.product_name) AS product_name,
+			COALESCE(SUM(CASE WHEN t.created_at >= ? THEN `+saleSignSQL("t")+` * tp.quantity ELSE 0 END), 0) AS quantity,
+			COALESCE(SUM(CASE WHEN t.created_at >= ? THEN `+saleSignSQL("t")+` * tp.quantity * tp.unit_price ELSE 0 END), 0) AS revenue,
+			COALESCE(SUM(CASE WHEN t.created_at < ? THEN `+saleSignSQL("t")+` * tp.quantity * tp.unit_price ELSE 0 END), 0) AS previous_revenue
+		FROM transaction_products tp
+		JOIN transactions t ON tp.transaction_id = t.id
+		WHERE t.transaction_type IN ('purchase', 'refund')
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY tp.product_id
+		HAVING revenue > 0
+		ORDER BY revenue DESC, quantity DESC
+		LIMIT ?
+	`, startDate, startDate, startDate, previousStart, endDate, limit)
+	if err != nil {
+		log.Errorf("Error getting top products: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]models.TopProduct, 0)
+	for rows.Next() {
+		product := models.TopProduct{Rank: len(products) + 1}
+		if err := rows.Scan(&product.ProductID, &product.ProductName, &product.Quantity, &product.Revenue, &product.PreviousRevenue); err != nil {
+			log.Errorf("Error scanning top product row: %v", err)
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+// GetTopUsers retrieves the limit users who spent the most from startDate to endDate, with
+// what they spent in the previous period from previousStart up to startDate
+func (r *AnalyticsRepository) GetTopUsers(previousStart, startDate, endDate time.Time, limit int) ([]models.TopUser, error) {
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	rows, err := r.db.Query(`
+		SELECT
+			u.id,
+			u.name,
+			u.employee_id,
+			COALESCE(SUM(CASE WHEN t.created_at >= ? THEN `+saleSignSQL("t")+` * t.amount ELSE 0 END), 0) AS spent,
+			COUNT(CASE WHEN t.created_at >= ? AND t.transaction_type = 'purchase' THEN 1 END) AS purchase_count,
+			COALESCE(SUM(CASE WHEN t.created_at < ? THEN `+saleSignSQL("t")+` * t.amount ELSE 0 END), 0) AS previous_spent
+		FROM transactions t
+		JOIN users u ON t.user_id = u.id
+		WHERE t.transaction_type IN ('purchase', 'refund')
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY u.id, u.name, u.employee_id
+		HAVING spent > 0
+		ORDER BY spent DESC, purchase_count DESC
+		LIMIT ?
+	`, startDate, startDate, startDate, previousStart, endDate, limit)
+	if err != nil {
+		log.Errorf("Error getting top users: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.TopUser, 0)
+	for rows.Next() {
+		user := models.TopUser{Rank: len(users) + 1}
+		if err := rows.Scan(&user.UserID, &user.UserName, &user.EmployeeID, &user.Spent, &user.PurchaseCount, &user.PreviousSpent); err != nil {
+			log.Errorf("Error scanning top user row: %v", err)
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
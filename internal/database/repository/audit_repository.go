@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"maya-canteen/internal/models"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AuditRepository handles all database operations related to the audit log
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// InitTable initializes the audit_logs table
+func (r *AuditRepository) InitTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS audit_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			changes TEXT NOT NULL DEFAULT '{}',
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity_type, entity_id);
+		CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+	`
+	_, err := r.db.Exec(query)
+	if err != nil {
+		log.Errorf("Error creating audit_logs table: %v", err)
+		return err
+	}
+	log.Info("Created Audit Logs Table")
+	return nil
+}
+
+// Create inserts a new audit log entry
+func (r *AuditRepository) Create(entry *models.AuditLog) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_logs (actor, action, entity_type, entity_id, changes, ip_address, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.Exec(query, entry.Actor, entry.Action, entry.EntityType, entry.EntityID, string(changes), entry.IPAddress, now)
+	if err != nil {
+		log.Errorf("Error inserting audit log: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	entry.ID = id
+	entry.CreatedAt = now
+	return nil
+}
+
+// List retrieves the audit log entries matching the filter, newest first
+func (r *AuditRepository) List(filter models.AuditLogFilter) ([]models.AuditLog, error) {
+	var conditions []string
+	var args []any
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.EntityType != "" {
+		conditions = append(conditions, "entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if !filter.StartDate.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.StartDate)
+	}
+	if !filter.EndDate.IsZero() {
+		// Include the whole end day
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.EndDate.Add(24*time.Hour-time.Second))
+	}
+
+	query := `SELECT id, actor, action, entity_type, entity_id, changes, ip_address, created_at FROM audit_logs`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Errorf("Error getting audit logs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditLog, 0)
+	for rows.Next() {
+		var entry models.AuditLog
+		var changes string
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Actor,
+			&entry.Action,
+			&entry.EntityType,
+			&entry.EntityID,
+			&changes,
+			&entry.IPAddress,
+			&entry.CreatedAt,
+		); err != nil {
+			log.Errorf("Error scanning audit log row: %v", err)
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+			log.Errorf("Error decoding audit log %d changes: %v", entry.ID, err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteBefore removes the audit log entries created before cutoff and returns how many were removed
+func (r *AuditRepository) DeleteBefore(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM audit_logs WHERE created_at < ?`, cutoff)
+	if err != nil {
+		log.Errorf("Error deleting old audit logs: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
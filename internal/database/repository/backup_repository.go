@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maya-canteen/internal/models"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BackupTables lists the tables included in a JSON backup, parents before children
+// so that a restore can insert them in order
+var BackupTables = []string{
+	"departments",
+	"users",
+	"categories",
+	"products",
+	"product_images",
+	"product_units",
+	"product_prices",
+	"pricing_rules",
+	"cash_sessions",
+	"transactions",
+	"transaction_products",
+	"menu_items",
+	"pre_orders",
+	"payment_statements",
+	"payment_lines",
+	"pending_deposits",
+	"balance_transfers",
+	"offboardings",
+	"top_up_bonus_rules",
+	"wallet_top_ups",
+	"installment_plans",
+	"installments",
+	"escalation_levels",
+	"escalation_notices",
+	"audit_logs",
+}
+
+// ErrInvalidBackup is returned when a backup bundle cannot be restored
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// requiredBackupTables must be present in a bundle for it to be restored
+var requiredBackupTables = []string{"users", "products", "transactions", "transaction_products"}
+
+// BackupRepository handles database backups and restores
+type BackupRepository struct {
+	db *sql.DB
+}
+
+// NewBackupRepository creates a new backup repository
+func NewBackupRepository(db *sql.DB) *BackupRepository {
+	return &BackupRepository{db: db}
+}
+
+// InitTable is a no-op, backups have no table of their own
+func (r *BackupRepository) InitTable() error {
+	return nil
+}
+
+// BackupTo writes a consistent snapshot of the database to path using VACUUM INTO.
+// path must not exist yet.
+func (r *BackupRepository) BackupTo(path string) error {
+	if _, err := r.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		log.Errorf("Error backing up database to %s: %v", path, err)
+		return err
+	}
+	return nil
+}
+
+// Export reads all backup tables into a JSON bundle inside a single read transaction
+func (r *BackupRepository) Export() (*models.BackupBundle, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bundle := &models.BackupBundle{
+		Version:   models.BackupBundleVersion,
+		CreatedAt: time.Now(),
+		Tables:    make(map[string][]map[string]any, len(BackupTables)),
+	}
+	for _, table := range BackupTables {
+		rows, err := exportTable(tx, table)
+		if err != nil {
+			log.Errorf("Error exporting %s table: %v", table, err)
+			return nil, err
+		}
+		bundle.Tables[table] = rows
+	}
+	return bundle, nil
+}
+
+// exportTable reads every row of a table as a column name to value map
+func exportTable(tx *sql.Tx, table string) ([]map[string]any, error) {
+	rows, err := tx.Query("SELECT * FROM " + table + " ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]any, 0)
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// Restore validates a JSON bundle and imports it in a single transaction.
+// Every backup table must be empty, apart from the default users a new database starts with
+// and their departments, unless overwrite is set, in which case all of their rows, including
+// users and the audit history, are replaced by those of the bundle.
+func (r *BackupRepository) Restore(bundle *models.BackupBundle, overwrite bool) (*models.RestoreResult, error) {
+	if err := validateBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if !overwrite {
+		if err := checkRestoreTarget(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Children first, so that nothing references a deleted row
+	for i := len(BackupTables) - 1; i >= 0; i-- {
+		if _, err := tx.Exec("DELETE FROM " + BackupTables[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &models.RestoreResult{Tables: make(map[string]int)}
+	for _, table := range BackupTables {
+		rows, ok := bundle.Tables[table]
+		if !ok {
+			continue
+		}
+		if table == "transactions" {
+			// Backups taken before transaction types were enforced may hold other types
+			for _, row := range rows {
+				if transactionType, ok := row["transaction_type"].(string); ok {
+					row["transaction_type"] = normalizeTransactionType(transactionType)
+				}
+			}
+		}
+		if err := restoreTable(tx, table, rows); err != nil {
+			log.Errorf("Error restoring %s table: %v", table, err)
+			return nil, err
+		}
+		result.Tables[table] = len(rows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkRestoreTarget returns an error unless every backup table is empty, apart from the
+// default users and their departments
+func checkRestoreTarget(tx *sql.Tx) error {
+	var defaultEmployeeIDs, defaultDepartments []any
+	for _, user := range defaultUsers {
+		defaultEmployeeIDs = append(defaultEmployeeIDs, user.EmployeeId)
+		defaultDepartments = append(defaultDepartments, user.Department)
+	}
+	for _, table := range BackupTables {
+		query := "SELECT COUNT(*) FROM " + table
+		var args []any
+		switch table {
+		case "users":
+			query += " WHERE employee_id NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(defaultEmployeeIDs)), ", ") + ")"
+			args = defaultEmployeeIDs
+		case "departments":
+			query += " WHERE name NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(defaultDepartments)), ", ") + ")"
+			args = defaultDepartments
+		}
+		var count int
+		if err := tx.QueryRow(query, args...).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: the %s table is not empty, restore needs an empty database unless it is overwritten", ErrInvalidBackup, table)
+		}
+	}
+	return nil
+}
+
+// validateBundle checks the bundle version, required tables and references between tables
+func validateBundle(bundle *models.BackupBundle) error {
+	if bundle.Version != models.BackupBundleVersion {
+		return fmt.Errorf("%w: unsupported bundle version %d", ErrInvalidBackup, bundle.Version)
+	}
+	for _, table := range requiredBackupTables {
+		if _, ok := bundle.Tables[table]; !ok {
+			return fmt.Errorf("%w: missing %s table", ErrInvalidBackup, table)
+		}
+	}
+	for table := range bundle.Tables {
+		if !slices.Contains(BackupTables, table) {
+			return fmt.Errorf("%w: unknown table %s", ErrInvalidBackup, table)
+		}
+	}
+
+	ids := make(map[string]map[string]bool)
+	for table, rows := range bundle.Tables {
+		ids[table] = make(map[string]bool, len(rows))
+		for i, row := range rows {
+			id, ok := row["id"]
+			if !ok || id == nil {
+				return fmt.Errorf("%w: %s row %d has no id", ErrInvalidBackup, table, i+1)
+			}
+			ids[table][fmt.Sprint(id)] = true
+		}
+	}
+
+	references := []struct{ table, column, parent string }{
+		{"transactions", "user_id", "users"},
+		{"transactions", "cash_session_id", "cash_sessions"},
+		{"transactions", "refund_of_id", "transactions"},
+		{"transaction_products", "transaction_id", "transactions"},
+		{"transaction_products", "product_id", "products"},
+		{"products", "category_id", "categories"},
+		{"product_images", "product_id", "products"},
+		{"product_units", "product_id", "products"},
+		{"product_prices", "product_id", "products"},
+		{"product_prices", "unit_id", "product_units"},
+		{"menu_items", "product_id", "products"},
+		{"menu_items", "unit_id", "product_units"},
+		{"pre_orders", "menu_item_id", "menu_items"},
+		{"pre_orders", "user_id", "users"},
+		{"pre_orders", "transaction_id", "transactions"},
+		{"payment_lines", "statement_id", "payment_statements"},
+		{"payment_lines", "user_id", "users"},
+		{"payment_lines", "transaction_id", "transactions"},
+		{"pending_deposits", "user_id", "users"},
+		{"pending_deposits", "transaction_id", "transactions"},
+		{"balance_transfers", "from_user_id", "users"},
+		{"balance_transfers", "to_user_id", "users"},
+		{"balance_transfers", "from_transaction_id", "transactions"},
+		{"balance_transfers", "to_transaction_id", "transactions"},
+		{"offboardings", "user_id", "users"},
+		{"offboardings", "transaction_id", "transactions"},
+		{"wallet_top_ups", "user_id", "users"},
+		{"wallet_top_ups", "bonus_rule_id", "top_up_bonus_rules"},
+		{"wallet_top_ups", "transaction_id", "transactions"},
+		{"wallet_top_ups", "bonus_transaction_id", "transactions"},
+		{"installment_plans", "user_id", "users"},
+		{"installments", "plan_id", "installment_plans"},
+		{"escalation_notices", "user_id", "users"},
+		{"escalation_notices", "level_id", "escalation_levels"},
+		{"transaction_products", "unit_id", "product_units"},
+		{"pricing_rules", "product_id", "products"},
+		{"pricing_rules", "category_id", "categories"},
+		{"pricing_rules", "department_id", "departments"},
+		{"pricing_rules", "user_id", "users"},
+		{"transaction_products", "discount_rule_id", "pricing_rules"},
+		{"transaction_products", "subsidy_rule_id", "pricing_rules"},
+		{"transaction_products", "refunded_line_id", "transaction_products"},
+	}
+	for _, ref := range references {
+		for i, row := range bundle.Tables[ref.table] {
+			if value := row[ref.column]; value != nil && !ids[ref.parent][fmt.Sprint(value)] {
+				return fmt.Errorf("%w: %s row %d references missing %s %v", ErrInvalidBackup, ref.table, i+1, ref.parent, value)
+			}
+		}
+	}
+	return nil
+}
+
+// restoreTable inserts the bundle rows of a table, ignoring columns the table does not have
+func restoreTable(tx *sql.Tx, table string, rows []map[string]any) error {
+	columnTypes, err := tableColumnTypes(tx, table)
+	if err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		var columns []string
+		var values []any
+		for column, value := range row {
+			columnType, ok := columnTypes[column]
+			if !ok {
+				continue
+			}
+			converted, err := convertBackupValue(columnType, value)
+			if err != nil {
+				return fmt.Errorf("%w: %s row %d column %s: %v", ErrInvalidBackup, table, i+1, column, err)
+			}
+			columns = append(columns, column)
+			values = append(values, converted)
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		query := "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + placeholders + ")"
+		if _, err := tx.Exec(query, values...); err != nil {
+			return fmt.Errorf("%s row %d: %w", table, i+1, err)
+		}
+	}
+	return nil
+}
+
+// tableColumnTypes returns the declared type of every column of a table
+func tableColumnTypes(tx *sql.Tx, table string) (map[string]string, error) {
+	rows, err := tx.Query(`SELECT name, type FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make(map[string]string)
+	for rows.Next() {
+		var name, columnType string
+		if err := rows.Scan(&name, &columnType); err != nil {
+			return nil, err
+		}
+		types[name] = strings.ToUpper(columnType)
+	}
+	return types, rows.Err()
+}
+
+// convertBackupValue converts a JSON decoded value back to what the column stores.
+// Timestamps are exported as RFC 3339 strings and blobs as base64 strings.
+func convertBackupValue(columnType string, value any) (any, error) {
+	text, isText := value.(string)
+	if !isText {
+		return value, nil
+	}
+	switch {
+	case strings.Contains(columnType, "DATE") || strings.Contains(columnType, "TIME"):
+		return time.Parse(time.RFC3339Nano, text)
+	case columnType == "BLOB":
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return value, nil
+}
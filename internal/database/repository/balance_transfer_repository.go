@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// balanceTransferQuery selects balance transfers with their users in the order
+// scanBalanceTransfer reads them
+const balanceTransferQuery = `
+	SELECT
+		bt.id,
+		bt.from_user_id,
+		fu.name,
+		bt.to_user_id,
+		tu.name,
+		bt.amount,
+		bt.note,
+		bt.status,
+		bt.requested_by,
+		bt.reviewed_by,
+		bt.reviewed_at,
+		bt.from_transaction_id,
+		bt.to_transaction_id,
+		bt.created_at,
+		bt.updated_at
+	FROM balance_transfers bt
+	JOIN users fu ON fu.id = bt.from_user_id
+	JOIN users tu ON tu.id = bt.to_user_id
+`
+
+// scanBalanceTransfer scans a row selected with balanceTransferQuery into a balance transfer
+func scanBalanceTransfer(row rowScanner, transfer *models.BalanceTransfer) error {
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&transfer.ID,
+		&transfer.FromUserID,
+		&transfer.FromUserName,
+		&transfer.ToUserID,
+		&transfer.ToUserName,
+		&transfer.Amount,
+		&transfer.Note,
+		&transfer.Status,
+		&transfer.RequestedBy,
+		&transfer.ReviewedBy,
+		&reviewedAt,
+		&transfer.FromTransactionID,
+		&transfer.ToTransactionID,
+		&transfer.CreatedAt,
+		&transfer.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if reviewedAt.Valid {
+		transfer.ReviewedAt = &reviewedAt.Time
+	}
+	return nil
+}
+
+// BalanceTransferRepository handles all database operations related to balance transfers
+type BalanceTransferRepository struct {
+	db DBTX
+}
+
+// NewBalanceTransferRepository creates a new balance transfer repository
+func NewBalanceTransferRepository(db *sql.DB) *BalanceTransferRepository {
+	return &BalanceTransferRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *BalanceTransferRepository) WithTx(tx *sql.Tx) BalanceTransferRepositoryInterface {
+	return &BalanceTransferRepository{db: tx}
+}
+
+// InitTable initializes the balance_transfers table
+func (r *BalanceTransferRepository) InitTable() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS balance_transfers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			from_user_id INTEGER NOT NULL REFERENCES users(id),
+			to_user_id INTEGER NOT NULL REFERENCES users(id),
+			amount REAL NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'pending',
+			requested_by TEXT NOT NULL DEFAULT '',
+			reviewed_by TEXT NOT NULL DEFAULT '',
+			reviewed_at DATETIME,
+			from_transaction_id INTEGER REFERENCES transactions(id),
+			to_transaction_id INTEGER REFERENCES transactions(id),
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_balance_transfers_from_user ON balance_transfers (from_user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_balance_transfers_to_user ON balance_transfers (to_user_id)`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.Exec(query); err != nil {
+			log.Errorf("Error creating balance transfers table: %v", err)
+			return err
+		}
+	}
+	log.Info("Created Balance Transfers Table")
+	return nil
+}
+
+// Create inserts a new pending balance transfer
+func (r *BalanceTransferRepository) Create(transfer *models.BalanceTransfer) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO balance_transfers (from_user_id, to_user_id, amount, note, status, requested_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, transfer.FromUserID, transfer.ToUserID, transfer.Amount, transfer.Note, models.BalanceTransferStatusPending, transfer.RequestedBy, now, now)
+	if err != nil {
+		log.Errorf("Error creating balance transfer: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	transfer.ID = id
+	transfer.Status = models.BalanceTransferStatusPending
+	transfer.CreatedAt = now
+	transfer.UpdatedAt = now
+	return nil
+}
+
+// GetAll retrieves the balance transfers with a status, or all transfers if status is
+// empty, the most recent first
+func (r *BalanceTransferRepository) GetAll(status string) ([]models.BalanceTransfer, error) {
+	if status == "" {
+		return r.list(balanceTransferQuery + ` ORDER BY bt.created_at DESC, bt.id DESC`)
+	}
+	return r.list(balanceTransferQuery+` WHERE bt.status = ? ORDER BY bt.created_at DESC, bt.id DESC`, status)
+}
+
+// GetByUser retrieves the balance transfers a user paid or received, the most recent first
+func (r *BalanceTransferRepository) GetByUser(userID int64) ([]models.BalanceTransfer, error) {
+	return r.list(balanceTransferQuery+` WHERE bt.from_user_id = ? OR bt.to_user_id = ? ORDER BY bt.created_at DESC, bt.id DESC`, userID, userID)
+}
+
+// list retrieves the balance transfers selected by query
+func (r *BalanceTransferRepository) list(query string, args ...any) ([]models.BalanceTransfer, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Errorf("Error getting balance transfers: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := make([]models.BalanceTransfer, 0)
+	for rows.Next() {
+		var transfer models.BalanceTransfer
+		if err := scanBalanceTransfer(rows, &transfer); err != nil {
+			log.Errorf("Error scanning balance transfer row: %v", err)
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// Get retrieves a single balance transfer by ID
+func (r *BalanceTransferRepository) Get(id int64) (*models.BalanceTransfer, error) {
+	var transfer models.BalanceTransfer
+	err := scanBalanceTransfer(r.db.QueryRow(balanceTransferQuery+` WHERE bt.id = ?`, id), &transfer)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting balance transfer: %v", err)
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// MarkCompleted marks a balance transfer as completed with the transactions created for it
+// and reports whether it was still pending
+func (r *BalanceTransferRepository) MarkCompleted(transfer *models.BalanceTransfer) (bool, error) {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		UPDATE balance_transfers
+		SET status = 'completed', reviewed_by = ?, reviewed_at = ?, from_transaction_id = ?, to_transaction_id = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, transfer.ReviewedBy, transfer.ReviewedAt, transfer.FromTransactionID, transfer.ToTransactionID, now, transfer.ID)
+	if err != nil {
+		log.Errorf("Error completing balance transfer: %v", err)
+		return false, err
+	}
+	completed, err := result.RowsAffected()
+	if completed > 0 {
+		transfer.Status = models.BalanceTransferStatusCompleted
+		transfer.UpdatedAt = now
+	}
+	return completed > 0, err
+}
+
+// Reject marks a balance transfer as rejected and reports whether it was still pending
+func (r *BalanceTransferRepository) Reject(id int64, reviewedBy, note string) (bool, error) {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		UPDATE balance_transfers
+		SET status = 'rejected', reviewed_by = ?, reviewed_at = ?, note = CASE WHEN ? = '' THEN note ELSE ? END, updated_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, reviewedBy, now, note, note, now, id)
+	if err != nil {
+		log.Errorf("Error rejecting balance transfer: %v", err)
+		return false, err
+	}
+	rejected, err := result.RowsAffected()
+	return rejected > 0, err
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// cashSessionColumns lists the cash_sessions columns in the order scanCashSession reads them
+const cashSessionColumns = `id, cashier, status, opening_float, cash_in, cash_sales, cash_out,
+	expected_amount, counted_amount, note, opened_at, closed_at, closed_by`
+
+// scanCashSession scans a row selected with cashSessionColumns into a cash session. The
+// difference is derived from the counted and expected amounts of closed sessions.
+func scanCashSession(row rowScanner, session *models.CashSession) error {
+	var countedAmount sql.NullFloat64
+	var closedAt sql.NullTime
+	err := row.Scan(
+		&session.ID,
+		&session.Cashier,
+		&session.Status,
+		&session.OpeningFloat,
+		&session.CashIn,
+		&session.CashSales,
+		&session.CashOut,
+		&session.ExpectedAmount,
+		&countedAmount,
+		&session.Note,
+		&session.OpenedAt,
+		&closedAt,
+		&session.ClosedBy,
+	)
+	if err != nil {
+		return err
+	}
+	if countedAmount.Valid {
+		difference := countedAmount.Float64 - session.ExpectedAmount
+		session.CountedAmount = &countedAmount.Float64
+		session.Difference = &difference
+	}
+	if closedAt.Valid {
+		session.ClosedAt = &closedAt.Time
+	}
+	return nil
+}
+
+// CashSessionRepository handles all database operations related to cash drawer sessions
+type CashSessionRepository struct {
+	db DBTX
+}
+
+// NewCashSessionRepository creates a new cash session repository
+func NewCashSessionRepository(db *sql.DB) *CashSessionRepository {
+	return &CashSessionRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *CashSessionRepository) WithTx(tx *sql.Tx) CashSessionRepositoryInterface {
+	return &CashSessionRepository{db: tx}
+}
+
+// InitTable initializes the cash_sessions table. A cashier can only have one open session.
+func (r *CashSessionRepository) InitTable() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS cash_sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cashier TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'open',
+			opening_float REAL NOT NULL DEFAULT 0,
+			cash_in REAL NOT NULL DEFAULT 0,
+			cash_sales REAL NOT NULL DEFAULT 0,
+			cash_out REAL NOT NULL DEFAULT 0,
+			expected_amount REAL NOT NULL DEFAULT 0,
+			counted_amount REAL,
+			note TEXT NOT NULL DEFAULT '',
+			opened_at DATETIME NOT NULL,
+			closed_at DATETIME,
+			closed_by TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_cash_sessions_open ON cash_sessions (cashier) WHERE status = 'open'`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.Exec(query); err != nil {
+			log.Errorf("Error creating cash sessions table: %v", err)
+			return err
+		}
+	}
+	log.Info("Created Cash Sessions Table")
+	return nil
+}
+
+// Create inserts a new open cash session
+func (r *CashSessionRepository) Create(session *models.CashSession) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO cash_sessions (cashier, status, opening_float, expected_amount, note, opened_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, session.Cashier, models.CashSessionStatusOpen, session.OpeningFloat, session.OpeningFloat, session.Note, now)
+	if err != nil {
+		log.Errorf("Error creating cash session: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	session.ID = id
+	session.Status = models.CashSessionStatusOpen
+	session.ExpectedAmount = session.OpeningFloat
+	session.OpenedAt = now
+	return nil
+}
+
+// GetAll retrieves all cash sessions, the most recently opened first
+func (r *CashSessionRepository) GetAll() ([]models.CashSession, error) {
+	rows, err := r.db.Query(`SELECT ` + cashSessionColumns + ` FROM cash_sessions ORDER BY opened_at DESC, id DESC`)
+	if err != nil {
+		log.Errorf("Error getting cash sessions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.CashSession, 0)
+	for rows.Next() {
+		var session models.CashSession
+		if err := scanCashSession(rows, &session); err != nil {
+			log.Errorf("Error scanning cash session row: %v", err)
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Get retrieves a single cash session by ID
+func (r *CashSessionRepository) Get(id int64) (*models.CashSession, error) {
+	return r.getOne(`SELECT `+cashSessionColumns+` FROM cash_sessions WHERE id = ?`, id)
+}
+
+// GetOpen retrieves the open cash session of a cashier, or nil if they have none
+func (r *CashSessionRepository) GetOpen(cashier string) (*models.CashSession, error) {
+	return r.getOne(`SELECT `+cashSessionColumns+` FROM cash_sessions WHERE cashier = ? AND status = 'open'`, cashier)
+}
+
+// getOne retrieves the cash session selected by query, or nil if there is none
+func (r *CashSessionRepository) getOne(query string, args ...any) (*models.CashSession, error) {
+	var session models.CashSession
+	err := scanCashSession(r.db.QueryRow(query, args...), &session)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting cash session: %v", err)
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetTotals sums the cash moved through the drawer by the transactions of a session that
+// were paid in cash and are not deleted
+func (r *CashSessionRepository) GetTotals(id int64) (models.CashSessionTotals, error) {
+	var totals models.CashSessionTotals
+	err := r.db.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN transaction_type IN ('deposit', 'top-up') THEN employee_share ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN transaction_type = 'purchase' THEN employee_share ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN transaction_type IN ('refund', 'adjustment') THEN employee_share ELSE 0 END), 0)
+		FROM transactions
+		WHERE cash_session_id = ? AND payment_method = 'cash' AND deleted_at IS NULL
+	`, id).Scan(&totals.CashIn, &totals.CashSales, &totals.CashOut)
+	if err != nil {
+		log.Errorf("Error getting cash session totals: %v", err)
+	}
+	return totals, err
+}
+
+// GetTransactions retrieves the transactions recorded in a session, oldest first
+func (r *CashSessionRepository) GetTransactions(id int64) ([]models.Transaction, error) {
+	rows, err := r.db.Query(`
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE cash_session_id = ? AND deleted_at IS NULL
+		ORDER BY created_at, id
+	`, id)
+	if err != nil {
+		log.Errorf("Error getting cash session transactions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0)
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := scanTransaction(rows, &transaction); err != nil {
+			log.Errorf("Error scanning transaction row: %v", err)
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+// Close records the totals and counted amount of a session and marks it closed. It reports
+// whether the session was still open.
+func (r *CashSessionRepository) Close(session *models.CashSession) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE cash_sessions
+		SET status = 'closed', cash_in = ?, cash_sales = ?, cash_out = ?, expected_amount = ?,
+			counted_amount = ?, note = ?, closed_at = ?, closed_by = ?
+		WHERE id = ? AND status = 'open'
+	`, session.CashIn, session.CashSales, session.CashOut, session.ExpectedAmount,
+		session.CountedAmount, session.Note, session.ClosedAt, session.ClosedBy, session.ID)
+	if err != nil {
+		log.Errorf("Error closing cash session: %v", err)
+		return false, err
+	}
+	closed, err := result.RowsAffected()
+	return closed > 0, err
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CategoryRepository handles all database operations related to product categories
+type CategoryRepository struct {
+	db *sql.DB
+}
+
+// NewCategoryRepository creates a new category repository
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// InitTable initializes the categories table
+func (r *CategoryRepository) InitTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS categories (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			sort_order INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)
+	`
+	_, err := r.db.Exec(query)
+	if err != nil {
+		log.Errorf("Error creating categories table: %v", err)
+		return err
+	}
+	log.Info("Created Categories Table")
+	return nil
+}
+
+// Create inserts a new category into the database
+func (r *CategoryRepository) Create(category *models.Category) error {
+	query := `INSERT INTO categories (name, sort_order, created_at, updated_at) VALUES (?, ?, ?, ?)`
+	now := time.Now()
+	result, err := r.db.Exec(query, category.Name, category.SortOrder, now, now)
+	if err != nil {
+		log.Errorf("Error inserting category: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	category.ID = id
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	return nil
+}
+
+// GetAll retrieves all categories in menu order
+func (r *CategoryRepository) GetAll() ([]models.Category, error) {
+	query := `SELECT id, name, sort_order, created_at, updated_at FROM categories ORDER BY sort_order ASC, name ASC`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Errorf("Error getting all categories: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var category models.Category
+		err := rows.Scan(
+			&category.ID,
+			&category.Name,
+			&category.SortOrder,
+			&category.CreatedAt,
+			&category.UpdatedAt,
+		)
+		if err != nil {
+			log.Errorf("Error scanning category row: %v", err)
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// Get retrieves a single category by ID
+func (r *CategoryRepository) Get(id int64) (*models.Category, error) {
+	query := `SELECT id, name, sort_order, created_at, updated_at FROM categories WHERE id = ?`
+	var category models.Category
+	err := r.db.QueryRow(query, id).Scan(
+		&category.ID,
+		&category.Name,
+		&category.SortOrder,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		log.Errorf("No category found with ID %d", id)
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting category by ID: %v", err)
+		return nil, err
+	}
+	return &category, nil
+}
+
+// Update updates an existing category
+func (r *CategoryRepository) Update(category *models.Category) error {
+	query := `UPDATE categories SET name = ?, sort_order = ?, updated_at = ? WHERE id = ?`
+	now := time.Now()
+	_, err := r.db.Exec(query, category.Name, category.SortOrder, now, category.ID)
+	if err != nil {
+		log.Errorf("Error updating category: %v", err)
+		return err
+	}
+	category.UpdatedAt = now
+	return nil
+}
+
+// Delete removes a category by ID, moves its products out of it and deactivates its pricing rules
+func (r *CategoryRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`UPDATE products SET category_id = NULL WHERE category_id = ?`, id)
+	if err != nil {
+		log.Errorf("Error unlinking products from category: %v", err)
+		return err
+	}
+
+	// Without their category the rules would apply to every product
+	_, err = r.db.Exec(`UPDATE pricing_rules SET category_id = NULL, active = 0 WHERE category_id = ?`, id)
+	if err != nil {
+		log.Errorf("Error deactivating pricing rules of category: %v", err)
+		return err
+	}
+
+	_, err = r.db.Exec(`DELETE FROM categories WHERE id = ?`, id)
+	if err != nil {
+		log.Errorf("Error deleting category: %v", err)
+		return err
+	}
+	return nil
+}
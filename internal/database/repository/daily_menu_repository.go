@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// menuItemQuery selects menu items with their product, unit and ordered quantity in the
+// order scanMenuItem reads them
+const menuItemQuery = `
+	SELECT
+		mi.id,
+		mi.menu_date,
+		mi.product_id,
+		p.name,
+		mi.unit_id,
+		pu.name,
+		pu.price,
+		mi.quantity_limit,
+		COALESCE((
+			SELECT SUM(po.quantity) FROM pre_orders po
+			WHERE po.menu_item_id = mi.id AND po.status <> 'cancelled'
+		), 0) AS ordered,
+		mi.created_at,
+		mi.updated_at
+	FROM menu_items mi
+	JOIN products p ON p.id = mi.product_id
+	JOIN product_units pu ON pu.id = mi.unit_id
+`
+
+// scanMenuItem scans a row selected with menuItemQuery into a menu item
+func scanMenuItem(row rowScanner, item *models.MenuItem) error {
+	var limit sql.NullInt64
+	err := row.Scan(
+		&item.ID,
+		&item.MenuDate,
+		&item.ProductID,
+		&item.ProductName,
+		&item.UnitID,
+		&item.UnitName,
+		&item.Price,
+		&limit,
+		&item.Ordered,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if limit.Valid {
+		quantityLimit := int(limit.Int64)
+		remaining := max(quantityLimit-item.Ordered, 0)
+		item.QuantityLimit = &quantityLimit
+		item.Remaining = &remaining
+	}
+	return nil
+}
+
+// preOrderQuery selects pre-orders with their user, product and unit in the order
+// scanPreOrder reads them
+const preOrderQuery = `
+	SELECT
+		po.id,
+		po.menu_item_id,
+		mi.menu_date,
+		po.user_id,
+		u.name,
+		u.employee_id,
+		mi.product_id,
+		p.name,
+		mi.unit_id,
+		pu.name,
+		po.quantity,
+		po.status,
+		po.transaction_id,
+		po.collected_at,
+		po.created_at,
+		po.updated_at
+	FROM pre_orders po
+	JOIN menu_items mi ON mi.id = po.menu_item_id
+	JOIN users u ON u.id = po.user_id
+	JOIN products p ON p.id = mi.product_id
+	JOIN product_units pu ON pu.id = mi.unit_id
+`
+
+// scanPreOrder scans a row selected with preOrderQuery into a pre-order
+func scanPreOrder(row rowScanner, order *models.PreOrder) error {
+	var collectedAt sql.NullTime
+	err := row.Scan(
+		&order.ID,
+		&order.MenuItemID,
+		&order.MenuDate,
+		&order.UserID,
+		&order.UserName,
+		&order.EmployeeID,
+		&order.ProductID,
+		&order.ProductName,
+		&order.UnitID,
+		&order.UnitName,
+		&order.Quantity,
+		&order.Status,
+		&order.TransactionID,
+		&collectedAt,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if collectedAt.Valid {
+		order.CollectedAt = &collectedAt.Time
+	}
+	return nil
+}
+
+// DailyMenuRepository handles all database operations related to daily menus and pre-orders
+type DailyMenuRepository struct {
+	db DBTX
+}
+
+// NewDailyMenuRepository creates a new daily menu repository
+func NewDailyMenuRepository(db *sql.DB) *DailyMenuRepository {
+	return &DailyMenuRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *DailyMenuRepository) WithTx(tx *sql.Tx) DailyMenuRepositoryInterface {
+	return &DailyMenuRepository{db: tx}
+}
+
+// InitTable initializes the menu_items and pre_orders tables
+func (r *DailyMenuRepository) InitTable() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS menu_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			menu_date TEXT NOT NULL,
+			product_id INTEGER NOT NULL REFERENCES products(id),
+			unit_id INTEGER NOT NULL REFERENCES product_units(id),
+			quantity_limit INTEGER,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			UNIQUE (menu_date, unit_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS pre_orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			menu_item_id INTEGER NOT NULL REFERENCES menu_items(id),
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			quantity INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			transaction_id INTEGER REFERENCES transactions(id),
+			collected_at DATETIME,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_pre_orders_pending ON pre_orders (menu_item_id, user_id) WHERE status = 'pending'`,
+		`CREATE INDEX IF NOT EXISTS idx_pre_orders_user ON pre_orders (user_id, status)`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.Exec(query); err != nil {
+			log.Errorf("Error creating daily menu tables: %v", err)
+			return err
+		}
+	}
+	log.Info("Created Daily Menu Tables")
+	return nil
+}
+
+// GetItems retrieves the items of the menu of a date, in menu order
+func (r *DailyMenuRepository) GetItems(date string) ([]models.MenuItem, error) {
+	rows, err := r.db.Query(menuItemQuery+` WHERE mi.menu_date = ? ORDER BY p.category_id IS NULL, p.category_id, p.sort_order, p.name, pu.sort_order`, date)
+	if err != nil {
+		log.Errorf("Error getting menu items: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.MenuItem, 0)
+	for rows.Next() {
+		var item models.MenuItem
+		if err := scanMenuItem(rows, &item); err != nil {
+			log.Errorf("Error scanning menu item row: %v", err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetItem retrieves a single menu item by ID
+func (r *DailyMenuRepository) GetItem(id int64) (*models.MenuItem, error) {
+	var item models.MenuItem
+	err := scanMenuItem(r.db.QueryRow(menuItemQuery+` WHERE mi.id = ?`, id), &item)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting menu item: %v", err)
+		return nil, err
+	}
+	return &item, nil
+}
+
+// SaveItem adds a product unit to the menu of a date, or updates its quantity limit if it
+// is already on the menu
+func (r *DailyMenuRepository) SaveItem(item *models.MenuItem) error {
+	query := `
+		INSERT INTO menu_items (menu_date, product_id, unit_id, quantity_limit, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (menu_date, unit_id) DO UPDATE SET
+			quantity_limit = excluded.quantity_limit,
+			updated_at = excluded.updated_at
+	`
+	now := time.Now()
+	_, err := r.db.Exec(query, item.MenuDate, item.ProductID, item.UnitID, item.QuantityLimit, now, now)
+	if err != nil {
+		log.Errorf("Error saving menu item: %v", err)
+	}
+	return err
+}
+
+// DeleteItem removes a menu item with its cancelled pre-orders
+func (r *DailyMenuRepository) DeleteItem(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM pre_orders WHERE menu_item_id = ? AND status = 'cancelled'`, id)
+	if err != nil {
+		log.Errorf("Error deleting cancelled pre-orders of menu item: %v", err)
+		return err
+	}
+	_, err = r.db.Exec(`DELETE FROM menu_items WHERE id = ?`, id)
+	if err != nil {
+		log.Errorf("Error deleting menu item: %v", err)
+	}
+	return err
+}
+
+// GetOrders retrieves the pending and collected pre-orders of a date, by product and employee
+func (r *DailyMenuRepository) GetOrders(date string) ([]models.PreOrder, error) {
+	return r.listOrders(preOrderQuery+` WHERE mi.menu_date = ? AND po.status <> 'cancelled' ORDER BY p.name, pu.name, u.name`, date)
+}
+
+// GetPendingOrdersForUser retrieves the pending pre-orders of a user for a date
+func (r *DailyMenuRepository) GetPendingOrdersForUser(userID int64, date string) ([]models.PreOrder, error) {
+	return r.listOrders(preOrderQuery+` WHERE po.user_id = ? AND mi.menu_date = ? AND po.status = 'pending' ORDER BY po.id`, userID, date)
+}
+
+func (r *DailyMenuRepository) listOrders(query string, args ...any) ([]models.PreOrder, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Errorf("Error getting pre-orders: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]models.PreOrder, 0)
+	for rows.Next() {
+		var order models.PreOrder
+		if err := scanPreOrder(rows, &order); err != nil {
+			log.Errorf("Error scanning pre-order row: %v", err)
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// GetOrder retrieves a single pre-order by ID
+func (r *DailyMenuRepository) GetOrder(id int64) (*models.PreOrder, error) {
+	return r.getOrder(preOrderQuery+` WHERE po.id = ?`, id)
+}
+
+// GetPendingOrder retrieves the pending pre-order of a user for a menu item
+func (r *DailyMenuRepository) GetPendingOrder(menuItemID, userID int64) (*models.PreOrder, error) {
+	return r.getOrder(preOrderQuery+` WHERE po.menu_item_id = ? AND po.user_id = ? AND po.status = 'pending'`, menuItemID, userID)
+}
+
+func (r *DailyMenuRepository) getOrder(query string, args ...any) (*models.PreOrder, error) {
+	var order models.PreOrder
+	err := scanPreOrder(r.db.QueryRow(query, args...), &order)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting pre-order: %v", err)
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CountActiveOrders returns the number of pending and collected pre-orders of a menu item
+func (r *DailyMenuRepository) CountActiveOrders(menuItemID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM pre_orders WHERE menu_item_id = ? AND status <> 'cancelled'`, menuItemID).Scan(&count)
+	if err != nil {
+		log.Errorf("Error counting pre-orders of menu item: %v", err)
+	}
+	return count, err
+}
+
+// CreateOrder inserts a new pending pre-order
+func (r *DailyMenuRepository) CreateOrder(order *models.PreOrder) error {
+	query := `
+		INSERT INTO pre_orders (menu_item_id, user_id, quantity, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.Exec(query, order.MenuItemID, order.UserID, order.Quantity, models.PreOrderStatusPending, now, now)
+	if err != nil {
+		log.Errorf("Error inserting pre-order: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	order.ID = id
+	order.Status = models.PreOrderStatusPending
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	return nil
+}
+
+// UpdateOrderQuantity changes the quantity of a pending pre-order
+func (r *DailyMenuRepository) UpdateOrderQuantity(id int64, quantity int) error {
+	_, err := r.db.Exec(`UPDATE pre_orders SET quantity = ?, updated_at = ? WHERE id = ? AND status = 'pending'`, quantity, time.Now(), id)
+	if err != nil {
+		log.Errorf("Error updating pre-order quantity: %v", err)
+	}
+	return err
+}
+
+// CancelOrder cancels a pending pre-order and reports whether it was found
+func (r *DailyMenuRepository) CancelOrder(id int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE pre_orders SET status = 'cancelled', updated_at = ? WHERE id = ? AND status = 'pending'`, time.Now(), id)
+	if err != nil {
+		log.Errorf("Error cancelling pre-order: %v", err)
+		return false, err
+	}
+	cancelled, err := result.RowsAffected()
+	return cancelled > 0, err
+}
+
+// MarkCollected marks a pending pre-order as collected with the purchase created for it
+func (r *DailyMenuRepository) MarkCollected(id, transactionID int64, collectedAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE pre_orders SET status = 'collected', transaction_id = ?, collected_at = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, transactionID, collectedAt, collectedAt, id)
+	if err != nil {
+		log.Errorf("Error marking pre-order as collected: %v", err)
+	}
+	return err
+}
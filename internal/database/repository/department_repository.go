@@ -0,0 +1,526 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DepartmentRepository handles all database operations related to departments
+type DepartmentRepository struct {
+	db DBTX
+}
+
+// NewDepartmentRepository creates a new department repository
+func NewDepartmentRepository(db *sql.DB) *DepartmentRepository {
+	return &DepartmentRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *DepartmentRepository) WithTx(tx *sql.Tx) DepartmentRepositoryInterface {
+	return &DepartmentRepository{db: tx}
+}
+
+// InitTable initializes the departments table and links existing users to it
+func (r *DepartmentRepository) InitTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS departments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			cost_center TEXT NOT NULL DEFAULT '',
+			billing_contact TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)
+	`
+	_, err := r.db.Exec(query)
+	if err != nil {
+		log.Errorf("Error creating departments table: %v", err)
+		return err
+	}
+	log.Info("Created Departments Table")
+
+	r.migrateUserDepartments()
+	return nil
+}
+
+// migrateUserDepartments creates a department for every free-text users.department
+// value and links users that have no department_id yet
+func (r *DepartmentRepository) migrateUserDepartments() {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		INSERT OR IGNORE INTO departments (name, created_at, updated_at)
+		SELECT DISTINCT TRIM(department), ?, ?
+		FROM users
+		WHERE TRIM(department) <> ''
+	`, now, now)
+	if err != nil {
+		log.Errorf("Error creating departments from users: %v", err)
+		return
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET department_id = (SELECT id FROM departments WHERE departments.name = TRIM(users.department))
+		WHERE department_id IS NULL AND TRIM(department) <> ''
+	`)
+	if err != nil {
+		log.Errorf("Error linking users to departments: %v", err)
+		return
+	}
+	if linked, _ := result.RowsAffected(); linked > 0 {
+		log.Infof("Linked %d users to departments", linked)
+	}
+}
+
+// Create inserts a new department into the database
+func (r *DepartmentRepository) Create(department *models.Department) error {
+	query := `
+		INSERT INTO departments (name, cost_center, billing_contact, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.Exec(
+		query,
+		department.Name,
+		department.CostCenter,
+		department.BillingContact,
+		now,
+		now,
+	)
+	if err != nil {
+		log.Errorf("Error inserting department: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	department.ID = id
+	department.CreatedAt = now
+	department.UpdatedAt = now
+	return nil
+}
+
+// GetAll retrieves all departments from the database
+func (r *DepartmentRepository) GetAll() ([]models.Department, error) {
+	query := `SELECT id, name, cost_center, billing_contact, created_at, updated_at FROM departments ORDER BY name ASC`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Errorf("Error getting all departments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var departments []models.Department
+	for rows.Next() {
+		var department models.Department
+		err := rows.Scan(
+			&department.ID,
+			&department.Name,
+			&department.CostCenter,
+			&department.BillingContact,
+			&department.CreatedAt,
+			&department.UpdatedAt,
+		)
+		if err != nil {
+			log.Errorf("Error scanning department row: %v", err)
+			return nil, err
+		}
+		departments = append(departments, department)
+	}
+	return departments, nil
+}
+
+// Get retrieves a single department by ID
+func (r *DepartmentRepository) Get(id int64) (*models.Department, error) {
+	query := `SELECT id, name, cost_center, billing_contact, created_at, updated_at FROM departments WHERE id = ?`
+	return r.getOne(query, id)
+}
+
+// GetByName retrieves a single department by its (case-insensitive) name
+func (r *DepartmentRepository) GetByName(name string) (*models.Department, error) {
+	query := `SELECT id, name, cost_center, billing_contact, created_at, updated_at FROM departments WHERE name = ?`
+	return r.getOne(query, name)
+}
+
+func (r *DepartmentRepository) getOne(query string, arg any) (*models.Department, error) {
+	var department models.Department
+	err := r.db.QueryRow(query, arg).Scan(
+		&department.ID,
+		&department.Name,
+		&department.CostCenter,
+		&department.BillingContact,
+		&department.CreatedAt,
+		&department.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		log.Errorf("No department found for %v", arg)
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting department: %v", err)
+		return nil, err
+	}
+	return &department, nil
+}
+
+// Update updates an existing department and keeps the users' department name in sync
+func (r *DepartmentRepository) Update(department *models.Department) error {
+	query := `
+		UPDATE departments
+		SET name = ?, cost_center = ?, billing_contact = ?, updated_at = ?
+		WHERE id = ?
+	`
+	now := time.Now()
+	_, err := r.db.Exec(
+		query,
+		department.Name,
+		department.CostCenter,
+		department.BillingContact,
+		now,
+		department.ID,
+	)
+	if err != nil {
+		log.Errorf("Error updating department: %v", err)
+		return err
+	}
+
+	_, err = r.db.Exec(`UPDATE users SET department = ? WHERE department_id = ?`, department.Name, department.ID)
+	if err != nil {
+		log.Errorf("Error updating department name for users: %v", err)
+		return err
+	}
+	department.UpdatedAt = now
+	return nil
+}
+
+// Delete removes a department by ID. Its users and pricing rules must be unlinked first.
+func (r *DepartmentRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM departments WHERE id = ?`, id)
+	if err != nil {
+		log.Errorf("Error deleting department: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetMonthlySpend retrieves purchases and deposits per department and month
+func (r *DepartmentRepository) GetMonthlySpend(startDate, endDate time.Time) ([]models.DepartmentSpend, error) {
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	// created_at is stored as "YYYY-MM-DD HH:MM:SS...", so the first 7 characters are the local month
+	query := `
+		SELECT
+			substr(t.created_at, 1, 7) AS month,
+			d.id,
+			d.name,
+			d.cost_center,
+			COALESCE(SUM(CASE WHEN t.transaction_type IN ('purchase', 'refund') THEN ` + saleSignSQL("t") + ` * t.amount ELSE 0 END), 0) AS purchase_total,
+			COALESCE(SUM(CASE WHEN t.transaction_type = 'deposit' THEN t.amount ELSE 0 END), 0) AS deposit_total,
+			COUNT(t.id) AS transaction_count,
+			COUNT(DISTINCT u.id) AS user_count
+		FROM transactions t
+		JOIN users u ON t.user_id = u.id
+		JOIN departments d ON u.department_id = d.id
+		WHERE t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY month, d.id, d.name, d.cost_center
+		ORDER BY month ASC, purchase_total DESC
+	`
+	rows, err := r.db.Query(query, startDate, endDate)
+	if err != nil {
+		log.Errorf("Error executing department spend query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spends []models.DepartmentSpend
+	for rows.Next() {
+		var spend models.DepartmentSpend
+		err := rows.Scan(
+			&spend.Month,
+			&spend.DepartmentID,
+			&spend.DepartmentName,
+			&spend.CostCenter,
+			&spend.PurchaseTotal,
+			&spend.DepositTotal,
+			&spend.TransactionCount,
+			&spend.UserCount,
+		)
+		if err != nil {
+			log.Errorf("Error scanning department spend row: %v", err)
+			return nil, err
+		}
+		spends = append(spends, spend)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error with department spend rows: %v", err)
+		return nil, err
+	}
+	return spends, nil
+}
+
+// GetTopConsumers retrieves the highest spending users of every department
+func (r *DepartmentRepository) GetTopConsumers(startDate, endDate time.Time, limit int) ([]models.DepartmentTopConsumer, error) {
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	query := `
+		SELECT department_id, department_name, rank, user_id, user_name, employee_id, total_spent, purchase_count
+		FROM (
+			SELECT
+				d.id AS department_id,
+				d.name AS department_name,
+				ROW_NUMBER() OVER (PARTITION BY d.id ORDER BY SUM(` + saleSignSQL("t") + ` * t.amount) DESC) AS rank,
+				u.id AS user_id,
+				u.name AS user_name,
+				u.employee_id,
+				SUM(` + saleSignSQL("t") + ` * t.amount) AS total_spent,
+				COUNT(CASE WHEN t.transaction_type = 'purchase' THEN t.id END) AS purchase_count
+			FROM transactions t
+			JOIN users u ON t.user_id = u.id
+			JOIN departments d ON u.department_id = d.id
+			WHERE t.transaction_type IN ('purchase', 'refund')
+			AND t.created_at BETWEEN ? AND ?
+			AND t.deleted_at IS NULL
+			GROUP BY d.id, u.id
+		)
+		WHERE rank <= ?
+		ORDER BY department_name ASC, rank ASC
+	`
+	rows, err := r.db.Query(query, startDate, endDate, limit)
+	if err != nil {
+		log.Errorf("Error executing department top consumers query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var consumers []models.DepartmentTopConsumer
+	for rows.Next() {
+		var consumer models.DepartmentTopConsumer
+		err := rows.Scan(
+			&consumer.DepartmentID,
+			&consumer.DepartmentName,
+			&consumer.Rank,
+			&consumer.UserID,
+			&consumer.UserName,
+			&consumer.EmployeeID,
+			&consumer.TotalSpent,
+			&consumer.PurchaseCount,
+		)
+		if err != nil {
+			log.Errorf("Error scanning department top consumer row: %v", err)
+			return nil, err
+		}
+		consumers = append(consumers, consumer)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error with department top consumer rows: %v", err)
+		return nil, err
+	}
+	return consumers, nil
+}
+
+// GetProductConsumption retrieves the products consumed by the users of every department,
+// with refunded products taken off
+func (r *DepartmentRepository) GetProductConsumption(startDate, endDate time.Time) ([]models.DepartmentProductConsumption, error) {
+	// Adjust endDate to include the entire day
+	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
+
+	query := `
+		SELECT
+			d.id,
+			d.name,
+			p.id,
+			p.name,
+			SUM(` + saleSignSQL("t") + ` * tp.quantity) AS quantity,
+			SUM(` + saleSignSQL("t") + ` * (tp.quantity * tp.unit_price - tp.discount_amount)) AS total_sales,
+			SUM(` + saleSignSQL("t") + ` * tp.subsidy_amount) AS company_share
+		FROM transaction_products tp
+		JOIN transactions t ON tp.transaction_id = t.id
+		JOIN products p ON tp.product_id = p.id
+		JOIN users u ON t.user_id = u.id
+		JOIN departments d ON u.department_id = d.id
+		WHERE t.transaction_type IN ('purchase', 'refund')
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY d.id, d.name, p.id, p.name
+		ORDER BY d.name ASC, total_sales DESC, p.name ASC
+	`
+	rows, err := r.db.Query(query, startDate, endDate)
+	if err != nil {
+		log.Errorf("Error executing department product consumption query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	consumption := make([]models.DepartmentProductConsumption, 0)
+	for rows.Next() {
+		var product models.DepartmentProductConsumption
+		err := rows.Scan(
+			&product.DepartmentID,
+			&product.DepartmentName,
+			&product.ProductID,
+			&product.ProductName,
+			&product.Quantity,
+			&product.TotalSales,
+			&product.CompanyShare,
+		)
+		if err != nil {
+			log.Errorf("Error scanning department product consumption row: %v", err)
+			return nil, err
+		}
+		consumption = append(consumption, product)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error with department product consumption rows: %v", err)
+		return nil, err
+	}
+	return consumption, nil
+}
+
+// GetBalances retrieves the outstanding balance of every department
+func (r *DepartmentRepository) GetBalances() ([]models.DepartmentBalance, error) {
+	query := `
+		SELECT
+			d.id,
+			d.name,
+			d.cost_center,
+			d.billing_contact,
+			COUNT(b.user_id) AS user_count,
+			COALESCE(SUM(CASE WHEN b.balance < 0 THEN 1 ELSE 0 END), 0) AS users_in_debt,
+			COALESCE(SUM(CASE WHEN b.balance < 0 THEN -b.balance ELSE 0 END), 0) AS outstanding_balance,
+			COALESCE(SUM(b.balance), 0) AS net_balance
+		FROM departments d
+		LEFT JOIN (
+			SELECT
+				users.id AS user_id,
+				users.department_id,
+				COALESCE(SUM(` + signedAmountSQL("transactions") + `), 0) AS balance
+			FROM users
+			LEFT JOIN transactions ON users.id = transactions.user_id AND transactions.deleted_at IS NULL
+			WHERE users.deleted_at IS NULL
+			GROUP BY users.id
+		) b ON b.department_id = d.id
+		GROUP BY d.id
+		ORDER BY outstanding_balance DESC, d.name ASC
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Errorf("Error executing department balances query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []models.DepartmentBalance
+	for rows.Next() {
+		var balance models.DepartmentBalance
+		err := rows.Scan(
+			&balance.DepartmentID,
+			&balance.DepartmentName,
+			&balance.CostCenter,
+			&balance.BillingContact,
+			&balance.UserCount,
+			&balance.UsersInDebt,
+			&balance.OutstandingBalance,
+			&balance.NetBalance,
+		)
+		if err != nil {
+			log.Errorf("Error scanning department balance row: %v", err)
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error with department balance rows: %v", err)
+		return nil, err
+	}
+	return balances, nil
+}
+
+// GetCompanyShares retrieves the transactions with a company share between periodStart and
+// periodEnd, totalled per user and grouped by department. Refunds are taken off.
+func (r *DepartmentRepository) GetCompanyShares(periodStart, periodEnd time.Time) ([]models.EmployerInvoiceDepartment, error) {
+	query := `
+		SELECT
+			d.id,
+			COALESCE(d.name, u.department),
+			COALESCE(d.cost_center, ''),
+			COALESCE(d.billing_contact, ''),
+			u.id,
+			u.employee_id,
+			u.name,
+			COUNT(t.id) AS transaction_count,
+			SUM(` + saleSignSQL("t") + ` * t.amount) AS total_amount,
+			SUM(` + saleSignSQL("t") + ` * t.employee_share) AS employee_share,
+			SUM(` + saleSignSQL("t") + ` * t.company_share) AS company_share
+		FROM transactions t
+		JOIN users u ON t.user_id = u.id
+		LEFT JOIN departments d ON u.department_id = d.id
+		WHERE t.company_share > 0
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY d.id, u.id
+		ORDER BY COALESCE(d.name, u.department) ASC, d.id ASC, u.name ASC
+	`
+	rows, err := r.db.Query(query, periodStart, periodEnd)
+	if err != nil {
+		log.Errorf("Error executing company shares query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	departments := make([]models.EmployerInvoiceDepartment, 0)
+	for rows.Next() {
+		var department models.EmployerInvoiceDepartment
+		var line models.EmployerInvoiceLine
+		err := rows.Scan(
+			&department.DepartmentID,
+			&department.DepartmentName,
+			&department.CostCenter,
+			&department.BillingContact,
+			&line.UserID,
+			&line.EmployeeID,
+			&line.Name,
+			&line.TransactionCount,
+			&line.TotalAmount,
+			&line.EmployeeShare,
+			&line.CompanyShare,
+		)
+		if err != nil {
+			log.Errorf("Error scanning company shares row: %v", err)
+			return nil, err
+		}
+
+		last := len(departments) - 1
+		if last < 0 || !sameDepartment(departments[last], department) {
+			departments = append(departments, department)
+			last++
+		}
+		current := &departments[last]
+		current.TransactionCount += line.TransactionCount
+		current.TotalAmount += line.TotalAmount
+		current.EmployeeShare += line.EmployeeShare
+		current.CompanyShare += line.CompanyShare
+		current.Employees = append(current.Employees, line)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error with company shares rows: %v", err)
+		return nil, err
+	}
+	return departments, nil
+}
+
+// sameDepartment reports whether two invoice rows belong to the same department. Users
+// without a department are grouped by their department name.
+func sameDepartment(a, b models.EmployerInvoiceDepartment) bool {
+	if a.DepartmentID == nil || b.DepartmentID == nil {
+		return a.DepartmentID == nil && b.DepartmentID == nil && a.DepartmentName == b.DepartmentName
+	}
+	return *a.DepartmentID == *b.DepartmentID
+}
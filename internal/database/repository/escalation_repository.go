@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// escalationLevelColumns lists the escalation_levels columns in the order scanEscalationLevel reads them
+const escalationLevelColumns = `id, name, min_age_days, min_amount, message_template, manager_phone, repeat_days,
+	active, created_at, updated_at`
+
+// scanEscalationLevel scans a row selected with escalationLevelColumns into an escalation level
+func scanEscalationLevel(row rowScanner, level *models.EscalationLevel) error {
+	return row.Scan(
+		&level.ID,
+		&level.Name,
+		&level.MinAgeDays,
+		&level.MinAmount,
+		&level.MessageTemplate,
+		&level.ManagerPhone,
+		&level.RepeatDays,
+		&level.Active,
+		&level.CreatedAt,
+		&level.UpdatedAt,
+	)
+}
+
+// EscalationRepository handles all database operations related to the escalation of overdue balances
+type EscalationRepository struct {
+	db DBTX
+}
+
+// NewEscalationRepository creates a new escalation repository
+func NewEscalationRepository(db *sql.DB) *EscalationRepository {
+	return &EscalationRepository{db: db}
+}
+
+// InitTable initializes the escalation_levels and escalation_notices tables
+func (r *EscalationRepository) InitTable() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS escalation_levels (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			min_age_days INTEGER NOT NULL,
+			min_amount REAL NOT NULL DEFAULT 0,
+			message_template TEXT NOT NULL,
+			manager_phone TEXT NOT NULL DEFAULT '',
+			repeat_days INTEGER NOT NULL DEFAULT 7,
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS escalation_notices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			level_id INTEGER REFERENCES escalation_levels(id),
+			level_name TEXT NOT NULL,
+			amount REAL NOT NULL,
+			age_days INTEGER NOT NULL,
+			manager_notified BOOLEAN NOT NULL DEFAULT 0,
+			sent_by TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_escalation_notices_user ON escalation_notices (user_id, created_at)`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.Exec(query); err != nil {
+			log.Errorf("Error creating escalation tables: %v", err)
+			return err
+		}
+	}
+	log.Info("Created Escalation Tables")
+	return nil
+}
+
+// GetLedger retrieves the effect of every transaction of the users that are not deleted on
+// their balances, by user and in the order the transactions were made. Transactions that do
+// not change the balance, such as purchases paid in cash, are left out.
+func (r *EscalationRepository) GetLedger() ([]models.LedgerEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT t.user_id, ` + signedAmountSQL("t") + ` AS amount, t.created_at
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.deleted_at IS NULL AND u.deleted_at IS NULL AND amount != 0
+		ORDER BY t.user_id, t.created_at, t.id
+	`)
+	if err != nil {
+		log.Errorf("Error getting ledger: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.LedgerEntry, 0)
+	for rows.Next() {
+		var entry models.LedgerEntry
+		if err := rows.Scan(&entry.UserID, &entry.Amount, &entry.CreatedAt); err != nil {
+			log.Errorf("Error scanning ledger row: %v", err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// CreateLevel inserts a new escalation level
+func (r *EscalationRepository) CreateLevel(level *models.EscalationLevel) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO escalation_levels (name, min_age_days, min_amount, message_template, manager_phone, repeat_days, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, level.Name, level.MinAgeDays, level.MinAmount, level.MessageTemplate, level.ManagerPhone, level.RepeatDays, level.Active, now, now)
+	if err != nil {
+		log.Errorf("Error inserting escalation level: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	level.ID = id
+	level.CreatedAt = now
+	level.UpdatedAt = now
+	return nil
+}
+
+// GetLevels retrieves all escalation levels, or only the active ones if activeOnly is set,
+// from the earliest to the latest
+func (r *EscalationRepository) GetLevels(activeOnly bool) ([]models.EscalationLevel, error) {
+	query := `SELECT ` + escalationLevelColumns + ` FROM escalation_levels`
+	if activeOnly {
+		query += ` WHERE active = 1`
+	}
+	query += ` ORDER BY min_age_days ASC, min_amount ASC, id ASC`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Errorf("Error getting escalation levels: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := make([]models.EscalationLevel, 0)
+	for rows.Next() {
+		var level models.EscalationLevel
+		if err := scanEscalationLevel(rows, &level); err != nil {
+			log.Errorf("Error scanning escalation level row: %v", err)
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	return levels, rows.Err()
+}
+
+// GetLevel retrieves a single escalation level by ID
+func (r *EscalationRepository) GetLevel(id int64) (*models.EscalationLevel, error) {
+	var level models.EscalationLevel
+	err := scanEscalationLevel(r.db.QueryRow(`SELECT `+escalationLevelColumns+` FROM escalation_levels WHERE id = ?`, id), &level)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting escalation level by ID: %v", err)
+		return nil, err
+	}
+	return &level, nil
+}
+
+// UpdateLevel updates an existing escalation level
+func (r *EscalationRepository) UpdateLevel(level *models.EscalationLevel) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		UPDATE escalation_levels
+		SET name = ?, min_age_days = ?, min_amount = ?, message_template = ?, manager_phone = ?, repeat_days = ?, active = ?, updated_at = ?
+		WHERE id = ?
+	`, level.Name, level.MinAgeDays, level.MinAmount, level.MessageTemplate, level.ManagerPhone, level.RepeatDays, level.Active, now, level.ID)
+	if err != nil {
+		log.Errorf("Error updating escalation level: %v", err)
+		return err
+	}
+	level.UpdatedAt = now
+	return nil
+}
+
+// DeleteLevel removes an escalation level by ID. Notices keep the name of the level, only
+// their reference to it is cleared.
+func (r *EscalationRepository) DeleteLevel(id int64) error {
+	_, err := r.db.Exec(`UPDATE escalation_notices SET level_id = NULL WHERE level_id = ?`, id)
+	if err != nil {
+		log.Errorf("Error unlinking notices from escalation level: %v", err)
+		return err
+	}
+	_, err = r.db.Exec(`DELETE FROM escalation_levels WHERE id = ?`, id)
+	if err != nil {
+		log.Errorf("Error deleting escalation level: %v", err)
+		return err
+	}
+	return nil
+}
+
+// CreateNotice records an escalation sent to a user
+func (r *EscalationRepository) CreateNotice(notice *models.EscalationNotice) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO escalation_notices (user_id, level_id, level_name, amount, age_days, manager_notified, sent_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, notice.UserID, notice.LevelID, notice.LevelName, notice.Amount, notice.AgeDays, notice.ManagerNotified, notice.SentBy, now)
+	if err != nil {
+		log.Errorf("Error inserting escalation notice: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	notice.ID = id
+	notice.CreatedAt = now
+	return nil
+}
+
+// GetNotices retrieves the escalation notices sent since a time, the most recent first
+func (r *EscalationRepository) GetNotices(since time.Time) ([]models.EscalationNotice, error) {
+	rows, err := r.db.Query(`
+		SELECT n.id, n.user_id, u.name, n.level_id, n.level_name, n.amount, n.age_days, n.manager_notified, n.sent_by, n.created_at
+		FROM escalation_notices n
+		JOIN users u ON u.id = n.user_id
+		WHERE n.created_at >= ?
+		ORDER BY n.created_at DESC, n.id DESC
+	`, since)
+	if err != nil {
+		log.Errorf("Error getting escalation notices: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	notices := make([]models.EscalationNotice, 0)
+	for rows.Next() {
+		var notice models.EscalationNotice
+		var levelID sql.NullInt64
+		err := rows.Scan(&notice.ID, &notice.UserID, &notice.UserName, &levelID, &notice.LevelName, &notice.Amount,
+			&notice.AgeDays, &notice.ManagerNotified, &notice.SentBy, &notice.CreatedAt)
+		if err != nil {
+			log.Errorf("Error scanning escalation notice row: %v", err)
+			return nil, err
+		}
+		if levelID.Valid {
+			notice.LevelID = &levelID.Int64
+		}
+		notices = append(notices, notice)
+	}
+	return notices, rows.Err()
+}
@@ -2,7 +2,11 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 	"reflect"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // Entity represents a database entity with ID, CreatedAt, and UpdatedAt fields
@@ -13,6 +17,19 @@ type Entity interface {
 	SetUpdatedAt(timestamp any)
 }
 
+// DBTX is the subset of *sql.DB and *sql.Tx used by repositories, so that a
+// repository can run its queries inside a database transaction
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 // GenericRepository provides a generic implementation for common repository operations
 type GenericRepository struct {
 	db        *sql.DB
@@ -119,3 +136,123 @@ func ScanRows(rows *sql.Rows, entityType reflect.Type) (any, error) {
 
 	return slice.Interface(), nil
 }
+
+// renameColumnIfNeeded renames a column of an existing table if it still has its old name
+func renameColumnIfNeeded(db DBTX, table, from, to string) {
+	var colExists bool
+	err := db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info(?)
+		WHERE name = ?
+	`, table, from).Scan(&colExists)
+	if err != nil || !colExists {
+		if err != nil {
+			log.Errorf("Error checking if %s column exists in %s table: %v", from, table, err)
+		}
+		return
+	}
+
+	_, err = db.Exec("ALTER TABLE " + table + " RENAME COLUMN " + from + " TO " + to)
+	if err != nil {
+		log.Errorf("Error renaming %s column of %s table to %s: %v", from, table, to, err)
+	} else {
+		log.Infof("Renamed %s column of %s table to %s", from, table, to)
+	}
+}
+
+// addColumnIfNeeded adds a column to an existing table if it does not exist yet.
+// definition is the column type and constraints, e.g. "INTEGER DEFAULT NULL".
+func addColumnIfNeeded(db DBTX, table, column, definition string) {
+	var colExists bool
+	err := db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info(?)
+		WHERE name = ?
+	`, table, column).Scan(&colExists)
+
+	if err != nil || colExists {
+		if err != nil {
+			log.Errorf("Error checking if %s column exists in %s table: %v", column, table, err)
+		}
+		return // Either error occurred or column already exists
+	}
+
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	if err != nil {
+		log.Errorf("Error adding %s column to %s table: %v", column, table, err)
+	} else {
+		log.Infof("Added %s column to %s table", column, table)
+	}
+}
+
+// rebuildTable recreates a table from its column definitions and copies over the columns the
+// old and new table have in common, for schema changes SQLite cannot make with ALTER TABLE,
+// such as dropping a NOT NULL constraint. columns is the body of the CREATE TABLE statement.
+// Indexes of the table are dropped with it and must be recreated by the caller. Foreign keys
+// must not be enforced, which SQLite does not do by default.
+func rebuildTable(db DBTX, table, columns string) error {
+	if sqlDB, ok := db.(*sql.DB); ok {
+		tx, err := sqlDB.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := rebuildTable(tx, table, columns); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	// Dropping the old table would cascade to its children if foreign keys were enforced
+	var foreignKeys bool
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return err
+	}
+	if foreignKeys {
+		return fmt.Errorf("cannot rebuild %s table while foreign keys are enforced", table)
+	}
+
+	rebuilt := table + "_rebuilt"
+	if _, err := db.Exec("CREATE TABLE " + rebuilt + " (" + columns + ")"); err != nil {
+		log.Errorf("Error creating rebuilt %s table: %v", table, err)
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT old.name
+		FROM pragma_table_info(?) old
+		JOIN pragma_table_info(?) new ON new.name = old.name
+		ORDER BY new.cid
+	`, table, rebuilt)
+	if err != nil {
+		return err
+	}
+	var shared []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		shared = append(shared, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	list := strings.Join(shared, ", ")
+	queries := []string{
+		"INSERT INTO " + rebuilt + " (" + list + ") SELECT " + list + " FROM " + table,
+		"DROP TABLE " + table,
+		"ALTER TABLE " + rebuilt + " RENAME TO " + table,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			log.Errorf("Error rebuilding %s table: %v", table, err)
+			return err
+		}
+	}
+	log.Infof("Rebuilt %s table", table)
+	return nil
+}
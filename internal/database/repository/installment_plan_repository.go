@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// installmentPlanQuery selects installment plans with their users in the order
+// scanInstallmentPlan reads them
+const installmentPlanQuery = `
+	SELECT
+		p.id,
+		p.user_id,
+		u.name,
+		u.employee_id,
+		p.total_amount,
+		p.installment_count,
+		p.status,
+		p.note,
+		p.created_by,
+		p.created_at,
+		p.updated_at
+	FROM installment_plans p
+	JOIN users u ON u.id = p.user_id
+`
+
+// scanInstallmentPlan scans a row selected with installmentPlanQuery into an installment plan
+func scanInstallmentPlan(row rowScanner, plan *models.InstallmentPlan) error {
+	return row.Scan(
+		&plan.ID,
+		&plan.UserID,
+		&plan.UserName,
+		&plan.EmployeeID,
+		&plan.TotalAmount,
+		&plan.InstallmentCount,
+		&plan.Status,
+		&plan.Note,
+		&plan.CreatedBy,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+}
+
+// InstallmentPlanRepository handles all database operations related to installment plans
+type InstallmentPlanRepository struct {
+	db DBTX
+}
+
+// NewInstallmentPlanRepository creates a new installment plan repository
+func NewInstallmentPlanRepository(db *sql.DB) *InstallmentPlanRepository {
+	return &InstallmentPlanRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *InstallmentPlanRepository) WithTx(tx *sql.Tx) InstallmentPlanRepositoryInterface {
+	return &InstallmentPlanRepository{db: tx}
+}
+
+// InitTable initializes the installment_plans and installments tables
+func (r *InstallmentPlanRepository) InitTable() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS installment_plans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			total_amount REAL NOT NULL,
+			installment_count INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT 'active',
+			note TEXT NOT NULL DEFAULT '',
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_installment_plans_user ON installment_plans (user_id)`,
+		`CREATE TABLE IF NOT EXISTS installments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			plan_id INTEGER NOT NULL REFERENCES installment_plans(id),
+			sequence INTEGER NOT NULL,
+			due_date DATE NOT NULL,
+			amount REAL NOT NULL,
+			UNIQUE (plan_id, sequence)
+		)`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.Exec(query); err != nil {
+			log.Errorf("Error creating installment plan tables: %v", err)
+			return err
+		}
+	}
+	log.Info("Created Installment Plan Tables")
+	return nil
+}
+
+// Create inserts a new active installment plan and its installments
+func (r *InstallmentPlanRepository) Create(plan *models.InstallmentPlan) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO installment_plans (user_id, total_amount, installment_count, status, note, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, plan.UserID, plan.TotalAmount, len(plan.Installments), models.InstallmentPlanStatusActive, plan.Note, plan.CreatedBy, now, now)
+	if err != nil {
+		log.Errorf("Error creating installment plan: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	plan.ID = id
+	plan.InstallmentCount = len(plan.Installments)
+	plan.Status = models.InstallmentPlanStatusActive
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+
+	for i := range plan.Installments {
+		installment := &plan.Installments[i]
+		installment.PlanID = id
+		result, err := r.db.Exec(`
+			INSERT INTO installments (plan_id, sequence, due_date, amount)
+			VALUES (?, ?, ?, ?)
+		`, id, installment.Sequence, installment.DueDate.Format("2006-01-02"), installment.Amount)
+		if err != nil {
+			log.Errorf("Error creating installment: %v", err)
+			return err
+		}
+		if installment.ID, err = result.LastInsertId(); err != nil {
+			log.Errorf("Error getting last insert ID: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAll retrieves the installment plans with a status, or all plans if status is empty, the
+// most recent first. Their installments are not loaded.
+func (r *InstallmentPlanRepository) GetAll(status string) ([]models.InstallmentPlan, error) {
+	if status == "" {
+		return r.list(installmentPlanQuery + ` ORDER BY p.created_at DESC, p.id DESC`)
+	}
+	return r.list(installmentPlanQuery+` WHERE p.status = ? ORDER BY p.created_at DESC, p.id DESC`, status)
+}
+
+// GetByUser retrieves the installment plans of a user, the most recent first. Their
+// installments are not loaded.
+func (r *InstallmentPlanRepository) GetByUser(userID int64) ([]models.InstallmentPlan, error) {
+	return r.list(installmentPlanQuery+` WHERE p.user_id = ? ORDER BY p.created_at DESC, p.id DESC`, userID)
+}
+
+// list retrieves the installment plans selected by query
+func (r *InstallmentPlanRepository) list(query string, args ...any) ([]models.InstallmentPlan, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Errorf("Error getting installment plans: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := make([]models.InstallmentPlan, 0)
+	for rows.Next() {
+		var plan models.InstallmentPlan
+		if err := scanInstallmentPlan(rows, &plan); err != nil {
+			log.Errorf("Error scanning installment plan row: %v", err)
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// Get retrieves a single installment plan by ID. Its installments are not loaded.
+func (r *InstallmentPlanRepository) Get(id int64) (*models.InstallmentPlan, error) {
+	var plan models.InstallmentPlan
+	err := scanInstallmentPlan(r.db.QueryRow(installmentPlanQuery+` WHERE p.id = ?`, id), &plan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting installment plan: %v", err)
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetActiveByUser retrieves the active installment plan of a user, or nil if the user has none
+func (r *InstallmentPlanRepository) GetActiveByUser(userID int64) (*models.InstallmentPlan, error) {
+	var plan models.InstallmentPlan
+	err := scanInstallmentPlan(r.db.QueryRow(installmentPlanQuery+` WHERE p.user_id = ? AND p.status = ?`, userID, models.InstallmentPlanStatusActive), &plan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting active installment plan: %v", err)
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetInstallments retrieves the installments of a plan in the order they fall due
+func (r *InstallmentPlanRepository) GetInstallments(planID int64) ([]models.Installment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, plan_id, sequence, due_date, amount
+		FROM installments
+		WHERE plan_id = ?
+		ORDER BY sequence
+	`, planID)
+	if err != nil {
+		log.Errorf("Error getting installments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	installments := make([]models.Installment, 0)
+	for rows.Next() {
+		var installment models.Installment
+		err := rows.Scan(&installment.ID, &installment.PlanID, &installment.Sequence, &installment.DueDate, &installment.Amount)
+		if err != nil {
+			log.Errorf("Error scanning installment row: %v", err)
+			return nil, err
+		}
+		installments = append(installments, installment)
+	}
+	return installments, rows.Err()
+}
+
+// GetPaidAmount sums the deposits carrying batchReference that are not deleted
+func (r *InstallmentPlanRepository) GetPaidAmount(batchReference string) (float64, error) {
+	var paid float64
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(employee_share), 0)
+		FROM transactions
+		WHERE batch_reference = ? AND transaction_type = ? AND deleted_at IS NULL
+	`, batchReference, models.TransactionTypeDeposit).Scan(&paid)
+	if err != nil {
+		log.Errorf("Error getting installment plan payments: %v", err)
+	}
+	return paid, err
+}
+
+// SetStatus changes the status of a plan from one status to another and reports whether the
+// plan had the from status
+func (r *InstallmentPlanRepository) SetStatus(id int64, from, to string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE installment_plans SET status = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, to, time.Now(), id, from)
+	if err != nil {
+		log.Errorf("Error updating installment plan status: %v", err)
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// offboardingQuery selects offboardings with their users in the order scanOffboarding reads them
+const offboardingQuery = `
+	SELECT
+		o.id,
+		o.user_id,
+		u.name,
+		u.employee_id,
+		o.final_balance,
+		o.settlement_method,
+		o.reason,
+		o.transaction_id,
+		o.processed_by,
+		o.created_at
+	FROM offboardings o
+	JOIN users u ON u.id = o.user_id
+`
+
+// scanOffboarding scans a row selected with offboardingQuery into an offboarding
+func scanOffboarding(row rowScanner, offboarding *models.Offboarding) error {
+	return row.Scan(
+		&offboarding.ID,
+		&offboarding.UserID,
+		&offboarding.UserName,
+		&offboarding.EmployeeID,
+		&offboarding.FinalBalance,
+		&offboarding.SettlementMethod,
+		&offboarding.Reason,
+		&offboarding.TransactionID,
+		&offboarding.ProcessedBy,
+		&offboarding.CreatedAt,
+	)
+}
+
+// OffboardingRepository handles all database operations related to offboarding users
+type OffboardingRepository struct {
+	db DBTX
+}
+
+// NewOffboardingRepository creates a new offboarding repository
+func NewOffboardingRepository(db *sql.DB) *OffboardingRepository {
+	return &OffboardingRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *OffboardingRepository) WithTx(tx *sql.Tx) OffboardingRepositoryInterface {
+	return &OffboardingRepository{db: tx}
+}
+
+// InitTable initializes the offboardings table
+func (r *OffboardingRepository) InitTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS offboardings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL UNIQUE REFERENCES users(id),
+			final_balance REAL NOT NULL,
+			settlement_method TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			transaction_id INTEGER REFERENCES transactions(id),
+			processed_by TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		)
+	`
+	if _, err := r.db.Exec(query); err != nil {
+		log.Errorf("Error creating offboardings table: %v", err)
+		return err
+	}
+	log.Info("Created Offboardings Table")
+	return nil
+}
+
+// Create records an offboarding and deactivates the user
+func (r *OffboardingRepository) Create(offboarding *models.Offboarding) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO offboardings (user_id, final_balance, settlement_method, reason, transaction_id, processed_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, offboarding.UserID, offboarding.FinalBalance, offboarding.SettlementMethod, offboarding.Reason,
+		offboarding.TransactionID, offboarding.ProcessedBy, now)
+	if err != nil {
+		log.Errorf("Error creating offboarding: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	offboarding.ID = id
+	offboarding.CreatedAt = now
+
+	if _, err := r.db.Exec(`UPDATE users SET active = 0, updated_at = ? WHERE id = ?`, now, offboarding.UserID); err != nil {
+		log.Errorf("Error deactivating offboarded user: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetAll retrieves all offboardings, the most recent first
+func (r *OffboardingRepository) GetAll() ([]models.Offboarding, error) {
+	rows, err := r.db.Query(offboardingQuery + ` ORDER BY o.created_at DESC, o.id DESC`)
+	if err != nil {
+		log.Errorf("Error getting offboardings: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	offboardings := make([]models.Offboarding, 0)
+	for rows.Next() {
+		var offboarding models.Offboarding
+		if err := scanOffboarding(rows, &offboarding); err != nil {
+			log.Errorf("Error scanning offboarding row: %v", err)
+			return nil, err
+		}
+		offboardings = append(offboardings, offboarding)
+	}
+	return offboardings, rows.Err()
+}
+
+// GetByUser retrieves the offboarding of a user, or nil if the user has not been offboarded
+func (r *OffboardingRepository) GetByUser(userID int64) (*models.Offboarding, error) {
+	var offboarding models.Offboarding
+	err := scanOffboarding(r.db.QueryRow(offboardingQuery+` WHERE o.user_id = ?`, userID), &offboarding)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting offboarding: %v", err)
+		return nil, err
+	}
+	return &offboarding, nil
+}
+
+// GetTransactions retrieves the transactions of a user that are not deleted, oldest first
+func (r *OffboardingRepository) GetTransactions(userID int64) ([]models.Transaction, error) {
+	rows, err := r.db.Query(`
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE user_id = ? AND deleted_at IS NULL
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		log.Errorf("Error getting statement transactions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0)
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := scanTransaction(rows, &transaction); err != nil {
+			log.Errorf("Error scanning transaction row: %v", err)
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+// GetWriteOffs retrieves the write-off transactions created from start up to end, oldest first
+func (r *OffboardingRepository) GetWriteOffs(start, end time.Time) ([]models.WriteOff, error) {
+	rows, err := r.db.Query(`
+		SELECT
+			t.id,
+			t.user_id,
+			u.name,
+			u.employee_id,
+			u.department,
+			t.employee_share,
+			t.description,
+			EXISTS (SELECT 1 FROM offboardings o WHERE o.transaction_id = t.id),
+			t.created_at
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.transaction_type = ? AND t.deleted_at IS NULL
+		AND t.created_at >= ? AND t.created_at < ?
+		ORDER BY t.created_at, t.id
+	`, models.TransactionTypeWriteOff, start, end)
+	if err != nil {
+		log.Errorf("Error getting write-offs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	writeOffs := make([]models.WriteOff, 0)
+	for rows.Next() {
+		var writeOff models.WriteOff
+		err := rows.Scan(
+			&writeOff.TransactionID,
+			&writeOff.UserID,
+			&writeOff.UserName,
+			&writeOff.EmployeeID,
+			&writeOff.Department,
+			&writeOff.Amount,
+			&writeOff.Description,
+			&writeOff.Offboarding,
+			&writeOff.CreatedAt,
+		)
+		if err != nil {
+			log.Errorf("Error scanning write-off row: %v", err)
+			return nil, err
+		}
+		writeOffs = append(writeOffs, writeOff)
+	}
+	return writeOffs, rows.Err()
+}
@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// paymentStatementQuery selects statements with the counts of their lines by status in the
+// order scanPaymentStatement reads them
+const paymentStatementQuery = `
+	SELECT
+		ps.id,
+		ps.source,
+		ps.file_name,
+		ps.uploaded_by,
+		ps.created_at,
+		COUNT(pl.id),
+		COALESCE(SUM(pl.status = 'matched'), 0),
+		COALESCE(SUM(pl.status = 'unmatched'), 0),
+		COALESCE(SUM(pl.status = 'posted'), 0),
+		COALESCE(SUM(pl.status = 'rejected'), 0),
+		COALESCE(SUM(pl.status = 'duplicate'), 0),
+		COALESCE(SUM(pl.amount), 0)
+	FROM payment_statements ps
+	LEFT JOIN payment_lines pl ON pl.statement_id = ps.id
+`
+
+// scanPaymentStatement scans a row selected with paymentStatementQuery into a statement
+func scanPaymentStatement(row rowScanner, statement *models.PaymentStatement) error {
+	return row.Scan(
+		&statement.ID,
+		&statement.Source,
+		&statement.FileName,
+		&statement.UploadedBy,
+		&statement.CreatedAt,
+		&statement.Summary.Lines,
+		&statement.Summary.Matched,
+		&statement.Summary.Unmatched,
+		&statement.Summary.Posted,
+		&statement.Summary.Rejected,
+		&statement.Summary.Duplicate,
+		&statement.Summary.TotalAmount,
+	)
+}
+
+// paymentLineQuery selects payment lines with their matched user in the order
+// scanPaymentLine reads them
+const paymentLineQuery = `
+	SELECT
+		pl.id,
+		pl.statement_id,
+		pl.line,
+		pl.paid_at,
+		pl.amount,
+		pl.reference,
+		pl.phone,
+		pl.description,
+		pl.status,
+		pl.match_method,
+		pl.user_id,
+		COALESCE(u.name, ''),
+		COALESCE(u.employee_id, ''),
+		pl.transaction_id,
+		pl.reviewed_by,
+		pl.reviewed_at,
+		pl.note,
+		pl.fingerprint,
+		pl.created_at,
+		pl.updated_at
+	FROM payment_lines pl
+	LEFT JOIN users u ON u.id = pl.user_id
+`
+
+// scanPaymentLine scans a row selected with paymentLineQuery into a payment line
+func scanPaymentLine(row rowScanner, line *models.PaymentLine) error {
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&line.ID,
+		&line.StatementID,
+		&line.Line,
+		&line.PaidAt,
+		&line.Amount,
+		&line.Reference,
+		&line.Phone,
+		&line.Description,
+		&line.Status,
+		&line.MatchMethod,
+		&line.UserID,
+		&line.UserName,
+		&line.EmployeeID,
+		&line.TransactionID,
+		&line.ReviewedBy,
+		&reviewedAt,
+		&line.Note,
+		&line.Fingerprint,
+		&line.CreatedAt,
+		&line.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if reviewedAt.Valid {
+		line.ReviewedAt = &reviewedAt.Time
+	}
+	return nil
+}
+
+// PaymentRepository handles all database operations related to payment statements
+type PaymentRepository struct {
+	db DBTX
+}
+
+// NewPaymentRepository creates a new payment repository
+func NewPaymentRepository(db *sql.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *PaymentRepository) WithTx(tx *sql.Tx) PaymentRepositoryInterface {
+	return &PaymentRepository{db: tx}
+}
+
+// InitTable initializes the payment_statements and payment_lines tables
+func (r *PaymentRepository) InitTable() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS payment_statements (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source TEXT NOT NULL,
+			file_name TEXT NOT NULL DEFAULT '',
+			uploaded_by TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS payment_lines (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			statement_id INTEGER NOT NULL REFERENCES payment_statements(id),
+			line INTEGER NOT NULL,
+			paid_at DATETIME NOT NULL,
+			amount REAL NOT NULL,
+			reference TEXT NOT NULL DEFAULT '',
+			phone TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			match_method TEXT NOT NULL DEFAULT '',
+			user_id INTEGER REFERENCES users(id),
+			transaction_id INTEGER REFERENCES transactions(id),
+			reviewed_by TEXT NOT NULL DEFAULT '',
+			reviewed_at DATETIME,
+			note TEXT NOT NULL DEFAULT '',
+			fingerprint TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_payment_lines_statement ON payment_lines (statement_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_payment_lines_status ON payment_lines (status)`,
+		`CREATE INDEX IF NOT EXISTS idx_payment_lines_fingerprint ON payment_lines (fingerprint)`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.Exec(query); err != nil {
+			log.Errorf("Error creating payment tables: %v", err)
+			return err
+		}
+	}
+	log.Info("Created Payment Tables")
+	return nil
+}
+
+// CreateStatement inserts a new statement without its lines
+func (r *PaymentRepository) CreateStatement(statement *models.PaymentStatement) error {
+	now := time.Now()
+	result, err := r.db.Exec(
+		`INSERT INTO payment_statements (source, file_name, uploaded_by, created_at) VALUES (?, ?, ?, ?)`,
+		statement.Source, statement.FileName, statement.UploadedBy, now,
+	)
+	if err != nil {
+		log.Errorf("Error inserting payment statement: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	statement.ID = id
+	statement.CreatedAt = now
+	return nil
+}
+
+// GetStatements retrieves all statements with their line counts, newest first
+func (r *PaymentRepository) GetStatements() ([]models.PaymentStatement, error) {
+	rows, err := r.db.Query(paymentStatementQuery + ` GROUP BY ps.id ORDER BY ps.created_at DESC, ps.id DESC`)
+	if err != nil {
+		log.Errorf("Error getting payment statements: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	statements := make([]models.PaymentStatement, 0)
+	for rows.Next() {
+		var statement models.PaymentStatement
+		if err := scanPaymentStatement(rows, &statement); err != nil {
+			log.Errorf("Error scanning payment statement row: %v", err)
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	return statements, rows.Err()
+}
+
+// GetStatement retrieves a single statement with its line counts by ID
+func (r *PaymentRepository) GetStatement(id int64) (*models.PaymentStatement, error) {
+	var statement models.PaymentStatement
+	err := scanPaymentStatement(r.db.QueryRow(paymentStatementQuery+` WHERE ps.id = ? GROUP BY ps.id`, id), &statement)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting payment statement: %v", err)
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// CreateLine inserts a new payment line
+func (r *PaymentRepository) CreateLine(line *models.PaymentLine) error {
+	query := `
+		INSERT INTO payment_lines (statement_id, line, paid_at, amount, reference, phone, description, status, match_method, user_id, fingerprint, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.Exec(query,
+		line.StatementID,
+		line.Line,
+		line.PaidAt,
+		line.Amount,
+		line.Reference,
+		line.Phone,
+		line.Description,
+		line.Status,
+		line.MatchMethod,
+		line.UserID,
+		line.Fingerprint,
+		now,
+		now,
+	)
+	if err != nil {
+		log.Errorf("Error inserting payment line: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	line.ID = id
+	line.CreatedAt = now
+	line.UpdatedAt = now
+	return nil
+}
+
+// HasFingerprint reports whether a payment with the fingerprint was imported before
+// and not marked as a duplicate itself
+func (r *PaymentRepository) HasFingerprint(fingerprint string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM payment_lines WHERE fingerprint = ? AND status <> 'duplicate')`, fingerprint).Scan(&exists)
+	if err != nil {
+		log.Errorf("Error checking payment fingerprint: %v", err)
+	}
+	return exists, err
+}
+
+// GetLines retrieves the lines of a statement in file order
+func (r *PaymentRepository) GetLines(statementID int64) ([]models.PaymentLine, error) {
+	return r.listLines(paymentLineQuery+` WHERE pl.statement_id = ? ORDER BY pl.line`, statementID)
+}
+
+// GetLinesByStatus retrieves the lines with any of the statuses, oldest payments first
+func (r *PaymentRepository) GetLinesByStatus(statuses ...string) ([]models.PaymentLine, error) {
+	query := paymentLineQuery + ` WHERE pl.status IN (`
+	args := make([]any, 0, len(statuses))
+	for i, status := range statuses {
+		if i > 0 {
+			query += `, `
+		}
+		query += `?`
+		args = append(args, status)
+	}
+	query += `) ORDER BY pl.paid_at, pl.id`
+	return r.listLines(query, args...)
+}
+
+func (r *PaymentRepository) listLines(query string, args ...any) ([]models.PaymentLine, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Errorf("Error getting payment lines: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make([]models.PaymentLine, 0)
+	for rows.Next() {
+		var line models.PaymentLine
+		if err := scanPaymentLine(rows, &line); err != nil {
+			log.Errorf("Error scanning payment line row: %v", err)
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// GetLine retrieves a single payment line by ID
+func (r *PaymentRepository) GetLine(id int64) (*models.PaymentLine, error) {
+	var line models.PaymentLine
+	err := scanPaymentLine(r.db.QueryRow(paymentLineQuery+` WHERE pl.id = ?`, id), &line)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting payment line: %v", err)
+		return nil, err
+	}
+	return &line, nil
+}
+
+// MarkPosted marks a matched or unmatched line as posted with the deposit created for it
+// and reports whether the line was still awaiting review
+func (r *PaymentRepository) MarkPosted(line *models.PaymentLine) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE payment_lines
+		SET status = 'posted', user_id = ?, match_method = ?, transaction_id = ?, reviewed_by = ?, reviewed_at = ?, note = ?, updated_at = ?
+		WHERE id = ? AND status IN ('matched', 'unmatched')
+	`, line.UserID, line.MatchMethod, line.TransactionID, line.ReviewedBy, line.ReviewedAt, line.Note, line.ReviewedAt, line.ID)
+	if err != nil {
+		log.Errorf("Error marking payment line as posted: %v", err)
+		return false, err
+	}
+	posted, err := result.RowsAffected()
+	return posted > 0, err
+}
+
+// Reject marks a matched or unmatched line as rejected and reports whether the line was
+// still awaiting review
+func (r *PaymentRepository) Reject(id int64, reviewedBy, note string) (bool, error) {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		UPDATE payment_lines
+		SET status = 'rejected', reviewed_by = ?, reviewed_at = ?, note = ?, updated_at = ?
+		WHERE id = ? AND status IN ('matched', 'unmatched')
+	`, reviewedBy, now, note, now, id)
+	if err != nil {
+		log.Errorf("Error rejecting payment line: %v", err)
+		return false, err
+	}
+	rejected, err := result.RowsAffected()
+	return rejected > 0, err
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pendingDepositQuery selects pending deposits with their user in the order
+// scanPendingDeposit reads them
+const pendingDepositQuery = `
+	SELECT
+		pd.id,
+		pd.user_id,
+		u.name,
+		u.employee_id,
+		pd.phone,
+		pd.message_id,
+		pd.media_type,
+		pd.mime_type,
+		pd.file_name,
+		pd.file_path,
+		pd.caption,
+		pd.status,
+		pd.amount,
+		pd.transaction_id,
+		pd.reviewed_by,
+		pd.reviewed_at,
+		pd.note,
+		pd.received_at,
+		pd.created_at,
+		pd.updated_at
+	FROM pending_deposits pd
+	JOIN users u ON u.id = pd.user_id
+`
+
+// scanPendingDeposit scans a row selected with pendingDepositQuery into a pending deposit
+func scanPendingDeposit(row rowScanner, deposit *models.PendingDeposit) error {
+	var amount sql.NullFloat64
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&deposit.ID,
+		&deposit.UserID,
+		&deposit.UserName,
+		&deposit.EmployeeID,
+		&deposit.Phone,
+		&deposit.MessageID,
+		&deposit.MediaType,
+		&deposit.MimeType,
+		&deposit.FileName,
+		&deposit.FilePath,
+		&deposit.Caption,
+		&deposit.Status,
+		&amount,
+		&deposit.TransactionID,
+		&deposit.ReviewedBy,
+		&reviewedAt,
+		&deposit.Note,
+		&deposit.ReceivedAt,
+		&deposit.CreatedAt,
+		&deposit.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if amount.Valid {
+		deposit.Amount = &amount.Float64
+	}
+	if reviewedAt.Valid {
+		deposit.ReviewedAt = &reviewedAt.Time
+	}
+	return nil
+}
+
+// PendingDepositRepository handles all database operations related to pending deposits
+type PendingDepositRepository struct {
+	db DBTX
+}
+
+// NewPendingDepositRepository creates a new pending deposit repository
+func NewPendingDepositRepository(db *sql.DB) *PendingDepositRepository {
+	return &PendingDepositRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *PendingDepositRepository) WithTx(tx *sql.Tx) PendingDepositRepositoryInterface {
+	return &PendingDepositRepository{db: tx}
+}
+
+// InitTable initializes the pending_deposits table
+func (r *PendingDepositRepository) InitTable() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS pending_deposits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			phone TEXT NOT NULL,
+			message_id TEXT NOT NULL UNIQUE,
+			media_type TEXT NOT NULL,
+			mime_type TEXT NOT NULL DEFAULT '',
+			file_name TEXT NOT NULL,
+			file_path TEXT NOT NULL,
+			caption TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'pending',
+			amount REAL,
+			transaction_id INTEGER REFERENCES transactions(id),
+			reviewed_by TEXT NOT NULL DEFAULT '',
+			reviewed_at DATETIME,
+			note TEXT NOT NULL DEFAULT '',
+			received_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_pending_deposits_status ON pending_deposits (status, received_at)`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.Exec(query); err != nil {
+			log.Errorf("Error creating pending deposits table: %v", err)
+			return err
+		}
+	}
+	log.Info("Created Pending Deposits Table")
+	return nil
+}
+
+// Create inserts a new pending deposit and reports whether it was created, which it is not
+// if a deposit for the same message already exists
+func (r *PendingDepositRepository) Create(deposit *models.PendingDeposit) (bool, error) {
+	query := `
+		INSERT INTO pending_deposits (user_id, phone, message_id, media_type, mime_type, file_name, file_path, caption, status, received_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (message_id) DO NOTHING
+	`
+	now := time.Now()
+	result, err := r.db.Exec(query,
+		deposit.UserID,
+		deposit.Phone,
+		deposit.MessageID,
+		deposit.MediaType,
+		deposit.MimeType,
+		deposit.FileName,
+		deposit.FilePath,
+		deposit.Caption,
+		models.PendingDepositStatusPending,
+		deposit.ReceivedAt,
+		now,
+		now,
+	)
+	if err != nil {
+		log.Errorf("Error inserting pending deposit: %v", err)
+		return false, err
+	}
+	if created, err := result.RowsAffected(); err != nil || created == 0 {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return false, err
+	}
+	deposit.ID = id
+	deposit.Status = models.PendingDepositStatusPending
+	deposit.CreatedAt = now
+	deposit.UpdatedAt = now
+	return true, nil
+}
+
+// GetByStatus retrieves the pending deposits with a status, oldest receipts first
+func (r *PendingDepositRepository) GetByStatus(status string) ([]models.PendingDeposit, error) {
+	rows, err := r.db.Query(pendingDepositQuery+` WHERE pd.status = ? ORDER BY pd.received_at, pd.id`, status)
+	if err != nil {
+		log.Errorf("Error getting pending deposits: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deposits := make([]models.PendingDeposit, 0)
+	for rows.Next() {
+		var deposit models.PendingDeposit
+		if err := scanPendingDeposit(rows, &deposit); err != nil {
+			log.Errorf("Error scanning pending deposit row: %v", err)
+			return nil, err
+		}
+		deposits = append(deposits, deposit)
+	}
+	return deposits, rows.Err()
+}
+
+// Get retrieves a single pending deposit by ID
+func (r *PendingDepositRepository) Get(id int64) (*models.PendingDeposit, error) {
+	var deposit models.PendingDeposit
+	err := scanPendingDeposit(r.db.QueryRow(pendingDepositQuery+` WHERE pd.id = ?`, id), &deposit)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting pending deposit: %v", err)
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// MarkApproved marks a pending deposit as approved with the deposit created for it and
+// reports whether it was still pending
+func (r *PendingDepositRepository) MarkApproved(deposit *models.PendingDeposit) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE pending_deposits
+		SET status = 'approved', amount = ?, transaction_id = ?, reviewed_by = ?, reviewed_at = ?, note = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, deposit.Amount, deposit.TransactionID, deposit.ReviewedBy, deposit.ReviewedAt, deposit.Note, deposit.ReviewedAt, deposit.ID)
+	if err != nil {
+		log.Errorf("Error approving pending deposit: %v", err)
+		return false, err
+	}
+	approved, err := result.RowsAffected()
+	return approved > 0, err
+}
+
+// Reject marks a pending deposit as rejected and reports whether it was still pending
+func (r *PendingDepositRepository) Reject(id int64, reviewedBy, note string) (bool, error) {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		UPDATE pending_deposits
+		SET status = 'rejected', reviewed_by = ?, reviewed_at = ?, note = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, reviewedBy, now, note, now, id)
+	if err != nil {
+		log.Errorf("Error rejecting pending deposit: %v", err)
+		return false, err
+	}
+	rejected, err := result.RowsAffected()
+	return rejected > 0, err
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pricingRuleColumns lists the pricing_rules columns in the order scanPricingRule reads them
+const pricingRuleColumns = `id, name, kind, type, value, buy_quantity, free_quantity, min_quantity,
+	product_id, category_id, department_id, user_id, valid_from, valid_until, start_time, end_time,
+	active, created_at, updated_at`
+
+// scanPricingRule scans a row selected with pricingRuleColumns into a pricing rule
+func scanPricingRule(row rowScanner, rule *models.PricingRule) error {
+	var validFrom, validUntil sql.NullTime
+	err := row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.Kind,
+		&rule.Type,
+		&rule.Value,
+		&rule.BuyQuantity,
+		&rule.FreeQuantity,
+		&rule.MinQuantity,
+		&rule.ProductID,
+		&rule.CategoryID,
+		&rule.DepartmentID,
+		&rule.UserID,
+		&validFrom,
+		&validUntil,
+		&rule.StartTime,
+		&rule.EndTime,
+		&rule.Active,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if validFrom.Valid {
+		rule.ValidFrom = &validFrom.Time
+	}
+	if validUntil.Valid {
+		rule.ValidUntil = &validUntil.Time
+	}
+	return nil
+}
+
+// PricingRuleRepository handles all database operations related to discount and subsidy rules
+type PricingRuleRepository struct {
+	db DBTX
+}
+
+// NewPricingRuleRepository creates a new pricing rule repository
+func NewPricingRuleRepository(db *sql.DB) *PricingRuleRepository {
+	return &PricingRuleRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *PricingRuleRepository) WithTx(tx *sql.Tx) PricingRuleRepositoryInterface {
+	return &PricingRuleRepository{db: tx}
+}
+
+// InitTable initializes the pricing_rules table
+func (r *PricingRuleRepository) InitTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS pricing_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			type TEXT NOT NULL,
+			value REAL NOT NULL DEFAULT 0,
+			buy_quantity INTEGER NOT NULL DEFAULT 0,
+			free_quantity INTEGER NOT NULL DEFAULT 0,
+			min_quantity INTEGER NOT NULL DEFAULT 0,
+			product_id INTEGER REFERENCES products(id),
+			category_id INTEGER REFERENCES categories(id),
+			department_id INTEGER REFERENCES departments(id),
+			user_id INTEGER REFERENCES users(id),
+			valid_from DATETIME,
+			valid_until DATETIME,
+			start_time TEXT NOT NULL DEFAULT '',
+			end_time TEXT NOT NULL DEFAULT '',
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)
+	`
+	if _, err := r.db.Exec(query); err != nil {
+		log.Errorf("Error creating pricing_rules table: %v", err)
+		return err
+	}
+	log.Info("Created Pricing Rules Table")
+	return nil
+}
+
+// Create inserts a new pricing rule into the database
+func (r *PricingRuleRepository) Create(rule *models.PricingRule) error {
+	query := `
+		INSERT INTO pricing_rules (
+			name, kind, type, value, buy_quantity, free_quantity, min_quantity,
+			product_id, category_id, department_id, user_id, valid_from, valid_until,
+			start_time, end_time, active, created_at, updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.Exec(
+		query,
+		rule.Name,
+		rule.Kind,
+		rule.Type,
+		rule.Value,
+		rule.BuyQuantity,
+		rule.FreeQuantity,
+		rule.MinQuantity,
+		rule.ProductID,
+		rule.CategoryID,
+		rule.DepartmentID,
+		rule.UserID,
+		rule.ValidFrom,
+		rule.ValidUntil,
+		rule.StartTime,
+		rule.EndTime,
+		rule.Active,
+		now,
+		now,
+	)
+	if err != nil {
+		log.Errorf("Error inserting pricing rule: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	rule.ID = id
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	return nil
+}
+
+// GetAll retrieves all pricing rules, or only the active ones if activeOnly is set
+func (r *PricingRuleRepository) GetAll(activeOnly bool) ([]models.PricingRule, error) {
+	query := `SELECT ` + pricingRuleColumns + ` FROM pricing_rules`
+	if activeOnly {
+		query += ` WHERE active = 1`
+	}
+	query += ` ORDER BY kind ASC, name ASC, id ASC`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Errorf("Error getting pricing rules: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]models.PricingRule, 0)
+	for rows.Next() {
+		var rule models.PricingRule
+		if err := scanPricingRule(rows, &rule); err != nil {
+			log.Errorf("Error scanning pricing rule row: %v", err)
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Get retrieves a single pricing rule by ID
+func (r *PricingRuleRepository) Get(id int64) (*models.PricingRule, error) {
+	query := `SELECT ` + pricingRuleColumns + ` FROM pricing_rules WHERE id = ?`
+	var rule models.PricingRule
+	err := scanPricingRule(r.db.QueryRow(query, id), &rule)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting pricing rule by ID: %v", err)
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Update updates an existing pricing rule
+func (r *PricingRuleRepository) Update(rule *models.PricingRule) error {
+	query := `
+		UPDATE pricing_rules
+		SET name = ?, kind = ?, type = ?, value = ?, buy_quantity = ?, free_quantity = ?, min_quantity = ?,
+			product_id = ?, category_id = ?, department_id = ?, user_id = ?, valid_from = ?, valid_until = ?,
+			start_time = ?, end_time = ?, active = ?, updated_at = ?
+		WHERE id = ?
+	`
+	now := time.Now()
+	_, err := r.db.Exec(
+		query,
+		rule.Name,
+		rule.Kind,
+		rule.Type,
+		rule.Value,
+		rule.BuyQuantity,
+		rule.FreeQuantity,
+		rule.MinQuantity,
+		rule.ProductID,
+		rule.CategoryID,
+		rule.DepartmentID,
+		rule.UserID,
+		rule.ValidFrom,
+		rule.ValidUntil,
+		rule.StartTime,
+		rule.EndTime,
+		rule.Active,
+		now,
+		rule.ID,
+	)
+	if err != nil {
+		log.Errorf("Error updating pricing rule: %v", err)
+		return err
+	}
+	rule.UpdatedAt = now
+	return nil
+}
+
+// Delete removes a pricing rule by ID. Transaction lines keep the name and amount of the
+// rule, only their reference to it is cleared.
+func (r *PricingRuleRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`UPDATE transaction_products SET discount_rule_id = NULL WHERE discount_rule_id = ?`, id)
+	if err != nil {
+		log.Errorf("Error unlinking discounted sales from pricing rule: %v", err)
+		return err
+	}
+	_, err = r.db.Exec(`UPDATE transaction_products SET subsidy_rule_id = NULL WHERE subsidy_rule_id = ?`, id)
+	if err != nil {
+		log.Errorf("Error unlinking subsidized sales from pricing rule: %v", err)
+		return err
+	}
+
+	_, err = r.db.Exec(`DELETE FROM pricing_rules WHERE id = ?`, id)
+	if err != nil {
+		log.Errorf("Error deleting pricing rule: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeactivateDepartment deactivates the rules of a department and clears their department,
+// since without it they would apply to every user
+func (r *PricingRuleRepository) DeactivateDepartment(departmentID int64) error {
+	_, err := r.db.Exec(`UPDATE pricing_rules SET department_id = NULL, active = 0, updated_at = ? WHERE department_id = ?`, time.Now(), departmentID)
+	if err != nil {
+		log.Errorf("Error deactivating pricing rules of department: %v", err)
+	}
+	return err
+}
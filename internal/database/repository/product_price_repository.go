@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// productPriceColumns lists the product_prices columns in the order scanProductPrice reads them
+const productPriceColumns = `id, product_id, unit_id, unit_name, price, effective_from, applied_at, reason, created_at`
+
+// scanProductPrice scans a row selected with productPriceColumns into a product price
+func scanProductPrice(row rowScanner, price *models.ProductPrice) error {
+	var appliedAt sql.NullTime
+	err := row.Scan(
+		&price.ID,
+		&price.ProductID,
+		&price.UnitID,
+		&price.UnitName,
+		&price.Price,
+		&price.EffectiveFrom,
+		&appliedAt,
+		&price.Reason,
+		&price.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if appliedAt.Valid {
+		price.AppliedAt = &appliedAt.Time
+	}
+	return nil
+}
+
+// ProductPriceRepository handles all database operations related to the price history of products
+type ProductPriceRepository struct {
+	db DBTX
+}
+
+// NewProductPriceRepository creates a new product price repository
+func NewProductPriceRepository(db *sql.DB) *ProductPriceRepository {
+	return &ProductPriceRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *ProductPriceRepository) WithTx(tx *sql.Tx) ProductPriceRepositoryInterface {
+	return &ProductPriceRepository{db: tx}
+}
+
+// InitTable initializes the product_prices table and records the current price of units
+// without a history as effective since their product was created. It must run after the
+// product_units table is initialized.
+func (r *ProductPriceRepository) InitTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS product_prices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL REFERENCES products(id),
+			unit_id INTEGER REFERENCES product_units(id),
+			unit_name TEXT NOT NULL,
+			price REAL NOT NULL,
+			effective_from DATETIME NOT NULL,
+			applied_at DATETIME,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		)
+	`
+	_, err := r.db.Exec(query)
+	if err != nil {
+		log.Errorf("Error creating product_prices table: %v", err)
+		return err
+	}
+	log.Info("Created Product Prices Table")
+
+	_, err = r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_product_prices_unit ON product_prices (unit_id, effective_from)`)
+	if err != nil {
+		log.Errorf("Error creating product_prices index: %v", err)
+		return err
+	}
+
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO product_prices (product_id, unit_id, unit_name, price, effective_from, applied_at, reason, created_at)
+		SELECT pu.product_id, pu.id, pu.name, pu.price, p.created_at, ?, 'Initial price', ?
+		FROM product_units pu
+		JOIN products p ON p.id = pu.product_id
+		WHERE NOT EXISTS (SELECT 1 FROM product_prices pp WHERE pp.unit_id = pu.id)
+	`, now, now)
+	if err != nil {
+		log.Errorf("Error recording initial product prices: %v", err)
+		return err
+	}
+	if recorded, _ := result.RowsAffected(); recorded > 0 {
+		log.Infof("Recorded the initial price of %d product units", recorded)
+	}
+	return nil
+}
+
+// Create inserts a new price history entry into the database
+func (r *ProductPriceRepository) Create(price *models.ProductPrice) error {
+	query := `
+		INSERT INTO product_prices (product_id, unit_id, unit_name, price, effective_from, applied_at, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.Exec(query, price.ProductID, price.UnitID, price.UnitName, price.Price, price.EffectiveFrom, price.AppliedAt, price.Reason, now)
+	if err != nil {
+		log.Errorf("Error inserting product price: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	price.ID = id
+	price.CreatedAt = now
+	return nil
+}
+
+// GetByProductID retrieves the price history of a product, oldest first
+func (r *ProductPriceRepository) GetByProductID(productID int64) ([]models.ProductPrice, error) {
+	return r.list(`SELECT `+productPriceColumns+` FROM product_prices WHERE product_id = ? ORDER BY effective_from ASC, id ASC`, productID)
+}
+
+// GetDue retrieves the scheduled prices effective at or before now, oldest first
+func (r *ProductPriceRepository) GetDue(now time.Time) ([]models.ProductPrice, error) {
+	return r.list(`SELECT `+productPriceColumns+` FROM product_prices WHERE applied_at IS NULL AND effective_from <= ? ORDER BY effective_from ASC, id ASC`, now)
+}
+
+func (r *ProductPriceRepository) list(query string, args ...any) ([]models.ProductPrice, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Errorf("Error getting product prices: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []models.ProductPrice
+	for rows.Next() {
+		var price models.ProductPrice
+		if err := scanProductPrice(rows, &price); err != nil {
+			log.Errorf("Error scanning product price row: %v", err)
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
+
+// Get retrieves a single price history entry by ID
+func (r *ProductPriceRepository) Get(id int64) (*models.ProductPrice, error) {
+	return r.get(`SELECT `+productPriceColumns+` FROM product_prices WHERE id = ?`, id)
+}
+
+// GetLatestApplied retrieves the most recent applied price of a unit
+func (r *ProductPriceRepository) GetLatestApplied(unitID int64) (*models.ProductPrice, error) {
+	return r.get(`SELECT `+productPriceColumns+` FROM product_prices WHERE unit_id = ? AND applied_at IS NOT NULL ORDER BY effective_from DESC, id DESC LIMIT 1`, unitID)
+}
+
+func (r *ProductPriceRepository) get(query string, args ...any) (*models.ProductPrice, error) {
+	var price models.ProductPrice
+	err := scanProductPrice(r.db.QueryRow(query, args...), &price)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting product price: %v", err)
+		return nil, err
+	}
+	return &price, nil
+}
+
+// MarkApplied records that a scheduled price was applied to its unit
+func (r *ProductPriceRepository) MarkApplied(id int64, appliedAt time.Time) error {
+	_, err := r.db.Exec(`UPDATE product_prices SET applied_at = ? WHERE id = ?`, appliedAt, id)
+	if err != nil {
+		log.Errorf("Error marking product price as applied: %v", err)
+	}
+	return err
+}
+
+// DeleteScheduled removes a price that has not been applied yet and reports whether it was found
+func (r *ProductPriceRepository) DeleteScheduled(id int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM product_prices WHERE id = ? AND applied_at IS NULL`, id)
+	if err != nil {
+		log.Errorf("Error deleting scheduled product price: %v", err)
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// GetApplied retrieves the applied prices of all products, grouped by product unit and
+// oldest first within each unit. Deleted units are grouped by name.
+func (r *ProductPriceRepository) GetApplied() ([]models.ProductPrice, error) {
+	return r.list(`
+		SELECT ` + productPriceColumns + ` FROM product_prices
+		WHERE applied_at IS NOT NULL
+		ORDER BY product_id, COALESCE(unit_id, unit_name), effective_from ASC, id ASC
+	`)
+}
+
+// GetUnitSales retrieves the quantity and revenue of the purchases of a product unit made
+// from from until before until. Units that were deleted are matched by name.
+func (r *ProductPriceRepository) GetUnitSales(productID int64, unitID *int64, unitName string, from, until time.Time) (int, float64, error) {
+	query := `
+		SELECT COALESCE(SUM(tp.quantity), 0), COALESCE(SUM(tp.quantity * tp.unit_price), 0)
+		FROM transaction_products tp
+		JOIN transactions t ON tp.transaction_id = t.id
+		WHERE tp.product_id = ? AND tp.unit_id IS ?
+		AND (tp.unit_id IS NOT NULL OR tp.unit_name = ?)
+		AND t.transaction_type = 'purchase'
+		AND t.deleted_at IS NULL
+		AND t.created_at >= ? AND t.created_at < ?
+	`
+	var quantity int
+	var revenue float64
+	err := r.db.QueryRow(query, productID, unitID, unitName, from, until).Scan(&quantity, &revenue)
+	if err != nil {
+		log.Errorf("Error getting product unit sales: %v", err)
+		return 0, 0, err
+	}
+	return quantity, revenue, nil
+}
@@ -2,12 +2,68 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 	"maya-canteen/internal/models"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// productColumns lists the products columns in the order scanProduct reads them
+const productColumns = `
+	id,
+	name,
+	description,
+	price,
+	type,
+	active,
+	is_single_unit,
+	single_unit_price,
+	category_id,
+	sort_order,
+	barcode,
+	EXISTS (SELECT 1 FROM product_images WHERE product_images.product_id = products.id) AS has_image,
+	created_at,
+	updated_at,
+	deleted_at`
+
+// scanProduct scans a row selected with productColumns into a product
+func scanProduct(row rowScanner, product *models.Product) error {
+	var categoryID sql.NullInt64
+	var hasImage bool
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&product.ID,
+		&product.Name,
+		&product.Description,
+		&product.Price,
+		&product.Type,
+		&product.Active,
+		&product.IsSingleUnit,
+		&product.SingleUnitPrice,
+		&categoryID,
+		&product.SortOrder,
+		&product.Barcode,
+		&hasImage,
+		&product.CreatedAt,
+		&product.UpdatedAt,
+		&deletedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if categoryID.Valid {
+		product.CategoryID = &categoryID.Int64
+	}
+	if hasImage {
+		product.ImageURL = fmt.Sprintf("/api/products/%d/image", product.ID)
+	}
+	if deletedAt.Valid {
+		product.DeletedAt = &deletedAt.Time
+	}
+	return nil
+}
+
 // ProductRepository handles all database operations related to products
 type ProductRepository struct {
 	db *sql.DB
@@ -32,6 +88,9 @@ func (r *ProductRepository) InitTable() error {
 			active BOOLEAN NOT NULL DEFAULT true,
       is_single_unit BOOLEAN NOT NULL DEFAULT false,
 			single_unit_price REAL NOT NULL DEFAULT 0,
+			category_id INTEGER REFERENCES categories(id),
+			sort_order INTEGER NOT NULL DEFAULT 0,
+			barcode TEXT NOT NULL DEFAULT '',
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL
 		)
@@ -42,6 +101,28 @@ func (r *ProductRepository) InitTable() error {
 		return err
 	}
 
+	addColumnIfNeeded(r.db, "products", "deleted_at", "DATETIME")
+	addColumnIfNeeded(r.db, "products", "category_id", "INTEGER REFERENCES categories(id)")
+	addColumnIfNeeded(r.db, "products", "sort_order", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfNeeded(r.db, "products", "barcode", "TEXT NOT NULL DEFAULT ''")
+
+	query = `
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_products_barcode ON products (barcode)
+		WHERE barcode <> '' AND deleted_at IS NULL;
+
+		CREATE TABLE IF NOT EXISTS product_images (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL UNIQUE REFERENCES products(id) ON DELETE CASCADE,
+			content_type TEXT NOT NULL,
+			data BLOB NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+	`
+	if _, err := r.db.Exec(query); err != nil {
+		log.Errorf("Error creating product barcode index and images table: %v", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -83,10 +164,13 @@ func (r *ProductRepository) Create(product *models.Product) error {
 			active,
 			is_single_unit,
 			single_unit_price,
+			category_id,
+			sort_order,
+			barcode,
 			created_at,
 			updated_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	now := time.Now()
 	result, err := r.db.Exec(
@@ -98,6 +182,9 @@ func (r *ProductRepository) Create(product *models.Product) error {
 		product.Active,
 		product.IsSingleUnit,
 		product.SingleUnitPrice,
+		product.CategoryID,
+		product.SortOrder,
+		product.Barcode,
 		now,
 		now,
 	)
@@ -115,22 +202,13 @@ func (r *ProductRepository) Create(product *models.Product) error {
 	return nil
 }
 
-// GetAll retrieves all products from the database
-func (r *ProductRepository) GetAll() ([]models.Product, error) {
-	query := `
-	SELECT
-		id,
-		name,
-		description,
-		price,
-		type,
-		active,
-		is_single_unit,
-		single_unit_price,
-		created_at,
-		updated_at
-  FROM products
-	ORDER BY name ASC`
+// GetAll retrieves all products from the database, including soft deleted products if includeDeleted is set
+func (r *ProductRepository) GetAll(includeDeleted bool) ([]models.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products`
+	if !includeDeleted {
+		query += ` WHERE deleted_at IS NULL`
+	}
+	query += ` ORDER BY name ASC`
 	rows, err := r.db.Query(query)
 	if err != nil {
 		log.Errorf("Error getting all products: %v", err)
@@ -141,18 +219,7 @@ func (r *ProductRepository) GetAll() ([]models.Product, error) {
 	var products []models.Product
 	for rows.Next() {
 		var product models.Product
-		err := rows.Scan(
-			&product.ID,
-			&product.Name,
-			&product.Description,
-			&product.Price,
-			&product.Type,
-			&product.Active,
-			&product.IsSingleUnit,
-			&product.SingleUnitPrice,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-		)
+		err := scanProduct(rows, &product)
 		if err != nil {
 			log.Errorf("Error scanning product row: %v", err)
 			return nil, err
@@ -162,35 +229,14 @@ func (r *ProductRepository) GetAll() ([]models.Product, error) {
 	return products, nil
 }
 
-// Get retrieves a single product by ID
-func (r *ProductRepository) Get(id int64) (*models.Product, error) {
-	query := `
-		SELECT
-			id,
-			name,
-			description,
-			price,
-			type,
-			active,
-			is_single_unit,
-			single_unit_price,
-			created_at,
-			updated_at
-		FROM products WHERE id = ?
-		`
+// Get retrieves a single product by ID, including a soft deleted product if includeDeleted is set
+func (r *ProductRepository) Get(id int64, includeDeleted bool) (*models.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products WHERE id = ?`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
 	var product models.Product
-	err := r.db.QueryRow(query, id).Scan(
-		&product.ID,
-		&product.Name,
-		&product.Description,
-		&product.Price,
-		&product.Type,
-		&product.Active,
-		&product.IsSingleUnit,
-		&product.SingleUnitPrice,
-		&product.CreatedAt,
-		&product.UpdatedAt,
-	)
+	err := scanProduct(r.db.QueryRow(query, id), &product)
 	if err == sql.ErrNoRows {
 		log.Errorf("No product found with ID %d", id)
 		return nil, nil
@@ -202,6 +248,21 @@ func (r *ProductRepository) Get(id int64) (*models.Product, error) {
 	return &product, nil
 }
 
+// GetByBarcode retrieves the product with the given barcode, or nil if there is none
+func (r *ProductRepository) GetByBarcode(barcode string) (*models.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products WHERE barcode = ? AND deleted_at IS NULL`
+	var product models.Product
+	err := scanProduct(r.db.QueryRow(query, barcode), &product)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting product by barcode: %v", err)
+		return nil, err
+	}
+	return &product, nil
+}
+
 // Update updates an existing product
 func (r *ProductRepository) Update(product *models.Product) error {
 	query := `
@@ -214,6 +275,9 @@ func (r *ProductRepository) Update(product *models.Product) error {
 			active = ?,
 			is_single_unit = ?,
 			single_unit_price = ?,
+			category_id = ?,
+			sort_order = ?,
+			barcode = ?,
 			updated_at = ?
 		WHERE id = ?
 	`
@@ -227,6 +291,9 @@ func (r *ProductRepository) Update(product *models.Product) error {
 		product.Active,
 		product.IsSingleUnit,
 		product.SingleUnitPrice,
+		product.CategoryID,
+		product.SortOrder,
+		product.Barcode,
 		now,
 		product.ID,
 	)
@@ -238,10 +305,10 @@ func (r *ProductRepository) Update(product *models.Product) error {
 	return nil
 }
 
-// Delete removes a product by ID
+// Delete soft deletes a product by ID
 func (r *ProductRepository) Delete(id int64) error {
-	query := `DELETE FROM products WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+	query := `UPDATE products SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	_, err := r.db.Exec(query, time.Now(), id)
 	if err != nil {
 		log.Errorf("Error deleting product: %v", err)
 		return err
@@ -249,3 +316,101 @@ func (r *ProductRepository) Delete(id int64) error {
 
 	return nil
 }
+
+// Restore undoes the soft delete of a product and reports whether a deleted product was found
+func (r *ProductRepository) Restore(id int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE products SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`, time.Now(), id)
+	if err != nil {
+		log.Errorf("Error restoring product: %v", err)
+		return false, err
+	}
+	restored, err := result.RowsAffected()
+	return restored > 0, err
+}
+
+// PurgeDeleted permanently removes products soft deleted before cutoff that were never sold or on a menu,
+// together with their images, units, price history and pricing rules
+func (r *ProductRepository) PurgeDeleted(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM products
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		AND NOT EXISTS (SELECT 1 FROM transaction_products WHERE transaction_products.product_id = products.id)
+		AND NOT EXISTS (SELECT 1 FROM menu_items WHERE menu_items.product_id = products.id)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error purging deleted products: %v", err)
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`DELETE FROM product_images WHERE product_id NOT IN (SELECT id FROM products)`)
+	if err != nil {
+		log.Errorf("Error purging images of deleted products: %v", err)
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`DELETE FROM product_prices WHERE product_id NOT IN (SELECT id FROM products)`)
+	if err != nil {
+		log.Errorf("Error purging price history of deleted products: %v", err)
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`DELETE FROM product_units WHERE product_id NOT IN (SELECT id FROM products)`)
+	if err != nil {
+		log.Errorf("Error purging units of deleted products: %v", err)
+		return 0, err
+	}
+
+	// Products that were never sold have no discounted sales either
+	_, err = r.db.Exec(`DELETE FROM pricing_rules WHERE product_id IS NOT NULL AND product_id NOT IN (SELECT id FROM products)`)
+	if err != nil {
+		log.Errorf("Error purging pricing rules of deleted products: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SaveImage stores the image of a product, replacing any previous image
+func (r *ProductRepository) SaveImage(image *models.ProductImage) error {
+	query := `
+		INSERT INTO product_images (product_id, content_type, data, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (product_id) DO UPDATE SET
+			content_type = excluded.content_type,
+			data = excluded.data,
+			updated_at = excluded.updated_at
+	`
+	now := time.Now()
+	_, err := r.db.Exec(query, image.ProductID, image.ContentType, image.Data, now)
+	if err != nil {
+		log.Errorf("Error saving product image: %v", err)
+		return err
+	}
+	image.UpdatedAt = now
+	return nil
+}
+
+// GetImage retrieves the image of a product, or nil if it has none
+func (r *ProductRepository) GetImage(productID int64) (*models.ProductImage, error) {
+	query := `SELECT product_id, content_type, data, updated_at FROM product_images WHERE product_id = ?`
+	var image models.ProductImage
+	err := r.db.QueryRow(query, productID).Scan(&image.ProductID, &image.ContentType, &image.Data, &image.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error getting product image: %v", err)
+		return nil, err
+	}
+	return &image, nil
+}
+
+// DeleteImage removes the image of a product and reports whether it had one
+func (r *ProductRepository) DeleteImage(productID int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM product_images WHERE product_id = ?`, productID)
+	if err != nil {
+		log.Errorf("Error deleting product image: %v", err)
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// productUnitColumns lists the product_units columns in the order scanProductUnit reads them
+const productUnitColumns = `id, product_id, name, price, stock_factor, is_default, sort_order, created_at, updated_at`
+
+// scanProductUnit scans a row selected with productUnitColumns into a product unit
+func scanProductUnit(row rowScanner, unit *models.ProductUnit) error {
+	return row.Scan(
+		&unit.ID,
+		&unit.ProductID,
+		&unit.Name,
+		&unit.Price,
+		&unit.StockFactor,
+		&unit.IsDefault,
+		&unit.SortOrder,
+		&unit.CreatedAt,
+		&unit.UpdatedAt,
+	)
+}
+
+// ProductUnitRepository handles all database operations related to product units
+type ProductUnitRepository struct {
+	db DBTX
+}
+
+// NewProductUnitRepository creates a new product unit repository
+func NewProductUnitRepository(db *sql.DB) *ProductUnitRepository {
+	return &ProductUnitRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *ProductUnitRepository) WithTx(tx *sql.Tx) ProductUnitRepositoryInterface {
+	return &ProductUnitRepository{db: tx}
+}
+
+// InitTable initializes the product_units table and converts the single unit pricing
+// of existing products and sales to units. It must run after the products and
+// transaction_products tables are initialized.
+func (r *ProductUnitRepository) InitTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS product_units (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL REFERENCES products(id),
+			name TEXT NOT NULL COLLATE NOCASE,
+			price REAL NOT NULL,
+			stock_factor REAL NOT NULL DEFAULT 1,
+			is_default BOOLEAN NOT NULL DEFAULT 0,
+			sort_order INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			UNIQUE (product_id, name)
+		)
+	`
+	_, err := r.db.Exec(query)
+	if err != nil {
+		log.Errorf("Error creating product_units table: %v", err)
+		return err
+	}
+	log.Info("Created Product Units Table")
+
+	if err := r.migrateLegacyUnits(); err != nil {
+		log.Errorf("Error converting single unit pricing to product units: %v", err)
+		return err
+	}
+	return nil
+}
+
+// migrateLegacyUnits creates the default units of products without units and links
+// the sales recorded before units existed to them
+func (r *ProductUnitRepository) migrateLegacyUnits() error {
+	rows, err := r.db.Query(`
+		SELECT id, price, single_unit_price FROM products
+		WHERE NOT EXISTS (SELECT 1 FROM product_units WHERE product_units.product_id = products.id)
+	`)
+	if err != nil {
+		return err
+	}
+	var products []models.Product
+	for rows.Next() {
+		var product models.Product
+		if err := rows.Scan(&product.ID, &product.Price, &product.SingleUnitPrice); err != nil {
+			rows.Close()
+			return err
+		}
+		products = append(products, product)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range products {
+		for _, unit := range models.DefaultProductUnits(&products[i]) {
+			if err := r.Create(&unit); err != nil {
+				return err
+			}
+		}
+	}
+	if len(products) > 0 {
+		log.Infof("Created units for %d products", len(products))
+	}
+
+	// Sales of singles use the Single unit, all others the default unit
+	result, err := r.db.Exec(`
+		UPDATE transaction_products
+		SET unit_id = pu.id, unit_name = pu.name, stock_factor = pu.stock_factor
+		FROM product_units pu
+		WHERE transaction_products.unit_id IS NULL
+		AND pu.product_id = transaction_products.product_id
+		AND CASE WHEN transaction_products.is_single_unit THEN pu.name = ? ELSE pu.is_default END
+	`, models.ProductUnitSingle)
+	if err != nil {
+		return err
+	}
+	if linked, _ := result.RowsAffected(); linked > 0 {
+		log.Infof("Linked %d sold products to their units", linked)
+	}
+	return nil
+}
+
+// Create inserts a new product unit into the database
+func (r *ProductUnitRepository) Create(unit *models.ProductUnit) error {
+	query := `
+		INSERT INTO product_units (product_id, name, price, stock_factor, is_default, sort_order, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.Exec(query, unit.ProductID, unit.Name, unit.Price, unit.StockFactor, unit.IsDefault, unit.SortOrder, now, now)
+	if err != nil {
+		log.Errorf("Error inserting product unit: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	unit.ID = id
+	unit.CreatedAt = now
+	unit.UpdatedAt = now
+	return nil
+}
+
+// GetAll retrieves the units of all products, the default unit of each product first
+func (r *ProductUnitRepository) GetAll() ([]models.ProductUnit, error) {
+	return r.list(`SELECT ` + productUnitColumns + ` FROM product_units ORDER BY product_id, is_default DESC, sort_order, id`)
+}
+
+// GetByProductID retrieves the units of a product, the default unit first
+func (r *ProductUnitRepository) GetByProductID(productID int64) ([]models.ProductUnit, error) {
+	return r.list(`SELECT `+productUnitColumns+` FROM product_units WHERE product_id = ? ORDER BY is_default DESC, sort_order, id`, productID)
+}
+
+func (r *ProductUnitRepository) list(query string, args ...any) ([]models.ProductUnit, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Errorf("Error getting product units: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []models.ProductUnit
+	for rows.Next() {
+		var unit models.ProductUnit
+		if err := scanProductUnit(rows, &unit); err != nil {
+			log.Errorf("Error scanning product unit row: %v", err)
+			return nil, err
+		}
+		units = append(units, unit)
+	}
+	return units, rows.Err()
+}
+
+// Get retrieves a single product unit by ID
+func (r *ProductUnitRepository) Get(id int64) (*models.ProductUnit, error) {
+	return r.get(`SELECT `+productUnitColumns+` FROM product_units WHERE id = ?`, id)
+}
+
+// GetByName retrieves a unit of a product by its name, ignoring case
+func (r *ProductUnitRepository) GetByName(productID int64, name string) (*models.ProductUnit, error) {
+	return r.get(`SELECT `+productUnitColumns+` FROM product_units WHERE product_id = ? AND name = ?`, productID, name)
+}
+
+// GetDefault retrieves the default unit of a product
+func (r *ProductUnitRepository) GetDefault(productID int64) (*models.ProductUnit, error) {
+	return r.get(`SELECT `+productUnitColumns+` FROM product_units WHERE product_id = ? AND is_default`, productID)
+}
+
+func (r *ProductUnitRepository) get(query string, args ...any) (*models.ProductUnit, error) {
+	var unit models.ProductUnit
+	err := scanProductUnit(r.db.QueryRow(query, args...), &unit)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting product unit: %v", err)
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// Update updates an existing product unit
+func (r *ProductUnitRepository) Update(unit *models.ProductUnit) error {
+	query := `
+		UPDATE product_units
+		SET name = ?, price = ?, stock_factor = ?, is_default = ?, sort_order = ?, updated_at = ?
+		WHERE id = ?
+	`
+	now := time.Now()
+	_, err := r.db.Exec(query, unit.Name, unit.Price, unit.StockFactor, unit.IsDefault, unit.SortOrder, now, unit.ID)
+	if err != nil {
+		log.Errorf("Error updating product unit: %v", err)
+		return err
+	}
+	unit.UpdatedAt = now
+	return nil
+}
+
+// MakeDefault makes unit the only default unit of its product and its price the product price
+func (r *ProductUnitRepository) MakeDefault(unit *models.ProductUnit) error {
+	now := time.Now()
+	_, err := r.db.Exec(`UPDATE product_units SET is_default = (id = ?), updated_at = ? WHERE product_id = ?`, unit.ID, now, unit.ProductID)
+	if err != nil {
+		log.Errorf("Error setting default product unit: %v", err)
+		return err
+	}
+	_, err = r.db.Exec(`UPDATE products SET price = ?, updated_at = ? WHERE id = ?`, unit.Price, now, unit.ProductID)
+	if err != nil {
+		log.Errorf("Error updating product price from default unit: %v", err)
+		return err
+	}
+	unit.IsDefault = true
+	return nil
+}
+
+// SetDefaultPrice sets the price of the default unit of a product
+func (r *ProductUnitRepository) SetDefaultPrice(productID int64, price float64) error {
+	_, err := r.db.Exec(`UPDATE product_units SET price = ?, updated_at = ? WHERE product_id = ? AND is_default`, price, time.Now(), productID)
+	if err != nil {
+		log.Errorf("Error updating default product unit price: %v", err)
+	}
+	return err
+}
+
+// Delete removes a product unit by ID with its scheduled prices. Sales and the price
+// history of the unit keep its name.
+func (r *ProductUnitRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`UPDATE transaction_products SET unit_id = NULL WHERE unit_id = ?`, id)
+	if err != nil {
+		log.Errorf("Error unlinking sales from product unit: %v", err)
+		return err
+	}
+
+	_, err = r.db.Exec(`DELETE FROM product_prices WHERE unit_id = ? AND applied_at IS NULL`, id)
+	if err != nil {
+		log.Errorf("Error deleting scheduled prices of product unit: %v", err)
+		return err
+	}
+	_, err = r.db.Exec(`UPDATE product_prices SET unit_id = NULL WHERE unit_id = ?`, id)
+	if err != nil {
+		log.Errorf("Error unlinking price history from product unit: %v", err)
+		return err
+	}
+
+	_, err = r.db.Exec(`DELETE FROM product_units WHERE id = ?`, id)
+	if err != nil {
+		log.Errorf("Error deleting product unit: %v", err)
+		return err
+	}
+	return nil
+}
@@ -16,36 +16,241 @@ type Repository interface {
 type UserRepositoryInterface interface {
 	Repository
 	Create(user *models.User) error
-	GetAll() ([]models.User, error)
+	GetAll(includeDeleted bool) ([]models.User, error)
 	Get(id int64) (*models.User, error)
 	Update(user *models.User) error
 	Delete(id int64) error
+	Restore(id int64) (bool, error)
+	PurgeDeleted(cutoff time.Time) (int64, error)
 	UpdateLastNotificationTime(id string) error
+	GetByEmployeeID(employeeID string) (*models.User, error)
+	GetByID(id int64) (*models.User, error)
+	ClearDepartment(department *models.Department) error
+	WithTx(tx *sql.Tx) UserRepositoryInterface
 }
 
 // TransactionRepositoryInterface defines operations for transaction data
 type TransactionRepositoryInterface interface {
 	Repository
 	Create(transaction *models.Transaction) error
-	GetAll() ([]models.Transaction, error)
-	Get(id int64) (*models.Transaction, error)
+	GetAll(includeDeleted bool) ([]models.Transaction, error)
+	Get(id int64, includeDeleted bool) (*models.Transaction, error)
 	Update(transaction *models.Transaction) error
 	Delete(id int64) error
+	Restore(id int64) (bool, error)
+	PurgeDeleted(cutoff time.Time) (int64, error)
 	GetByUserID(userID int64, limit int) ([]models.EmployeeTransaction, error)
 	GetByDateRange(startDate, endDate time.Time) ([]models.Transaction, error)
 	GetLatest(limit int) ([]models.Transaction, error)
 	GetUsersBalances() ([]models.UserBalance, error)
 	GetUserBalanceByID(userID int64) (models.UserBalance, error)
+	GetPeriodBalances(periodEnd time.Time) ([]models.PayrollDeduction, error)
+	CountBatchReferences(prefix string) (int, error)
+	GetRefundedTotals(purchaseID int64) (amount, companyShare float64, err error)
+	WithTx(tx *sql.Tx) TransactionRepositoryInterface
 }
 
 // ProductRepositoryInterface defines operations for product data
 type ProductRepositoryInterface interface {
 	Repository
 	Create(product *models.Product) error
-	GetAll() ([]models.Product, error)
-	Get(id int64) (*models.Product, error)
+	GetAll(includeDeleted bool) ([]models.Product, error)
+	Get(id int64, includeDeleted bool) (*models.Product, error)
 	Update(product *models.Product) error
 	Delete(id int64) error
+	Restore(id int64) (bool, error)
+	PurgeDeleted(cutoff time.Time) (int64, error)
+	GetByBarcode(barcode string) (*models.Product, error)
+	SaveImage(image *models.ProductImage) error
+	GetImage(productID int64) (*models.ProductImage, error)
+	DeleteImage(productID int64) (bool, error)
+}
+
+// CategoryRepositoryInterface defines operations for product category data
+type CategoryRepositoryInterface interface {
+	Repository
+	Create(category *models.Category) error
+	GetAll() ([]models.Category, error)
+	Get(id int64) (*models.Category, error)
+	Update(category *models.Category) error
+	Delete(id int64) error
+}
+
+// ProductUnitRepositoryInterface defines operations for the sellable units of products
+type ProductUnitRepositoryInterface interface {
+	InitTable() error
+	Create(unit *models.ProductUnit) error
+	GetAll() ([]models.ProductUnit, error)
+	GetByProductID(productID int64) ([]models.ProductUnit, error)
+	Get(id int64) (*models.ProductUnit, error)
+	GetByName(productID int64, name string) (*models.ProductUnit, error)
+	GetDefault(productID int64) (*models.ProductUnit, error)
+	Update(unit *models.ProductUnit) error
+	MakeDefault(unit *models.ProductUnit) error
+	SetDefaultPrice(productID int64, price float64) error
+	Delete(id int64) error
+	WithTx(tx *sql.Tx) ProductUnitRepositoryInterface
+}
+
+// ProductPriceRepositoryInterface defines operations for the price history of products
+type ProductPriceRepositoryInterface interface {
+	InitTable() error
+	Create(price *models.ProductPrice) error
+	GetByProductID(productID int64) ([]models.ProductPrice, error)
+	GetDue(now time.Time) ([]models.ProductPrice, error)
+	Get(id int64) (*models.ProductPrice, error)
+	GetLatestApplied(unitID int64) (*models.ProductPrice, error)
+	MarkApplied(id int64, appliedAt time.Time) error
+	DeleteScheduled(id int64) (bool, error)
+	GetApplied() ([]models.ProductPrice, error)
+	GetUnitSales(productID int64, unitID *int64, unitName string, from, until time.Time) (int, float64, error)
+	WithTx(tx *sql.Tx) ProductPriceRepositoryInterface
+}
+
+// DailyMenuRepositoryInterface defines operations for daily menus and pre-orders
+type DailyMenuRepositoryInterface interface {
+	InitTable() error
+	GetItems(date string) ([]models.MenuItem, error)
+	GetItem(id int64) (*models.MenuItem, error)
+	SaveItem(item *models.MenuItem) error
+	DeleteItem(id int64) error
+	GetOrders(date string) ([]models.PreOrder, error)
+	GetPendingOrdersForUser(userID int64, date string) ([]models.PreOrder, error)
+	GetOrder(id int64) (*models.PreOrder, error)
+	GetPendingOrder(menuItemID, userID int64) (*models.PreOrder, error)
+	CountActiveOrders(menuItemID int64) (int, error)
+	CreateOrder(order *models.PreOrder) error
+	UpdateOrderQuantity(id int64, quantity int) error
+	CancelOrder(id int64) (bool, error)
+	MarkCollected(id, transactionID int64, collectedAt time.Time) error
+	WithTx(tx *sql.Tx) DailyMenuRepositoryInterface
+}
+
+// PricingRuleRepositoryInterface defines operations for discount and subsidy rules
+type PricingRuleRepositoryInterface interface {
+	Repository
+	Create(rule *models.PricingRule) error
+	GetAll(activeOnly bool) ([]models.PricingRule, error)
+	Get(id int64) (*models.PricingRule, error)
+	Update(rule *models.PricingRule) error
+	Delete(id int64) error
+	DeactivateDepartment(departmentID int64) error
+	WithTx(tx *sql.Tx) PricingRuleRepositoryInterface
+}
+
+// PaymentRepositoryInterface defines operations for payment statements and their lines
+type PaymentRepositoryInterface interface {
+	Repository
+	CreateStatement(statement *models.PaymentStatement) error
+	GetStatements() ([]models.PaymentStatement, error)
+	GetStatement(id int64) (*models.PaymentStatement, error)
+	CreateLine(line *models.PaymentLine) error
+	HasFingerprint(fingerprint string) (bool, error)
+	GetLines(statementID int64) ([]models.PaymentLine, error)
+	GetLinesByStatus(statuses ...string) ([]models.PaymentLine, error)
+	GetLine(id int64) (*models.PaymentLine, error)
+	MarkPosted(line *models.PaymentLine) (bool, error)
+	Reject(id int64, reviewedBy, note string) (bool, error)
+	WithTx(tx *sql.Tx) PaymentRepositoryInterface
+}
+
+// PendingDepositRepositoryInterface defines operations for receipts awaiting a deposit
+type PendingDepositRepositoryInterface interface {
+	Repository
+	Create(deposit *models.PendingDeposit) (bool, error)
+	GetByStatus(status string) ([]models.PendingDeposit, error)
+	Get(id int64) (*models.PendingDeposit, error)
+	MarkApproved(deposit *models.PendingDeposit) (bool, error)
+	Reject(id int64, reviewedBy, note string) (bool, error)
+	WithTx(tx *sql.Tx) PendingDepositRepositoryInterface
+}
+
+// CashSessionRepositoryInterface defines operations for cash drawer sessions
+type CashSessionRepositoryInterface interface {
+	Repository
+	Create(session *models.CashSession) error
+	GetAll() ([]models.CashSession, error)
+	Get(id int64) (*models.CashSession, error)
+	GetOpen(cashier string) (*models.CashSession, error)
+	GetTotals(id int64) (models.CashSessionTotals, error)
+	GetTransactions(id int64) ([]models.Transaction, error)
+	Close(session *models.CashSession) (bool, error)
+	WithTx(tx *sql.Tx) CashSessionRepositoryInterface
+}
+
+// BalanceTransferRepositoryInterface defines operations for balance transfers between users
+type BalanceTransferRepositoryInterface interface {
+	Repository
+	Create(transfer *models.BalanceTransfer) error
+	GetAll(status string) ([]models.BalanceTransfer, error)
+	GetByUser(userID int64) ([]models.BalanceTransfer, error)
+	Get(id int64) (*models.BalanceTransfer, error)
+	MarkCompleted(transfer *models.BalanceTransfer) (bool, error)
+	Reject(id int64, reviewedBy, note string) (bool, error)
+	WithTx(tx *sql.Tx) BalanceTransferRepositoryInterface
+}
+
+// OffboardingRepositoryInterface defines operations for offboarding departing users
+type OffboardingRepositoryInterface interface {
+	Repository
+	Create(offboarding *models.Offboarding) error
+	GetAll() ([]models.Offboarding, error)
+	GetByUser(userID int64) (*models.Offboarding, error)
+	GetTransactions(userID int64) ([]models.Transaction, error)
+	GetWriteOffs(start, end time.Time) ([]models.WriteOff, error)
+	WithTx(tx *sql.Tx) OffboardingRepositoryInterface
+}
+
+// WalletRepositoryInterface defines operations for wallet top-ups and their bonus rules
+type WalletRepositoryInterface interface {
+	Repository
+	CreateBonusRule(rule *models.TopUpBonusRule) error
+	GetBonusRules(activeOnly bool) ([]models.TopUpBonusRule, error)
+	GetBonusRule(id int64) (*models.TopUpBonusRule, error)
+	UpdateBonusRule(rule *models.TopUpBonusRule) error
+	DeleteBonusRule(id int64) error
+	CreateTopUp(topUp *models.TopUp) error
+	GetTopUps(userID int64) ([]models.TopUp, error)
+	GetTopUpTotals() (float64, float64, error)
+	WithTx(tx *sql.Tx) WalletRepositoryInterface
+}
+
+// InstallmentPlanRepositoryInterface defines operations for installment plans and their installments
+type InstallmentPlanRepositoryInterface interface {
+	Repository
+	Create(plan *models.InstallmentPlan) error
+	GetAll(status string) ([]models.InstallmentPlan, error)
+	GetByUser(userID int64) ([]models.InstallmentPlan, error)
+	Get(id int64) (*models.InstallmentPlan, error)
+	GetActiveByUser(userID int64) (*models.InstallmentPlan, error)
+	GetInstallments(planID int64) ([]models.Installment, error)
+	GetPaidAmount(batchReference string) (float64, error)
+	SetStatus(id int64, from, to string) (bool, error)
+	WithTx(tx *sql.Tx) InstallmentPlanRepositoryInterface
+}
+
+// EscalationRepositoryInterface defines operations for the escalation policy of overdue balances
+type EscalationRepositoryInterface interface {
+	Repository
+	GetLedger() ([]models.LedgerEntry, error)
+	CreateLevel(level *models.EscalationLevel) error
+	GetLevels(activeOnly bool) ([]models.EscalationLevel, error)
+	GetLevel(id int64) (*models.EscalationLevel, error)
+	UpdateLevel(level *models.EscalationLevel) error
+	DeleteLevel(id int64) error
+	CreateNotice(notice *models.EscalationNotice) error
+	GetNotices(since time.Time) ([]models.EscalationNotice, error)
+}
+
+// AnalyticsRepositoryInterface defines the sales aggregates of the analytics dashboards.
+// It has no tables of its own, so it does not embed Repository.
+type AnalyticsRepositoryInterface interface {
+	GetPeriods(previousStart, startDate, endDate time.Time) (models.AnalyticsPeriod, models.AnalyticsPeriod, error)
+	GetRevenue(startDate, endDate time.Time, granularity string) ([]models.RevenuePoint, error)
+	GetHeatmap(startDate, endDate time.Time) ([]models.HeatmapCell, error)
+	GetDailyCustomers(startDate, endDate time.Time) ([]models.DailyCustomers, error)
+	GetTopProducts(previousStart, startDate, endDate time.Time, limit int) ([]models.TopProduct, error)
+	GetTopUsers(previousStart, startDate, endDate time.Time, limit int) ([]models.TopUser, error)
 }
 
 // TransactionProductRepositoryInterface defines operations for transaction product relationships
@@ -53,8 +258,43 @@ type TransactionProductRepositoryInterface interface {
 	Repository
 	Create(transactionProduct *models.TransactionProduct) error
 	GetByTransactionID(transactionID int64) ([]models.TransactionProduct, error)
+	GetRefundedQuantities(transactionID int64) (map[int64]int, error)
 	GetProductSalesSummary(startDate, endDate time.Time) ([]models.ProductSalesSummary, error)
 	GetTransactionProductDetails(startDate, endDate time.Time) ([]models.TransactionProductDetail, error)
+	WithTx(tx *sql.Tx) TransactionProductRepositoryInterface
+}
+
+// DepartmentRepositoryInterface defines operations for department data and reports
+type DepartmentRepositoryInterface interface {
+	Repository
+	Create(department *models.Department) error
+	GetAll() ([]models.Department, error)
+	Get(id int64) (*models.Department, error)
+	GetByName(name string) (*models.Department, error)
+	Update(department *models.Department) error
+	Delete(id int64) error
+	GetMonthlySpend(startDate, endDate time.Time) ([]models.DepartmentSpend, error)
+	GetTopConsumers(startDate, endDate time.Time, limit int) ([]models.DepartmentTopConsumer, error)
+	GetProductConsumption(startDate, endDate time.Time) ([]models.DepartmentProductConsumption, error)
+	GetBalances() ([]models.DepartmentBalance, error)
+	GetCompanyShares(periodStart, periodEnd time.Time) ([]models.EmployerInvoiceDepartment, error)
+	WithTx(tx *sql.Tx) DepartmentRepositoryInterface
+}
+
+// BackupRepositoryInterface defines database backup and restore operations
+type BackupRepositoryInterface interface {
+	Repository
+	BackupTo(path string) error
+	Export() (*models.BackupBundle, error)
+	Restore(bundle *models.BackupBundle, overwrite bool) (*models.RestoreResult, error)
+}
+
+// AuditRepositoryInterface defines audit log operations
+type AuditRepositoryInterface interface {
+	Repository
+	Create(entry *models.AuditLog) error
+	List(filter models.AuditLogFilter) ([]models.AuditLog, error)
+	DeleteBefore(cutoff time.Time) (int64, error)
 }
 
 // RepositoryFactory creates and returns repositories
@@ -86,3 +326,88 @@ func (f *RepositoryFactory) NewProductRepository() ProductRepositoryInterface {
 func (f *RepositoryFactory) NewTransactionProductRepository() TransactionProductRepositoryInterface {
 	return NewTransactionProductRepository(f.db)
 }
+
+// NewDepartmentRepository creates a new department repository
+func (f *RepositoryFactory) NewDepartmentRepository() DepartmentRepositoryInterface {
+	return NewDepartmentRepository(f.db)
+}
+
+// NewBackupRepository creates a new backup repository
+func (f *RepositoryFactory) NewBackupRepository() BackupRepositoryInterface {
+	return NewBackupRepository(f.db)
+}
+
+// NewAuditRepository creates a new audit repository
+func (f *RepositoryFactory) NewAuditRepository() AuditRepositoryInterface {
+	return NewAuditRepository(f.db)
+}
+
+// NewCategoryRepository creates a new category repository
+func (f *RepositoryFactory) NewCategoryRepository() CategoryRepositoryInterface {
+	return NewCategoryRepository(f.db)
+}
+
+// NewProductUnitRepository creates a new product unit repository
+func (f *RepositoryFactory) NewProductUnitRepository() ProductUnitRepositoryInterface {
+	return NewProductUnitRepository(f.db)
+}
+
+// NewProductPriceRepository creates a new product price repository
+func (f *RepositoryFactory) NewProductPriceRepository() ProductPriceRepositoryInterface {
+	return NewProductPriceRepository(f.db)
+}
+
+// NewDailyMenuRepository creates a new daily menu repository
+func (f *RepositoryFactory) NewDailyMenuRepository() DailyMenuRepositoryInterface {
+	return NewDailyMenuRepository(f.db)
+}
+
+// NewPricingRuleRepository creates a new pricing rule repository
+func (f *RepositoryFactory) NewPricingRuleRepository() PricingRuleRepositoryInterface {
+	return NewPricingRuleRepository(f.db)
+}
+
+// NewPaymentRepository creates a new payment repository
+func (f *RepositoryFactory) NewPaymentRepository() PaymentRepositoryInterface {
+	return NewPaymentRepository(f.db)
+}
+
+// NewPendingDepositRepository creates a new pending deposit repository
+func (f *RepositoryFactory) NewPendingDepositRepository() PendingDepositRepositoryInterface {
+	return NewPendingDepositRepository(f.db)
+}
+
+// NewCashSessionRepository creates a new cash session repository
+func (f *RepositoryFactory) NewCashSessionRepository() CashSessionRepositoryInterface {
+	return NewCashSessionRepository(f.db)
+}
+
+// NewBalanceTransferRepository creates a new balance transfer repository
+func (f *RepositoryFactory) NewBalanceTransferRepository() BalanceTransferRepositoryInterface {
+	return NewBalanceTransferRepository(f.db)
+}
+
+// NewOffboardingRepository creates a new offboarding repository
+func (f *RepositoryFactory) NewOffboardingRepository() OffboardingRepositoryInterface {
+	return NewOffboardingRepository(f.db)
+}
+
+// NewWalletRepository creates a new wallet repository
+func (f *RepositoryFactory) NewWalletRepository() WalletRepositoryInterface {
+	return NewWalletRepository(f.db)
+}
+
+// NewInstallmentPlanRepository creates a new installment plan repository
+func (f *RepositoryFactory) NewInstallmentPlanRepository() InstallmentPlanRepositoryInterface {
+	return NewInstallmentPlanRepository(f.db)
+}
+
+// NewEscalationRepository creates a new escalation repository
+func (f *RepositoryFactory) NewEscalationRepository() EscalationRepositoryInterface {
+	return NewEscalationRepository(f.db)
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func (f *RepositoryFactory) NewAnalyticsRepository() AnalyticsRepositoryInterface {
+	return NewAnalyticsRepository(f.db)
+}
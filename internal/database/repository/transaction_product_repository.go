@@ -10,7 +10,7 @@ import (
 
 // TransactionProductRepository handles all database operations related to transaction products
 type TransactionProductRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 // NewTransactionProductRepository creates a new transaction product repository
@@ -18,6 +18,11 @@ func NewTransactionProductRepository(db *sql.DB) *TransactionProductRepository {
 	return &TransactionProductRepository{db: db}
 }
 
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *TransactionProductRepository) WithTx(tx *sql.Tx) TransactionProductRepositoryInterface {
+	return &TransactionProductRepository{db: tx}
+}
+
 // InitTable initializes the transaction_products table
 func (r *TransactionProductRepository) InitTable() error {
 	query := `
@@ -29,6 +34,16 @@ func (r *TransactionProductRepository) InitTable() error {
 			quantity INTEGER NOT NULL,
 			unit_price REAL NOT NULL,
 			is_single_unit BOOLEAN NOT NULL DEFAULT false,
+			unit_id INTEGER REFERENCES product_units(id),
+			unit_name TEXT NOT NULL DEFAULT '',
+			stock_factor REAL NOT NULL DEFAULT 1,
+			discount_rule_id INTEGER REFERENCES pricing_rules(id),
+			discount_name TEXT NOT NULL DEFAULT '',
+			discount_amount REAL NOT NULL DEFAULT 0,
+			subsidy_rule_id INTEGER REFERENCES pricing_rules(id),
+			subsidy_name TEXT NOT NULL DEFAULT '',
+			subsidy_amount REAL NOT NULL DEFAULT 0,
+			refunded_line_id INTEGER REFERENCES transaction_products(id),
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL,
 			FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
@@ -42,6 +57,17 @@ func (r *TransactionProductRepository) InitTable() error {
 	}
 
 	log.Info("Created Transaction Products Table")
+
+	addColumnIfNeeded(r.db, "transaction_products", "unit_id", "INTEGER REFERENCES product_units(id)")
+	addColumnIfNeeded(r.db, "transaction_products", "unit_name", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfNeeded(r.db, "transaction_products", "stock_factor", "REAL NOT NULL DEFAULT 1")
+	addColumnIfNeeded(r.db, "transaction_products", "discount_rule_id", "INTEGER REFERENCES pricing_rules(id)")
+	addColumnIfNeeded(r.db, "transaction_products", "discount_name", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfNeeded(r.db, "transaction_products", "discount_amount", "REAL NOT NULL DEFAULT 0")
+	addColumnIfNeeded(r.db, "transaction_products", "subsidy_rule_id", "INTEGER REFERENCES pricing_rules(id)")
+	addColumnIfNeeded(r.db, "transaction_products", "subsidy_name", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfNeeded(r.db, "transaction_products", "subsidy_amount", "REAL NOT NULL DEFAULT 0")
+	addColumnIfNeeded(r.db, "transaction_products", "refunded_line_id", "INTEGER REFERENCES transaction_products(id)")
 	return nil
 }
 
@@ -55,10 +81,20 @@ func (r *TransactionProductRepository) Create(transactionProduct *models.Transac
 			quantity,
 			unit_price,
 			is_single_unit,
+			unit_id,
+			unit_name,
+			stock_factor,
+			discount_rule_id,
+			discount_name,
+			discount_amount,
+			subsidy_rule_id,
+			subsidy_name,
+			subsidy_amount,
+			refunded_line_id,
 			created_at,
 			updated_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	now := time.Now()
 	result, err := r.db.Exec(
@@ -69,6 +105,16 @@ func (r *TransactionProductRepository) Create(transactionProduct *models.Transac
 		transactionProduct.Quantity,
 		transactionProduct.UnitPrice,
 		transactionProduct.IsSingleUnit,
+		transactionProduct.UnitID,
+		transactionProduct.UnitName,
+		transactionProduct.StockFactor,
+		transactionProduct.DiscountRuleID,
+		transactionProduct.DiscountName,
+		transactionProduct.DiscountAmount,
+		transactionProduct.SubsidyRuleID,
+		transactionProduct.SubsidyName,
+		transactionProduct.SubsidyAmount,
+		transactionProduct.RefundedLineID,
 		now,
 		now,
 	)
@@ -98,6 +144,16 @@ func (r *TransactionProductRepository) GetByTransactionID(transactionID int64) (
 			quantity,
 			unit_price,
 			is_single_unit,
+			unit_id,
+			unit_name,
+			stock_factor,
+			discount_rule_id,
+			discount_name,
+			discount_amount,
+			subsidy_rule_id,
+			subsidy_name,
+			subsidy_amount,
+			refunded_line_id,
 			created_at,
 			updated_at
 		FROM transaction_products
@@ -122,6 +178,16 @@ func (r *TransactionProductRepository) GetByTransactionID(transactionID int64) (
 			&product.Quantity,
 			&product.UnitPrice,
 			&product.IsSingleUnit,
+			&product.UnitID,
+			&product.UnitName,
+			&product.StockFactor,
+			&product.DiscountRuleID,
+			&product.DiscountName,
+			&product.DiscountAmount,
+			&product.SubsidyRuleID,
+			&product.SubsidyName,
+			&product.SubsidyAmount,
+			&product.RefundedLineID,
 			&product.CreatedAt,
 			&product.UpdatedAt,
 		)
@@ -137,7 +203,38 @@ func (r *TransactionProductRepository) GetByTransactionID(transactionID int64) (
 	return products, nil
 }
 
-// GetProductSalesSummary retrieves sales statistics for all products
+// GetRefundedQuantities retrieves how much of each product line of a purchase has been
+// returned by refunds that are not deleted, by line ID
+func (r *TransactionProductRepository) GetRefundedQuantities(transactionID int64) (map[int64]int, error) {
+	rows, err := r.db.Query(`
+		SELECT refund.refunded_line_id, SUM(refund.quantity)
+		FROM transaction_products refund
+		JOIN transactions t ON refund.transaction_id = t.id
+		JOIN transaction_products line ON refund.refunded_line_id = line.id
+		WHERE line.transaction_id = ? AND t.deleted_at IS NULL
+		GROUP BY refund.refunded_line_id
+	`, transactionID)
+	if err != nil {
+		log.Errorf("Error getting refunded quantities: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunded := make(map[int64]int)
+	for rows.Next() {
+		var lineID int64
+		var quantity int
+		if err := rows.Scan(&lineID, &quantity); err != nil {
+			log.Errorf("Error scanning refunded quantity row: %v", err)
+			return nil, err
+		}
+		refunded[lineID] = quantity
+	}
+	return refunded, rows.Err()
+}
+
+// GetProductSalesSummary retrieves sales statistics for all products. Sales are totalled after
+// line discounts.
 func (r *TransactionProductRepository) GetProductSalesSummary(startDate, endDate time.Time) ([]models.ProductSalesSummary, error) {
 	// Adjust endDate to include the entire day
 	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
@@ -147,15 +244,17 @@ func (r *TransactionProductRepository) GetProductSalesSummary(startDate, endDate
 			p.id AS product_id,
 			p.name AS product_name,
 			p.type AS product_type,
-			SUM(tp.quantity) AS total_quantity,
-			SUM(tp.quantity * tp.unit_price) AS total_sales,
-			SUM(CASE WHEN tp.is_single_unit = 1 THEN tp.quantity ELSE 0 END) AS single_unit_sold,
-			SUM(CASE WHEN tp.is_single_unit = 0 THEN tp.quantity ELSE 0 END) AS full_unit_sold
+			SUM(` + saleSignSQL("t") + ` * tp.quantity) AS total_quantity,
+			SUM(` + saleSignSQL("t") + ` * (tp.quantity * tp.unit_price - tp.discount_amount)) AS total_sales,
+			SUM(CASE WHEN tp.is_single_unit = 1 THEN ` + saleSignSQL("t") + ` * tp.quantity ELSE 0 END) AS single_unit_sold,
+			SUM(CASE WHEN tp.is_single_unit = 0 THEN ` + saleSignSQL("t") + ` * tp.quantity ELSE 0 END) AS full_unit_sold,
+			SUM(` + saleSignSQL("t") + ` * tp.quantity * tp.stock_factor) AS stock_quantity
 		FROM transaction_products tp
 		JOIN products p ON tp.product_id = p.id
 		JOIN transactions t ON tp.transaction_id = t.id
-		WHERE t.transaction_type = 'purchase'
+		WHERE t.transaction_type IN ('purchase', 'refund')
 		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
 		GROUP BY p.id, p.name, p.type
 		ORDER BY total_sales DESC
 	`
@@ -177,6 +276,7 @@ func (r *TransactionProductRepository) GetProductSalesSummary(startDate, endDate
 			&summary.TotalSales,
 			&summary.SingleUnitSold,
 			&summary.FullUnitSold,
+			&summary.StockQuantity,
 		)
 		if err != nil {
 			log.Errorf("Error scanning product sales summary row: %v", err)
@@ -188,10 +288,73 @@ func (r *TransactionProductRepository) GetProductSalesSummary(startDate, endDate
 		log.Errorf("Error with product sales summary rows: %v", err)
 		return nil, err
 	}
+
+	variants, err := r.getUnitSales(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	for i := range summaries {
+		summaries[i].Variants = variants[summaries[i].ProductID]
+		if summaries[i].Variants == nil {
+			summaries[i].Variants = []models.ProductUnitSales{}
+		}
+	}
 	return summaries, nil
 }
 
-// GetTransactionProductDetails retrieves product details with transaction context
+// getUnitSales retrieves the sales of each product unit by product ID. The endDate must
+// already include the entire day.
+func (r *TransactionProductRepository) getUnitSales(startDate, endDate time.Time) (map[int64][]models.ProductUnitSales, error) {
+	query := `
+		SELECT
+			tp.product_id,
+			tp.unit_id,
+			tp.unit_name,
+			SUM(` + saleSignSQL("t") + ` * tp.quantity) AS quantity,
+			SUM(` + saleSignSQL("t") + ` * tp.quantity * tp.stock_factor) AS stock_quantity,
+			SUM(` + saleSignSQL("t") + ` * (tp.quantity * tp.unit_price - tp.discount_amount)) AS total_sales
+		FROM transaction_products tp
+		JOIN transactions t ON tp.transaction_id = t.id
+		WHERE t.transaction_type IN ('purchase', 'refund')
+		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
+		GROUP BY tp.product_id, tp.unit_id, tp.unit_name
+		ORDER BY total_sales DESC
+	`
+	rows, err := r.db.Query(query, startDate, endDate)
+	if err != nil {
+		log.Errorf("Error executing product unit sales query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := make(map[int64][]models.ProductUnitSales)
+	for rows.Next() {
+		var productID int64
+		var unit models.ProductUnitSales
+		err := rows.Scan(
+			&productID,
+			&unit.UnitID,
+			&unit.UnitName,
+			&unit.Quantity,
+			&unit.StockQuantity,
+			&unit.TotalSales,
+		)
+		if err != nil {
+			log.Errorf("Error scanning product unit sales row: %v", err)
+			return nil, err
+		}
+		sales[productID] = append(sales[productID], unit)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error with product unit sales rows: %v", err)
+		return nil, err
+	}
+	return sales, nil
+}
+
+// GetTransactionProductDetails retrieves product details with transaction context. Returned
+// products of refunds have negative quantities and amounts.
 func (r *TransactionProductRepository) GetTransactionProductDetails(startDate, endDate time.Time) ([]models.TransactionProductDetail, error) {
 	// Adjust endDate to include the entire day
 	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
@@ -203,17 +366,22 @@ func (r *TransactionProductRepository) GetTransactionProductDetails(startDate, e
 			tp.product_id,
 			tp.product_name,
 			p.type AS product_type,
-			tp.quantity,
+			` + saleSignSQL("t") + ` * tp.quantity,
 			tp.unit_price,
-			(tp.quantity * tp.unit_price) AS total_price,
+			` + saleSignSQL("t") + ` * tp.quantity * tp.unit_price AS total_price,
 			tp.is_single_unit,
+			tp.unit_id,
+			tp.unit_name,
+			` + saleSignSQL("t") + ` * tp.discount_amount,
+			` + saleSignSQL("t") + ` * tp.subsidy_amount,
 			tp.created_at,
 			tp.updated_at
 		FROM transaction_products tp
 		JOIN products p ON tp.product_id = p.id
 		JOIN transactions t ON tp.transaction_id = t.id
-		WHERE t.transaction_type = 'purchase'
+		WHERE t.transaction_type IN ('purchase', 'refund')
 		AND t.created_at BETWEEN ? AND ?
+		AND t.deleted_at IS NULL
 		ORDER BY tp.transaction_id DESC, tp.id ASC
 	`
 	rows, err := r.db.Query(query, startDate, endDate)
@@ -236,6 +404,10 @@ func (r *TransactionProductRepository) GetTransactionProductDetails(startDate, e
 			&detail.UnitPrice,
 			&detail.TotalPrice,
 			&detail.IsSingleUnit,
+			&detail.UnitID,
+			&detail.UnitName,
+			&detail.DiscountAmount,
+			&detail.SubsidyAmount,
 			&detail.CreatedAt,
 			&detail.UpdatedAt,
 		)
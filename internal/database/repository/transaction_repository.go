@@ -2,15 +2,97 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"math"
 	"maya-canteen/internal/models"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// signedAmountSQL returns the SQL expression for the employee share of a transaction signed by
+// its effect on the user's balance, as given by models.TransactionTypeSign. Purchases and
+// refunds paid in cash are settled on the spot and do not affect the balance. The company
+// share is billed to the employer and never affects the balance either.
+// alias is the name the transactions table is referenced by in the query.
+func signedAmountSQL(alias string) string {
+	expression := "CASE WHEN " + alias + ".payment_method = 'cash' AND " + alias + ".transaction_type IN ('purchase', 'refund') THEN 0"
+	for _, transactionType := range models.TransactionTypes {
+		expression += fmt.Sprintf(" WHEN %s.transaction_type = '%s' THEN %d * %s.employee_share", alias, transactionType, models.TransactionTypeSign(transactionType), alias)
+	}
+	return expression + " ELSE 0 END"
+}
+
+// saleSignSQL returns the SQL expression for the sign of a sale in sales reports: refunds
+// take the returned products off their purchase, so they count negatively
+func saleSignSQL(alias string) string {
+	return "CASE WHEN " + alias + ".transaction_type = 'refund' THEN -1 ELSE 1 END"
+}
+
+// isPrepaid reports whether a balance is positive once rounded to cents, meaning the user has
+// funds paid in advance that purchases draw on before the user owes anything
+func isPrepaid(balance float64) bool {
+	return math.Round(balance*100) > 0
+}
+
+// transactionColumns lists the transactions columns in the order scanTransaction reads them
+const transactionColumns = `
+	id,
+	user_id,
+	amount,
+	description,
+	transaction_type,
+	batch_reference,
+	discount_amount,
+	employee_share,
+	company_share,
+	payment_method,
+	cash_session_id,
+	refund_of_id,
+	created_at,
+	updated_at,
+	deleted_at`
+
+// scanTransaction scans a row selected with transactionColumns into a transaction
+func scanTransaction(row rowScanner, transaction *models.Transaction) error {
+	var userID sql.NullInt64
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&transaction.ID,
+		&userID,
+		&transaction.Amount,
+		&transaction.Description,
+		&transaction.TransactionType,
+		&transaction.BatchReference,
+		&transaction.DiscountAmount,
+		&transaction.EmployeeShare,
+		&transaction.CompanyShare,
+		&transaction.PaymentMethod,
+		&transaction.CashSessionID,
+		&transaction.RefundOfID,
+		&transaction.CreatedAt,
+		&transaction.UpdatedAt,
+		&deletedAt,
+	)
+	if err != nil {
+		return err
+	}
+	transaction.UserID = userID.Int64
+	if deletedAt.Valid {
+		transaction.DeletedAt = &deletedAt.Time
+	}
+	return nil
+}
+
+// transactionUserID returns the user of a transaction to store, which is NULL for guest sales
+func transactionUserID(transaction *models.Transaction) sql.NullInt64 {
+	return sql.NullInt64{Int64: transaction.UserID, Valid: transaction.UserID != 0}
+}
+
 // TransactionRepository handles all database operations related to transactions
 type TransactionRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 // NewTransactionRepository creates a new transaction repository
@@ -18,30 +100,177 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
-// InitTable initializes the transactions table
-func (r *TransactionRepository) InitTable() error {
-	query := `
-		CREATE TABLE IF NOT EXISTS transactions (
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *TransactionRepository) WithTx(tx *sql.Tx) TransactionRepositoryInterface {
+	return &TransactionRepository{db: tx}
+}
+
+// transactionTypeCheck is the constraint that limits transaction types to the valid types
+var transactionTypeCheck = "CHECK (transaction_type IN ('" + strings.Join(models.TransactionTypes, "', '") + "'))"
+
+// transactionTypeAliases maps the types that were accepted before transaction types were
+// enforced to the valid type they are normalized to
+var transactionTypeAliases = map[string]string{
+	"withdrawal": models.TransactionTypeAdjustment,
+	"writeoff":   models.TransactionTypeWriteOff,
+	"write_off":  models.TransactionTypeWriteOff,
+	"write off":  models.TransactionTypeWriteOff,
+}
+
+// transactionsTableColumns defines the columns of the transactions table. The user is
+// NULL for walk-in guest sales.
+var transactionsTableColumns = `
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			user_id INTEGER NOT NULL,
+			user_id INTEGER,
 			amount REAL NOT NULL,
 			description TEXT,
-			transaction_type TEXT NOT NULL,
+			transaction_type TEXT NOT NULL ` + transactionTypeCheck + `,
+			batch_reference TEXT NOT NULL DEFAULT '',
+			discount_amount REAL NOT NULL DEFAULT 0,
+			employee_share REAL,
+			company_share REAL NOT NULL DEFAULT 0,
+			payment_method TEXT NOT NULL DEFAULT 'account',
+			cash_session_id INTEGER REFERENCES cash_sessions(id),
+			refund_of_id INTEGER REFERENCES transactions(id),
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL,
+			deleted_at DATETIME,
 			FOREIGN KEY (user_id) REFERENCES users(id)
-		)
-	`
-	_, err := r.db.Exec(query)
+		`
+
+// InitTable initializes the transactions table
+func (r *TransactionRepository) InitTable() error {
+	_, err := r.db.Exec(`CREATE TABLE IF NOT EXISTS transactions (` + transactionsTableColumns + `)`)
 	if err != nil {
 		log.Errorf("Error creating transactions table: %v", err)
 	}
 
+	addColumnIfNeeded(r.db, "transactions", "batch_reference", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfNeeded(r.db, "transactions", "deleted_at", "DATETIME")
+	addColumnIfNeeded(r.db, "transactions", "discount_amount", "REAL NOT NULL DEFAULT 0")
+	renameColumnIfNeeded(r.db, "transactions", "subsidy_amount", "company_share")
+	addColumnIfNeeded(r.db, "transactions", "company_share", "REAL NOT NULL DEFAULT 0")
+	addColumnIfNeeded(r.db, "transactions", "employee_share", "REAL")
+	addColumnIfNeeded(r.db, "transactions", "payment_method", "TEXT NOT NULL DEFAULT 'account'")
+	addColumnIfNeeded(r.db, "transactions", "cash_session_id", "INTEGER REFERENCES cash_sessions(id)")
+	addColumnIfNeeded(r.db, "transactions", "refund_of_id", "INTEGER REFERENCES transactions(id)")
+	if err := r.normalizeTransactionTypes(); err != nil {
+		return err
+	}
+	if err := r.migrateGuestSales(); err != nil {
+		return err
+	}
+	if err := r.migrateTransactionTypeCheck(); err != nil {
+		return err
+	}
+	if err := r.migrateShares(); err != nil {
+		return err
+	}
+
 	log.Info("Created Transactions Table")
 	return nil
 }
 
-// Create inserts a new transaction into the database
+// migrateGuestSales rebuilds transactions tables created when every transaction needed a
+// user, so that guest sales can be recorded without one
+func (r *TransactionRepository) migrateGuestSales() error {
+	var userRequired bool
+	err := r.db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('transactions') WHERE name = 'user_id' AND "notnull" = 1`).Scan(&userRequired)
+	if err != nil {
+		log.Errorf("Error checking if transactions need a user: %v", err)
+		return err
+	}
+	if !userRequired {
+		return nil
+	}
+	return rebuildTable(r.db, "transactions", transactionsTableColumns)
+}
+
+// normalizeTransactionType returns the valid type a transaction type that was accepted before
+// transaction types were enforced stands for. Types are lowercased and trimmed, old names are
+// mapped to their valid type, and unknown types, which used to be debited, become adjustments.
+func normalizeTransactionType(transactionType string) string {
+	normalized := strings.ToLower(strings.TrimSpace(transactionType))
+	if alias, ok := transactionTypeAliases[normalized]; ok {
+		return alias
+	}
+	if models.TransactionTypeSign(normalized) == 0 {
+		return models.TransactionTypeAdjustment
+	}
+	return normalized
+}
+
+// normalizeTransactionTypes rewrites the types of transactions created before transaction
+// types were enforced to valid types with normalizeTransactionType
+func (r *TransactionRepository) normalizeTransactionTypes() error {
+	rows, err := r.db.Query(`SELECT DISTINCT transaction_type FROM transactions WHERE transaction_type NOT IN ('` + strings.Join(models.TransactionTypes, "', '") + `')`)
+	if err != nil {
+		log.Errorf("Error getting invalid transaction types: %v", err)
+		return err
+	}
+	var invalid []string
+	for rows.Next() {
+		var transactionType string
+		if err := rows.Scan(&transactionType); err != nil {
+			rows.Close()
+			return err
+		}
+		invalid = append(invalid, transactionType)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, transactionType := range invalid {
+		normalized := normalizeTransactionType(transactionType)
+		result, err := r.db.Exec(`UPDATE transactions SET transaction_type = ? WHERE transaction_type = ?`, normalized, transactionType)
+		if err != nil {
+			log.Errorf("Error normalizing %q transactions: %v", transactionType, err)
+			return err
+		}
+		count, _ := result.RowsAffected()
+		log.Infof("Changed the type of %d %q transactions to %s", count, transactionType, normalized)
+	}
+	return nil
+}
+
+// migrateTransactionTypeCheck rebuilds transactions tables that do not limit transaction types
+// to the current valid types
+func (r *TransactionRepository) migrateTransactionTypeCheck() error {
+	var checked bool
+	err := r.db.QueryRow(`SELECT instr(sql, ?) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'transactions'`, transactionTypeCheck).Scan(&checked)
+	if err != nil {
+		log.Errorf("Error checking the transaction type constraint: %v", err)
+		return err
+	}
+	if checked {
+		return nil
+	}
+	return rebuildTable(r.db, "transactions", transactionsTableColumns)
+}
+
+// migrateShares splits transactions created before payer splits existed. Their amount was
+// charged fully to the user, except for subsidies, which were left out of the amount and are
+// added back so that the amount is again the total of both shares.
+func (r *TransactionRepository) migrateShares() error {
+	result, err := r.db.Exec(`
+		UPDATE transactions
+		SET employee_share = amount, amount = amount + company_share
+		WHERE employee_share IS NULL
+	`)
+	if err != nil {
+		log.Errorf("Error splitting transactions into employee and company shares: %v", err)
+		return err
+	}
+	if migrated, _ := result.RowsAffected(); migrated > 0 {
+		log.Infof("Split %d transactions into employee and company shares", migrated)
+	}
+	return nil
+}
+
+// Create inserts a new transaction into the database. The employee share is the part of the
+// amount that is not paid by the company.
 func (r *TransactionRepository) Create(transaction *models.Transaction) error {
 	query := `
 		INSERT INTO transactions (
@@ -49,21 +278,44 @@ func (r *TransactionRepository) Create(transaction *models.Transaction) error {
       amount,
       description,
       transaction_type,
+      batch_reference,
+      discount_amount,
+      employee_share,
+      company_share,
+      payment_method,
+      cash_session_id,
+      refund_of_id,
       created_at,
       updated_at
     )
 		VALUES (
-      ?, ?, ?, ?, ?, ?
+      ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
     )
 	`
 	now := time.Now()
+	transaction.EmployeeShare = transaction.Amount - transaction.CompanyShare
+	if transaction.PaymentMethod == "" {
+		transaction.PaymentMethod = models.PaymentMethodAccount
+	}
+	// Back-dated transactions (e.g. bulk imports) keep their own creation time
+	createdAt := now
+	if !transaction.CreatedAt.IsZero() {
+		createdAt = transaction.CreatedAt
+	}
 	result, err := r.db.Exec(
 		query,
-		transaction.UserID,
+		transactionUserID(transaction),
 		transaction.Amount,
 		transaction.Description,
 		transaction.TransactionType,
-		now,
+		transaction.BatchReference,
+		transaction.DiscountAmount,
+		transaction.EmployeeShare,
+		transaction.CompanyShare,
+		transaction.PaymentMethod,
+		transaction.CashSessionID,
+		transaction.RefundOfID,
+		createdAt,
 		now,
 	)
 	if err != nil {
@@ -76,14 +328,18 @@ func (r *TransactionRepository) Create(transaction *models.Transaction) error {
 		return err
 	}
 	transaction.ID = id
-	transaction.CreatedAt = now
+	transaction.CreatedAt = createdAt
 	transaction.UpdatedAt = now
 	return nil
 }
 
-// GetAll retrieves all transactions from the database
-func (r *TransactionRepository) GetAll() ([]models.Transaction, error) {
-	query := `SELECT * FROM transactions ORDER BY created_at DESC`
+// GetAll retrieves all transactions from the database, including soft deleted transactions if includeDeleted is set
+func (r *TransactionRepository) GetAll(includeDeleted bool) ([]models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
+	if !includeDeleted {
+		query += ` WHERE deleted_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC`
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -93,15 +349,7 @@ func (r *TransactionRepository) GetAll() ([]models.Transaction, error) {
 	var transactions []models.Transaction
 	for rows.Next() {
 		var transaction models.Transaction
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.UserID,
-			&transaction.Amount,
-			&transaction.Description,
-			&transaction.TransactionType,
-			&transaction.CreatedAt,
-			&transaction.UpdatedAt,
-		)
+		err := scanTransaction(rows, &transaction)
 		if err != nil {
 			log.Errorf("Error scanning row: %v", err)
 			return nil, err
@@ -111,23 +359,18 @@ func (r *TransactionRepository) GetAll() ([]models.Transaction, error) {
 	return transactions, nil
 }
 
-// Get retrieves a single transaction by ID
-func (r *TransactionRepository) Get(id int64) (*models.Transaction, error) {
+// Get retrieves a single transaction by ID, including a soft deleted transaction if includeDeleted is set
+func (r *TransactionRepository) Get(id int64, includeDeleted bool) (*models.Transaction, error) {
 	query := `
-    SELECT *
+    SELECT ` + transactionColumns + `
     FROM transactions
     WHERE id = ?
   `
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
 	var transaction models.Transaction
-	err := r.db.QueryRow(query, id).Scan(
-		&transaction.ID,
-		&transaction.UserID,
-		&transaction.Amount,
-		&transaction.Description,
-		&transaction.TransactionType,
-		&transaction.CreatedAt,
-		&transaction.UpdatedAt,
-	)
+	err := scanTransaction(r.db.QueryRow(query, id), &transaction)
 	if err == sql.ErrNoRows {
 		log.Errorf("Transaction with ID %d not found", id)
 		return nil, nil
@@ -139,20 +382,25 @@ func (r *TransactionRepository) Get(id int64) (*models.Transaction, error) {
 	return &transaction, nil
 }
 
-// Update updates an existing transaction
+// Update updates an existing transaction. The company share is kept, up to the new amount,
+// and the rest of the amount becomes the employee share.
 func (r *TransactionRepository) Update(transaction *models.Transaction) error {
 	query := `
 		UPDATE transactions
-		SET user_id = ?, amount = ?, description = ?, transaction_type = ?, updated_at = ?
+		SET user_id = ?, amount = ?, description = ?, transaction_type = ?,
+			company_share = MIN(company_share, ?), employee_share = ? - MIN(company_share, ?), updated_at = ?
 		WHERE id = ?
 	`
 	now := time.Now()
 	_, err := r.db.Exec(
 		query,
-		transaction.UserID,
+		transactionUserID(transaction),
 		transaction.Amount,
 		transaction.Description,
 		transaction.TransactionType,
+		transaction.Amount,
+		transaction.Amount,
+		transaction.Amount,
 		now,
 		transaction.ID,
 	)
@@ -163,10 +411,10 @@ func (r *TransactionRepository) Update(transaction *models.Transaction) error {
 	return nil
 }
 
-// Delete removes a transaction by ID
+// Delete soft deletes a transaction by ID, which removes it from balances and reports
 func (r *TransactionRepository) Delete(id int64) error {
-	query := `DELETE FROM transactions WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+	query := `UPDATE transactions SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	_, err := r.db.Exec(query, time.Now(), id)
 	if err != nil {
 		log.Errorf("Error deleting transaction: %v", err)
 		return err
@@ -175,6 +423,116 @@ func (r *TransactionRepository) Delete(id int64) error {
 	return nil
 }
 
+// Restore undoes the soft delete of a transaction and reports whether a deleted transaction was found
+func (r *TransactionRepository) Restore(id int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE transactions SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`, time.Now(), id)
+	if err != nil {
+		log.Errorf("Error restoring transaction: %v", err)
+		return false, err
+	}
+	restored, err := result.RowsAffected()
+	return restored > 0, err
+}
+
+// PurgeDeleted permanently removes transactions soft deleted before cutoff together with
+// their products. Run it inside a database transaction with WithTx.
+func (r *TransactionRepository) PurgeDeleted(cutoff time.Time) (int64, error) {
+	_, err := r.db.Exec(`
+		UPDATE transaction_products SET refunded_line_id = NULL
+		WHERE refunded_line_id IN (
+			SELECT id FROM transaction_products
+			WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+		)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error unlinking refund lines from deleted transactions: %v", err)
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`
+		DELETE FROM transaction_products
+		WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error purging products of deleted transactions: %v", err)
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE pre_orders SET transaction_id = NULL
+		WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error unlinking pre-orders from deleted transactions: %v", err)
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE payment_lines SET transaction_id = NULL
+		WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error unlinking payment lines from deleted transactions: %v", err)
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE pending_deposits SET transaction_id = NULL
+		WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error unlinking pending deposits from deleted transactions: %v", err)
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE offboardings SET transaction_id = NULL
+		WHERE transaction_id IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error unlinking offboardings from deleted transactions: %v", err)
+		return 0, err
+	}
+
+	for _, column := range []string{"transaction_id", "bonus_transaction_id"} {
+		_, err = r.db.Exec(`
+			UPDATE wallet_top_ups SET `+column+` = NULL
+			WHERE `+column+` IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+		`, cutoff)
+		if err != nil {
+			log.Errorf("Error unlinking top-ups from deleted transactions: %v", err)
+			return 0, err
+		}
+	}
+
+	for _, column := range []string{"from_transaction_id", "to_transaction_id"} {
+		_, err = r.db.Exec(`
+			UPDATE balance_transfers SET `+column+` = NULL
+			WHERE `+column+` IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+		`, cutoff)
+		if err != nil {
+			log.Errorf("Error unlinking balance transfers from deleted transactions: %v", err)
+			return 0, err
+		}
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE transactions SET refund_of_id = NULL
+		WHERE refund_of_id IN (SELECT id FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error unlinking refunds from deleted transactions: %v", err)
+		return 0, err
+	}
+
+	result, err := r.db.Exec(`DELETE FROM transactions WHERE deleted_at IS NOT NULL AND deleted_at < ?`, cutoff)
+	if err != nil {
+		log.Errorf("Error purging deleted transactions: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // GetByUserID retrieves all transactions for a specific user
 func (r *TransactionRepository) GetByUserID(userID int64, limit int) ([]models.EmployeeTransaction, error) {
 	query := `
@@ -185,13 +543,15 @@ func (r *TransactionRepository) GetByUserID(userID int64, limit int) ([]models.E
         transactions.id,
         transactions.user_id,
         transactions.amount,
+        transactions.employee_share,
+        transactions.company_share,
         transactions.description,
         transactions.transaction_type,
         transactions.created_at,
         transactions.updated_at
 	  FROM transactions
 	  LEFT JOIN users ON transactions.user_id = users.id
-	  WHERE users.employee_id = ?
+	  WHERE users.employee_id = ? AND transactions.deleted_at IS NULL
 	  ORDER BY transactions.created_at DESC
 		LIMIT ?;
 	`
@@ -212,6 +572,8 @@ func (r *TransactionRepository) GetByUserID(userID int64, limit int) ([]models.E
 			&transaction.ID,
 			&transaction.UserID,
 			&transaction.Amount,
+			&transaction.EmployeeShare,
+			&transaction.CompanyShare,
 			&transaction.Description,
 			&transaction.TransactionType,
 			&transaction.CreatedAt,
@@ -235,7 +597,7 @@ func (r *TransactionRepository) GetByDateRange(startDate, endDate time.Time) ([]
 	// Adjust endDate to include the entire day
 	endDate = endDate.Add(24 * time.Hour).Add(-1 * time.Second)
 
-	query := `SELECT * FROM transactions WHERE created_at BETWEEN ? AND ? ORDER BY created_at DESC`
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE created_at BETWEEN ? AND ? AND deleted_at IS NULL ORDER BY created_at DESC`
 	rows, err := r.db.Query(query, startDate, endDate)
 	if err != nil {
 		log.Errorf("Error executing query: %v", err)
@@ -246,15 +608,7 @@ func (r *TransactionRepository) GetByDateRange(startDate, endDate time.Time) ([]
 	var transactions []models.Transaction
 	for rows.Next() {
 		var transaction models.Transaction
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.UserID,
-			&transaction.Amount,
-			&transaction.Description,
-			&transaction.TransactionType,
-			&transaction.CreatedAt,
-			&transaction.UpdatedAt,
-		)
+		err := scanTransaction(rows, &transaction)
 		if err != nil {
 			log.Errorf("Error scanning row: %v", err)
 			return nil, err
@@ -266,7 +620,7 @@ func (r *TransactionRepository) GetByDateRange(startDate, endDate time.Time) ([]
 
 // GetLatest retrieves the latest transactions with a limit
 func (r *TransactionRepository) GetLatest(limit int) ([]models.Transaction, error) {
-	query := `SELECT * FROM transactions ORDER BY created_at DESC LIMIT ?`
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT ?`
 	rows, err := r.db.Query(query, limit)
 	if err != nil {
 		log.Errorf("Error executing query: %v", err)
@@ -277,15 +631,7 @@ func (r *TransactionRepository) GetLatest(limit int) ([]models.Transaction, erro
 	var transactions []models.Transaction
 	for rows.Next() {
 		var transaction models.Transaction
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.UserID,
-			&transaction.Amount,
-			&transaction.Description,
-			&transaction.TransactionType,
-			&transaction.CreatedAt,
-			&transaction.UpdatedAt,
-		)
+		err := scanTransaction(rows, &transaction)
 		if err != nil {
 			log.Errorf("Error scanning row: %v", err)
 			return nil, err
@@ -306,9 +652,10 @@ func (r *TransactionRepository) GetUsersBalances() ([]models.UserBalance, error)
           users.phone,
           users.active,
           users.last_notification,
-          COALESCE(SUM(CASE WHEN transactions.transaction_type = 'deposit' THEN transactions.amount ELSE -transactions.amount END), 0) AS balance
+          COALESCE(SUM(` + signedAmountSQL("transactions") + `), 0) AS balance
         FROM users
-        LEFT JOIN transactions ON users.id = transactions.user_id
+        LEFT JOIN transactions ON users.id = transactions.user_id AND transactions.deleted_at IS NULL
+        WHERE users.deleted_at IS NULL
         GROUP BY users.id
     `
 	rows, err := r.db.Query(query)
@@ -341,6 +688,7 @@ func (r *TransactionRepository) GetUsersBalances() ([]models.UserBalance, error)
 			lastNotification := lastNotificationNull.Time
 			balance.LastNotification = &lastNotification
 		}
+		balance.Prepaid = isPrepaid(balance.Balance)
 
 		balances = append(balances, balance)
 	}
@@ -357,9 +705,9 @@ func (r *TransactionRepository) GetUserBalanceByID(userID int64) (models.UserBal
       users.phone,
       users.active,
       last_notification,
-		  COALESCE(SUM(CASE WHEN transactions.transaction_type = 'deposit' THEN transactions.amount ELSE -transactions.amount END), 0) AS balance
+		  COALESCE(SUM(` + signedAmountSQL("transactions") + `), 0) AS balance
 		FROM users
-		LEFT JOIN transactions ON users.id = transactions.user_id
+		LEFT JOIN transactions ON users.id = transactions.user_id AND transactions.deleted_at IS NULL
 		WHERE users.id = ?
 		GROUP BY users.id
 	`
@@ -385,6 +733,93 @@ func (r *TransactionRepository) GetUserBalanceByID(userID int64) (models.UserBal
 		lastNotification := lastNotificationNull.Time
 		balance.LastNotification = &lastNotification
 	}
+	balance.Prepaid = isPrepaid(balance.Balance)
 
 	return balance, nil
 }
+
+// CountBatchReferences returns how many distinct batch references start with prefix, including
+// those of deleted transactions
+func (r *TransactionRepository) CountBatchReferences(prefix string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(DISTINCT batch_reference) FROM transactions WHERE batch_reference LIKE ?`, prefix+"%").Scan(&count)
+	if err != nil {
+		log.Errorf("Error counting batch references: %v", err)
+	}
+	return count, err
+}
+
+// GetRefundedTotals returns the amount and company share refunded so far by the refunds of a
+// purchase that are not deleted
+func (r *TransactionRepository) GetRefundedTotals(purchaseID int64) (amount, companyShare float64, err error) {
+	err = r.db.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0), COALESCE(SUM(company_share), 0)
+		FROM transactions
+		WHERE refund_of_id = ? AND deleted_at IS NULL
+	`, purchaseID).Scan(&amount, &companyShare)
+	if err != nil {
+		log.Errorf("Error getting refunded totals: %v", err)
+	}
+	return amount, companyShare, err
+}
+
+// GetPeriodBalances retrieves the balance of every active user at the end of a payroll period
+// together with the current balance. Payments are applied to the oldest debt first, so
+// everything credited after periodEnd, such as later deposits and settlements, counts towards
+// the period, while purchases made after it do not. The amount to deduct is the debt of the
+// period, but never more than the user currently owes.
+func (r *TransactionRepository) GetPeriodBalances(periodEnd time.Time) ([]models.PayrollDeduction, error) {
+	signedAmount := signedAmountSQL("transactions")
+	query := `
+		SELECT
+			users.id,
+			users.employee_id,
+			users.name,
+			users.department,
+			COALESCE(SUM(
+				CASE WHEN transactions.created_at <= ? OR ` + signedAmount + ` > 0
+				THEN ` + signedAmount + `
+				ELSE 0 END
+			), 0) AS balance,
+			COALESCE(SUM(` + signedAmount + `), 0) AS current_balance
+		FROM users
+		LEFT JOIN transactions ON users.id = transactions.user_id AND transactions.deleted_at IS NULL
+		WHERE users.active = 1 AND users.deleted_at IS NULL
+		GROUP BY users.id
+		ORDER BY users.department ASC, users.name ASC
+	`
+	rows, err := r.db.Query(query, periodEnd)
+	if err != nil {
+		log.Errorf("Error executing period balances query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deductions []models.PayrollDeduction
+	for rows.Next() {
+		var deduction models.PayrollDeduction
+		err := rows.Scan(
+			&deduction.UserID,
+			&deduction.EmployeeID,
+			&deduction.Name,
+			&deduction.Department,
+			&deduction.Balance,
+			&deduction.CurrentBalance,
+		)
+		if err != nil {
+			log.Errorf("Error scanning period balance row: %v", err)
+			return nil, err
+		}
+		deduction.Balance = math.Round(deduction.Balance*100) / 100
+		deduction.CurrentBalance = math.Round(deduction.CurrentBalance*100) / 100
+		if owed := -math.Max(deduction.Balance, deduction.CurrentBalance); owed > 0 {
+			deduction.AmountToDeduct = owed
+		}
+		deductions = append(deductions, deduction)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Error with period balance rows: %v", err)
+		return nil, err
+	}
+	return deductions, nil
+}
@@ -9,9 +9,48 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// userColumns lists the users columns in the order scanUser reads them
+const userColumns = `id, name, employee_id, department, department_id, phone, active, credit_limit, last_notification, created_at, updated_at, deleted_at`
+
+// scanUser scans a row selected with userColumns into a user
+func scanUser(row rowScanner, user *models.User) error {
+	var lastNotificationNull sql.NullTime
+	var departmentID sql.NullInt64
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&user.ID,
+		&user.Name,
+		&user.EmployeeId,
+		&user.Department,
+		&departmentID,
+		&user.Phone,
+		&user.Active,
+		&user.CreditLimit,
+		&lastNotificationNull,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&deletedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if lastNotificationNull.Valid {
+		user.LastNotification = &lastNotificationNull.Time
+	}
+	if departmentID.Valid {
+		user.DepartmentID = &departmentID.Int64
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+	return nil
+}
+
 // UserRepository handles all database operations related to users
 type UserRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 // NewUserRepository creates a new user repository
@@ -19,6 +58,11 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *UserRepository) WithTx(tx *sql.Tx) UserRepositoryInterface {
+	return &UserRepository{db: tx}
+}
+
 // InitTable initializes the users table
 func (r *UserRepository) InitTable() error {
 	// First check if the active column exists, if not, add it
@@ -34,6 +78,8 @@ func (r *UserRepository) InitTable() error {
 			phone TEXT,
 			active BOOLEAN NOT NULL DEFAULT 1,
       last_notification DATETIME,
+			department_id INTEGER REFERENCES departments(id),
+			credit_limit REAL NOT NULL DEFAULT 0,
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL
 		)
@@ -45,34 +91,42 @@ func (r *UserRepository) InitTable() error {
 	}
 	log.Info("Created Users Table")
 
-	err1 := r.Create(&models.User{
+	addColumnIfNeeded(r.db, "users", "department_id", "INTEGER REFERENCES departments(id)")
+	addColumnIfNeeded(r.db, "users", "credit_limit", "REAL NOT NULL DEFAULT 0")
+	addColumnIfNeeded(r.db, "users", "deleted_at", "DATETIME")
+
+	for _, user := range defaultUsers {
+		if err := r.Create(&user); err != nil {
+			log.Errorf("Error in adding admin possibly already exists to the database: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// defaultUsers are the admin accounts every new database starts with
+var defaultUsers = []models.User{
+	{
 		Name:       "Abdul Rafay",
 		EmployeeId: "10081",
 		Department: "Development Dept",
 		Phone:      "+923452324442",
 		Active:     true,
-	})
-	err2 := r.Create(&models.User{
+	},
+	{
 		Name:       "Qasim Imtiaz",
 		EmployeeId: "1023",
 		Department: "Development Dept",
 		Phone:      "+923452565003",
 		Active:     true,
-	})
-
-	err3 := r.Create(&models.User{
+	},
+	{
 		Name:       "Syed Kazim Raza",
 		EmployeeId: "10024",
 		Department: "Admin Dept",
 		Phone:      "+923422949447",
 		Active:     true,
-	})
-
-	if err1 != nil || err2 != nil || err3 != nil {
-		log.Errorf("Error in adding admin possibly already exists to the database:\n %v\n %v\n %v\n", err1, err2, err3)
-	}
-
-	return nil
+	},
 }
 
 // addActiveColumnIfNeeded checks if the active column exists and adds it if needed
@@ -129,8 +183,8 @@ func (r *UserRepository) addLastNotificationColumnIfNeeded() {
 // Create inserts a new user into the database
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (name, employee_id, department, phone, active, last_notification, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO users (name, employee_id, department, department_id, phone, active, credit_limit, last_notification, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	now := time.Now()
 	// If Active field is not explicitly set, default to true (active)
@@ -151,8 +205,10 @@ func (r *UserRepository) Create(user *models.User) error {
 		user.Name,
 		user.EmployeeId,
 		user.Department,
+		user.DepartmentID,
 		user.Phone,
 		user.Active,
+		user.CreditLimit,
 		lastNotification,
 		now,
 		now,
@@ -172,9 +228,13 @@ func (r *UserRepository) Create(user *models.User) error {
 	return nil
 }
 
-// GetAll retrieves all users from the database
-func (r *UserRepository) GetAll() ([]models.User, error) {
-	query := `SELECT id, name, employee_id, department, phone, active, last_notification, created_at, updated_at FROM users ORDER BY name ASC`
+// GetAll retrieves all users from the database, including soft deleted users if includeDeleted is set
+func (r *UserRepository) GetAll(includeDeleted bool) ([]models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users`
+	if !includeDeleted {
+		query += ` WHERE deleted_at IS NULL`
+	}
+	query += ` ORDER BY name ASC`
 	rows, err := r.db.Query(query)
 	if err != nil {
 		log.Errorf("Error getting all users: %v", err)
@@ -185,28 +245,11 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		var lastNotificationNull sql.NullTime
-
-		err := rows.Scan(
-			&user.ID,
-			&user.Name,
-			&user.EmployeeId,
-			&user.Department,
-			&user.Phone,
-			&user.Active,
-			&lastNotificationNull,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
+		err := scanUser(rows, &user)
 		if err != nil {
 			log.Errorf("Error scanning user row: %v", err)
 			return nil, err
 		}
-
-		if lastNotificationNull.Valid {
-			user.LastNotification = &lastNotificationNull.Time
-		}
-
 		users = append(users, user)
 	}
 	return users, nil
@@ -215,22 +258,10 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 // Get retrieves a single user by ID
 func (r *UserRepository) Get(id int64) (*models.User, error) {
 	fmt.Println("Get user by ID", id)
-	query := `SELECT id, name, employee_id, department, phone, active, last_notification, created_at, updated_at FROM users WHERE employee_id = ?`
+	query := `SELECT ` + userColumns + ` FROM users WHERE employee_id = ? AND deleted_at IS NULL`
 
 	var user models.User
-	var lastNotificationNull sql.NullTime
-
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.EmployeeId,
-		&user.Department,
-		&user.Phone,
-		&user.Active,
-		&lastNotificationNull,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := scanUser(r.db.QueryRow(query, id), &user)
 
 	if err == sql.ErrNoRows {
 		log.Errorf("No user found with ID %d", id)
@@ -241,31 +272,15 @@ func (r *UserRepository) Get(id int64) (*models.User, error) {
 		return nil, err
 	}
 
-	if lastNotificationNull.Valid {
-		user.LastNotification = &lastNotificationNull.Time
-	}
-
 	return &user, nil
 }
 
 // GetByEmployeeID retrieves a single user by employee ID
 func (r *UserRepository) GetByEmployeeID(employeeID string) (*models.User, error) {
-	query := `SELECT id, name, employee_id, department, phone, active, last_notification, created_at, updated_at FROM users WHERE employee_id = ?`
+	query := `SELECT ` + userColumns + ` FROM users WHERE employee_id = ? AND deleted_at IS NULL`
 
 	var user models.User
-	var lastNotificationNull sql.NullTime
-
-	err := r.db.QueryRow(query, employeeID).Scan(
-		&user.ID,
-		&user.Name,
-		&user.EmployeeId,
-		&user.Department,
-		&user.Phone,
-		&user.Active,
-		&lastNotificationNull,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := scanUser(r.db.QueryRow(query, employeeID), &user)
 
 	if err == sql.ErrNoRows {
 		log.Errorf("No user found with employee ID %s", employeeID)
@@ -276,8 +291,23 @@ func (r *UserRepository) GetByEmployeeID(employeeID string) (*models.User, error
 		return nil, err
 	}
 
-	if lastNotificationNull.Valid {
-		user.LastNotification = &lastNotificationNull.Time
+	return &user, nil
+}
+
+// GetByID retrieves a single user by its row ID, including soft deleted users.
+// Get looks users up by employee ID instead.
+func (r *UserRepository) GetByID(id int64) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+	var user models.User
+	err := scanUser(r.db.QueryRow(query, id), &user)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting user by ID: %v", err)
+		return nil, err
 	}
 
 	return &user, nil
@@ -288,7 +318,7 @@ func (r *UserRepository) Update(user *models.User) error {
 	fmt.Println("Edit user by ID", user)
 	query := `
 		UPDATE users
-		SET name = ?, employee_id = ?, department = ?, phone = ?, active = ?, updated_at = ?
+		SET name = ?, employee_id = ?, department = ?, department_id = ?, phone = ?, active = ?, credit_limit = ?, updated_at = ?
 		WHERE id = ?
 	`
 	now := time.Now()
@@ -297,8 +327,10 @@ func (r *UserRepository) Update(user *models.User) error {
 		user.Name,
 		user.EmployeeId,
 		user.Department,
+		user.DepartmentID,
 		user.Phone,
 		user.Active,
+		user.CreditLimit,
 		now,
 		user.ID,
 	)
@@ -310,10 +342,64 @@ func (r *UserRepository) Update(user *models.User) error {
 	return nil
 }
 
-// Delete removes a user by ID
+// Delete soft deletes a user by ID. The user's transactions are kept.
 func (r *UserRepository) Delete(id int64) error {
-	query := `DELETE FROM users WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+	query := `UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	_, err := r.db.Exec(query, time.Now(), id)
+	return err
+}
+
+// Restore undoes the soft delete of a user and reports whether a deleted user was found
+func (r *UserRepository) Restore(id int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE users SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`, time.Now(), id)
+	if err != nil {
+		log.Errorf("Error restoring user: %v", err)
+		return false, err
+	}
+	restored, err := result.RowsAffected()
+	return restored > 0, err
+}
+
+// PurgeDeleted permanently removes users soft deleted before cutoff that have no transactions, pre-orders,
+// payment lines, receipts, balance transfers, offboardings, top-ups, installment plans or escalation notices left, together with the pricing rules of those users
+func (r *UserRepository) PurgeDeleted(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM users
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		AND NOT EXISTS (SELECT 1 FROM transactions WHERE transactions.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM pre_orders WHERE pre_orders.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM payment_lines WHERE payment_lines.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM pending_deposits WHERE pending_deposits.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM balance_transfers WHERE balance_transfers.from_user_id = users.id OR balance_transfers.to_user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM offboardings WHERE offboardings.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM wallet_top_ups WHERE wallet_top_ups.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM installment_plans WHERE installment_plans.user_id = users.id)
+		AND NOT EXISTS (SELECT 1 FROM escalation_notices WHERE escalation_notices.user_id = users.id)
+	`, cutoff)
+	if err != nil {
+		log.Errorf("Error purging deleted users: %v", err)
+		return 0, err
+	}
+
+	// Users without transactions have no discounted sales either
+	_, err = r.db.Exec(`DELETE FROM pricing_rules WHERE user_id IS NOT NULL AND user_id NOT IN (SELECT id FROM users)`)
+	if err != nil {
+		log.Errorf("Error purging pricing rules of deleted users: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ClearDepartment removes the users of a department from it, including users only linked to
+// it by name
+func (r *UserRepository) ClearDepartment(department *models.Department) error {
+	_, err := r.db.Exec(`
+		UPDATE users SET department = '', department_id = NULL, updated_at = ?
+		WHERE department_id = ? OR (department_id IS NULL AND TRIM(department) = ? COLLATE NOCASE)
+	`, time.Now(), department.ID, department.Name)
+	if err != nil {
+		log.Errorf("Error clearing department of users: %v", err)
+	}
 	return err
 }
 
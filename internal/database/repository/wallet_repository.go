@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"database/sql"
+	"maya-canteen/internal/models"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// bonusRuleColumns lists the top_up_bonus_rules columns in the order scanBonusRule reads them
+const bonusRuleColumns = `id, name, min_amount, bonus_amount, bonus_percent, valid_from, valid_until,
+	active, created_at, updated_at`
+
+// scanBonusRule scans a row selected with bonusRuleColumns into a top-up bonus rule
+func scanBonusRule(row rowScanner, rule *models.TopUpBonusRule) error {
+	var validFrom, validUntil sql.NullTime
+	err := row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.MinAmount,
+		&rule.BonusAmount,
+		&rule.BonusPercent,
+		&validFrom,
+		&validUntil,
+		&rule.Active,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if validFrom.Valid {
+		rule.ValidFrom = &validFrom.Time
+	}
+	if validUntil.Valid {
+		rule.ValidUntil = &validUntil.Time
+	}
+	return nil
+}
+
+// topUpQuery selects wallet top-ups with their users in the order scanTopUp reads them
+const topUpQuery = `
+	SELECT
+		t.id,
+		t.user_id,
+		u.name,
+		t.amount,
+		t.bonus_amount,
+		t.bonus_rule_id,
+		t.bonus_rule_name,
+		t.payment_method,
+		t.note,
+		t.transaction_id,
+		t.bonus_transaction_id,
+		t.created_by,
+		t.created_at
+	FROM wallet_top_ups t
+	JOIN users u ON u.id = t.user_id
+`
+
+// scanTopUp scans a row selected with topUpQuery into a top-up
+func scanTopUp(row rowScanner, topUp *models.TopUp) error {
+	return row.Scan(
+		&topUp.ID,
+		&topUp.UserID,
+		&topUp.UserName,
+		&topUp.Amount,
+		&topUp.BonusAmount,
+		&topUp.BonusRuleID,
+		&topUp.BonusRuleName,
+		&topUp.PaymentMethod,
+		&topUp.Note,
+		&topUp.TransactionID,
+		&topUp.BonusTransactionID,
+		&topUp.CreatedBy,
+		&topUp.CreatedAt,
+	)
+}
+
+// WalletRepository handles all database operations related to wallet top-ups and their bonus rules
+type WalletRepository struct {
+	db DBTX
+}
+
+// NewWalletRepository creates a new wallet repository
+func NewWalletRepository(db *sql.DB) *WalletRepository {
+	return &WalletRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries inside tx
+func (r *WalletRepository) WithTx(tx *sql.Tx) WalletRepositoryInterface {
+	return &WalletRepository{db: tx}
+}
+
+// InitTable initializes the top_up_bonus_rules and wallet_top_ups tables
+func (r *WalletRepository) InitTable() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS top_up_bonus_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			min_amount REAL NOT NULL DEFAULT 0,
+			bonus_amount REAL NOT NULL DEFAULT 0,
+			bonus_percent REAL NOT NULL DEFAULT 0,
+			valid_from DATETIME,
+			valid_until DATETIME,
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS wallet_top_ups (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			amount REAL NOT NULL,
+			bonus_amount REAL NOT NULL DEFAULT 0,
+			bonus_rule_id INTEGER REFERENCES top_up_bonus_rules(id),
+			bonus_rule_name TEXT NOT NULL DEFAULT '',
+			payment_method TEXT NOT NULL DEFAULT 'account',
+			note TEXT NOT NULL DEFAULT '',
+			transaction_id INTEGER REFERENCES transactions(id),
+			bonus_transaction_id INTEGER REFERENCES transactions(id),
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_wallet_top_ups_user ON wallet_top_ups (user_id)`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.Exec(query); err != nil {
+			log.Errorf("Error creating wallet tables: %v", err)
+			return err
+		}
+	}
+	log.Info("Created Wallet Tables")
+	return nil
+}
+
+// CreateBonusRule inserts a new top-up bonus rule
+func (r *WalletRepository) CreateBonusRule(rule *models.TopUpBonusRule) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO top_up_bonus_rules (name, min_amount, bonus_amount, bonus_percent, valid_from, valid_until, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rule.Name, rule.MinAmount, rule.BonusAmount, rule.BonusPercent, rule.ValidFrom, rule.ValidUntil, rule.Active, now, now)
+	if err != nil {
+		log.Errorf("Error inserting top-up bonus rule: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	rule.ID = id
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	return nil
+}
+
+// GetBonusRules retrieves all top-up bonus rules, or only the active ones if activeOnly is set
+func (r *WalletRepository) GetBonusRules(activeOnly bool) ([]models.TopUpBonusRule, error) {
+	query := `SELECT ` + bonusRuleColumns + ` FROM top_up_bonus_rules`
+	if activeOnly {
+		query += ` WHERE active = 1`
+	}
+	query += ` ORDER BY min_amount ASC, name ASC, id ASC`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Errorf("Error getting top-up bonus rules: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]models.TopUpBonusRule, 0)
+	for rows.Next() {
+		var rule models.TopUpBonusRule
+		if err := scanBonusRule(rows, &rule); err != nil {
+			log.Errorf("Error scanning top-up bonus rule row: %v", err)
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetBonusRule retrieves a single top-up bonus rule by ID
+func (r *WalletRepository) GetBonusRule(id int64) (*models.TopUpBonusRule, error) {
+	var rule models.TopUpBonusRule
+	err := scanBonusRule(r.db.QueryRow(`SELECT `+bonusRuleColumns+` FROM top_up_bonus_rules WHERE id = ?`, id), &rule)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Error in getting top-up bonus rule by ID: %v", err)
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateBonusRule updates an existing top-up bonus rule
+func (r *WalletRepository) UpdateBonusRule(rule *models.TopUpBonusRule) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		UPDATE top_up_bonus_rules
+		SET name = ?, min_amount = ?, bonus_amount = ?, bonus_percent = ?, valid_from = ?, valid_until = ?, active = ?, updated_at = ?
+		WHERE id = ?
+	`, rule.Name, rule.MinAmount, rule.BonusAmount, rule.BonusPercent, rule.ValidFrom, rule.ValidUntil, rule.Active, now, rule.ID)
+	if err != nil {
+		log.Errorf("Error updating top-up bonus rule: %v", err)
+		return err
+	}
+	rule.UpdatedAt = now
+	return nil
+}
+
+// DeleteBonusRule removes a top-up bonus rule by ID. Top-ups keep the name of the rule, only
+// their reference to it is cleared.
+func (r *WalletRepository) DeleteBonusRule(id int64) error {
+	_, err := r.db.Exec(`UPDATE wallet_top_ups SET bonus_rule_id = NULL WHERE bonus_rule_id = ?`, id)
+	if err != nil {
+		log.Errorf("Error unlinking top-ups from bonus rule: %v", err)
+		return err
+	}
+	_, err = r.db.Exec(`DELETE FROM top_up_bonus_rules WHERE id = ?`, id)
+	if err != nil {
+		log.Errorf("Error deleting top-up bonus rule: %v", err)
+		return err
+	}
+	return nil
+}
+
+// CreateTopUp records a top-up whose transactions have been created
+func (r *WalletRepository) CreateTopUp(topUp *models.TopUp) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO wallet_top_ups (
+			user_id, amount, bonus_amount, bonus_rule_id, bonus_rule_name, payment_method, note,
+			transaction_id, bonus_transaction_id, created_by, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, topUp.UserID, topUp.Amount, topUp.BonusAmount, topUp.BonusRuleID, topUp.BonusRuleName, topUp.PaymentMethod,
+		topUp.Note, topUp.TransactionID, topUp.BonusTransactionID, topUp.CreatedBy, now)
+	if err != nil {
+		log.Errorf("Error creating top-up: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Errorf("Error getting last insert ID: %v", err)
+		return err
+	}
+	topUp.ID = id
+	topUp.CreatedAt = now
+	return nil
+}
+
+// GetTopUps retrieves the top-ups of a user, or of all users if userID is 0, the most recent first
+func (r *WalletRepository) GetTopUps(userID int64) ([]models.TopUp, error) {
+	query := topUpQuery
+	var args []any
+	if userID != 0 {
+		query += ` WHERE t.user_id = ?`
+		args = append(args, userID)
+	}
+	rows, err := r.db.Query(query+` ORDER BY t.created_at DESC, t.id DESC`, args...)
+	if err != nil {
+		log.Errorf("Error getting top-ups: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	topUps := make([]models.TopUp, 0)
+	for rows.Next() {
+		var topUp models.TopUp
+		if err := scanTopUp(rows, &topUp); err != nil {
+			log.Errorf("Error scanning top-up row: %v", err)
+			return nil, err
+		}
+		topUps = append(topUps, topUp)
+	}
+	return topUps, rows.Err()
+}
+
+// GetTopUpTotals sums the amounts and bonuses of all top-ups whose transactions are not deleted
+func (r *WalletRepository) GetTopUpTotals() (float64, float64, error) {
+	var topUps, bonuses float64
+	err := r.db.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN topped.deleted_at IS NULL THEN w.amount ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN bonus.id IS NOT NULL AND bonus.deleted_at IS NULL THEN w.bonus_amount ELSE 0 END), 0)
+		FROM wallet_top_ups w
+		LEFT JOIN transactions topped ON topped.id = w.transaction_id
+		LEFT JOIN transactions bonus ON bonus.id = w.bonus_transaction_id
+	`).Scan(&topUps, &bonuses)
+	if err != nil {
+		log.Errorf("Error getting top-up totals: %v", err)
+	}
+	return topUps, bonuses, err
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"maya-canteen/internal/models"
+	"time"
+)
+
+// ErrInvalidTopUp is returned when a wallet top-up cannot be made as requested
+var ErrInvalidTopUp = errors.New("invalid top-up")
+
+// Wallet operations
+func (s *service) InitWalletTable() error {
+	return s.walletRepository.InitTable()
+}
+
+func (s *service) CreateTopUpBonusRule(rule *models.TopUpBonusRule) error {
+	return s.walletRepository.CreateBonusRule(rule)
+}
+
+func (s *service) GetTopUpBonusRules(activeOnly bool) ([]models.TopUpBonusRule, error) {
+	return s.walletRepository.GetBonusRules(activeOnly)
+}
+
+func (s *service) GetTopUpBonusRule(id int64) (*models.TopUpBonusRule, error) {
+	return s.walletRepository.GetBonusRule(id)
+}
+
+func (s *service) UpdateTopUpBonusRule(rule *models.TopUpBonusRule) error {
+	return s.walletRepository.UpdateBonusRule(rule)
+}
+
+func (s *service) DeleteTopUpBonusRule(id int64) error {
+	return s.walletRepository.DeleteBonusRule(id)
+}
+
+// TopUpWallet credits a user's wallet with money paid in advance, plus the bonus of the active
+// bonus rule that grants the most on the part of the amount left after paying off what the
+// user owes. Top-ups paid in cash go through the cash
+// session cashSessionID. Purchases charged to the account draw on the balance, so they use
+// the prepaid funds before the user owes anything.
+func (s *service) TopUpWallet(topUp *models.TopUp, cashSessionID *int64) error {
+	if topUp.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be greater than zero", ErrInvalidTopUp)
+	}
+	user, err := s.userRepository.GetByID(topUp.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.DeletedAt != nil {
+		return fmt.Errorf("%w: user %d not found", ErrInvalidTopUp, topUp.UserID)
+	}
+	if err := s.offboardedError(user.ID, ErrInvalidTopUp); err != nil {
+		return err
+	}
+	if topUp.PaymentMethod == models.PaymentMethodCash && cashSessionID == nil {
+		return fmt.Errorf("%w: open a cash session before taking top-ups in cash", ErrInvalidTopUp)
+	}
+
+	rules, err := s.walletRepository.GetBonusRules(true)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	description := "Wallet top-up"
+	if topUp.Note != "" {
+		description += ": " + topUp.Note
+	}
+	return s.withTx(func(tx *sql.Tx) error {
+		transactions := s.transactionRepository.WithTx(tx)
+		balance, err := transactions.GetUserBalanceByID(user.ID)
+		if err != nil {
+			return err
+		}
+		topUpBonus(topUp, rules, balance.Balance, now)
+
+		transaction := models.Transaction{
+			UserID:          user.ID,
+			Amount:          topUp.Amount,
+			Description:     description,
+			TransactionType: models.TransactionTypeTopUp,
+			PaymentMethod:   topUp.PaymentMethod,
+			CashSessionID:   cashSessionID,
+			CreatedAt:       now,
+		}
+		if err := transactions.Create(&transaction); err != nil {
+			return err
+		}
+		topUp.TransactionID = &transaction.ID
+
+		if topUp.BonusAmount > 0 {
+			bonus := models.Transaction{
+				UserID:          user.ID,
+				Amount:          topUp.BonusAmount,
+				Description:     "Top-up bonus: " + topUp.BonusRuleName,
+				TransactionType: models.TransactionTypeBonus,
+				PaymentMethod:   models.PaymentMethodAccount,
+				CashSessionID:   cashSessionID,
+				CreatedAt:       now,
+			}
+			if err := transactions.Create(&bonus); err != nil {
+				return err
+			}
+			topUp.BonusTransactionID = &bonus.ID
+		}
+
+		topUp.UserName = user.Name
+		return s.walletRepository.WithTx(tx).CreateTopUp(topUp)
+	})
+}
+
+// topUpBonus sets the bonus of the active rule that grants the most on the top-up. Only the
+// part of the top-up left after paying off what the user owes at balance is prepaid, so
+// settling debts through top-ups earns no bonus.
+func topUpBonus(topUp *models.TopUp, rules []models.TopUpBonusRule, balance float64, at time.Time) {
+	prepaid := roundAmount(topUp.Amount + math.Min(balance, 0))
+	topUp.BonusAmount, topUp.BonusRuleID, topUp.BonusRuleName = 0, nil, ""
+	if prepaid <= 0 {
+		return
+	}
+	for i := range rules {
+		if bonus := roundAmount(rules[i].Bonus(prepaid, at)); bonus > topUp.BonusAmount {
+			topUp.BonusAmount = bonus
+			topUp.BonusRuleID = &rules[i].ID
+			topUp.BonusRuleName = rules[i].Name
+		}
+	}
+}
+
+func (s *service) GetTopUps(userID int64) ([]models.TopUp, error) {
+	return s.walletRepository.GetTopUps(userID)
+}
+
+// GetPrepaidLiability returns the prepaid funds the canteen holds, which are the positive
+// balances of the users
+func (s *service) GetPrepaidLiability() (*models.PrepaidLiability, error) {
+	balances, err := s.transactionRepository.GetUsersBalances()
+	if err != nil {
+		return nil, err
+	}
+	topUps, bonuses, err := s.walletRepository.GetTopUpTotals()
+	if err != nil {
+		return nil, err
+	}
+
+	liability := &models.PrepaidLiability{
+		AsOf:         time.Now(),
+		TotalTopUps:  topUps,
+		TotalBonuses: bonuses,
+		Users:        []models.UserBalance{},
+	}
+	for _, balance := range balances {
+		if !balance.Prepaid {
+			continue
+		}
+		liability.Users = append(liability.Users, balance)
+		liability.TotalLiability += balance.Balance
+	}
+	liability.TotalLiability = roundAmount(liability.TotalLiability)
+	liability.UserCount = len(liability.Users)
+	return liability, nil
+}
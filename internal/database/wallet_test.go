@@ -0,0 +1,75 @@
+package database
+
+import (
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestBonusRule creates an active rule granting percent on top-ups of at least minAmount
+func createTestBonusRule(t *testing.T, s *service, name string, minAmount, percent float64) *models.TopUpBonusRule {
+	t.Helper()
+	rule := &models.TopUpBonusRule{Name: name, MinAmount: minAmount, BonusPercent: percent, Active: true}
+	require.NoError(t, s.CreateTopUpBonusRule(rule))
+	return rule
+}
+
+func TestTopUpWalletBonus(t *testing.T) {
+	s := newTestService(t)
+	createTestBonusRule(t, s, "Five percent", 1000, 5)
+	rule := createTestBonusRule(t, s, "Ten percent", 5000, 10)
+	user := createTestUser(t, s, "6001")
+
+	topUp := &models.TopUp{UserID: user.ID, Amount: 5000, PaymentMethod: models.PaymentMethodAccount}
+	require.NoError(t, s.TopUpWallet(topUp, nil))
+	assert.Equal(t, 500.0, topUp.BonusAmount, "the rule granting the most wins")
+	assert.Equal(t, &rule.ID, topUp.BonusRuleID)
+	assert.Equal(t, 5500.0, balanceOf(t, s, user.ID))
+
+	small := &models.TopUp{UserID: user.ID, Amount: 500, PaymentMethod: models.PaymentMethodAccount}
+	require.NoError(t, s.TopUpWallet(small, nil))
+	assert.Zero(t, small.BonusAmount)
+	assert.Nil(t, small.BonusTransactionID)
+}
+
+func TestTopUpWalletBonusSkipsDebt(t *testing.T) {
+	s := newTestService(t)
+	createTestBonusRule(t, s, "Ten percent", 1000, 10)
+	user := createTestUser(t, s, "6002")
+	createTestTransaction(t, s, user.ID, models.TransactionTypePurchase, 4500)
+
+	// Settling arrears through a top-up earns nothing
+	topUp := &models.TopUp{UserID: user.ID, Amount: 5000, PaymentMethod: models.PaymentMethodAccount}
+	require.NoError(t, s.TopUpWallet(topUp, nil))
+	assert.Zero(t, topUp.BonusAmount, "only 500 is prepaid, below the minimum of the rule")
+	assert.Equal(t, 500.0, balanceOf(t, s, user.ID))
+
+	createTestTransaction(t, s, user.ID, models.TransactionTypePurchase, 1500)
+	topUp = &models.TopUp{UserID: user.ID, Amount: 3000, PaymentMethod: models.PaymentMethodAccount}
+	require.NoError(t, s.TopUpWallet(topUp, nil))
+	assert.Equal(t, 200.0, topUp.BonusAmount, "the bonus is on the 2000 left after the 1000 owed")
+	assert.Equal(t, 2200.0, balanceOf(t, s, user.ID))
+}
+
+func TestPrepaidLiability(t *testing.T) {
+	s := newTestService(t)
+	createTestBonusRule(t, s, "Ten percent", 1000, 10)
+	prepaid := createTestUser(t, s, "6003")
+	owing := createTestUser(t, s, "6004")
+
+	require.NoError(t, s.TopUpWallet(&models.TopUp{UserID: prepaid.ID, Amount: 2000, PaymentMethod: models.PaymentMethodAccount}, nil))
+	createTestTransaction(t, s, prepaid.ID, models.TransactionTypePurchase, 700)
+	createTestTransaction(t, s, owing.ID, models.TransactionTypePurchase, 300)
+	require.NoError(t, s.TopUpWallet(&models.TopUp{UserID: owing.ID, Amount: 200, PaymentMethod: models.PaymentMethodAccount}, nil))
+
+	liability, err := s.GetPrepaidLiability()
+	require.NoError(t, err)
+	assert.Equal(t, 2200.0, liability.TotalTopUps)
+	assert.Equal(t, 200.0, liability.TotalBonuses)
+	require.Equal(t, 1, liability.UserCount, "users who owe money are no liability")
+	assert.Equal(t, prepaid.ID, liability.Users[0].UserID)
+	assert.Equal(t, 1500.0, liability.TotalLiability)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+	"maya-canteen/internal/phone"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultEscalationRepeatDays is how long a user waits before being escalated at the same
+// level again when a level does not set it
+const defaultEscalationRepeatDays = 7
+
+// AgingHandler handles the aging of overdue balances and their escalation policy
+type AgingHandler struct {
+	common.BaseHandler
+}
+
+// NewAgingHandler creates a new aging handler
+func NewAgingHandler(db database.Service) *AgingHandler {
+	return &AgingHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// GetAgingReport handles GET /api/reports/aging
+//
+// Buckets the unpaid amount of each user into current, 30, 60 and 90+ days.
+func (h *AgingHandler) GetAgingReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.DB.GetAgingReport()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, report)
+}
+
+// GetEscalationNotices handles GET /api/escalation-notices?days=30
+//
+// Lists the escalations sent within the last days, 30 by default.
+func (h *AgingHandler) GetEscalationNotices(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			h.HandleError(w, errors.InvalidInput("days must be a positive number"))
+			return
+		}
+		days = parsed
+	}
+
+	notices, err := h.DB.GetEscalationNotices(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, notices)
+}
+
+// CreateEscalationLevel handles POST /api/escalation-levels
+func (h *AgingHandler) CreateEscalationLevel(w http.ResponseWriter, r *http.Request) {
+	level := models.EscalationLevel{Active: true, RepeatDays: defaultEscalationRepeatDays}
+	if err := h.DecodeJSON(r, &level); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := validateEscalationLevel(&level); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.CreateEscalationLevel(&level); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityEscalationLevel, level.ID, nil, level)
+
+	common.RespondWithSuccess(w, http.StatusCreated, level)
+}
+
+// GetEscalationLevels handles GET /api/escalation-levels
+func (h *AgingHandler) GetEscalationLevels(w http.ResponseWriter, r *http.Request) {
+	levels, err := h.DB.GetEscalationLevels()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, levels)
+}
+
+// GetEscalationLevel handles GET /api/escalation-levels/{id}
+func (h *AgingHandler) GetEscalationLevel(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	level, err := h.DB.GetEscalationLevel(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if level == nil {
+		h.HandleError(w, errors.NotFound("Escalation level", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, level)
+}
+
+// UpdateEscalationLevel handles PUT /api/escalation-levels/{id}
+func (h *AgingHandler) UpdateEscalationLevel(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	level := models.EscalationLevel{Active: true, RepeatDays: defaultEscalationRepeatDays}
+	if err := h.DecodeJSON(r, &level); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	level.ID = id
+
+	before, err := h.DB.GetEscalationLevel(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Escalation level", id))
+		return
+	}
+
+	if err := validateEscalationLevel(&level); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.UpdateEscalationLevel(&level); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	level.CreatedAt = before.CreatedAt
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityEscalationLevel, id, before, level)
+
+	common.RespondWithSuccess(w, http.StatusOK, level)
+}
+
+// DeleteEscalationLevel handles DELETE /api/escalation-levels/{id}
+//
+// Notices sent at the level keep its name. To stop a level without losing it, update it with
+// active set to false instead.
+func (h *AgingHandler) DeleteEscalationLevel(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	before, err := h.DB.GetEscalationLevel(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Escalation level", id))
+		return
+	}
+
+	if err := h.DB.DeleteEscalationLevel(id); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionDelete, models.AuditEntityEscalationLevel, id, before, nil)
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// validateEscalationLevel checks the name, thresholds, template and manager phone of an
+// escalation level, normalizing the phone number
+func validateEscalationLevel(level *models.EscalationLevel) error {
+	level.Name = strings.TrimSpace(level.Name)
+	if level.Name == "" {
+		return errors.InvalidInput("Escalation level name is required")
+	}
+	if level.MinAgeDays < 0 || level.MinAmount < 0 {
+		return errors.InvalidInput("min_age_days and min_amount must not be negative")
+	}
+	if level.RepeatDays <= 0 {
+		return errors.InvalidInput("repeat_days must be greater than zero")
+	}
+	if strings.TrimSpace(level.MessageTemplate) == "" {
+		return errors.InvalidInput("message_template is required")
+	}
+	managerPhone, err := phone.Normalize(level.ManagerPhone)
+	if err != nil {
+		return errors.InvalidInput(fmt.Sprintf("Invalid manager_phone %q", level.ManagerPhone))
+	}
+	level.ManagerPhone = managerPhone
+	return nil
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEscalationLevel(t *testing.T) {
+	level := models.EscalationLevel{Name: " Final notice ", MinAgeDays: 90, MessageTemplate: "Pay {balance}", ManagerPhone: "0300-1234567", RepeatDays: 7}
+	if assert.NoError(t, validateEscalationLevel(&level)) {
+		assert.Equal(t, "Final notice", level.Name)
+		assert.Equal(t, "+923001234567", level.ManagerPhone)
+	}
+
+	for name, invalid := range map[string]models.EscalationLevel{
+		"missing name":     {MessageTemplate: "x", RepeatDays: 7},
+		"negative age":     {Name: "x", MinAgeDays: -1, MessageTemplate: "x", RepeatDays: 7},
+		"no repeat days":   {Name: "x", MessageTemplate: "x"},
+		"missing template": {Name: "x", MessageTemplate: " ", RepeatDays: 7},
+		"invalid phone":    {Name: "x", MessageTemplate: "x", RepeatDays: 7, ManagerPhone: "call me"},
+	} {
+		assert.Error(t, validateEscalationLevel(&invalid), name)
+	}
+}
+
+func TestEscalationSummary(t *testing.T) {
+	level := models.EscalationLevel{Name: "Manager", MinAgeDays: 60}
+	users := []models.UserAging{
+		{UserName: "Ali", EmployeeID: "1023", AgingBuckets: models.AgingBuckets{Total: 950}, AgeDays: 70},
+		{UserName: "Sara", EmployeeID: "10024", AgingBuckets: models.AgingBuckets{Total: 120.5}, AgeDays: 61},
+	}
+
+	summary := escalationSummary(level, users)
+	assert.Contains(t, summary, "2 employee(s) have owed money for 60 days or more")
+	assert.Contains(t, summary, "- Ali (1023): PKR 950.00, 70 days\n")
+	assert.Contains(t, summary, "- Sara (10024): PKR 120.50, 61 days\n")
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+)
+
+// Bounds of the number of top products or users
+const (
+	defaultAnalyticsLimit = 10
+	maxAnalyticsLimit     = 100
+)
+
+// AnalyticsHandler handles the spending analytics of the dashboards
+type AnalyticsHandler struct {
+	common.BaseHandler
+}
+
+// NewAnalyticsHandler creates a new analytics handler
+func NewAnalyticsHandler(db database.Service) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// AnalyticsRequest represents the request body of the analytics endpoints
+type AnalyticsRequest struct {
+	DateRangeRequest
+	Granularity string `json:"granularity"` // day, week or month for the revenue series, day by default
+	Limit       int    `json:"limit"`       // Number of top products or users, 10 by default
+}
+
+// parse decodes and validates the analytics request of r
+func (h *AnalyticsHandler) parse(r *http.Request) (AnalyticsRequest, time.Time, time.Time, error) {
+	var request AnalyticsRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		return request, time.Time{}, time.Time{}, err
+	}
+	startDate, endDate, err := request.Parse()
+	if err != nil {
+		return request, time.Time{}, time.Time{}, err
+	}
+
+	switch request.Granularity {
+	case "":
+		request.Granularity = models.AnalyticsGranularityDay
+	case models.AnalyticsGranularityDay, models.AnalyticsGranularityWeek, models.AnalyticsGranularityMonth:
+	default:
+		return request, time.Time{}, time.Time{}, errors.InvalidInput("Invalid granularity. Expected day, week or month")
+	}
+	if request.Limit == 0 {
+		request.Limit = defaultAnalyticsLimit
+	}
+	if request.Limit < 0 || request.Limit > maxAnalyticsLimit {
+		return request, time.Time{}, time.Time{}, errors.InvalidInput("limit must be between 1 and 100")
+	}
+	return request, startDate, endDate, nil
+}
+
+// GetSummary handles POST /api/analytics/summary
+//
+// Returns revenue, purchases, average basket size and unique customers of the period, compared
+// with the period of the same length before it.
+func (h *AnalyticsHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	_, startDate, endDate, err := h.parse(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	summary, err := h.DB.GetAnalyticsSummary(startDate, endDate)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, summary)
+}
+
+// GetRevenue handles POST /api/analytics/revenue
+//
+// Returns the revenue by day, week or month, with the total compared with the previous period.
+func (h *AnalyticsHandler) GetRevenue(w http.ResponseWriter, r *http.Request) {
+	request, startDate, endDate, err := h.parse(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	series, err := h.DB.GetRevenueSeries(startDate, endDate, request.Granularity)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, series)
+}
+
+// GetHeatmap handles POST /api/analytics/heatmap
+//
+// Returns the purchases by weekday and hour of the day, to find the peak hours.
+func (h *AnalyticsHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	_, startDate, endDate, err := h.parse(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	cells, err := h.DB.GetSalesHeatmap(startDate, endDate)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, cells)
+}
+
+// GetDailyCustomers handles POST /api/analytics/daily-customers
+func (h *AnalyticsHandler) GetDailyCustomers(w http.ResponseWriter, r *http.Request) {
+	_, startDate, endDate, err := h.parse(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	days, err := h.DB.GetDailyCustomers(startDate, endDate)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, days)
+}
+
+// GetTopProducts handles POST /api/analytics/top-products
+func (h *AnalyticsHandler) GetTopProducts(w http.ResponseWriter, r *http.Request) {
+	request, startDate, endDate, err := h.parse(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	products, err := h.DB.GetTopProducts(startDate, endDate, request.Limit)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, products)
+}
+
+// GetTopUsers handles POST /api/analytics/top-users
+func (h *AnalyticsHandler) GetTopUsers(w http.ResponseWriter, r *http.Request) {
+	request, startDate, endDate, err := h.parse(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	users, err := h.DB.GetTopUsers(startDate, endDate, request.Limit)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, users)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyticsRequestParse(t *testing.T) {
+	h := NewAnalyticsHandler(nil)
+	parse := func(body string) (AnalyticsRequest, error) {
+		request, _, _, err := h.parse(httptest.NewRequest("POST", "/api/analytics/summary", strings.NewReader(body)))
+		return request, err
+	}
+
+	request, err := parse(`{"startDate":"2026-01-01","endDate":"2026-01-31"}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.AnalyticsGranularityDay, request.Granularity)
+		assert.Equal(t, defaultAnalyticsLimit, request.Limit)
+	}
+
+	request, err = parse(`{"startDate":"2026-01-01","endDate":"2026-03-31","granularity":"month","limit":5}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.AnalyticsGranularityMonth, request.Granularity)
+		assert.Equal(t, 5, request.Limit)
+	}
+
+	for _, body := range []string{
+		`{"startDate":"2026-01-31","endDate":"2026-01-01"}`,
+		`{"startDate":"2026-01-01","endDate":"2026-01-31","granularity":"year"}`,
+		`{"startDate":"2026-01-01","endDate":"2026-01-31","limit":101}`,
+		`{"startDate":"01/01/2026","endDate":"2026-01-31"}`,
+	} {
+		_, err := parse(body)
+		assert.Error(t, err, body)
+	}
+}
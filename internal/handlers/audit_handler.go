@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+)
+
+// Audit log page sizes
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditHandler handles audit log HTTP requests
+type AuditHandler struct {
+	common.BaseHandler
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(db database.Service) *AuditHandler {
+	return &AuditHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// GetAuditLogs handles GET /api/audit
+//
+// Supported filters are actor, action, entity_type, entity_id, start_date and end_date
+// (YYYY-MM-DD), with limit and offset for paging. Entries are returned newest first.
+func (h *AuditHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.AuditLogFilter{
+		Actor:      query.Get("actor"),
+		Action:     query.Get("action"),
+		EntityType: query.Get("entity_type"),
+		Limit:      defaultAuditLimit,
+	}
+
+	if value := query.Get("entity_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			h.HandleError(w, errors.InvalidInput("Invalid entity_id parameter. Must be a number."))
+			return
+		}
+		filter.EntityID = id
+	}
+
+	if value := query.Get("start_date"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.HandleError(w, errors.InvalidInput("Invalid start date format. Expected YYYY-MM-DD"))
+			return
+		}
+		filter.StartDate = date
+	}
+
+	if value := query.Get("end_date"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.HandleError(w, errors.InvalidInput("Invalid end date format. Expected YYYY-MM-DD"))
+			return
+		}
+		filter.EndDate = date
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxAuditLimit {
+			h.HandleError(w, errors.InvalidInput("Invalid limit parameter. Must be between 1 and 1000."))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			h.HandleError(w, errors.InvalidInput("Invalid offset parameter. Must be a non-negative number."))
+			return
+		}
+		filter.Offset = offset
+	}
+
+	entries, err := h.DB.GetAuditLogs(filter)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, entries)
+}
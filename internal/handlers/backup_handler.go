@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/database/repository"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BackupHandler handles database backup and restore HTTP requests
+type BackupHandler struct {
+	common.BaseHandler
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(db database.Service) *BackupHandler {
+	return &BackupHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// Backup handles GET /api/admin/backup?format=sqlite|json
+//
+// The sqlite format (the default) downloads a consistent snapshot of the database file,
+// while the json format downloads a bundle of all canteen data that can be restored
+// with POST /api/admin/restore.
+func (h *BackupHandler) Backup(w http.ResponseWriter, r *http.Request) {
+	fileName := "canteen_backup_" + time.Now().Format("2006_01_02_150405")
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "sqlite":
+		h.streamSQLiteBackup(w, fileName+".db")
+	case "json":
+		bundle, err := h.DB.ExportBackup()
+		if err != nil {
+			h.HandleError(w, errors.Internal(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", fileName))
+		if err := json.NewEncoder(w).Encode(bundle); err != nil {
+			log.Errorf("Error writing JSON backup: %v", err)
+		}
+	default:
+		h.HandleError(w, errors.InvalidInput("Invalid format. Expected sqlite or json"))
+	}
+}
+
+// streamSQLiteBackup snapshots the database into a temporary file and streams it
+func (h *BackupHandler) streamSQLiteBackup(w http.ResponseWriter, fileName string) {
+	dir, err := os.MkdirTemp("", "canteen-backup-")
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, fileName)
+	if err := h.DB.BackupTo(path); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	if info, err := file.Stat(); err == nil {
+		w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		log.Errorf("Error streaming SQLite backup: %v", err)
+	}
+}
+
+// Restore handles POST /api/admin/restore?overwrite=true|false
+//
+// The JSON bundle is read from the request body, or from the "file" field of a
+// multipart upload. The bundle is validated and imported in a single transaction,
+// and only into an empty database unless overwrite is set, which replaces all
+// canteen data and is recorded in the audit log.
+func (h *BackupHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	overwrite, _ := strconv.ParseBool(r.URL.Query().Get("overwrite"))
+	var bundle models.BackupBundle
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			h.HandleError(w, errors.InvalidInput("Failed to get file from form"))
+			return
+		}
+		defer file.Close()
+
+		if err := json.NewDecoder(file).Decode(&bundle); err != nil {
+			h.HandleError(w, errors.InvalidInput("Invalid backup file: "+err.Error()))
+			return
+		}
+	} else if err := h.DecodeJSON(r, &bundle); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	result, err := h.DB.RestoreBackup(&bundle, overwrite)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidBackup) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if overwrite {
+		h.Audit(r, models.AuditActionRestore, models.AuditEntityBackup, 0, nil, map[string]any{"overwrite": true, "bundle_created_at": bundle.CreatedAt, "tables": result.Tables})
+	}
+
+	common.RespondWithSuccess(w, http.StatusCreated, result)
+}
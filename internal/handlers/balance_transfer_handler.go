@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// BalanceTransferHandler handles balance transfer HTTP requests
+type BalanceTransferHandler struct {
+	common.BaseHandler
+}
+
+// NewBalanceTransferHandler creates a new balance transfer handler
+func NewBalanceTransferHandler(db database.Service) *BalanceTransferHandler {
+	return &BalanceTransferHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// CreateBalanceTransferRequest represents the request body for creating a balance transfer
+type CreateBalanceTransferRequest struct {
+	FromUserID      int64   `json:"from_user_id"`
+	ToUserID        int64   `json:"to_user_id"`
+	Amount          float64 `json:"amount"`
+	Note            string  `json:"note"`
+	RequireApproval bool    `json:"require_approval"` // Wait for the payer to approve the transfer
+}
+
+// RejectBalanceTransferRequest represents the request body for rejecting a balance transfer
+type RejectBalanceTransferRequest struct {
+	Note string `json:"note"`
+}
+
+// CreateBalanceTransfer handles POST /api/transfers
+//
+// Debits the payer and credits the payee right away, or records the transfer as pending
+// until the payer approves it if require_approval is set.
+func (h *BalanceTransferHandler) CreateBalanceTransfer(w http.ResponseWriter, r *http.Request) {
+	var request CreateBalanceTransferRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.FromUserID == 0 || request.ToUserID == 0 {
+		h.HandleError(w, errors.InvalidInput("from_user_id and to_user_id are required"))
+		return
+	}
+	if request.Amount <= 0 {
+		h.HandleError(w, errors.InvalidInput("Amount must be greater than zero"))
+		return
+	}
+
+	transfer := models.BalanceTransfer{
+		FromUserID:  request.FromUserID,
+		ToUserID:    request.ToUserID,
+		Amount:      request.Amount,
+		Note:        request.Note,
+		RequestedBy: common.RequestActor(r),
+	}
+	if err := h.DB.CreateBalanceTransfer(&transfer, request.RequireApproval); err != nil {
+		if errors.Is(err, database.ErrInvalidTransfer) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.auditTransactions(r, &transfer)
+
+	common.RespondWithSuccess(w, http.StatusCreated, transfer)
+}
+
+// GetBalanceTransfers handles GET /api/transfers
+func (h *BalanceTransferHandler) GetBalanceTransfers(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.BalanceTransferStatusPending, models.BalanceTransferStatusCompleted, models.BalanceTransferStatusRejected:
+	default:
+		h.HandleError(w, errors.InvalidInput("Invalid status. Expected pending, completed or rejected"))
+		return
+	}
+
+	transfers, err := h.DB.GetBalanceTransfers(status)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, transfers)
+}
+
+// GetBalanceTransfer handles GET /api/transfers/{id}
+func (h *BalanceTransferHandler) GetBalanceTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	transfer, err := h.DB.GetBalanceTransfer(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if transfer == nil {
+		h.HandleError(w, errors.NotFound("Balance transfer", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, transfer)
+}
+
+// GetUserBalanceTransfers handles GET /api/users/{user_id}/transfers
+func (h *BalanceTransferHandler) GetUserBalanceTransfers(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.ParseID(mux.Vars(r), "user_id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	transfers, err := h.DB.GetUserBalanceTransfers(userID)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, transfers)
+}
+
+// ApproveBalanceTransfer handles POST /api/transfers/{id}/approve
+//
+// Executes a transfer on the payer's behalf. The payer's balance must still cover it.
+func (h *BalanceTransferHandler) ApproveBalanceTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	transfer, err := h.DB.ApproveBalanceTransfer(id, common.RequestActor(r))
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidTransfer) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if transfer == nil {
+		h.HandleError(w, errors.NotFound("Balance transfer", id))
+		return
+	}
+	h.auditTransactions(r, transfer)
+
+	common.RespondWithSuccess(w, http.StatusOK, transfer)
+}
+
+// RejectBalanceTransfer handles POST /api/transfers/{id}/reject
+func (h *BalanceTransferHandler) RejectBalanceTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request RejectBalanceTransferRequest
+	if r.ContentLength != 0 {
+		if err := h.DecodeJSON(r, &request); err != nil {
+			h.HandleError(w, err)
+			return
+		}
+	}
+
+	transfer, err := h.DB.GetBalanceTransfer(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if transfer == nil {
+		h.HandleError(w, errors.NotFound("Balance transfer", id))
+		return
+	}
+
+	rejected, err := h.DB.RejectBalanceTransfer(id, common.RequestActor(r), request.Note)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if !rejected {
+		h.HandleError(w, errors.InvalidInput(fmt.Sprintf("Balance transfer %d is %s and cannot be rejected", id, transfer.Status)))
+		return
+	}
+
+	transfer, err = h.DB.GetBalanceTransfer(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, transfer)
+}
+
+// auditTransactions records the transactions created for a completed transfer
+func (h *BalanceTransferHandler) auditTransactions(r *http.Request, transfer *models.BalanceTransfer) {
+	for _, id := range []*int64{transfer.FromTransactionID, transfer.ToTransactionID} {
+		if id == nil {
+			continue
+		}
+		transaction, err := h.DB.GetTransaction(*id, false)
+		if err != nil || transaction == nil {
+			continue
+		}
+		h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, *id, nil, transaction)
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// CashSessionHandler handles cash drawer session HTTP requests
+type CashSessionHandler struct {
+	common.BaseHandler
+}
+
+// NewCashSessionHandler creates a new cash session handler
+func NewCashSessionHandler(db database.Service) *CashSessionHandler {
+	return &CashSessionHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// OpenCashSessionRequest represents the request body for opening a cash session
+type OpenCashSessionRequest struct {
+	OpeningFloat float64 `json:"opening_float"` // Cash in the drawer at the start of the shift
+	Note         string  `json:"note"`
+}
+
+// CloseCashSessionRequest represents the request body for closing a cash session
+type CloseCashSessionRequest struct {
+	CountedAmount *float64 `json:"counted_amount"` // Cash counted in the drawer at the end of the shift
+	Note          string   `json:"note"`
+}
+
+// OpenCashSession handles POST /api/cash-sessions
+//
+// Opens a session for the requesting cashier. Transactions they record while it is open are
+// linked to it, and those paid in cash are counted in the drawer.
+func (h *CashSessionHandler) OpenCashSession(w http.ResponseWriter, r *http.Request) {
+	var request OpenCashSessionRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.OpeningFloat < 0 {
+		h.HandleError(w, errors.InvalidInput("opening_float cannot be negative"))
+		return
+	}
+
+	session := models.CashSession{
+		Cashier:      common.RequestActor(r),
+		OpeningFloat: request.OpeningFloat,
+		Note:         request.Note,
+	}
+	if err := h.DB.OpenCashSession(&session); err != nil {
+		if errors.Is(err, database.ErrInvalidCashSession) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusCreated, session)
+}
+
+// GetCashSessions handles GET /api/cash-sessions
+func (h *CashSessionHandler) GetCashSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.DB.GetCashSessions()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, sessions)
+}
+
+// GetCurrentCashSession handles GET /api/cash-sessions/current
+//
+// Returns the open session of the requesting cashier.
+func (h *CashSessionHandler) GetCurrentCashSession(w http.ResponseWriter, r *http.Request) {
+	cashier := common.RequestActor(r)
+	session, err := h.DB.GetOpenCashSession(cashier)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if session == nil {
+		h.HandleError(w, errors.Newf(errors.ErrNotFound, "NOT_FOUND", "%s has no open cash session", cashier))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, session)
+}
+
+// GetCashSession handles GET /api/cash-sessions/{id}
+//
+// Returns the session with its transactions and, once closed, its over/short report.
+func (h *CashSessionHandler) GetCashSession(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	session, err := h.DB.GetCashSession(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if session == nil {
+		h.HandleError(w, errors.NotFound("Cash session", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, session)
+}
+
+// CloseCashSession handles POST /api/cash-sessions/{id}/close
+//
+// Closes the session with the counted amount and returns the over/short report: the
+// difference between the counted amount and the opening float plus cash deposits and cash
+// sales, less cash refunds.
+func (h *CashSessionHandler) CloseCashSession(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request CloseCashSessionRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.CountedAmount == nil || *request.CountedAmount < 0 {
+		h.HandleError(w, errors.InvalidInput("counted_amount is required and cannot be negative"))
+		return
+	}
+
+	session, err := h.DB.CloseCashSession(id, *request.CountedAmount, common.RequestActor(r), request.Note)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCashSession) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if session == nil {
+		h.HandleError(w, errors.NotFound("Cash session", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, session)
+}
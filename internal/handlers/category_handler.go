@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// CategoryHandler handles product category and menu HTTP requests
+type CategoryHandler struct {
+	common.BaseHandler
+}
+
+// NewCategoryHandler creates a new category handler
+func NewCategoryHandler(db database.Service) *CategoryHandler {
+	return &CategoryHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// CreateCategory handles POST /api/categories
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	if err := h.DecodeJSON(r, &category); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		h.HandleError(w, errors.InvalidInput("Category name is required"))
+		return
+	}
+
+	if err := h.DB.CreateCategory(&category); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusCreated, category)
+}
+
+// GetAllCategories handles GET /api/categories
+func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.DB.GetAllCategories()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, categories)
+}
+
+// GetCategory handles GET /api/categories/{id}
+func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	category, err := h.DB.GetCategory(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	if category == nil {
+		h.HandleError(w, errors.NotFound("Category", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, category)
+}
+
+// UpdateCategory handles PUT /api/categories/{id}
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var category models.Category
+	if err := h.DecodeJSON(r, &category); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	category.ID = id
+
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		h.HandleError(w, errors.InvalidInput("Category name is required"))
+		return
+	}
+
+	if err := h.DB.UpdateCategory(&category); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, category)
+}
+
+// DeleteCategory handles DELETE /api/categories/{id}
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.DeleteCategory(id); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// GetMenu handles GET /api/menu
+//
+// Returns the active products grouped by category in menu order, for counter displays and kiosks.
+func (h *CategoryHandler) GetMenu(w http.ResponseWriter, r *http.Request) {
+	menu, err := h.DB.GetMenu()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, menu)
+}
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"encoding/json"
+	"maya-canteen/internal/models"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ActorHeader is the request header that identifies who made a change.
+// Requests without it are audited as auditAnonymousActor.
+const ActorHeader = "X-Actor"
+
+const auditAnonymousActor = "anonymous"
+
+// auditIgnoredFields are not recorded in audit diffs since they change on every write
+var auditIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Audit records a create, update or delete of an entity. before is nil for creates and
+// after is nil for deletes. Failures are logged and never fail the request.
+func (h *BaseHandler) Audit(r *http.Request, action, entityType string, entityID int64, before, after any) {
+	h.AuditAs(RequestActor(r), ClientIP(r), action, entityType, entityID, before, after)
+}
+
+// AuditAs records a change made outside of an HTTP request, such as by a device or scheduler
+func (h *BaseHandler) AuditAs(actor, ipAddress, action, entityType string, entityID int64, before, after any) {
+	changes, err := AuditDiff(before, after)
+	if err != nil {
+		log.Errorf("Error computing audit diff for %s %d: %v", entityType, entityID, err)
+		return
+	}
+	if action == models.AuditActionUpdate && len(changes) == 0 {
+		return
+	}
+
+	entry := &models.AuditLog{
+		Actor:      actor,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    changes,
+		IPAddress:  ipAddress,
+	}
+	if err := h.DB.CreateAuditLog(entry); err != nil {
+		log.Errorf("Error recording audit log for %s %s %d: %v", action, entityType, entityID, err)
+	}
+}
+
+// AuditDiff compares the JSON representation of two entities and returns the changed fields.
+// Either side may be nil, in which case every field of the other side is reported.
+func AuditDiff(before, after any) (map[string]models.AuditChange, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]models.AuditChange)
+	for field, value := range beforeFields {
+		if afterValue, ok := afterFields[field]; !ok || !reflect.DeepEqual(value, afterValue) {
+			changes[field] = models.AuditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = models.AuditChange{Before: nil, After: value}
+		}
+	}
+	return changes, nil
+}
+
+// auditFields returns the JSON fields of an entity, without the ignored fields
+func auditFields(entity any) (map[string]any, error) {
+	fields := make(map[string]any)
+	if entity == nil || reflect.ValueOf(entity).Kind() == reflect.Pointer && reflect.ValueOf(entity).IsNil() {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for field := range auditIgnoredFields {
+		delete(fields, field)
+	}
+	return fields, nil
+}
+
+// RequestActor returns who made the request, from the X-Actor header
+func RequestActor(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get(ActorHeader)); actor != "" {
+		return actor
+	}
+	return auditAnonymousActor
+}
+
+// ClientIP returns the client IP address, preferring the first X-Forwarded-For entry
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditDiff(t *testing.T) {
+	before := &models.Product{ID: 1, Name: "Tea", Price: 50, Type: "drink"}
+	after := &models.Product{ID: 1, Name: "Tea", Price: 60, Type: "drink"}
+
+	changes, err := AuditDiff(before, after)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]models.AuditChange{
+		"price": {Before: float64(50), After: float64(60)},
+	}, changes)
+
+	changes, err = AuditDiff(nil, after)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AuditChange{Before: nil, After: "Tea"}, changes["name"])
+	assert.NotContains(t, changes, "created_at")
+
+	var deleted *models.Product
+	changes, err = AuditDiff(before, deleted)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AuditChange{Before: "Tea", After: nil}, changes["name"])
+}
+
+func TestRequestActorAndClientIP(t *testing.T) {
+	r := httptest.NewRequest("PUT", "/api/products/1", nil)
+	r.RemoteAddr = "10.0.0.5:51234"
+	assert.Equal(t, "anonymous", RequestActor(r))
+	assert.Equal(t, "10.0.0.5", ClientIP(r))
+
+	r.Header.Set(ActorHeader, "cashier-1")
+	r.Header.Set("X-Forwarded-For", "192.168.1.20, 10.0.0.1")
+	assert.Equal(t, "cashier-1", RequestActor(r))
+	assert.Equal(t, "192.168.1.20", ClientIP(r))
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// menuDateFormat is the format of the {date} path variable of daily menus
+const menuDateFormat = "2006-01-02"
+
+// punchActor is the audit actor of purchases created by a ZK device punch
+const punchActor = "zk-device"
+
+// DailyMenuHandler handles daily menu and pre-order HTTP requests
+type DailyMenuHandler struct {
+	common.BaseHandler
+}
+
+// NewDailyMenuHandler creates a new daily menu handler
+func NewDailyMenuHandler(db database.Service) *DailyMenuHandler {
+	return &DailyMenuHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// PublishMenuRequest represents the request body for publishing the menu of a date
+type PublishMenuRequest struct {
+	Items []struct {
+		ProductID     int64 `json:"product_id"`
+		UnitID        int64 `json:"unit_id"`        // Defaults to the product's default unit
+		QuantityLimit *int  `json:"quantity_limit"` // Omit for unlimited quantities
+	} `json:"items"`
+}
+
+// PreOrderRequest represents the request body for placing pre-orders
+type PreOrderRequest struct {
+	UserID int64                 `json:"user_id"`
+	Items  []models.PreOrderLine `json:"items"`
+}
+
+// CollectPreOrdersRequest represents the request body for collecting pre-orders
+type CollectPreOrdersRequest struct {
+	UserID     int64  `json:"user_id"`
+	EmployeeID string `json:"employee_id"` // Used when user_id is not set
+}
+
+// parseMenuDate returns the {date} path variable in YYYY-MM-DD form
+func parseMenuDate(r *http.Request) (string, error) {
+	date, err := time.ParseInLocation(menuDateFormat, mux.Vars(r)["date"], time.Local)
+	if err != nil {
+		return "", errors.InvalidInput("Invalid date format. Expected YYYY-MM-DD")
+	}
+	return date.Format(menuDateFormat), nil
+}
+
+// GetDailyMenu handles GET /api/menu/{date}
+func (h *DailyMenuHandler) GetDailyMenu(w http.ResponseWriter, r *http.Request) {
+	date, err := parseMenuDate(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	menu, err := h.DB.GetDailyMenu(date)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, menu)
+}
+
+// PublishDailyMenu handles PUT /api/menu/{date}
+//
+// Replaces the items on the menu of the date. Items that were already pre-ordered cannot
+// be removed, but their quantity limit can be changed.
+func (h *DailyMenuHandler) PublishDailyMenu(w http.ResponseWriter, r *http.Request) {
+	date, err := parseMenuDate(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request PublishMenuRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	items := make([]models.MenuItem, 0, len(request.Items))
+	for _, requested := range request.Items {
+		if requested.QuantityLimit != nil && *requested.QuantityLimit < 0 {
+			h.HandleError(w, errors.InvalidInput("Quantity limits must not be negative"))
+			return
+		}
+		product, err := h.DB.GetProduct(requested.ProductID, false)
+		if err != nil {
+			h.HandleError(w, errors.Internal(err))
+			return
+		}
+		if product == nil {
+			h.HandleError(w, errors.NotFound("Product", requested.ProductID))
+			return
+		}
+		items = append(items, models.MenuItem{
+			ProductID:     requested.ProductID,
+			UnitID:        requested.UnitID,
+			QuantityLimit: requested.QuantityLimit,
+		})
+	}
+
+	menu, err := h.DB.PublishDailyMenu(date, items)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidMenu) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, menu)
+}
+
+// PlacePreOrders handles POST /api/menu/{date}/orders
+//
+// Employees can pre-order from the menu of any later date. Each quantity replaces the
+// employee's earlier pre-order of the item, and a quantity of zero cancels it.
+func (h *DailyMenuHandler) PlacePreOrders(w http.ResponseWriter, r *http.Request) {
+	date, err := parseMenuDate(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if date <= time.Now().Format(menuDateFormat) {
+		h.HandleError(w, errors.InvalidInput("Pre-orders can only be placed for later dates"))
+		return
+	}
+
+	var request PreOrderRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if len(request.Items) == 0 {
+		h.HandleError(w, errors.InvalidInput("At least one item is required"))
+		return
+	}
+
+	user, err := h.DB.GetUserByID(request.UserID)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if user == nil || user.DeletedAt != nil {
+		h.HandleError(w, errors.NotFound("User", request.UserID))
+		return
+	}
+	if !user.Active {
+		h.HandleError(w, errors.InvalidInput("Inactive users cannot place pre-orders"))
+		return
+	}
+
+	orders, err := h.DB.PlacePreOrders(user.ID, date, request.Items)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidPreOrder) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, orders)
+}
+
+// GetMenuOrders handles GET /api/menu/{date}/orders
+//
+// Returns the kitchen tally of the pre-orders of the date.
+func (h *DailyMenuHandler) GetMenuOrders(w http.ResponseWriter, r *http.Request) {
+	date, err := parseMenuDate(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	tally, err := h.DB.GetMenuOrderTally(date)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, tally)
+}
+
+// CancelPreOrder handles DELETE /api/menu/{date}/orders/{orderId}
+func (h *DailyMenuHandler) CancelPreOrder(w http.ResponseWriter, r *http.Request) {
+	date, err := parseMenuDate(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	id, err := h.ParseID(mux.Vars(r), "orderId")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	order, err := h.DB.GetPreOrder(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if order == nil || order.MenuDate != date {
+		h.HandleError(w, errors.NotFound("Pre-order", id))
+		return
+	}
+	if order.Status != models.PreOrderStatusPending {
+		h.HandleError(w, errors.InvalidInput("Only pending pre-orders can be cancelled"))
+		return
+	}
+
+	if _, err := h.DB.CancelPreOrder(id); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// CollectPreOrders handles POST /api/menu/{date}/orders/collect
+//
+// Turns the employee's pending pre-orders of the date into a purchase.
+func (h *DailyMenuHandler) CollectPreOrders(w http.ResponseWriter, r *http.Request) {
+	date, err := parseMenuDate(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request CollectPreOrdersRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var user *models.User
+	if request.UserID != 0 {
+		user, err = h.DB.GetUserByID(request.UserID)
+	} else {
+		user, err = h.DB.GetUserByEmployeeID(request.EmployeeID)
+	}
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if user == nil {
+		h.HandleError(w, errors.InvalidInput("User not found"))
+		return
+	}
+
+	collection, err := h.DB.CollectPreOrders(user.ID, date)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidPreOrder) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if collection == nil {
+		h.HandleError(w, errors.NotFound("Pending pre-orders of user", user.ID))
+		return
+	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, collection.Transaction.ID, nil, collection.Transaction)
+
+	common.RespondWithSuccess(w, http.StatusCreated, collection)
+}
+
+// CollectOnPunch collects today's pre-orders of the employee who punched in on the ZK device.
+// Employees without pending pre-orders are ignored.
+func (h *DailyMenuHandler) CollectOnPunch(employeeID string, attendedAt time.Time) {
+	user, err := h.DB.GetUserByEmployeeID(employeeID)
+	if err != nil || user == nil {
+		return
+	}
+
+	collection, err := h.DB.CollectPreOrders(user.ID, attendedAt.Format(menuDateFormat))
+	if err != nil {
+		log.Errorf("Error collecting pre-orders of employee %s on punch: %v", employeeID, err)
+		return
+	}
+	if collection == nil {
+		return
+	}
+	h.AuditAs(punchActor, "", models.AuditActionCreate, models.AuditEntityTransaction, collection.Transaction.ID, nil, collection.Transaction)
+	log.Infof("Collected %d pre-orders of employee %s on punch", len(collection.Orders), employeeID)
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// employerInvoiceHeader is the header row of the employer invoice export
+var employerInvoiceHeader = []string{"department", "cost_center", "employee_id", "name", "transactions", "total_amount", "employee_share", "company_share"}
+
+// DepartmentHandler handles department-related HTTP requests
+type DepartmentHandler struct {
+	common.BaseHandler
+}
+
+// NewDepartmentHandler creates a new department handler
+func NewDepartmentHandler(db database.Service) *DepartmentHandler {
+	return &DepartmentHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// CreateDepartment handles POST /api/departments
+func (h *DepartmentHandler) CreateDepartment(w http.ResponseWriter, r *http.Request) {
+	var department models.Department
+	if err := h.DecodeJSON(r, &department); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	department.Name = strings.TrimSpace(department.Name)
+	if department.Name == "" {
+		h.HandleError(w, errors.InvalidInput("Department name is required"))
+		return
+	}
+
+	if err := h.DB.CreateDepartment(&department); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusCreated, department)
+}
+
+// GetAllDepartments handles GET /api/departments
+func (h *DepartmentHandler) GetAllDepartments(w http.ResponseWriter, r *http.Request) {
+	departments, err := h.DB.GetAllDepartments()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, departments)
+}
+
+// GetDepartment handles GET /api/departments/{id}
+func (h *DepartmentHandler) GetDepartment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	department, err := h.DB.GetDepartment(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	if department == nil {
+		h.HandleError(w, errors.NotFound("Department", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, department)
+}
+
+// UpdateDepartment handles PUT /api/departments/{id}
+func (h *DepartmentHandler) UpdateDepartment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var department models.Department
+	if err := h.DecodeJSON(r, &department); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	department.ID = id
+
+	department.Name = strings.TrimSpace(department.Name)
+	if department.Name == "" {
+		h.HandleError(w, errors.InvalidInput("Department name is required"))
+		return
+	}
+
+	if err := h.DB.UpdateDepartment(&department); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, department)
+}
+
+// DeleteDepartment handles DELETE /api/departments/{id}
+func (h *DepartmentHandler) DeleteDepartment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.DeleteDepartment(id); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// DepartmentReportRequest represents the request body for department reports
+type DepartmentReportRequest struct {
+	DateRangeRequest
+	Limit int `json:"limit,omitempty"`
+}
+
+// GetDepartmentSpend handles POST /api/reports/department-spend
+func (h *DepartmentHandler) GetDepartmentSpend(w http.ResponseWriter, r *http.Request) {
+	var request DepartmentReportRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	startDate, endDate, err := request.Parse()
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	spend, err := h.DB.GetDepartmentMonthlySpend(startDate, endDate)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, spend)
+}
+
+// GetDepartmentTopConsumers handles POST /api/reports/department-top-consumers
+func (h *DepartmentHandler) GetDepartmentTopConsumers(w http.ResponseWriter, r *http.Request) {
+	var request DepartmentReportRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	startDate, endDate, err := request.Parse()
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = 5 // Default to the top 5 consumers of every department
+	}
+
+	consumers, err := h.DB.GetDepartmentTopConsumers(startDate, endDate, limit)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, consumers)
+}
+
+// GetDepartmentProducts handles POST /api/reports/department-products
+func (h *DepartmentHandler) GetDepartmentProducts(w http.ResponseWriter, r *http.Request) {
+	var request DepartmentReportRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	startDate, endDate, err := request.Parse()
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	consumption, err := h.DB.GetDepartmentProductConsumption(startDate, endDate)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, consumption)
+}
+
+// GetDepartmentBalances handles GET /api/reports/department-balances
+func (h *DepartmentHandler) GetDepartmentBalances(w http.ResponseWriter, r *http.Request) {
+	balances, err := h.DB.GetDepartmentBalances()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, balances)
+}
+
+// GetEmployerInvoice handles GET /api/reports/employer-invoice?month=YYYY-MM&format=json|csv
+//
+// Totals the company shares of the month per department and employee, for billing the employer.
+func (h *DepartmentHandler) GetEmployerInvoice(w http.ResponseWriter, r *http.Request) {
+	period, err := parsePayrollMonth(r.URL.Query().Get("month"))
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	invoice, err := h.DB.GetEmployerInvoice(period)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		common.RespondWithSuccess(w, http.StatusOK, invoice)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=employer_invoice_%s.csv", period.Format("2006_01")))
+		if err := writeEmployerInvoiceCSV(w, invoice); err != nil {
+			log.Errorf("Error writing employer invoice CSV: %v", err)
+		}
+	default:
+		h.HandleError(w, errors.InvalidInput("Invalid format. Expected json or csv"))
+	}
+}
+
+// writeEmployerInvoiceCSV writes an employer invoice as CSV, one row per employee
+func writeEmployerInvoiceCSV(w http.ResponseWriter, invoice *models.EmployerInvoice) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(employerInvoiceHeader); err != nil {
+		return err
+	}
+	formatAmount := func(amount float64) string {
+		return strconv.FormatFloat(amount, 'f', 2, 64)
+	}
+	for _, department := range invoice.Departments {
+		for _, line := range department.Employees {
+			record := []string{
+				department.DepartmentName,
+				department.CostCenter,
+				line.EmployeeID,
+				line.Name,
+				strconv.Itoa(line.TransactionCount),
+				formatAmount(line.TotalAmount),
+				formatAmount(line.EmployeeShare),
+				formatAmount(line.CompanyShare),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// InstallmentPlanHandler handles interest-free installment plans for outstanding balances
+type InstallmentPlanHandler struct {
+	common.BaseHandler
+}
+
+// NewInstallmentPlanHandler creates a new installment plan handler
+func NewInstallmentPlanHandler(db database.Service) *InstallmentPlanHandler {
+	return &InstallmentPlanHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// CreateInstallmentPlanRequest represents the request body for creating an installment plan
+type CreateInstallmentPlanRequest struct {
+	TotalAmount  float64 `json:"total_amount"` // Defaults to the whole outstanding balance
+	Installments int     `json:"installments"`
+	FirstDueDate string  `json:"first_due_date"` // YYYY-MM-DD, later installments fall due monthly
+	Note         string  `json:"note"`
+}
+
+// InstallmentPaymentRequest represents the request body for paying towards an installment plan
+type InstallmentPaymentRequest struct {
+	Amount        float64 `json:"amount"`
+	PaymentMethod string  `json:"payment_method"` // "account" for bank transfers and the like, or "cash"
+	Note          string  `json:"note"`
+}
+
+// installmentNotice describes the installment a user on plan is asked to pay in balance
+// reminders, or returns an empty string if nothing is due under the plan
+func installmentNotice(plan *models.InstallmentPlan) string {
+	if plan == nil || plan.AmountDue() <= 0 {
+		return ""
+	}
+	var notice string
+	if next := plan.NextInstallment; next != nil {
+		notice = fmt.Sprintf("This is installment %d of %d of your payment plan, due on %s.",
+			next.Sequence, plan.InstallmentCount, next.DueDate.Format("2 Jan 2006"))
+		if plan.OverdueAmount > 0 {
+			notice += fmt.Sprintf(" It includes PKR %.2f of missed installments.", plan.OverdueAmount)
+		}
+	} else {
+		notice = "This is the amount of the missed installments of your payment plan."
+	}
+	return notice + fmt.Sprintf(" PKR %.2f of the plan remains to be paid.", plan.RemainingAmount)
+}
+
+// CreateInstallmentPlan handles POST /api/users/{user_id}/installment-plans
+//
+// Splits what the user owes into monthly installments. A user can only have one active plan.
+func (h *InstallmentPlanHandler) CreateInstallmentPlan(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.ParseID(mux.Vars(r), "user_id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request CreateInstallmentPlanRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.Installments == 0 {
+		h.HandleError(w, errors.InvalidInput("installments is required"))
+		return
+	}
+	firstDueDate, err := time.Parse("2006-01-02", request.FirstDueDate)
+	if err != nil {
+		h.HandleError(w, errors.InvalidInput("Invalid first_due_date format. Expected YYYY-MM-DD"))
+		return
+	}
+
+	plan := models.InstallmentPlan{
+		UserID:           userID,
+		TotalAmount:      request.TotalAmount,
+		InstallmentCount: request.Installments,
+		Note:             strings.TrimSpace(request.Note),
+		CreatedBy:        common.RequestActor(r),
+	}
+	if err := h.DB.CreateInstallmentPlan(&plan, firstDueDate); err != nil {
+		if errors.Is(err, database.ErrInvalidInstallmentPlan) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityInstallmentPlan, plan.ID, nil, plan)
+
+	common.RespondWithSuccess(w, http.StatusCreated, plan)
+}
+
+// GetInstallmentPlans handles GET /api/installment-plans
+//
+// Supports filtering by status and, with missed=true, to plans with missed installments.
+func (h *InstallmentPlanHandler) GetInstallmentPlans(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.InstallmentPlanStatusActive, models.InstallmentPlanStatusCompleted, models.InstallmentPlanStatusCancelled:
+	default:
+		h.HandleError(w, errors.InvalidInput("Invalid status. Expected active, completed or cancelled"))
+		return
+	}
+	missedOnly, _ := strconv.ParseBool(r.URL.Query().Get("missed"))
+
+	plans, err := h.DB.GetInstallmentPlans(status, missedOnly)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, plans)
+}
+
+// GetUserInstallmentPlans handles GET /api/users/{user_id}/installment-plans
+func (h *InstallmentPlanHandler) GetUserInstallmentPlans(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.ParseID(mux.Vars(r), "user_id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	plans, err := h.DB.GetUserInstallmentPlans(userID)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, plans)
+}
+
+// GetInstallmentPlan handles GET /api/installment-plans/{id}
+func (h *InstallmentPlanHandler) GetInstallmentPlan(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	plan, err := h.DB.GetInstallmentPlan(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if plan == nil {
+		h.HandleError(w, errors.NotFound("Installment plan", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, plan)
+}
+
+// PayInstallmentPlan handles POST /api/installment-plans/{id}/payments
+//
+// Records a payment towards the plan as a deposit. Cash payments go through the requesting
+// cashier's open cash session.
+func (h *InstallmentPlanHandler) PayInstallmentPlan(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request InstallmentPaymentRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.Amount <= 0 {
+		h.HandleError(w, errors.InvalidInput("Amount must be greater than zero"))
+		return
+	}
+	if request.PaymentMethod == "" {
+		request.PaymentMethod = models.PaymentMethodAccount
+	}
+	if request.PaymentMethod != models.PaymentMethodAccount && request.PaymentMethod != models.PaymentMethodCash {
+		h.HandleError(w, errors.InvalidInput("Invalid payment_method. Expected account or cash"))
+		return
+	}
+
+	session, err := h.DB.GetOpenCashSession(common.RequestActor(r))
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	var cashSessionID *int64
+	if session != nil {
+		cashSessionID = &session.ID
+	}
+
+	before, err := h.DB.GetInstallmentPlan(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Installment plan", id))
+		return
+	}
+
+	plan, err := h.DB.PayInstallmentPlan(id, request.Amount, request.PaymentMethod, strings.TrimSpace(request.Note), cashSessionID)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidInstallmentPlan) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if plan == nil {
+		h.HandleError(w, errors.NotFound("Installment plan", id))
+		return
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityInstallmentPlan, id, before, plan)
+
+	common.RespondWithSuccess(w, http.StatusOK, plan)
+}
+
+// CancelInstallmentPlan handles POST /api/installment-plans/{id}/cancel
+//
+// Payments already made stay on the user's balance.
+func (h *InstallmentPlanHandler) CancelInstallmentPlan(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	before, err := h.DB.GetInstallmentPlan(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Installment plan", id))
+		return
+	}
+
+	cancelled, err := h.DB.CancelInstallmentPlan(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if !cancelled {
+		h.HandleError(w, errors.InvalidInput(fmt.Sprintf("Installment plan %d is %s", id, before.Status)))
+		return
+	}
+
+	plan, err := h.DB.GetInstallmentPlan(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityInstallmentPlan, id, before, plan)
+
+	common.RespondWithSuccess(w, http.StatusOK, plan)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallmentNotice(t *testing.T) {
+	assert.Empty(t, installmentNotice(nil))
+
+	next := models.Installment{Sequence: 2, DueDate: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), Amount: 500, PaidAmount: 100}
+	plan := &models.InstallmentPlan{InstallmentCount: 4, RemainingAmount: 1900, NextInstallment: &next}
+	assert.Equal(t, 400.0, plan.AmountDue())
+	assert.Equal(t, "This is installment 2 of 4 of your payment plan, due on 1 Nov 2026. PKR 1900.00 of the plan remains to be paid.", installmentNotice(plan))
+
+	plan.OverdueAmount, plan.MissedCount = 250, 1
+	assert.Equal(t, 650.0, plan.AmountDue())
+	assert.Contains(t, installmentNotice(plan), "It includes PKR 250.00 of missed installments.")
+
+	plan.NextInstallment = nil
+	assert.Equal(t, "This is the amount of the missed installments of your payment plan. PKR 1900.00 of the plan remains to be paid.", installmentNotice(plan))
+
+	plan.OverdueAmount = 0
+	assert.Empty(t, installmentNotice(plan), "nothing is due once every installment is paid")
+}
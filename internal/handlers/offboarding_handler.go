@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// OffboardingHandler handles the offboarding of departing employees
+type OffboardingHandler struct {
+	common.BaseHandler
+}
+
+// NewOffboardingHandler creates a new offboarding handler
+func NewOffboardingHandler(db database.Service) *OffboardingHandler {
+	return &OffboardingHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// OffboardUserRequest represents the request body for offboarding a user
+type OffboardUserRequest struct {
+	SettlementMethod string `json:"settlement_method"` // payroll, cash or write-off, not needed for a zero balance
+	Reason           string `json:"reason"`            // Required for write-offs
+}
+
+// GetFinalStatement handles GET /api/users/{id}/offboarding
+//
+// Returns the final statement of a user, to review the balance before offboarding them or
+// to see how it was settled afterwards.
+func (h *OffboardingHandler) GetFinalStatement(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	statement, err := h.DB.GetFinalStatement(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if statement == nil {
+		h.HandleError(w, errors.NotFound("User", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, statement)
+}
+
+// OffboardUser handles POST /api/users/{id}/offboarding
+//
+// Settles the final balance of a departing user and deactivates them. Settling in cash goes
+// through the requesting cashier's open cash session.
+func (h *OffboardingHandler) OffboardUser(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request OffboardUserRequest
+	if r.ContentLength != 0 {
+		if err := h.DecodeJSON(r, &request); err != nil {
+			h.HandleError(w, err)
+			return
+		}
+	}
+	method := strings.ToLower(strings.TrimSpace(request.SettlementMethod))
+
+	before, err := h.DB.GetUserByID(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("User", id))
+		return
+	}
+
+	actor := common.RequestActor(r)
+	session, err := h.DB.GetOpenCashSession(actor)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	var cashSessionID *int64
+	if session != nil {
+		cashSessionID = &session.ID
+	}
+
+	statement, err := h.DB.OffboardUser(id, method, strings.TrimSpace(request.Reason), actor, cashSessionID)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidOffboarding) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if statement == nil {
+		h.HandleError(w, errors.NotFound("User", id))
+		return
+	}
+
+	if transactionID := statement.Offboarding.TransactionID; transactionID != nil {
+		for _, transaction := range statement.Transactions {
+			if transaction.ID == *transactionID {
+				h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, transaction.ID, nil, transaction)
+			}
+		}
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityUser, id, before, statement.User)
+
+	common.RespondWithSuccess(w, http.StatusCreated, statement)
+}
+
+// GetOffboardings handles GET /api/offboardings
+func (h *OffboardingHandler) GetOffboardings(w http.ResponseWriter, r *http.Request) {
+	offboardings, err := h.DB.GetOffboardings()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, offboardings)
+}
+
+// GetWriteOffReport handles POST /api/reports/write-offs
+func (h *OffboardingHandler) GetWriteOffReport(w http.ResponseWriter, r *http.Request) {
+	var request DateRangeRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	startDate, endDate, err := request.Parse()
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	report, err := h.DB.GetWriteOffReport(startDate, endDate)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, report)
+}
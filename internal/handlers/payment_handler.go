@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// paymentSources lists the accepted statement sources
+var paymentSources = []string{"jazzcash", "easypaisa", "bank"}
+
+// paymentDateFormats lists the accepted formats of statement dates, in local time. Dates
+// written with slashes are read day first, as JazzCash and local banks write them.
+var paymentDateFormats = slices.Concat(importDateFormats, []string{
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02/01/2006",
+	"02-Jan-2006 15:04:05",
+	"02-Jan-2006",
+	"02 Jan 2006",
+})
+
+// paymentColumns maps each statement field to the header names it is read from, in order
+// of preference
+var paymentColumns = map[string][]string{
+	"date":        {"date", "transaction_date", "date_time", "datetime", "value_date", "posting_date"},
+	"amount":      {"credit", "credit_amount", "amount", "deposit"},
+	"type":        {"type", "dr/cr", "cr/dr", "transaction_type"},
+	"reference":   {"reference", "reference_no", "transaction_id", "tid", "trx_id", "ref"},
+	"phone":       {"phone", "msisdn", "sender_msisdn", "sender_number", "mobile"},
+	"description": {"description", "details", "narration", "remarks"},
+	"sender":      {"sender_name", "sender", "name"},
+}
+
+// PaymentHandler handles payment statement and reconciliation HTTP requests
+type PaymentHandler struct {
+	common.BaseHandler
+}
+
+// NewPaymentHandler creates a new payment handler
+func NewPaymentHandler(db database.Service) *PaymentHandler {
+	return &PaymentHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// PaymentStatementUploadResponse represents the response for a statement upload
+type PaymentStatementUploadResponse struct {
+	Statement *models.PaymentStatement `json:"statement"`
+	Skipped   int                      `json:"skipped"` // Debits and rows without an amount
+	Errors    []string                 `json:"errors"`
+}
+
+// ApprovePaymentRequest represents the request body for approving a payment line
+type ApprovePaymentRequest struct {
+	UserID *int64 `json:"user_id"` // Overrides the matched user
+}
+
+// RejectPaymentRequest represents the request body for rejecting a payment line
+type RejectPaymentRequest struct {
+	Note string `json:"note"`
+}
+
+// paymentStatementColumns resolves the header of a statement to the column of each field
+func paymentStatementColumns(header []string) map[string]int {
+	index := headerIndex(header)
+	columns := make(map[string]int, len(paymentColumns))
+	for field, names := range paymentColumns {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				columns[field] = i
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// parsePaymentAmount parses a statement amount, ignoring currency symbols and thousands separators
+func parsePaymentAmount(value string) (float64, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, strings.TrimPrefix(strings.ToLower(value), "rs."))
+	return strconv.ParseFloat(cleaned, 64)
+}
+
+// parsePaymentDate parses a statement date using the accepted payment date formats
+func parsePaymentDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("date is required")
+	}
+	for _, format := range paymentDateFormats {
+		if date, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or DD/MM/YYYY", value)
+}
+
+// UploadStatement handles POST /api/payments/statements
+//
+// The uploaded CSV or Excel file is a bank or JazzCash statement with a date and an amount
+// or credit column, and optionally reference, phone, description, sender name and debit or
+// credit type columns. Debits are skipped. Each credit is matched to a user by phone, by an
+// employee ID in the reference or description, or by an amount equal to a single user's
+// outstanding balance.
+// The "source" form field names the statement source and defaults to jazzcash.
+func (h *PaymentHandler) UploadStatement(w http.ResponseWriter, r *http.Request) {
+	records, err := readSpreadsheetUpload(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if len(records) == 0 {
+		h.HandleError(w, errors.InvalidInput("The file is empty"))
+		return
+	}
+
+	source := strings.ToLower(strings.TrimSpace(r.FormValue("source")))
+	if source == "" {
+		source = "jazzcash"
+	}
+	if !slices.Contains(paymentSources, source) {
+		h.HandleError(w, errors.InvalidInput(fmt.Sprintf("Invalid source. Expected one of %s", strings.Join(paymentSources, ", "))))
+		return
+	}
+
+	columns := paymentStatementColumns(records[0])
+	for _, required := range []string{"date", "amount"} {
+		if _, ok := columns[required]; !ok {
+			h.HandleError(w, errors.InvalidInput("Missing required column: "+required))
+			return
+		}
+	}
+
+	statement := &models.PaymentStatement{
+		Source:     source,
+		UploadedBy: common.RequestActor(r),
+	}
+	if _, fileHeader, err := r.FormFile("file"); err == nil {
+		statement.FileName = fileHeader.Filename
+	}
+	response := PaymentStatementUploadResponse{Errors: make([]string, 0)}
+
+	for i, record := range records[1:] {
+		line := i + 2 // Header is line 1
+		if isBlankRecord(record) {
+			continue
+		}
+		row := spreadsheetRow{columns: columns, record: record}
+
+		amountCell := row.Get("amount")
+		if kind := strings.ToLower(row.Get("type")); amountCell == "" || strings.HasPrefix(kind, "d") {
+			response.Skipped++
+			continue
+		}
+		amount, err := parsePaymentAmount(amountCell)
+		if err != nil {
+			response.Errors = append(response.Errors, fmt.Sprintf("Line %d: invalid amount %q", line, amountCell))
+			continue
+		}
+		if amount <= 0 {
+			response.Skipped++
+			continue
+		}
+		paidAt, err := parsePaymentDate(row.Get("date"))
+		if err != nil {
+			response.Errors = append(response.Errors, fmt.Sprintf("Line %d: %s", line, err.Error()))
+			continue
+		}
+
+		description := row.Get("description")
+		if sender := row.Get("sender"); sender != "" && !strings.Contains(description, sender) {
+			description = strings.TrimPrefix(description+" - "+sender, " - ")
+		}
+		statement.Lines = append(statement.Lines, models.PaymentLine{
+			Line:        line,
+			PaidAt:      paidAt,
+			Amount:      amount,
+			Reference:   row.Get("reference"),
+			Phone:       row.Get("phone"),
+			Description: description,
+		})
+	}
+
+	if len(statement.Lines) == 0 {
+		response.Errors = append(response.Errors, "The file has no credits to import")
+		common.RespondWithJSON(w, http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	if err := h.DB.ImportPaymentStatement(statement); err != nil {
+		log.Errorf("Error importing payment statement: %v", err)
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	response.Statement = statement
+
+	common.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+// GetStatements handles GET /api/payments/statements
+func (h *PaymentHandler) GetStatements(w http.ResponseWriter, r *http.Request) {
+	statements, err := h.DB.GetPaymentStatements()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, statements)
+}
+
+// GetStatement handles GET /api/payments/statements/{id}
+func (h *PaymentHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	statement, err := h.DB.GetPaymentStatement(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if statement == nil {
+		h.HandleError(w, errors.NotFound("Payment statement", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, statement)
+}
+
+// GetReviewQueue handles GET /api/payments/review?status=matched|unmatched
+//
+// Returns the lines awaiting review, oldest payments first. Without a status both matched
+// and unmatched lines are returned.
+func (h *PaymentHandler) GetReviewQueue(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != models.PaymentLineStatusMatched && status != models.PaymentLineStatusUnmatched {
+		h.HandleError(w, errors.InvalidInput("Invalid status. Expected matched or unmatched"))
+		return
+	}
+
+	lines, err := h.DB.GetPaymentReviewQueue(status)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, lines)
+}
+
+// ApproveLine handles POST /api/payments/lines/{id}/approve
+//
+// Posts a deposit for the line to its matched user, or to the user in the request body,
+// which is required for unmatched lines.
+func (h *PaymentHandler) ApproveLine(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request ApprovePaymentRequest
+	if r.ContentLength != 0 {
+		if err := h.DecodeJSON(r, &request); err != nil {
+			h.HandleError(w, err)
+			return
+		}
+	}
+
+	before, err := h.DB.GetPaymentLine(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Payment line", id))
+		return
+	}
+
+	approval, err := h.DB.ApprovePaymentLine(id, request.UserID, common.RequestActor(r))
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidPaymentLine) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if approval == nil {
+		h.HandleError(w, errors.NotFound("Payment line", id))
+		return
+	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, approval.Transaction.ID, nil, approval.Transaction)
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityPaymentLine, id, before, approval.Line)
+
+	common.RespondWithSuccess(w, http.StatusCreated, approval)
+}
+
+// RejectLine handles POST /api/payments/lines/{id}/reject
+func (h *PaymentHandler) RejectLine(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request RejectPaymentRequest
+	if r.ContentLength != 0 {
+		if err := h.DecodeJSON(r, &request); err != nil {
+			h.HandleError(w, err)
+			return
+		}
+	}
+
+	before, err := h.DB.GetPaymentLine(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Payment line", id))
+		return
+	}
+
+	rejected, err := h.DB.RejectPaymentLine(id, common.RequestActor(r), request.Note)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if !rejected {
+		h.HandleError(w, errors.InvalidInput(fmt.Sprintf("Payment line %d is %s and cannot be rejected", id, before.Status)))
+		return
+	}
+
+	line, err := h.DB.GetPaymentLine(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityPaymentLine, id, before, line)
+
+	common.RespondWithSuccess(w, http.StatusOK, line)
+}
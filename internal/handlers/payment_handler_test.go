@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePaymentAmount(t *testing.T) {
+	for value, expected := range map[string]float64{
+		"1500":         1500,
+		"1,500.50":     1500.5,
+		"Rs. 2,000":    2000,
+		"PKR 750":      750,
+		"-300":         -300,
+		" 42.10 ":      42.1,
+		"Rs.1,000,000": 1000000,
+	} {
+		amount, err := parsePaymentAmount(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, amount, value)
+	}
+
+	_, err := parsePaymentAmount("n/a")
+	assert.Error(t, err)
+}
+
+func TestParsePaymentDate(t *testing.T) {
+	date, err := parsePaymentDate("03/05/2024 14:30")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 3, 14, 30, 0, 0, time.Local), date, "slashed dates are day first")
+
+	date, err = parsePaymentDate("03-May-2024")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 3, 0, 0, 0, 0, time.Local), date)
+
+	_, err = parsePaymentDate("")
+	assert.Error(t, err)
+}
+
+func TestPaymentStatementColumns(t *testing.T) {
+	columns := paymentStatementColumns([]string{"Transaction Date", "TID", "MSISDN", "Amount", "Credit", "Narration"})
+
+	assert.Equal(t, 0, columns["date"])
+	assert.Equal(t, 1, columns["reference"])
+	assert.Equal(t, 2, columns["phone"])
+	assert.Equal(t, 4, columns["amount"], "credit is preferred over amount")
+	assert.Equal(t, 5, columns["description"])
+	_, ok := columns["sender"]
+	assert.False(t, ok)
+}
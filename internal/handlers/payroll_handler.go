@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xuri/excelize/v2"
+)
+
+// payrollExportHeader is the header row of the payroll deduction export
+var payrollExportHeader = []string{"employee_id", "name", "department", "amount_to_deduct"}
+
+// PayrollHandler handles payroll deduction HTTP requests
+type PayrollHandler struct {
+	common.BaseHandler
+}
+
+// NewPayrollHandler creates a new payroll handler
+func NewPayrollHandler(db database.Service) *PayrollHandler {
+	return &PayrollHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// PayrollSettleRequest represents the request body for settling payroll deductions
+type PayrollSettleRequest struct {
+	Month   string  `json:"month"`              // YYYY-MM
+	UserIDs []int64 `json:"user_ids,omitempty"` // Optional subset of users to settle
+}
+
+// parsePayrollMonth parses a YYYY-MM month, defaulting to the current month
+func parsePayrollMonth(month string) (time.Time, error) {
+	if month == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local), nil
+	}
+	period, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return time.Time{}, errors.InvalidInput("Invalid month format. Expected YYYY-MM")
+	}
+	return period, nil
+}
+
+// ExportPayrollDeductions handles GET /api/payroll/deductions?month=YYYY-MM&format=json|csv|xlsx
+func (h *PayrollHandler) ExportPayrollDeductions(w http.ResponseWriter, r *http.Request) {
+	period, err := parsePayrollMonth(r.URL.Query().Get("month"))
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	deductions, err := h.DB.GetPayrollDeductions(period)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	fileName := "payroll_deductions_" + period.Format("2006_01")
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		common.RespondWithSuccess(w, http.StatusOK, deductions)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", fileName))
+		if err := writePayrollCSV(w, deductions); err != nil {
+			log.Errorf("Error writing payroll CSV: %v", err)
+		}
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", fileName))
+		if err := writePayrollXLSX(w, deductions); err != nil {
+			log.Errorf("Error writing payroll XLSX: %v", err)
+		}
+	default:
+		h.HandleError(w, errors.InvalidInput("Invalid format. Expected json, csv or xlsx"))
+	}
+}
+
+// SettlePayrollDeductions handles POST /api/payroll/settle
+func (h *PayrollHandler) SettlePayrollDeductions(w http.ResponseWriter, r *http.Request) {
+	var request PayrollSettleRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	period, err := parsePayrollMonth(request.Month)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	settlement, err := h.DB.SettlePayrollDeductions(period, request.UserIDs)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusCreated, settlement)
+}
+
+// writePayrollCSV writes the deductions as CSV
+func writePayrollCSV(w http.ResponseWriter, deductions []models.PayrollDeduction) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(payrollExportHeader); err != nil {
+		return err
+	}
+	for _, d := range deductions {
+		record := []string{d.EmployeeID, d.Name, d.Department, strconv.FormatFloat(d.AmountToDeduct, 'f', 2, 64)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writePayrollXLSX writes the deductions as an Excel workbook
+func writePayrollXLSX(w http.ResponseWriter, deductions []models.PayrollDeduction) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	sheet := file.GetSheetName(0)
+	if err := file.SetSheetRow(sheet, "A1", &payrollExportHeader); err != nil {
+		return err
+	}
+	for i, d := range deductions {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		row := []any{d.EmployeeID, d.Name, d.Department, d.AmountToDeduct}
+		if err := file.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	return file.Write(w)
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// depositConfirmationTemplate is the WhatsApp reply sent when a receipt is approved
+const depositConfirmationTemplate = "Dear %s,\nYour payment of *PKR %.2f* has been received and added to your canteen account.\nYour current balance is: *PKR %.2f*\n\nThis is an automated message from Maya Canteen Management System."
+
+// receiptExtensions maps the mime types of common receipts to a file extension
+var receiptExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// PendingDepositHandler handles payment receipts sent over WhatsApp and their approval queue
+type PendingDepositHandler struct {
+	common.BaseHandler
+	whatsapp   *WhatsAppHandler
+	ReceiptDir string // Directory receipts are saved to
+}
+
+// NewPendingDepositHandler creates a new pending deposit handler that sends confirmations
+// with the WhatsApp client returned by getClient
+func NewPendingDepositHandler(db database.Service, getClient func() *whatsmeow.Client) *PendingDepositHandler {
+	return &PendingDepositHandler{
+		BaseHandler: common.NewBaseHandler(db),
+		whatsapp:    NewWhatsAppHandler(db, getClient),
+		ReceiptDir:  "receipts",
+	}
+}
+
+// ApprovePendingDepositRequest represents the request body for approving a pending deposit
+type ApprovePendingDepositRequest struct {
+	Amount float64 `json:"amount"` // Amount shown on the receipt
+	Note   string  `json:"note"`
+}
+
+// RejectPendingDepositRequest represents the request body for rejecting a pending deposit
+type RejectPendingDepositRequest struct {
+	Note string `json:"note"`
+}
+
+// receiptMedia is the image or document attached to an incoming WhatsApp message
+type receiptMedia struct {
+	download  whatsmeow.DownloadableMessage
+	mediaType string
+	mimeType  string
+	fileName  string
+	caption   string
+}
+
+// receiptMediaOf returns the image or document of a message, or nil if it has none
+func receiptMediaOf(message *waProto.Message) *receiptMedia {
+	if image := message.GetImageMessage(); image != nil {
+		return &receiptMedia{download: image, mediaType: "image", mimeType: image.GetMimetype(), caption: image.GetCaption()}
+	}
+	document := message.GetDocumentMessage()
+	if document == nil {
+		document = message.GetDocumentWithCaptionMessage().GetMessage().GetDocumentMessage()
+	}
+	if document != nil {
+		return &receiptMedia{
+			download:  document,
+			mediaType: "document",
+			mimeType:  document.GetMimetype(),
+			fileName:  document.GetFileName(),
+			caption:   document.GetCaption(),
+		}
+	}
+	return nil
+}
+
+// receiptFileName returns the name a receipt is saved under
+func receiptFileName(employeeID, messageID string, media *receiptMedia) string {
+	extension := strings.ToLower(filepath.Ext(media.fileName))
+	if extension == "" {
+		extension = receiptExtensions[strings.SplitN(media.mimeType, ";", 2)[0]]
+	}
+	if extension == "" {
+		extension = ".bin"
+	}
+	safe := func(value string) string {
+		return strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+				return r
+			}
+			return '_'
+		}, value)
+	}
+	return fmt.Sprintf("%s_%s%s", safe(employeeID), safe(messageID), extension)
+}
+
+// SaveReceipt saves an image or document sent by a registered user over WhatsApp to the
+// receipt directory, and adds it to the approval queue as a pending deposit. Other
+// messages, and messages from groups or unknown numbers, are ignored.
+func (h *PendingDepositHandler) SaveReceipt(msg *events.Message) {
+	if msg.Info.IsFromMe || msg.Info.IsGroup {
+		return
+	}
+	media := receiptMediaOf(msg.Message)
+	if media == nil {
+		return
+	}
+
+	// Senders may be addressed by their hidden LID, with the phone number as alternative
+	sender := msg.Info.Sender
+	if sender.Server == types.HiddenUserServer && !msg.Info.SenderAlt.IsEmpty() {
+		sender = msg.Info.SenderAlt
+	}
+	user, err := h.DB.GetUserByPhone("+" + sender.User)
+	if err != nil {
+		log.Errorf("Error looking up sender of WhatsApp receipt %s: %v", msg.Info.ID, err)
+		return
+	}
+	if user == nil {
+		log.Infof("Ignoring WhatsApp %s %s from unregistered number %s", media.mediaType, msg.Info.ID, sender.User)
+		return
+	}
+
+	client := h.whatsapp.GetWhatsAppClient()
+	if client == nil {
+		log.Errorf("Cannot download WhatsApp receipt %s: WhatsApp client is not initialized", msg.Info.ID)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	data, err := client.Download(ctx, media.download)
+	if err != nil {
+		log.Errorf("Error downloading WhatsApp receipt %s: %v", msg.Info.ID, err)
+		return
+	}
+
+	if err := os.MkdirAll(h.ReceiptDir, 0o755); err != nil {
+		log.Errorf("Error creating receipt directory %s: %v", h.ReceiptDir, err)
+		return
+	}
+	fileName := receiptFileName(user.EmployeeId, msg.Info.ID, media)
+	filePath := filepath.Join(h.ReceiptDir, fileName)
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		log.Errorf("Error saving WhatsApp receipt %s: %v", msg.Info.ID, err)
+		return
+	}
+
+	deposit := &models.PendingDeposit{
+		UserID:     user.ID,
+		Phone:      user.Phone,
+		MessageID:  msg.Info.ID,
+		MediaType:  media.mediaType,
+		MimeType:   media.mimeType,
+		FileName:   fileName,
+		FilePath:   filePath,
+		Caption:    strings.TrimSpace(media.caption),
+		ReceivedAt: msg.Info.Timestamp,
+	}
+	created, err := h.DB.CreatePendingDeposit(deposit)
+	if err != nil {
+		log.Errorf("Error saving pending deposit for WhatsApp receipt %s: %v", msg.Info.ID, err)
+		return
+	}
+	if created {
+		log.Infof("Saved WhatsApp receipt %s from %s as pending deposit %d", msg.Info.ID, user.Name, deposit.ID)
+	}
+}
+
+// GetPendingDeposits handles GET /api/deposits/pending?status=pending|approved|rejected
+//
+// Returns the receipts with the status, oldest first. The status defaults to pending.
+func (h *PendingDepositHandler) GetPendingDeposits(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = models.PendingDepositStatusPending
+	case models.PendingDepositStatusPending, models.PendingDepositStatusApproved, models.PendingDepositStatusRejected:
+	default:
+		h.HandleError(w, errors.InvalidInput("Invalid status. Expected pending, approved or rejected"))
+		return
+	}
+
+	deposits, err := h.DB.GetPendingDeposits(status)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, deposits)
+}
+
+// GetReceipt handles GET /api/deposits/pending/{id}/receipt
+func (h *PendingDepositHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	deposit, err := h.DB.GetPendingDeposit(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if deposit == nil {
+		h.HandleError(w, errors.NotFound("Pending deposit", id))
+		return
+	}
+	if _, err := os.Stat(deposit.FilePath); err != nil {
+		h.HandleError(w, errors.NotFound("Receipt file of pending deposit", id))
+		return
+	}
+
+	if deposit.MimeType != "" {
+		w.Header().Set("Content-Type", deposit.MimeType)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", deposit.FileName))
+	http.ServeFile(w, r, deposit.FilePath)
+}
+
+// ApprovePendingDeposit handles POST /api/deposits/pending/{id}/approve
+//
+// Posts a deposit of the amount on the receipt and replies to the employee on WhatsApp.
+// A failed reply does not fail the approval and is reported in confirmation_sent.
+func (h *PendingDepositHandler) ApprovePendingDeposit(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request ApprovePendingDepositRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.Amount <= 0 {
+		h.HandleError(w, errors.InvalidInput("Amount must be greater than zero"))
+		return
+	}
+
+	approval, err := h.DB.ApprovePendingDeposit(id, request.Amount, common.RequestActor(r), request.Note)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidPendingDeposit) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if approval == nil {
+		h.HandleError(w, errors.NotFound("Pending deposit", id))
+		return
+	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, approval.Transaction.ID, nil, approval.Transaction)
+
+	approval.ConfirmationSent = h.sendConfirmation(approval)
+
+	common.RespondWithSuccess(w, http.StatusOK, approval)
+}
+
+// sendConfirmation replies to the sender of an approved receipt with the deposited amount
+// and their new balance, and reports whether the reply was sent
+func (h *PendingDepositHandler) sendConfirmation(approval *models.PendingDepositApproval) bool {
+	deposit := approval.PendingDeposit
+	balance, err := h.DB.GetUserBalanceByUserID(deposit.UserID)
+	if err != nil {
+		log.Errorf("Error getting balance for deposit confirmation of receipt %d: %v", deposit.ID, err)
+		return false
+	}
+
+	message := fmt.Sprintf(depositConfirmationTemplate, deposit.UserName, approval.Transaction.Amount, balance.Balance)
+	if err := h.whatsapp.SendWhatsAppMessage(deposit.Phone, message); err != nil {
+		log.Errorf("Error sending deposit confirmation for receipt %d: %v", deposit.ID, err)
+		return false
+	}
+	return true
+}
+
+// RejectPendingDeposit handles POST /api/deposits/pending/{id}/reject
+func (h *PendingDepositHandler) RejectPendingDeposit(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request RejectPendingDepositRequest
+	if r.ContentLength != 0 {
+		if err := h.DecodeJSON(r, &request); err != nil {
+			h.HandleError(w, err)
+			return
+		}
+	}
+
+	deposit, err := h.DB.GetPendingDeposit(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if deposit == nil {
+		h.HandleError(w, errors.NotFound("Pending deposit", id))
+		return
+	}
+
+	rejected, err := h.DB.RejectPendingDeposit(id, common.RequestActor(r), request.Note)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if !rejected {
+		h.HandleError(w, errors.InvalidInput(fmt.Sprintf("Pending deposit %d is %s and cannot be rejected", id, deposit.Status)))
+		return
+	}
+
+	deposit, err = h.DB.GetPendingDeposit(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, deposit)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestReceiptMediaOf(t *testing.T) {
+	image := receiptMediaOf(&waProto.Message{ImageMessage: &waProto.ImageMessage{
+		Mimetype: proto.String("image/jpeg"),
+		Caption:  proto.String("paid 1500"),
+	}})
+	if assert.NotNil(t, image) {
+		assert.Equal(t, "image", image.mediaType)
+		assert.Equal(t, "paid 1500", image.caption)
+		assert.Equal(t, "1023_ABC.jpg", receiptFileName("1023", "ABC", image))
+	}
+
+	document := receiptMediaOf(&waProto.Message{DocumentWithCaptionMessage: &waProto.FutureProofMessage{
+		Message: &waProto.Message{DocumentMessage: &waProto.DocumentMessage{
+			Mimetype: proto.String("application/pdf"),
+			FileName: proto.String("Receipt.PDF"),
+		}},
+	}})
+	if assert.NotNil(t, document) {
+		assert.Equal(t, "document", document.mediaType)
+		assert.Equal(t, "E_1_x_y.pdf", receiptFileName("E/1", "x.y", document), "names are kept inside the receipt directory")
+	}
+
+	assert.Nil(t, receiptMediaOf(&waProto.Message{Conversation: proto.String("hello")}))
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// PricingRuleHandler handles discount and subsidy rule HTTP requests
+type PricingRuleHandler struct {
+	common.BaseHandler
+}
+
+// NewPricingRuleHandler creates a new pricing rule handler
+func NewPricingRuleHandler(db database.Service) *PricingRuleHandler {
+	return &PricingRuleHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// CreatePricingRule handles POST /api/pricing-rules
+func (h *PricingRuleHandler) CreatePricingRule(w http.ResponseWriter, r *http.Request) {
+	rule := models.PricingRule{Active: true}
+	if err := h.DecodeJSON(r, &rule); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.validatePricingRule(&rule); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.CreatePricingRule(&rule); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityPricingRule, rule.ID, nil, rule)
+
+	common.RespondWithSuccess(w, http.StatusCreated, rule)
+}
+
+// GetAllPricingRules handles GET /api/pricing-rules?active=true
+func (h *PricingRuleHandler) GetAllPricingRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.DB.GetAllPricingRules(r.URL.Query().Get("active") == "true")
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, rules)
+}
+
+// GetPricingRule handles GET /api/pricing-rules/{id}
+func (h *PricingRuleHandler) GetPricingRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	rule, err := h.DB.GetPricingRule(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	if rule == nil {
+		h.HandleError(w, errors.NotFound("Pricing rule", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, rule)
+}
+
+// UpdatePricingRule handles PUT /api/pricing-rules/{id}
+func (h *PricingRuleHandler) UpdatePricingRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	rule := models.PricingRule{Active: true}
+	if err := h.DecodeJSON(r, &rule); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	rule.ID = id
+
+	before, err := h.DB.GetPricingRule(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Pricing rule", id))
+		return
+	}
+
+	if err := h.validatePricingRule(&rule); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.UpdatePricingRule(&rule); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	rule.CreatedAt = before.CreatedAt
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityPricingRule, id, before, rule)
+
+	common.RespondWithSuccess(w, http.StatusOK, rule)
+}
+
+// DeletePricingRule handles DELETE /api/pricing-rules/{id}
+//
+// Sales the rule was applied to keep its name and amount. To stop a rule without losing it,
+// update it with active set to false instead.
+func (h *PricingRuleHandler) DeletePricingRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	before, err := h.DB.GetPricingRule(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Pricing rule", id))
+		return
+	}
+
+	if err := h.DB.DeletePricingRule(id); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionDelete, models.AuditEntityPricingRule, id, before, nil)
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// validatePricingRule checks the kind, type and amounts of a rule and that the product,
+// category, department and user it is narrowed down to exist. Daily windows are normalized
+// to "HH:MM".
+func (h *PricingRuleHandler) validatePricingRule(rule *models.PricingRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return errors.InvalidInput("Pricing rule name is required")
+	}
+	if rule.Kind != models.PricingRuleKindDiscount && rule.Kind != models.PricingRuleKindSubsidy {
+		return errors.InvalidInput("Invalid kind. Expected discount or subsidy")
+	}
+
+	switch rule.Type {
+	case models.PricingRuleTypePercentage:
+		if rule.Value <= 0 || rule.Value > 100 {
+			return errors.InvalidInput("Percentage rules need a value between 0 and 100")
+		}
+	case models.PricingRuleTypeFixed:
+		if rule.Value <= 0 {
+			return errors.InvalidInput("Fixed rules need a value greater than zero")
+		}
+	case models.PricingRuleTypeBuyXGetY:
+		if rule.Kind == models.PricingRuleKindSubsidy {
+			return errors.InvalidInput("Subsidies cannot be buy_x_get_y rules")
+		}
+		if rule.BuyQuantity < 1 || rule.FreeQuantity < 1 {
+			return errors.InvalidInput("buy_x_get_y rules need a buy_quantity and free_quantity of at least 1")
+		}
+		rule.Value = 0
+	default:
+		return errors.InvalidInput("Invalid type. Expected percentage, fixed or buy_x_get_y")
+	}
+	if rule.Type != models.PricingRuleTypeBuyXGetY {
+		rule.BuyQuantity, rule.FreeQuantity = 0, 0
+	}
+	if rule.MinQuantity < 0 {
+		return errors.InvalidInput("min_quantity must not be negative")
+	}
+
+	if rule.ValidFrom != nil && rule.ValidUntil != nil && !rule.ValidUntil.After(*rule.ValidFrom) {
+		return errors.InvalidInput("valid_until must be after valid_from")
+	}
+	for _, clock := range []*string{&rule.StartTime, &rule.EndTime} {
+		if *clock == "" {
+			continue
+		}
+		parsed, err := time.Parse("15:04", *clock)
+		if err != nil {
+			return errors.InvalidInput("Invalid start_time or end_time. Expected HH:MM")
+		}
+		*clock = parsed.Format("15:04")
+	}
+
+	if rule.ProductID != nil {
+		product, err := h.DB.GetProduct(*rule.ProductID, false)
+		if err != nil {
+			return errors.Internal(err)
+		}
+		if product == nil {
+			return errors.InvalidInput("Product not found")
+		}
+	}
+	if rule.CategoryID != nil {
+		category, err := h.DB.GetCategory(*rule.CategoryID)
+		if err != nil {
+			return errors.Internal(err)
+		}
+		if category == nil {
+			return errors.InvalidInput("Category not found")
+		}
+	}
+	if rule.DepartmentID != nil {
+		department, err := h.DB.GetDepartment(*rule.DepartmentID)
+		if err != nil {
+			return errors.Internal(err)
+		}
+		if department == nil {
+			return errors.InvalidInput("Department not found")
+		}
+	}
+	if rule.UserID != nil {
+		user, err := h.DB.GetUserByID(*rule.UserID)
+		if err != nil {
+			return errors.Internal(err)
+		}
+		if user == nil || user.DeletedAt != nil {
+			return errors.InvalidInput("User not found")
+		}
+	}
+	return nil
+}
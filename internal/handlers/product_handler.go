@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strings"
 
 	"maya-canteen/internal/database"
 	"maya-canteen/internal/errors"
@@ -11,6 +16,12 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// maxProductImageSize is the maximum size of an uploaded product image
+const maxProductImageSize = 2 << 20 // 2 MB
+
+// productImageTypes lists the accepted product image content types
+var productImageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
 type ProductHandler struct {
 	common.BaseHandler
 }
@@ -28,16 +39,22 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.validateProduct(&product); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
 	if err := h.DB.CreateProduct(&product); err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
 	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityProduct, product.ID, nil, product)
 
 	common.RespondWithSuccess(w, http.StatusCreated, product)
 }
 
 func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := h.DB.GetAllProducts()
+	products, err := h.DB.GetAllProducts(includeDeletedParam(r))
 	if err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
@@ -54,7 +71,7 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := h.DB.GetProduct(id)
+	product, err := h.DB.GetProduct(id, includeDeletedParam(r))
 	if err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
@@ -83,11 +100,33 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 	product.ID = id
 
+	before, err := h.DB.GetProduct(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Product", id))
+		return
+	}
+
+	if err := h.validateProduct(&product); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
 	if err := h.DB.UpdateProduct(&product); err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
 	}
 
+	// Audit the stored state, since updates do not write every column
+	after, err := h.DB.GetProduct(id, false)
+	if err != nil || after == nil {
+		after = &product
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityProduct, id, before, after)
+
 	common.RespondWithSuccess(w, http.StatusOK, product)
 }
 
@@ -99,10 +138,242 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := h.DB.GetProduct(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Product", id))
+		return
+	}
+
 	if err := h.DB.DeleteProduct(id); err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
 	}
+	h.Audit(r, models.AuditActionDelete, models.AuditEntityProduct, id, before, nil)
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// RestoreProduct handles POST /api/products/{id}/restore
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	before, err := h.DB.GetProduct(id, true)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	restored, err := h.DB.RestoreProduct(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if !restored {
+		h.HandleError(w, errors.NotFound("Deleted product", id))
+		return
+	}
+
+	product, err := h.DB.GetProduct(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionRestore, models.AuditEntityProduct, id, before, product)
+
+	common.RespondWithSuccess(w, http.StatusOK, product)
+}
+
+// validateProduct checks that the category exists, that the barcode is not used by another
+// product and that the units of a new product are valid
+func (h *ProductHandler) validateProduct(product *models.Product) error {
+	names := make(map[string]bool, len(product.Units))
+	for i := range product.Units {
+		if err := validateProductUnit(&product.Units[i]); err != nil {
+			return err
+		}
+		name := strings.ToLower(product.Units[i].Name)
+		if names[name] {
+			return errors.InvalidInput("Unit " + product.Units[i].Name + " is listed more than once")
+		}
+		names[name] = true
+	}
+
+	if product.CategoryID != nil {
+		category, err := h.DB.GetCategory(*product.CategoryID)
+		if err != nil {
+			return errors.Internal(err)
+		}
+		if category == nil {
+			return errors.InvalidInput(fmt.Sprintf("Category with ID %d does not exist", *product.CategoryID))
+		}
+	}
+
+	product.Barcode = strings.TrimSpace(product.Barcode)
+	if product.Barcode != "" {
+		existing, err := h.DB.GetProductByBarcode(product.Barcode)
+		if err != nil {
+			return errors.Internal(err)
+		}
+		if existing != nil && existing.ID != product.ID {
+			return errors.InvalidInput(fmt.Sprintf("Barcode %s is already used by %s", product.Barcode, existing.Name))
+		}
+	}
+	return nil
+}
+
+// GetProductByBarcode handles GET /api/products/barcode/{barcode}
+func (h *ProductHandler) GetProductByBarcode(w http.ResponseWriter, r *http.Request) {
+	barcode := mux.Vars(r)["barcode"]
+
+	product, err := h.DB.GetProductByBarcode(barcode)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	if product == nil {
+		h.HandleError(w, errors.NotFound("Product with barcode", barcode))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, product)
+}
+
+// UploadProductImage handles PUT /api/products/{id}/image
+//
+// The image is read from the "image" field of a multipart form and stored in the database.
+func (h *ProductHandler) UploadProductImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	product, err := h.DB.GetProduct(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if product == nil {
+		h.HandleError(w, errors.NotFound("Product", id))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxProductImageSize+1<<10) // Leave room for the form fields
+	if err := r.ParseMultipartForm(maxProductImageSize); err != nil {
+		h.HandleError(w, errors.InvalidInput("Failed to parse form, images may be at most 2 MB"))
+		return
+	}
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		h.HandleError(w, errors.InvalidInput("Failed to get image from form"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxProductImageSize+1))
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if len(data) > maxProductImageSize {
+		h.HandleError(w, errors.InvalidInput("Images may be at most 2 MB"))
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(productImageTypes, contentType) {
+		h.HandleError(w, errors.InvalidInput("Unsupported image type "+contentType+". Expected JPEG, PNG, GIF or WebP"))
+		return
+	}
+
+	previous, err := h.DB.GetProductImage(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	image := models.ProductImage{ProductID: id, ContentType: contentType, Data: data}
+	if err := h.DB.SaveProductImage(&image); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityProduct, id, productImageAudit(previous), productImageAudit(&image))
+
+	product.ImageURL = fmt.Sprintf("/api/products/%d/image", id)
+	common.RespondWithSuccess(w, http.StatusOK, product)
+}
+
+// productImageAudit describes the image of a product for the audit log, the image itself
+// being too large to record. image may be nil if the product has no image.
+func productImageAudit(image *models.ProductImage) map[string]any {
+	if image == nil {
+		return map[string]any{"image_url": ""}
+	}
+	return map[string]any{
+		"image_url":          fmt.Sprintf("/api/products/%d/image", image.ProductID),
+		"image_content_type": image.ContentType,
+		"image_size":         len(image.Data),
+	}
+}
+
+// GetProductImage handles GET /api/products/{id}/image
+func (h *ProductHandler) GetProductImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	image, err := h.DB.GetProductImage(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if image == nil {
+		h.HandleError(w, errors.NotFound("Image of product", id))
+		return
+	}
+
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.ServeContent(w, r, "", image.UpdatedAt, bytes.NewReader(image.Data))
+}
+
+// DeleteProductImage handles DELETE /api/products/{id}/image
+func (h *ProductHandler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	image, err := h.DB.GetProductImage(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	deleted, err := h.DB.DeleteProductImage(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if !deleted {
+		h.HandleError(w, errors.NotFound("Image of product", id))
+		return
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityProduct, id, productImageAudit(image), productImageAudit(nil))
 
 	common.RespondWithSuccess(w, http.StatusNoContent, nil)
 }
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductImageAudit(t *testing.T) {
+	old := &models.ProductImage{ProductID: 7, ContentType: "image/png", Data: make([]byte, 10)}
+	replacement := &models.ProductImage{ProductID: 7, ContentType: "image/jpeg", Data: make([]byte, 20)}
+
+	changes, err := common.AuditDiff(productImageAudit(nil), productImageAudit(old))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/products/7/image", changes["image_url"].After)
+
+	// Replacing an image keeps its URL, so the audit tells the images apart by type and size
+	changes, err = common.AuditDiff(productImageAudit(old), productImageAudit(replacement))
+	require.NoError(t, err)
+	assert.NotContains(t, changes, "image_url")
+	assert.Contains(t, changes, "image_size")
+
+	changes, err = common.AuditDiff(productImageAudit(replacement), productImageAudit(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "", changes["image_url"].After)
+}
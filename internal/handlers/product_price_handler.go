@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// priceSchedulerActor is the audit actor of scheduled prices applied once they are due
+const priceSchedulerActor = "price-scheduler"
+
+// ProductPriceRequest represents the request body for changing the price of a product unit
+type ProductPriceRequest struct {
+	UnitID        *int64  `json:"unit_id"` // Defaults to the product's default unit
+	Price         float64 `json:"price"`
+	EffectiveFrom string  `json:"effective_from"` // Empty to change the price right away
+	Reason        string  `json:"reason"`
+}
+
+// GetProductPrices handles GET /api/products/{id}/prices?at=YYYY-MM-DD
+//
+// Returns the price timeline of the product: its past, current and scheduled prices. With
+// the at parameter only the price of each unit at the end of that day is returned, which for
+// future days includes the scheduled prices.
+func (h *ProductHandler) GetProductPrices(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	timeline, err := h.DB.GetProductPriceTimeline(product.ID)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	if at := r.URL.Query().Get("at"); at != "" {
+		date, err := time.ParseInLocation("2006-01-02", at, time.Local)
+		if err != nil {
+			h.HandleError(w, errors.InvalidInput("Invalid at date format. Expected YYYY-MM-DD"))
+			return
+		}
+		timeline = pricesAt(timeline, date.AddDate(0, 0, 1))
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, timeline)
+}
+
+// pricesAt returns the price of each unit in effect just before at, from a timeline ordered oldest first
+func pricesAt(timeline []models.ProductPrice, at time.Time) []models.ProductPrice {
+	prices := make([]models.ProductPrice, 0)
+	index := make(map[string]int)
+	for _, price := range timeline {
+		if !price.EffectiveFrom.Before(at) {
+			continue
+		}
+		key := "name:" + price.UnitName
+		if price.UnitID != nil {
+			key = fmt.Sprint("id:", *price.UnitID)
+		}
+		if i, ok := index[key]; ok {
+			prices[i] = price
+			continue
+		}
+		index[key] = len(prices)
+		prices = append(prices, price)
+	}
+	return prices
+}
+
+// ScheduleProductPrice handles POST /api/products/{id}/prices
+//
+// Changes the price of a unit of the product right away, or schedules the change when
+// effective_from is in the future.
+func (h *ProductHandler) ScheduleProductPrice(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var request ProductPriceRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.Price < 0 {
+		h.HandleError(w, errors.InvalidInput("Price must not be negative"))
+		return
+	}
+
+	var unit *models.ProductUnit
+	for i := range product.Units {
+		if (request.UnitID == nil && product.Units[i].IsDefault) || (request.UnitID != nil && product.Units[i].ID == *request.UnitID) {
+			unit = &product.Units[i]
+			break
+		}
+	}
+	if unit == nil {
+		h.HandleError(w, errors.InvalidInput(fmt.Sprintf("Product %s has no such unit", product.Name)))
+		return
+	}
+
+	price := models.ProductPrice{
+		ProductID: product.ID,
+		UnitID:    &unit.ID,
+		UnitName:  unit.Name,
+		Price:     request.Price,
+		Reason:    strings.TrimSpace(request.Reason),
+	}
+	if request.EffectiveFrom != "" {
+		effectiveFrom, err := parseEffectiveFrom(request.EffectiveFrom)
+		if err != nil {
+			h.HandleError(w, err)
+			return
+		}
+		price.EffectiveFrom = effectiveFrom
+	}
+
+	if err := h.DB.ScheduleProductPrice(&price); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if price.AppliedAt != nil {
+		h.auditProductUnits(r, product)
+	}
+
+	common.RespondWithSuccess(w, http.StatusCreated, price)
+}
+
+// parseEffectiveFrom parses the effective time of a price change, which must not be in the past
+func parseEffectiveFrom(value string) (time.Time, error) {
+	for _, format := range importDateFormats {
+		effectiveFrom, err := time.ParseInLocation(format, value, time.Local)
+		if err != nil {
+			continue
+		}
+		// Allow for clock differences with the client
+		if effectiveFrom.Before(time.Now().Add(-time.Minute)) {
+			return time.Time{}, errors.InvalidInput("effective_from must not be in the past")
+		}
+		return effectiveFrom, nil
+	}
+	return time.Time{}, errors.InvalidInput("Invalid effective_from format. Expected YYYY-MM-DD, YYYY-MM-DD HH:MM or RFC 3339")
+}
+
+// CancelProductPrice handles DELETE /api/products/{id}/prices/{priceId}
+//
+// Only scheduled prices can be cancelled, the price history is kept.
+func (h *ProductHandler) CancelProductPrice(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	priceID, err := h.ParseID(mux.Vars(r), "priceId")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	price, err := h.DB.GetProductPrice(priceID)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if price == nil || price.ProductID != product.ID {
+		h.HandleError(w, errors.NotFound("Price of product", priceID))
+		return
+	}
+	if price.AppliedAt != nil {
+		h.HandleError(w, errors.InvalidInput("Only scheduled prices can be cancelled"))
+		return
+	}
+
+	cancelled, err := h.DB.CancelScheduledProductPrice(priceID)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if cancelled {
+		h.Audit(r, models.AuditActionUpdate, models.AuditEntityProduct, product.ID, price, nil)
+	}
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// ApplyDuePrices applies the scheduled prices that are due at now, recording each as an
+// update of its product by the price scheduler
+func (h *ProductHandler) ApplyDuePrices(now time.Time) (int, error) {
+	applied, err := h.DB.ApplyDueProductPrices(now)
+	for _, price := range applied {
+		scheduled := price
+		scheduled.AppliedAt = nil
+		scheduled.Status = models.ProductPriceStatusScheduled
+		h.AuditAs(priceSchedulerActor, "", models.AuditActionUpdate, models.AuditEntityProduct, price.ProductID, scheduled, price)
+	}
+	return len(applied), err
+}
+
+// GetPriceImpactReport handles POST /api/reports/price-impact
+func (h *ProductHandler) GetPriceImpactReport(w http.ResponseWriter, r *http.Request) {
+	var dateRange DateRangeRequest
+	if err := h.DecodeJSON(r, &dateRange); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	startDate, endDate, err := dateRange.Parse()
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	report, err := h.DB.GetPriceImpactReport(startDate, endDate)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, report)
+}
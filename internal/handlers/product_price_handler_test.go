@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPricesAt(t *testing.T) {
+	pack, single := int64(1), int64(2)
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 9, 0, 0, 0, time.Local) }
+	timeline := []models.ProductPrice{
+		{ID: 1, UnitID: &pack, UnitName: "Pack", Price: 500, EffectiveFrom: day(1)},
+		{ID: 2, UnitID: &single, UnitName: "Single", Price: 30, EffectiveFrom: day(1)},
+		{ID: 3, UnitID: &pack, UnitName: "Pack", Price: 550, EffectiveFrom: day(10)},
+		{ID: 4, UnitName: "Loose", Price: 10, EffectiveFrom: day(2)},
+		{ID: 5, UnitID: &pack, UnitName: "Pack", Price: 600, EffectiveFrom: day(20)},
+	}
+
+	ids := func(prices []models.ProductPrice) []int64 {
+		var ids []int64
+		for _, price := range prices {
+			ids = append(ids, price.ID)
+		}
+		return ids
+	}
+
+	assert.Empty(t, pricesAt(timeline, day(1)))
+	assert.Equal(t, []int64{1, 2}, ids(pricesAt(timeline, day(2))))
+	assert.Equal(t, []int64{3, 2, 4}, ids(pricesAt(timeline, day(15))))
+	assert.Equal(t, []int64{5, 2, 4}, ids(pricesAt(timeline, day(21))), "scheduled prices count once effective")
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// validateProductUnit trims the unit name and checks the unit price and stock factor.
+// A missing stock factor defaults to 1.
+func validateProductUnit(unit *models.ProductUnit) error {
+	unit.Name = strings.TrimSpace(unit.Name)
+	if unit.Name == "" {
+		return errors.InvalidInput("Unit name is required")
+	}
+	if unit.Price < 0 {
+		return errors.InvalidInput(fmt.Sprintf("Price of unit %s must not be negative", unit.Name))
+	}
+	if unit.StockFactor < 0 {
+		return errors.InvalidInput(fmt.Sprintf("Stock factor of unit %s must be positive", unit.Name))
+	}
+	if unit.StockFactor == 0 {
+		unit.StockFactor = 1
+	}
+	return nil
+}
+
+// GetProductUnits handles GET /api/products/{id}/units
+func (h *ProductHandler) GetProductUnits(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, product.Units)
+}
+
+// CreateProductUnit handles POST /api/products/{id}/units
+func (h *ProductHandler) CreateProductUnit(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var unit models.ProductUnit
+	if err := h.DecodeJSON(r, &unit); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	unit.ProductID = product.ID
+
+	if err := h.checkProductUnit(product, &unit); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.CreateProductUnit(&unit); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.auditProductUnits(r, product)
+
+	common.RespondWithSuccess(w, http.StatusCreated, unit)
+}
+
+// UpdateProductUnit handles PUT /api/products/{id}/units/{unitId}
+//
+// Marking a unit as the default makes its price the product price. The default unit
+// cannot be unmarked, mark another unit as the default instead.
+func (h *ProductHandler) UpdateProductUnit(w http.ResponseWriter, r *http.Request) {
+	product, existing, ok := h.productUnitFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var unit models.ProductUnit
+	if err := h.DecodeJSON(r, &unit); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	unit.ID = existing.ID
+	unit.ProductID = product.ID
+	unit.CreatedAt = existing.CreatedAt
+
+	if existing.IsDefault && !unit.IsDefault {
+		h.HandleError(w, errors.InvalidInput("The default unit cannot be unmarked, mark another unit as the default instead"))
+		return
+	}
+	if err := h.checkProductUnit(product, &unit); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.UpdateProductUnit(&unit); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.auditProductUnits(r, product)
+
+	common.RespondWithSuccess(w, http.StatusOK, unit)
+}
+
+// DeleteProductUnit handles DELETE /api/products/{id}/units/{unitId}
+//
+// Past sales keep the name and stock factor of the deleted unit.
+func (h *ProductHandler) DeleteProductUnit(w http.ResponseWriter, r *http.Request) {
+	product, unit, ok := h.productUnitFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if unit.IsDefault {
+		h.HandleError(w, errors.InvalidInput("The default unit cannot be deleted, mark another unit as the default first"))
+		return
+	}
+
+	if err := h.DB.DeleteProductUnit(unit.ID); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.auditProductUnits(r, product)
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// checkProductUnit validates a unit and checks that its name is not used by another unit of the product
+func (h *ProductHandler) checkProductUnit(product *models.Product, unit *models.ProductUnit) error {
+	if err := validateProductUnit(unit); err != nil {
+		return err
+	}
+	for _, other := range product.Units {
+		if other.ID != unit.ID && strings.EqualFold(other.Name, unit.Name) {
+			return errors.InvalidInput(fmt.Sprintf("Product %s already has a unit named %s", product.Name, other.Name))
+		}
+	}
+	return nil
+}
+
+// productFromPath loads the product of the {id} path variable with its units,
+// responding with an error if it does not exist
+func (h *ProductHandler) productFromPath(w http.ResponseWriter, r *http.Request) (*models.Product, bool) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return nil, false
+	}
+
+	product, err := h.DB.GetProduct(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return nil, false
+	}
+	if product == nil {
+		h.HandleError(w, errors.NotFound("Product", id))
+		return nil, false
+	}
+	return product, true
+}
+
+// productUnitFromPath loads the product and unit of the {id} and {unitId} path variables,
+// responding with an error if either does not exist
+func (h *ProductHandler) productUnitFromPath(w http.ResponseWriter, r *http.Request) (*models.Product, *models.ProductUnit, bool) {
+	product, ok := h.productFromPath(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	unitID, err := h.ParseID(mux.Vars(r), "unitId")
+	if err != nil {
+		h.HandleError(w, err)
+		return nil, nil, false
+	}
+	for i := range product.Units {
+		if product.Units[i].ID == unitID {
+			return product, &product.Units[i], true
+		}
+	}
+	h.HandleError(w, errors.NotFound("Unit of product", unitID))
+	return nil, nil, false
+}
+
+// auditProductUnits records a change to the units of a product as an update of the product
+func (h *ProductHandler) auditProductUnits(r *http.Request, before *models.Product) {
+	after, err := h.DB.GetProduct(before.ID, false)
+	if err != nil || after == nil {
+		return
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityProduct, before.ID, before, after)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+)
+
+// includeDeletedParam reports whether the include_deleted query parameter asks for soft deleted rows
+func includeDeletedParam(r *http.Request) bool {
+	includeDeleted, _ := strconv.ParseBool(r.URL.Query().Get("include_deleted"))
+	return includeDeleted
+}
+
+// PurgeHandler handles permanently removing soft deleted rows
+type PurgeHandler struct {
+	common.BaseHandler
+}
+
+// NewPurgeHandler creates a new purge handler
+func NewPurgeHandler(db database.Service) *PurgeHandler {
+	return &PurgeHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// PurgeRequest represents the request body for purging soft deleted rows
+type PurgeRequest struct {
+	OlderThanDays int `json:"older_than_days"` // Only rows deleted at least this many days ago are purged
+}
+
+// PurgeDeleted handles POST /api/admin/purge
+func (h *PurgeHandler) PurgeDeleted(w http.ResponseWriter, r *http.Request) {
+	var request PurgeRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.OlderThanDays < 0 {
+		h.HandleError(w, errors.InvalidInput("older_than_days cannot be negative"))
+		return
+	}
+
+	result, err := h.DB.PurgeDeleted(time.Now().AddDate(0, 0, -request.OlderThanDays))
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, result)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"maya-canteen/internal/errors"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxUploadSize is the maximum size of an uploaded CSV or Excel file
+const maxUploadSize = 10 << 20 // 10 MB
+
+// readSpreadsheetUpload reads the "file" form field of a multipart request as a CSV or
+// Excel (.xlsx) file and returns its rows. For Excel files the first sheet is used.
+func readSpreadsheetUpload(r *http.Request) ([][]string, error) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return nil, errors.InvalidInput("Failed to parse form")
+	}
+
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		return nil, errors.InvalidInput("Failed to get file from form")
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(fileHeader.Filename), ".xlsx") {
+		workbook, err := excelize.OpenReader(file)
+		if err != nil {
+			return nil, errors.InvalidInput("Failed to read Excel file: " + err.Error())
+		}
+		defer workbook.Close()
+
+		rows, err := workbook.GetRows(workbook.GetSheetName(0))
+		if err != nil {
+			return nil, errors.InvalidInput("Failed to read Excel sheet: " + err.Error())
+		}
+		return rows, nil
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Optional trailing columns may be missing
+	reader.TrimLeadingSpace = true
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.InvalidInput("Failed to read CSV: " + err.Error())
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+// headerIndex maps normalized header names (lower case, spaces as underscores) to their column index
+func headerIndex(header []string) map[string]int {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, " ", "_")
+		if _, exists := index[name]; !exists {
+			index[name] = i
+		}
+	}
+	return index
+}
+
+// spreadsheetRow gives access to the cells of a row by header name
+type spreadsheetRow struct {
+	columns map[string]int
+	record  []string
+}
+
+// Get returns the trimmed value of the named column, or "" if the column or cell is missing
+func (row spreadsheetRow) Get(column string) string {
+	i, ok := row.columns[column]
+	if !ok || i >= len(row.record) {
+		return ""
+	}
+	return strings.TrimSpace(row.record[i])
+}
+
+// Has reports whether the named column is present in the header
+func (row spreadsheetRow) Has(column string) bool {
+	_, ok := row.columns[column]
+	return ok
+}
+
+// isBlankRecord reports whether every cell of the record is empty
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"maya-canteen/internal/database"
@@ -28,23 +31,83 @@ func NewTransactionHandler(db database.Service) *TransactionHandler {
 
 // TransactionRequest represents the request body for creating a transaction with products
 type TransactionRequest struct {
-	UserID          int64                   `json:"user_id"`
+	UserID          int64                   `json:"user_id"` // Omitted for walk-in guest sales
 	Amount          float64                 `json:"amount"`
+	CompanyShare    float64                 `json:"company_share"` // Part of the amount paid by the employer
 	Description     string                  `json:"description"`
 	TransactionType string                  `json:"transaction_type"`
+	PaymentMethod   string                  `json:"payment_method"` // "account" (default) or "cash", which needs an open cash session
 	Products        []TransactionProductDTO `json:"products,omitempty"`
 }
 
+// RefundRequest represents the request body for refunding products of a purchase
+type RefundRequest struct {
+	Lines  []models.RefundLine `json:"lines"`
+	Reason string              `json:"reason"`
+}
+
 // TransactionProductDTO represents a product in a transaction request
 type TransactionProductDTO struct {
 	ProductID    int64   `json:"product_id"`
 	ProductName  string  `json:"product_name"`
 	Quantity     int     `json:"quantity"`
 	UnitPrice    float64 `json:"unit_price"`
-	IsSingleUnit bool    `json:"is_single_unit"`
+	IsSingleUnit bool    `json:"is_single_unit"` // Deprecated: use UnitID
+	UnitID       *int64  `json:"unit_id"`        // Defaults to the product's default unit
+}
+
+// creatableTransactionTypes lists the transaction types that can be created directly. Refunds,
+// transfers, top-ups and their bonuses are created through their own endpoints.
+var creatableTransactionTypes = []string{
+	models.TransactionTypePurchase,
+	models.TransactionTypeDeposit,
+	models.TransactionTypeAdjustment,
+	models.TransactionTypeWriteOff,
+}
+
+// transferLegChangeMessage explains why one side of a balance transfer cannot be updated or
+// deleted on its own
+const transferLegChangeMessage = "Transfer transactions cannot be changed individually, create a transfer back instead"
+
+// parseTransactionType normalizes the case and spacing of a requested transaction type and
+// checks that transactions of the type can be created directly
+func parseTransactionType(value string) (string, error) {
+	transactionType := strings.ToLower(strings.TrimSpace(value))
+	if !slices.Contains(creatableTransactionTypes, transactionType) {
+		return "", errors.InvalidInput(fmt.Sprintf("Invalid transaction_type %q. Expected one of %s", value, strings.Join(creatableTransactionTypes, ", ")))
+	}
+	return transactionType, nil
+}
+
+// validateTransactionUpdate checks an update of the transaction before like a new transaction.
+// Updates keep the payment method and company share, so these have to suit a new type.
+// Refunds must keep the amount and user of the products they return.
+func validateTransactionUpdate(before, transaction *models.Transaction) error {
+	if transaction.Amount <= 0 {
+		return errors.InvalidInput("amount must be greater than zero")
+	}
+	if before.TransactionType == models.TransactionTypeRefund && (transaction.Amount != before.Amount || transaction.UserID != before.UserID) {
+		return errors.InvalidInput("The amount and user of a refund cannot be changed, refund the products instead")
+	}
+	cash := before.PaymentMethod == models.PaymentMethodCash
+	if transaction.TransactionType != before.TransactionType {
+		if before.CompanyShare > 0 && transaction.TransactionType != models.TransactionTypePurchase {
+			return errors.InvalidInput("Only purchases can have a company share")
+		}
+		if cash && transaction.TransactionType != models.TransactionTypePurchase && transaction.TransactionType != models.TransactionTypeDeposit {
+			return errors.InvalidInput("Only purchases and deposits can be paid in cash")
+		}
+	}
+	if transaction.UserID == 0 && (transaction.TransactionType != models.TransactionTypePurchase || !cash) {
+		return errors.InvalidInput("Transactions without a user_id must be guest purchases paid in cash")
+	}
+	return nil
 }
 
 // CreateTransaction handles POST /api/transactions
+//
+// A purchase without a user_id is a walk-in guest sale. It must be paid in cash in an open
+// cash session, and is left out of user balances but counted in product sales.
 func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 	var request TransactionRequest
 
@@ -54,17 +117,77 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	transactionType, err := parseTransactionType(request.TransactionType)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	request.TransactionType = transactionType
+	if request.Amount <= 0 {
+		h.HandleError(w, errors.InvalidInput("amount must be greater than zero"))
+		return
+	}
+	if request.CompanyShare < 0 || request.CompanyShare > request.Amount {
+		h.HandleError(w, errors.InvalidInput("company_share must be between 0 and the amount"))
+		return
+	}
+	if request.CompanyShare > 0 && request.TransactionType != models.TransactionTypePurchase {
+		h.HandleError(w, errors.InvalidInput("Only purchases can have a company share"))
+		return
+	}
+
+	// Sales without a user are walk-in guests paying cash
+	guest := request.UserID == 0
+	if request.PaymentMethod == "" {
+		request.PaymentMethod = models.PaymentMethodAccount
+		if guest {
+			request.PaymentMethod = models.PaymentMethodCash
+		}
+	}
+	if request.PaymentMethod != models.PaymentMethodAccount && request.PaymentMethod != models.PaymentMethodCash {
+		h.HandleError(w, errors.InvalidInput("Invalid payment_method. Expected account or cash"))
+		return
+	}
+	if request.PaymentMethod == models.PaymentMethodCash && request.TransactionType != models.TransactionTypePurchase && request.TransactionType != models.TransactionTypeDeposit {
+		h.HandleError(w, errors.InvalidInput("Only purchases and deposits can be paid in cash"))
+		return
+	}
+	if guest && (request.TransactionType != models.TransactionTypePurchase || request.PaymentMethod != models.PaymentMethodCash || request.CompanyShare > 0) {
+		h.HandleError(w, errors.InvalidInput("Transactions without a user_id must be guest purchases paid in cash"))
+		return
+	}
+
+	// Transactions are linked to the cashier's open session, which cash transactions need
+	session, err := h.DB.GetOpenCashSession(common.RequestActor(r))
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if session == nil && request.PaymentMethod == models.PaymentMethodCash {
+		h.HandleError(w, errors.InvalidInput("Open a cash session before recording cash transactions"))
+		return
+	}
+
 	// Create the transaction model
 	transaction := models.Transaction{
 		UserID:          request.UserID,
 		Amount:          request.Amount,
+		CompanyShare:    request.CompanyShare,
 		Description:     request.Description,
 		TransactionType: request.TransactionType,
+		PaymentMethod:   request.PaymentMethod,
+	}
+	if session != nil {
+		transaction.CashSessionID = &session.ID
 	}
 
-	// If it's a deposit or has no products, use the simple transaction creation
-	if request.TransactionType == "deposit" || len(request.Products) == 0 {
+	// Only purchases have products, others use the simple transaction creation
+	if request.TransactionType != models.TransactionTypePurchase || len(request.Products) == 0 {
 		if err := h.DB.CreateTransaction(&transaction); err != nil {
+			if errors.Is(err, database.ErrUserOffboarded) || errors.Is(err, database.ErrCreditLimitExceeded) {
+				h.HandleError(w, errors.InvalidInput(err.Error()))
+				return
+			}
 			h.HandleError(w, errors.Internal(err))
 			return
 		}
@@ -78,24 +201,89 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 				Quantity:     productDTO.Quantity,
 				UnitPrice:    productDTO.UnitPrice,
 				IsSingleUnit: productDTO.IsSingleUnit,
+				UnitID:       productDTO.UnitID,
 			}
 			transactionProducts = append(transactionProducts, product)
 		}
 
 		// Create transaction with products
 		if err := h.DB.CreateTransactionWithProducts(&transaction, transactionProducts); err != nil {
+			if errors.Is(err, database.ErrInvalidProductUnit) || errors.Is(err, database.ErrUserOffboarded) || errors.Is(err, database.ErrCreditLimitExceeded) {
+				h.HandleError(w, errors.InvalidInput(err.Error()))
+				return
+			}
 			log.Errorf("Error creating transaction with products: %v", err)
 			h.HandleError(w, errors.Internal(err))
 			return
 		}
 	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, transaction.ID, nil, transaction)
 
 	common.RespondWithSuccess(w, http.StatusCreated, transaction)
 }
 
+// RefundTransaction handles POST /api/transactions/{id}/refund
+//
+// Returns quantities of product lines of a purchase with a linked refund transaction. Refunds
+// of cash purchases are paid out of the cashier's open cash session.
+func (h *TransactionHandler) RefundTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	var request RefundRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	purchase, err := h.DB.GetTransaction(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if purchase == nil {
+		h.HandleError(w, errors.NotFound("Transaction", id))
+		return
+	}
+
+	session, err := h.DB.GetOpenCashSession(common.RequestActor(r))
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if session == nil && purchase.PaymentMethod == models.PaymentMethodCash {
+		h.HandleError(w, errors.InvalidInput("Open a cash session before refunding a cash purchase"))
+		return
+	}
+	var cashSessionID *int64
+	if session != nil {
+		cashSessionID = &session.ID
+	}
+
+	refund, err := h.DB.RefundTransaction(id, request.Lines, request.Reason, cashSessionID)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidRefund) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if refund == nil {
+		h.HandleError(w, errors.NotFound("Transaction", id))
+		return
+	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, refund.Transaction.ID, nil, refund.Transaction)
+
+	common.RespondWithSuccess(w, http.StatusCreated, refund)
+}
+
 // GetAllTransactions handles GET /api/transactions
 func (h *TransactionHandler) GetAllTransactions(w http.ResponseWriter, r *http.Request) {
-	transactions, err := h.DB.GetAllTransactions()
+	transactions, err := h.DB.GetAllTransactions(includeDeletedParam(r))
 	if err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
@@ -144,7 +332,7 @@ func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	transaction, err := h.DB.GetTransaction(id)
+	transaction, err := h.DB.GetTransaction(id, includeDeletedParam(r))
 	if err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
@@ -155,9 +343,9 @@ func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Get associated products if this is a purchase transaction
+	// Get associated products if this is a purchase or the refund of one
 	var transactionProducts []models.TransactionProduct = nil
-	if transaction.TransactionType == "purchase" {
+	if transaction.TransactionType == models.TransactionTypePurchase || transaction.TransactionType == models.TransactionTypeRefund {
 		transactionProducts, err = h.DB.GetTransactionProducts(id)
 		if err != nil {
 			h.HandleError(w, errors.Internal(err))
@@ -193,11 +381,56 @@ func (h *TransactionHandler) UpdateTransaction(w http.ResponseWriter, r *http.Re
 	}
 	transaction.ID = id
 
+	before, err := h.DB.GetTransaction(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Transaction", id))
+		return
+	}
+
+	if before.TransactionType == models.TransactionTypeTransfer {
+		h.HandleError(w, errors.InvalidInput(transferLegChangeMessage))
+		return
+	}
+
+	// Refunds keep their type, others can change to types that can be created directly
+	if slices.Contains(creatableTransactionTypes, before.TransactionType) {
+		transactionType, err := parseTransactionType(transaction.TransactionType)
+		if err != nil {
+			h.HandleError(w, err)
+			return
+		}
+		transaction.TransactionType = transactionType
+	} else if transactionType := strings.ToLower(strings.TrimSpace(transaction.TransactionType)); transactionType != before.TransactionType {
+		h.HandleError(w, errors.InvalidInput(fmt.Sprintf("The type of a %s cannot be changed", before.TransactionType)))
+		return
+	} else {
+		transaction.TransactionType = transactionType
+	}
+	if err := validateTransactionUpdate(before, &transaction); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
 	if err := h.DB.UpdateTransaction(&transaction); err != nil {
+		if errors.Is(err, database.ErrUserOffboarded) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
 		h.HandleError(w, errors.Internal(err))
 		return
 	}
 
+	// Audit the stored state, since updates do not write every column
+	after, err := h.DB.GetTransaction(id, false)
+	if err != nil || after == nil {
+		after = &transaction
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityTransaction, id, before, after)
+
 	common.RespondWithSuccess(w, http.StatusOK, transaction)
 }
 
@@ -210,14 +443,72 @@ func (h *TransactionHandler) DeleteTransaction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	before, err := h.DB.GetTransaction(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Transaction", id))
+		return
+	}
+	if before.TransactionType == models.TransactionTypeTransfer {
+		h.HandleError(w, errors.InvalidInput(transferLegChangeMessage))
+		return
+	}
+
 	if err := h.DB.DeleteTransaction(id); err != nil {
+		if errors.Is(err, database.ErrPurchaseRefunded) || errors.Is(err, database.ErrUserOffboarded) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
 		h.HandleError(w, errors.Internal(err))
 		return
 	}
+	h.Audit(r, models.AuditActionDelete, models.AuditEntityTransaction, id, before, nil)
 
 	common.RespondWithSuccess(w, http.StatusNoContent, nil)
 }
 
+// RestoreTransaction handles POST /api/transactions/{id}/restore
+func (h *TransactionHandler) RestoreTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	before, err := h.DB.GetTransaction(id, true)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	restored, err := h.DB.RestoreTransaction(id)
+	if err != nil {
+		if errors.Is(err, database.ErrUserOffboarded) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if !restored {
+		h.HandleError(w, errors.NotFound("Deleted transaction", id))
+		return
+	}
+
+	transaction, err := h.DB.GetTransaction(id, false)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionRestore, models.AuditEntityTransaction, id, before, transaction)
+
+	common.RespondWithSuccess(w, http.StatusOK, transaction)
+}
+
 // GetTransactionsByUserID handles GET /api/users/{user_id}/transactions
 func (h *TransactionHandler) GetTransactionsByUserID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -262,6 +553,25 @@ type DateRangeRequest struct {
 	EndDate   string `json:"endDate"`
 }
 
+// Parse parses the start and end dates (YYYY-MM-DD) and validates the range
+func (d DateRangeRequest) Parse() (time.Time, time.Time, error) {
+	startDate, err := time.Parse("2006-01-02", d.StartDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.InvalidInput("Invalid start date format. Expected YYYY-MM-DD")
+	}
+
+	endDate, err := time.Parse("2006-01-02", d.EndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.InvalidInput("Invalid end date format. Expected YYYY-MM-DD")
+	}
+
+	if endDate.Before(startDate) {
+		return time.Time{}, time.Time{}, errors.InvalidInput("End date cannot be before start date")
+	}
+
+	return startDate, endDate, nil
+}
+
 // GetTransactionsByDateRange handles POST /api/transactions/date-range
 func (h *TransactionHandler) GetTransactionsByDateRange(w http.ResponseWriter, r *http.Request) {
 	var dateRange DateRangeRequest
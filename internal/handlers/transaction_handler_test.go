@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"testing"
+
+	"maya-canteen/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTransactionType(t *testing.T) {
+	transactionType, err := parseTransactionType(" Deposit ")
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionTypeDeposit, transactionType)
+
+	transactionType, err = parseTransactionType("write-off")
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionTypeWriteOff, transactionType)
+
+	for _, value := range []string{"", "deposits", "withdrawal", "refund", "transfer", "top-up", "bonus"} {
+		_, err := parseTransactionType(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestTransactionTypeSign(t *testing.T) {
+	for _, transactionType := range models.TransactionTypes {
+		assert.NotZero(t, models.TransactionTypeSign(transactionType), transactionType)
+	}
+	assert.Equal(t, 1, models.TransactionTypeSign(models.TransactionTypeDeposit))
+	assert.Equal(t, -1, models.TransactionTypeSign(models.TransactionTypePurchase))
+	assert.Zero(t, models.TransactionTypeSign("Deposit"))
+}
+
+func TestValidateTransactionUpdate(t *testing.T) {
+	purchase := &models.Transaction{UserID: 1, Amount: 100, TransactionType: models.TransactionTypePurchase, PaymentMethod: models.PaymentMethodAccount}
+	assert.NoError(t, validateTransactionUpdate(purchase, &models.Transaction{UserID: 1, Amount: 80, TransactionType: models.TransactionTypeAdjustment}))
+	for _, amount := range []float64{0, -100} {
+		assert.Error(t, validateTransactionUpdate(purchase, &models.Transaction{UserID: 1, Amount: amount, TransactionType: models.TransactionTypePurchase}), amount)
+	}
+	assert.Error(t, validateTransactionUpdate(purchase, &models.Transaction{Amount: 100, TransactionType: models.TransactionTypePurchase}), "account purchases need a user")
+
+	subsidised := &models.Transaction{UserID: 1, Amount: 100, CompanyShare: 50, TransactionType: models.TransactionTypePurchase, PaymentMethod: models.PaymentMethodAccount}
+	assert.Error(t, validateTransactionUpdate(subsidised, &models.Transaction{UserID: 1, Amount: 100, TransactionType: models.TransactionTypeDeposit}))
+
+	guest := &models.Transaction{Amount: 100, TransactionType: models.TransactionTypePurchase, PaymentMethod: models.PaymentMethodCash}
+	assert.NoError(t, validateTransactionUpdate(guest, &models.Transaction{Amount: 120, TransactionType: models.TransactionTypePurchase}))
+	assert.Error(t, validateTransactionUpdate(guest, &models.Transaction{Amount: 100, TransactionType: models.TransactionTypeDeposit}))
+	assert.Error(t, validateTransactionUpdate(guest, &models.Transaction{UserID: 1, Amount: 100, TransactionType: models.TransactionTypeAdjustment}), "cash cannot be adjusted")
+
+	refund := &models.Transaction{UserID: 1, Amount: 100, TransactionType: models.TransactionTypeRefund, PaymentMethod: models.PaymentMethodAccount}
+	assert.NoError(t, validateTransactionUpdate(refund, &models.Transaction{UserID: 1, Amount: 100, TransactionType: models.TransactionTypeRefund, Description: "Wrong order"}))
+	assert.Error(t, validateTransactionUpdate(refund, &models.Transaction{UserID: 1, Amount: 90, TransactionType: models.TransactionTypeRefund}))
+	assert.Error(t, validateTransactionUpdate(refund, &models.Transaction{UserID: 2, Amount: 100, TransactionType: models.TransactionTypeRefund}))
+
+	cashRefund := &models.Transaction{UserID: 1, Amount: 100, CompanyShare: 20, TransactionType: models.TransactionTypeRefund, PaymentMethod: models.PaymentMethodCash}
+	assert.NoError(t, validateTransactionUpdate(cashRefund, &models.Transaction{UserID: 1, Amount: 100, TransactionType: models.TransactionTypeRefund, Description: "Cold meal"}))
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// importTransactionTypes lists the transaction types accepted by the bulk import
+var importTransactionTypes = []string{
+	models.TransactionTypePurchase,
+	models.TransactionTypeDeposit,
+	models.TransactionTypeAdjustment,
+	models.TransactionTypeWriteOff,
+}
+
+// importDateFormats lists the accepted formats of the date column, in local time
+var importDateFormats = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC3339,
+}
+
+// ImportTransactions handles POST /api/transactions/import?dry_run=true
+//
+// The uploaded CSV or Excel file must have the columns employee_id, date, type and
+// amount, and may have description and products. Products are written as
+// "product_id:quantity" pairs separated by ";", with an optional unit name suffix
+// for units other than the default, e.g. "3:2;7:1:single". When products are given
+// the amount may be left empty and is computed from the current unit prices.
+//
+// In dry-run mode the file is only validated. Otherwise all rows are imported in a
+// single database transaction, and nothing is imported if any row is invalid.
+func (h *TransactionHandler) ImportTransactions(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	records, err := readSpreadsheetUpload(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if len(records) == 0 {
+		h.HandleError(w, errors.InvalidInput("The file is empty"))
+		return
+	}
+
+	columns := headerIndex(records[0])
+	for _, required := range []string{"employee_id", "date", "type", "amount"} {
+		if _, ok := columns[required]; !ok {
+			h.HandleError(w, errors.InvalidInput("Missing required column: "+required))
+			return
+		}
+	}
+
+	validator := newTransactionImportValidator(h)
+	result := models.TransactionImportResult{
+		DryRun: dryRun,
+		Errors: make([]models.TransactionImportRowError, 0),
+	}
+
+	var rows []models.TransactionImportRow
+	for i, record := range records[1:] {
+		line := i + 2 // Header is line 1
+		if isBlankRecord(record) {
+			continue
+		}
+		result.TotalRows++
+
+		row, rowErrors := validator.validate(spreadsheetRow{columns: columns, record: record}, line)
+		if len(rowErrors) > 0 {
+			result.InvalidRows++
+			result.Errors = append(result.Errors, models.TransactionImportRowError{
+				Line:       line,
+				EmployeeID: row.EmployeeID,
+				Errors:     rowErrors,
+			})
+			continue
+		}
+		result.ValidRows++
+		result.TotalAmount += row.Transaction.Amount
+		rows = append(rows, row)
+	}
+
+	if dryRun {
+		result.Rows = rows
+		common.RespondWithJSON(w, http.StatusOK, result)
+		return
+	}
+
+	if result.InvalidRows > 0 || result.ValidRows == 0 {
+		common.RespondWithJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	result.BatchReference = fmt.Sprintf("IMPORT-%d", time.Now().Unix())
+	if err := h.DB.ImportTransactions(rows, result.BatchReference); err != nil {
+		if errors.Is(err, database.ErrUserOffboarded) || errors.Is(err, database.ErrCreditLimitExceeded) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		log.Errorf("Error importing transactions: %v", err)
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	for _, row := range rows {
+		h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, row.Transaction.ID, nil, row.Transaction)
+	}
+	result.Imported = len(rows)
+	result.Rows = rows
+
+	common.RespondWithJSON(w, http.StatusCreated, result)
+}
+
+// transactionImportValidator validates import rows, caching user and product lookups
+type transactionImportValidator struct {
+	h        *TransactionHandler
+	users    map[string]*models.User
+	products map[int64]*models.Product
+}
+
+func newTransactionImportValidator(h *TransactionHandler) *transactionImportValidator {
+	return &transactionImportValidator{
+		h:        h,
+		users:    make(map[string]*models.User),
+		products: make(map[int64]*models.Product),
+	}
+}
+
+func (v *transactionImportValidator) user(employeeID string) (*models.User, error) {
+	if user, ok := v.users[employeeID]; ok {
+		return user, nil
+	}
+	user, err := v.h.DB.GetUserByEmployeeID(employeeID)
+	if err != nil {
+		return nil, err
+	}
+	v.users[employeeID] = user
+	return user, nil
+}
+
+func (v *transactionImportValidator) product(id int64) (*models.Product, error) {
+	if product, ok := v.products[id]; ok {
+		return product, nil
+	}
+	product, err := v.h.DB.GetProduct(id, false)
+	if err != nil {
+		return nil, err
+	}
+	v.products[id] = product
+	return product, nil
+}
+
+// validate converts a spreadsheet row into an import row and returns its validation errors
+func (v *transactionImportValidator) validate(row spreadsheetRow, line int) (models.TransactionImportRow, []string) {
+	var rowErrors []string
+	importRow := models.TransactionImportRow{
+		Line:       line,
+		EmployeeID: row.Get("employee_id"),
+	}
+	transaction := &importRow.Transaction
+	transaction.Description = row.Get("description")
+
+	if importRow.EmployeeID == "" {
+		rowErrors = append(rowErrors, "employee_id is required")
+	} else if user, err := v.user(importRow.EmployeeID); err != nil {
+		rowErrors = append(rowErrors, "failed to look up employee: "+err.Error())
+	} else if user == nil {
+		rowErrors = append(rowErrors, fmt.Sprintf("no user with employee_id %s", importRow.EmployeeID))
+	} else {
+		transaction.UserID = user.ID
+	}
+
+	if createdAt, err := parseImportDate(row.Get("date")); err != nil {
+		rowErrors = append(rowErrors, err.Error())
+	} else {
+		transaction.CreatedAt = createdAt
+	}
+
+	transaction.TransactionType = strings.ToLower(strings.TrimSpace(row.Get("type")))
+	if !slices.Contains(importTransactionTypes, transaction.TransactionType) {
+		rowErrors = append(rowErrors, fmt.Sprintf("invalid type %q, expected one of %s", row.Get("type"), strings.Join(importTransactionTypes, ", ")))
+	}
+
+	var productTotal float64
+	if productsCell := row.Get("products"); productsCell != "" {
+		if transaction.TransactionType != models.TransactionTypePurchase {
+			rowErrors = append(rowErrors, "products are only allowed on purchases")
+		}
+		products, total, productErrors := v.parseProducts(productsCell)
+		rowErrors = append(rowErrors, productErrors...)
+		importRow.Products = products
+		productTotal = total
+	}
+
+	amountCell := row.Get("amount")
+	switch {
+	case amountCell == "" && len(importRow.Products) > 0:
+		transaction.Amount = productTotal
+	case amountCell == "":
+		rowErrors = append(rowErrors, "amount is required")
+	default:
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(amountCell, ",", ""), 64)
+		if err != nil || amount <= 0 {
+			rowErrors = append(rowErrors, fmt.Sprintf("invalid amount %q, expected a positive number", amountCell))
+			break
+		}
+		if len(importRow.Products) > 0 && math.Abs(amount-productTotal) > 0.01 {
+			rowErrors = append(rowErrors, fmt.Sprintf("amount %.2f does not match the products total %.2f", amount, productTotal))
+		}
+		transaction.Amount = amount
+	}
+
+	return importRow, rowErrors
+}
+
+// parseProducts parses "product_id:quantity[:unit]" pairs separated by ";"
+func (v *transactionImportValidator) parseProducts(cell string) ([]models.TransactionProduct, float64, []string) {
+	var products []models.TransactionProduct
+	var rowErrors []string
+	var total float64
+
+	for _, entry := range strings.Split(cell, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			rowErrors = append(rowErrors, fmt.Sprintf("invalid product %q, expected product_id:quantity[:unit]", entry))
+			continue
+		}
+
+		productID, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("invalid product id %q", parts[0]))
+			continue
+		}
+		quantity, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || quantity <= 0 {
+			rowErrors = append(rowErrors, fmt.Sprintf("invalid quantity %q for product %d", parts[1], productID))
+			continue
+		}
+
+		product, err := v.product(productID)
+		if err != nil {
+			rowErrors = append(rowErrors, "failed to look up product: "+err.Error())
+			continue
+		}
+		if product == nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("no product with id %d", productID))
+			continue
+		}
+
+		sold := models.TransactionProduct{
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			Quantity:    quantity,
+			UnitPrice:   product.Price,
+		}
+		if len(parts) == 3 {
+			unitName := strings.TrimSpace(parts[2])
+			index := slices.IndexFunc(product.Units, func(unit models.ProductUnit) bool {
+				return strings.EqualFold(unit.Name, unitName)
+			})
+			if index < 0 {
+				rowErrors = append(rowErrors, fmt.Sprintf("product %s is not sold in unit %q", product.Name, unitName))
+				continue
+			}
+			sold.UnitID = &product.Units[index].ID
+			sold.UnitPrice = product.Units[index].Price
+		}
+
+		products = append(products, sold)
+		total += sold.UnitPrice * float64(quantity)
+	}
+
+	return products, total, rowErrors
+}
+
+// parseImportDate parses the date column using the accepted import date formats
+func parseImportDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("date is required")
+	}
+	for _, format := range importDateFormats {
+		if date, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			if date.After(time.Now()) {
+				return time.Time{}, fmt.Errorf("date %q is in the future", value)
+			}
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or YYYY-MM-DD HH:MM", value)
+}
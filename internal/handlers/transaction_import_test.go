@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImportDate(t *testing.T) {
+	t.Run("AcceptsDateAndDateTime", func(t *testing.T) {
+		date, err := parseImportDate("2024-03-05")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local), date)
+
+		date, err = parseImportDate("2024-03-05 13:45")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 3, 5, 13, 45, 0, 0, time.Local), date)
+	})
+
+	t.Run("RejectsInvalidAndFutureDates", func(t *testing.T) {
+		_, err := parseImportDate("")
+		assert.Error(t, err)
+
+		_, err = parseImportDate("05/03/2024")
+		assert.Error(t, err)
+
+		_, err = parseImportDate(time.Now().AddDate(0, 0, 2).Format("2006-01-02"))
+		assert.Error(t, err)
+	})
+}
+
+func TestSpreadsheetRow(t *testing.T) {
+	columns := headerIndex([]string{"\ufeffEmployee ID", "Date", " amount "})
+	row := spreadsheetRow{columns: columns, record: []string{" 1023 ", "2024-03-05"}}
+
+	assert.Equal(t, "1023", row.Get("employee_id"))
+	assert.Equal(t, "2024-03-05", row.Get("date"))
+	assert.Equal(t, "", row.Get("amount"), "missing trailing cell should be empty")
+	assert.True(t, row.Has("amount"))
+	assert.False(t, row.Has("products"))
+	assert.True(t, isBlankRecord([]string{"", "  "}))
+}
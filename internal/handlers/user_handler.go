@@ -1,15 +1,16 @@
 package handlers
 
 import (
-	"encoding/csv"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"maya-canteen/internal/database"
 	"maya-canteen/internal/errors"
 	"maya-canteen/internal/handlers/common"
 	"maya-canteen/internal/models"
+	"maya-canteen/internal/phone"
 
 	"github.com/gorilla/mux"
 )
@@ -33,18 +34,23 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		h.HandleError(w, err)
 		return
 	}
+	if user.CreditLimit < 0 {
+		h.HandleError(w, errors.InvalidInput("credit_limit must not be negative"))
+		return
+	}
 
 	if err := h.DB.CreateUser(&user); err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
 	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityUser, user.ID, nil, user)
 
 	common.RespondWithSuccess(w, http.StatusCreated, user)
 }
 
 // GetAllUsers handles GET /api/users
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.DB.GetAllUsers()
+	users, err := h.DB.GetAllUsers(includeDeletedParam(r))
 	if err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
@@ -85,18 +91,48 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user models.User
-	if err := h.DecodeJSON(r, &user); err != nil {
+	// The credit limit is only changed when the request sets it
+	var request struct {
+		models.User
+		CreditLimit *float64 `json:"credit_limit"`
+	}
+	if err := h.DecodeJSON(r, &request); err != nil {
 		h.HandleError(w, err)
 		return
 	}
+	user := request.User
 	user.ID = id
 
+	before, err := h.DB.GetUserByID(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil || before.DeletedAt != nil {
+		h.HandleError(w, errors.NotFound("User", id))
+		return
+	}
+	user.CreditLimit = before.CreditLimit
+	if request.CreditLimit != nil {
+		if *request.CreditLimit < 0 {
+			h.HandleError(w, errors.InvalidInput("credit_limit must not be negative"))
+			return
+		}
+		user.CreditLimit = *request.CreditLimit
+	}
+
 	if err := h.DB.UpdateUser(&user); err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
 	}
 
+	// Audit the stored state, since updates do not write every column
+	after, err := h.DB.GetUserByID(id)
+	if err != nil || after == nil {
+		after = &user
+	}
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityUser, id, before, after)
+
 	common.RespondWithSuccess(w, http.StatusOK, user)
 }
 
@@ -109,105 +145,279 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := h.DB.GetUserByID(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil || before.DeletedAt != nil {
+		h.HandleError(w, errors.NotFound("User", id))
+		return
+	}
+
 	if err := h.DB.DeleteUser(id); err != nil {
 		h.HandleError(w, errors.Internal(err))
 		return
 	}
+	h.Audit(r, models.AuditActionDelete, models.AuditEntityUser, id, before, nil)
 
 	common.RespondWithSuccess(w, http.StatusNoContent, nil)
 }
 
-// CSVUploadResponse represents the response for CSV upload
-type CSVUploadResponse struct {
-	Success int      `json:"success"`
-	Failed  int      `json:"failed"`
-	Errors  []string `json:"errors"`
-}
-
-// UploadUserCSV handles the CSV upload and creates users from it
-func (h *UserHandler) UploadUserCSV(w http.ResponseWriter, r *http.Request) {
-	// Parse the multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10 MB max
+// RestoreUser handles POST /api/users/{id}/restore
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := h.ParseID(vars, "id")
 	if err != nil {
-		common.RespondWithError(w, http.StatusBadRequest, "Failed to parse form")
+		h.HandleError(w, err)
 		return
 	}
 
-	file, _, err := r.FormFile("file")
+	before, err := h.DB.GetUserByID(id)
 	if err != nil {
-		common.RespondWithError(w, http.StatusBadRequest, "Failed to get file from form")
+		h.HandleError(w, errors.Internal(err))
 		return
 	}
-	defer file.Close()
 
-	// Read CSV
-	reader := csv.NewReader(file)
+	restored, err := h.DB.RestoreUser(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if !restored {
+		h.HandleError(w, errors.NotFound("Deleted user", id))
+		return
+	}
 
-	// Read header
-	header, err := reader.Read()
+	user, err := h.DB.GetUserByID(id)
 	if err != nil {
-		common.RespondWithError(w, http.StatusBadRequest, "Failed to read CSV header")
+		h.HandleError(w, errors.Internal(err))
 		return
 	}
+	h.Audit(r, models.AuditActionRestore, models.AuditEntityUser, id, before, user)
 
-	// Validate header
-	expectedHeaders := []string{"name", "employee_id", "department", "phone"}
-	if !validateHeaders(header, expectedHeaders) {
-		common.RespondWithError(w, http.StatusBadRequest, "Invalid CSV headers. Expected: name, employee_id, department, phone")
+	common.RespondWithSuccess(w, http.StatusOK, user)
+}
+
+// CSVUploadResponse represents the response for CSV upload
+type CSVUploadResponse struct {
+	Success int             `json:"success"`
+	Failed  int             `json:"failed"`
+	Errors  []string        `json:"errors"`
+	DryRun  bool            `json:"dry_run"`
+	Mode    string          `json:"mode"`
+	Created int             `json:"created"`
+	Updated int             `json:"updated"`
+	Rows    []UserImportRow `json:"rows"`
+}
+
+// UserImportRow represents the planned or applied result of a single CSV row
+type UserImportRow struct {
+	Line   int         `json:"line"`
+	Action string      `json:"action"` // "create", "update" or "error"
+	User   models.User `json:"user"`
+	Errors []string    `json:"errors,omitempty"`
+
+	before *models.User // Existing user of an update, for the audit log
+}
+
+const (
+	userImportModeCreate = "create"
+	userImportModeUpsert = "upsert"
+)
+
+// UploadUserCSV handles POST /api/users/upload-csv?mode=create|upsert&dry_run=true
+//
+// Columns are matched by header name: name and employee_id are required, while
+// department, phone, active and credit_limit are optional. In create mode (the default)
+// existing employee IDs are rejected, while upsert mode updates them and only
+// overwrites the columns that have a value. Employee IDs of deleted users are rejected
+// in both modes. Phones are normalized to E.164. The file is imported as a whole: if any
+// row has an error nothing is saved. In dry-run mode the planned changes are returned
+// without saving anything.
+func (h *UserHandler) UploadUserCSV(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = userImportModeCreate
+	}
+	if mode != userImportModeCreate && mode != userImportModeUpsert {
+		h.HandleError(w, errors.InvalidInput("Invalid mode. Expected create or upsert"))
 		return
 	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	records, err := readSpreadsheetUpload(r)
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if len(records) == 0 {
+		h.HandleError(w, errors.InvalidInput("The file is empty"))
+		return
+	}
+
+	columns := headerIndex(records[0])
+	for _, required := range []string{"name", "employee_id"} {
+		if _, ok := columns[required]; !ok {
+			h.HandleError(w, errors.InvalidInput("Missing required column: "+required))
+			return
+		}
+	}
 
 	response := CSVUploadResponse{
-		Success: 0,
-		Failed:  0,
-		Errors:  make([]string, 0),
+		Errors: make([]string, 0),
+		DryRun: dryRun,
+		Mode:   mode,
+		Rows:   make([]UserImportRow, 0),
 	}
 
-	// Read and process each row
-	lineNum := 1 // Start from 1 as header is line 0
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
+	// Employee IDs stay taken by deleted users until they are purged
+	users, err := h.DB.GetAllUsers(true)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	deleted := make(map[string]bool)
+	for _, user := range users {
+		if user.DeletedAt != nil {
+			deleted[user.EmployeeId] = true
 		}
-		if err != nil {
-			response.Failed++
-			response.Errors = append(response.Errors, fmt.Sprintf("Line %d: Failed to read row", lineNum))
+	}
+
+	seen := make(map[string]int)
+	for i, record := range records[1:] {
+		line := i + 2 // Header is line 1
+		if isBlankRecord(record) {
 			continue
 		}
 
-		// Create user from CSV record
-		user := models.User{
-			Name:       record[0],
-			EmployeeId: record[1],
-			Department: record[2],
-			Phone:      record[3],
+		row := h.planUserImportRow(spreadsheetRow{columns: columns, record: record}, line, mode, deleted)
+		if firstLine, duplicate := seen[row.User.EmployeeId]; duplicate && row.User.EmployeeId != "" {
+			row.Action = "error"
+			row.Errors = append(row.Errors, fmt.Sprintf("employee_id %s is repeated from line %d", row.User.EmployeeId, firstLine))
+		} else {
+			seen[row.User.EmployeeId] = line
 		}
 
-		// Attempt to create the user
-		err = h.DB.CreateUser(&user)
-		if err != nil {
-			response.Failed++
-			response.Errors = append(response.Errors, fmt.Sprintf("Line %d: %s", lineNum, err.Error()))
-		} else {
+		switch row.Action {
+		case "create":
+			response.Success++
+			response.Created++
+		case "update":
 			response.Success++
+			response.Updated++
+		default:
+			response.Failed++
+			response.Errors = append(response.Errors, fmt.Sprintf("Line %d: %s", line, strings.Join(row.Errors, "; ")))
 		}
+		response.Rows = append(response.Rows, row)
+	}
+	if dryRun {
+		common.RespondWithJSON(w, http.StatusOK, response)
+		return
+	}
+	if response.Failed > 0 {
+		response.Success, response.Created, response.Updated = 0, 0, 0
+		response.Errors = append(response.Errors, "No users were imported, fix the errors and upload the file again")
+		common.RespondWithJSON(w, http.StatusOK, response)
+		return
+	}
 
-		lineNum++
+	imported := make([]*models.User, len(response.Rows))
+	for i := range response.Rows {
+		imported[i] = &response.Rows[i].User
+	}
+	if err := h.DB.ImportUsers(imported); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	for _, row := range response.Rows {
+		action := models.AuditActionCreate
+		if row.Action == "update" {
+			action = models.AuditActionUpdate
+		}
+		h.Audit(r, action, models.AuditEntityUser, row.User.ID, row.before, row.User)
 	}
 
 	common.RespondWithJSON(w, http.StatusOK, response)
 }
 
-// validateHeaders checks if the CSV headers match the expected headers
-func validateHeaders(actual, expected []string) bool {
-	if len(actual) != len(expected) {
-		return false
+// planUserImportRow validates a CSV row and decides whether it creates or updates a user.
+// deleted holds the employee IDs of deleted users, which cannot be imported.
+func (h *UserHandler) planUserImportRow(row spreadsheetRow, line int, mode string, deleted map[string]bool) UserImportRow {
+	result := UserImportRow{Line: line}
+	employeeID := row.Get("employee_id")
+
+	var user models.User
+	existing, err := h.DB.GetUserByEmployeeID(employeeID)
+	switch {
+	case employeeID == "":
+		result.Errors = append(result.Errors, "employee_id is required")
+	case err != nil:
+		result.Errors = append(result.Errors, "failed to look up employee: "+err.Error())
+	case deleted[employeeID]:
+		result.Errors = append(result.Errors, fmt.Sprintf("employee_id %s belongs to a deleted user, restore the user instead", employeeID))
+	case existing != nil && mode != userImportModeUpsert:
+		result.Errors = append(result.Errors, fmt.Sprintf("user with employee_id %s already exists (use mode=upsert to update)", employeeID))
+	case existing != nil:
+		user = *existing
+		result.Action = "update"
+		result.before = existing
+	default:
+		user = models.User{Active: true}
+		result.Action = "create"
+	}
+	user.EmployeeId = employeeID
+
+	if name := row.Get("name"); name != "" {
+		user.Name = name
+	} else if result.Action != "update" {
+		result.Errors = append(result.Errors, "name is required")
 	}
-	for i, header := range actual {
-		if header != expected[i] {
-			return false
+
+	if department := row.Get("department"); department != "" {
+		user.Department = department
+		user.DepartmentID = nil // Resolved again from the name when saved
+	}
+
+	if rawPhone := row.Get("phone"); rawPhone != "" {
+		normalized, err := phone.Normalize(rawPhone)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid phone %q", rawPhone))
 		}
+		user.Phone = normalized
+	}
+
+	if rawActive := row.Get("active"); rawActive != "" {
+		active, err := parseActiveCell(rawActive)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		user.Active = active
+	}
+
+	if rawLimit := row.Get("credit_limit"); rawLimit != "" {
+		limit, err := strconv.ParseFloat(strings.ReplaceAll(rawLimit, ",", ""), 64)
+		if err != nil || limit < 0 {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid credit_limit %q, expected a non-negative number", rawLimit))
+		}
+		user.CreditLimit = limit
+	}
+
+	result.User = user
+	if len(result.Errors) > 0 {
+		result.Action = "error"
+	}
+	return result
+}
+
+// parseActiveCell parses the active column of a user import
+func parseActiveCell(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "y", "active":
+		return true, nil
+	case "0", "false", "no", "n", "inactive":
+		return false, nil
 	}
-	return true
+	return false, fmt.Errorf("invalid active value %q, expected true or false", value)
 }
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// WalletHandler handles prepaid wallet top-ups and their bonus rules
+type WalletHandler struct {
+	common.BaseHandler
+}
+
+// NewWalletHandler creates a new wallet handler
+func NewWalletHandler(db database.Service) *WalletHandler {
+	return &WalletHandler{
+		BaseHandler: common.NewBaseHandler(db),
+	}
+}
+
+// TopUpRequest represents the request body for topping up a wallet
+type TopUpRequest struct {
+	UserID        int64   `json:"user_id"`
+	Amount        float64 `json:"amount"`
+	PaymentMethod string  `json:"payment_method"` // "account" for bank transfers and the like, or "cash"
+	Note          string  `json:"note"`
+}
+
+// TopUpWallet handles POST /api/wallet/top-ups
+//
+// Credits the amount and the best applicable bonus to the user's wallet. Cash top-ups go
+// through the requesting cashier's open cash session.
+func (h *WalletHandler) TopUpWallet(w http.ResponseWriter, r *http.Request) {
+	var request TopUpRequest
+	if err := h.DecodeJSON(r, &request); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	if request.UserID == 0 {
+		h.HandleError(w, errors.InvalidInput("user_id is required"))
+		return
+	}
+	if request.Amount <= 0 {
+		h.HandleError(w, errors.InvalidInput("Amount must be greater than zero"))
+		return
+	}
+	if request.PaymentMethod == "" {
+		request.PaymentMethod = models.PaymentMethodAccount
+	}
+	if request.PaymentMethod != models.PaymentMethodAccount && request.PaymentMethod != models.PaymentMethodCash {
+		h.HandleError(w, errors.InvalidInput("Invalid payment_method. Expected account or cash"))
+		return
+	}
+
+	actor := common.RequestActor(r)
+	session, err := h.DB.GetOpenCashSession(actor)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	var cashSessionID *int64
+	if session != nil {
+		cashSessionID = &session.ID
+	}
+
+	topUp := models.TopUp{
+		UserID:        request.UserID,
+		Amount:        request.Amount,
+		PaymentMethod: request.PaymentMethod,
+		Note:          strings.TrimSpace(request.Note),
+		CreatedBy:     actor,
+	}
+	if err := h.DB.TopUpWallet(&topUp, cashSessionID); err != nil {
+		if errors.Is(err, database.ErrInvalidTopUp) {
+			h.HandleError(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	for _, id := range []*int64{topUp.TransactionID, topUp.BonusTransactionID} {
+		if id == nil {
+			continue
+		}
+		if transaction, err := h.DB.GetTransaction(*id, false); err == nil && transaction != nil {
+			h.Audit(r, models.AuditActionCreate, models.AuditEntityTransaction, *id, nil, transaction)
+		}
+	}
+
+	common.RespondWithSuccess(w, http.StatusCreated, topUp)
+}
+
+// GetTopUps handles GET /api/wallet/top-ups
+func (h *WalletHandler) GetTopUps(w http.ResponseWriter, r *http.Request) {
+	topUps, err := h.DB.GetTopUps(0)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, topUps)
+}
+
+// GetUserTopUps handles GET /api/users/{user_id}/top-ups
+func (h *WalletHandler) GetUserTopUps(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.ParseID(mux.Vars(r), "user_id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	topUps, err := h.DB.GetTopUps(userID)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, topUps)
+}
+
+// GetPrepaidLiability handles GET /api/reports/prepaid-liability
+func (h *WalletHandler) GetPrepaidLiability(w http.ResponseWriter, r *http.Request) {
+	liability, err := h.DB.GetPrepaidLiability()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, liability)
+}
+
+// CreateBonusRule handles POST /api/wallet/bonus-rules
+func (h *WalletHandler) CreateBonusRule(w http.ResponseWriter, r *http.Request) {
+	rule := models.TopUpBonusRule{Active: true}
+	if err := h.DecodeJSON(r, &rule); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := validateBonusRule(&rule); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.CreateTopUpBonusRule(&rule); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionCreate, models.AuditEntityBonusRule, rule.ID, nil, rule)
+
+	common.RespondWithSuccess(w, http.StatusCreated, rule)
+}
+
+// GetBonusRules handles GET /api/wallet/bonus-rules?active=true
+func (h *WalletHandler) GetBonusRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.DB.GetTopUpBonusRules(r.URL.Query().Get("active") == "true")
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, rules)
+}
+
+// GetBonusRule handles GET /api/wallet/bonus-rules/{id}
+func (h *WalletHandler) GetBonusRule(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	rule, err := h.DB.GetTopUpBonusRule(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if rule == nil {
+		h.HandleError(w, errors.NotFound("Top-up bonus rule", id))
+		return
+	}
+
+	common.RespondWithSuccess(w, http.StatusOK, rule)
+}
+
+// UpdateBonusRule handles PUT /api/wallet/bonus-rules/{id}
+func (h *WalletHandler) UpdateBonusRule(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	rule := models.TopUpBonusRule{Active: true}
+	if err := h.DecodeJSON(r, &rule); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+	rule.ID = id
+
+	before, err := h.DB.GetTopUpBonusRule(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Top-up bonus rule", id))
+		return
+	}
+
+	if err := validateBonusRule(&rule); err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	if err := h.DB.UpdateTopUpBonusRule(&rule); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	rule.CreatedAt = before.CreatedAt
+	h.Audit(r, models.AuditActionUpdate, models.AuditEntityBonusRule, id, before, rule)
+
+	common.RespondWithSuccess(w, http.StatusOK, rule)
+}
+
+// DeleteBonusRule handles DELETE /api/wallet/bonus-rules/{id}
+//
+// Top-ups the rule was applied to keep its name. To stop a rule without losing it, update it
+// with active set to false instead.
+func (h *WalletHandler) DeleteBonusRule(w http.ResponseWriter, r *http.Request) {
+	id, err := h.ParseID(mux.Vars(r), "id")
+	if err != nil {
+		h.HandleError(w, err)
+		return
+	}
+
+	before, err := h.DB.GetTopUpBonusRule(id)
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if before == nil {
+		h.HandleError(w, errors.NotFound("Top-up bonus rule", id))
+		return
+	}
+
+	if err := h.DB.DeleteTopUpBonusRule(id); err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	h.Audit(r, models.AuditActionDelete, models.AuditEntityBonusRule, id, before, nil)
+
+	common.RespondWithSuccess(w, http.StatusNoContent, nil)
+}
+
+// validateBonusRule checks the name, amounts and validity period of a top-up bonus rule
+func validateBonusRule(rule *models.TopUpBonusRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return errors.InvalidInput("Bonus rule name is required")
+	}
+	if rule.MinAmount < 0 || rule.BonusAmount < 0 {
+		return errors.InvalidInput("min_amount and bonus_amount must not be negative")
+	}
+	if rule.BonusPercent < 0 || rule.BonusPercent > 100 {
+		return errors.InvalidInput("bonus_percent must be between 0 and 100")
+	}
+	if rule.BonusAmount == 0 && rule.BonusPercent == 0 {
+		return errors.InvalidInput("A bonus_amount or bonus_percent is required")
+	}
+	if rule.ValidFrom != nil && rule.ValidUntil != nil && !rule.ValidUntil.After(*rule.ValidFrom) {
+		return errors.InvalidInput("valid_until must be after valid_from")
+	}
+	return nil
+}
@@ -33,8 +33,14 @@ func (m *whatsAppClientManager) IsConnected() bool {
 	return m.client.IsConnected()
 }
 
-func EventHandler(evt any, broadcastFunc func(event string, data map[string]any), clientMgr WhatsAppClientManager) {
+// EventHandler handles WhatsApp client events. Incoming messages are passed to onMessage,
+// if set, in a goroutine so that slow handlers do not hold up the event stream.
+func EventHandler(evt any, broadcastFunc func(event string, data map[string]any), clientMgr WhatsAppClientManager, onMessage func(*events.Message)) {
 	switch v := evt.(type) {
+	case *events.Message:
+		if onMessage != nil {
+			go onMessage(v)
+		}
 	case *events.Connected:
 		log.Info("Connected to WhatsApp")
 		broadcastFunc("whatsapp_status", map[string]any{
@@ -91,7 +97,7 @@ func GetWhatsappPath() (dbUri string, filePath string) {
 	return dbUri, filePath
 }
 
-func SetupWhatsapp(broadcastFunc func(event string, data map[string]any), registerQRChannelGetter func(func(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error))) (*whatsmeow.Client, string) {
+func SetupWhatsapp(broadcastFunc func(event string, data map[string]any), registerQRChannelGetter func(func(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error)), onMessage func(*events.Message)) (*whatsmeow.Client, string) {
 	dbLog := waLog.Stdout("Database", "INFO", true)
 	dbUri, filePath := GetWhatsappPath()
 	log.Infof("Using WhatsApp database at: %s", filePath)
@@ -126,7 +132,7 @@ func SetupWhatsapp(broadcastFunc func(event string, data map[string]any), regist
 		return true
 	}
 	clientMgr := &whatsAppClientManager{client: client}
-	client.AddEventHandler(func(evt any) { EventHandler(evt, broadcastFunc, clientMgr) })
+	client.AddEventHandler(func(evt any) { EventHandler(evt, broadcastFunc, clientMgr, onMessage) })
 	registerQRChannelGetter(func(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error) {
 		return client.GetQRChannel(ctx)
 	})
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"maya-canteen/internal/errors"
+	"maya-canteen/internal/handlers/common"
+	"maya-canteen/internal/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// escalationMessage fills in the template of the escalation level a user reached
+func (h *WhatsAppHandler) escalationMessage(aging models.UserAging) string {
+	message := h.formatBalanceMessage(aging.EscalationLevel.MessageTemplate, aging.UserName, aging.EmployeeID, aging.Balance)
+	return strings.ReplaceAll(message, "{age_days}", strconv.Itoa(aging.AgeDays))
+}
+
+// escalationSummary lists the users who reached an escalation level for its manager
+func escalationSummary(level models.EscalationLevel, users []models.UserAging) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("**Overdue Balances: %s**\n\n", level.Name))
+	builder.WriteString(fmt.Sprintf("%d employee(s) have owed money for %d days or more:\n", len(users), level.MinAgeDays))
+	for _, aging := range users {
+		builder.WriteString(fmt.Sprintf("- %s (%s): PKR %.2f, %d days\n", aging.UserName, aging.EmployeeID, aging.Total, aging.AgeDays))
+	}
+	builder.WriteString("\nThis is an automated message from Maya Canteen Management System.")
+	return builder.String()
+}
+
+// SendEscalations handles POST /api/whatsapp/escalations?dry_run=true
+//
+// Sends the users whose balances reached an escalation level the level's message, and the
+// level's manager a summary of those users. Users are not escalated at the same level again
+// within its repeat days. With dry_run, only lists the users who would be escalated.
+func (h *WhatsAppHandler) SendEscalations(w http.ResponseWriter, r *http.Request) {
+	// Acquire global lock to prevent concurrent bulk operations
+	bulkOperationMutex.Lock()
+	defer bulkOperationMutex.Unlock()
+
+	due, err := h.DB.GetDueEscalations()
+	if err != nil {
+		h.HandleError(w, errors.Internal(err))
+		return
+	}
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		common.RespondWithSuccess(w, http.StatusOK, due)
+		return
+	}
+
+	client := h.GetWhatsAppClient()
+	if client == nil || !client.IsLoggedIn() || !client.IsConnected() {
+		log.Warn("WhatsApp client is not available")
+		common.RespondWithError(w, http.StatusInternalServerError, "WhatsApp client is not available")
+		return
+	}
+
+	common.RespondWithJSON(w, http.StatusAccepted, map[string]any{
+		"success":     true,
+		"message":     fmt.Sprintf("Sending escalations to %d users in the background", len(due)),
+		"total_users": len(due),
+	})
+
+	sentBy := common.RequestActor(r)
+	go h.sendEscalations(due, sentBy)
+}
+
+// sendEscalations sends the escalations of due users with a randomized delay between messages,
+// then records a notice for every user who was messaged or reported to a manager
+func (h *WhatsAppHandler) sendEscalations(due []models.UserAging, sentBy string) {
+	pause := func() {
+		time.Sleep(notificationDelayMin + rand.N(notificationDelayMax-notificationDelayMin))
+	}
+
+	messaged := make(map[int64]bool)
+	for _, aging := range due {
+		if aging.Phone == "" {
+			continue
+		}
+		if err := h.SendWhatsAppMessage(aging.Phone, h.escalationMessage(aging)); err != nil {
+			log.Warnf("Failed to send escalation to %s (%s): %v", aging.UserName, aging.Phone, err)
+		} else {
+			messaged[aging.UserID] = true
+		}
+		pause()
+	}
+
+	levels := make(map[int64]models.EscalationLevel)
+	byLevel := make(map[int64][]models.UserAging)
+	for _, aging := range due {
+		levels[aging.EscalationLevel.ID] = *aging.EscalationLevel
+		byLevel[aging.EscalationLevel.ID] = append(byLevel[aging.EscalationLevel.ID], aging)
+	}
+	managerNotified := make(map[int64]bool)
+	for id, users := range byLevel {
+		level := levels[id]
+		if level.ManagerPhone == "" {
+			continue
+		}
+		if err := h.SendWhatsAppMessage(level.ManagerPhone, escalationSummary(level, users)); err != nil {
+			log.Warnf("Failed to send escalation summary of level %s to %s: %v", level.Name, level.ManagerPhone, err)
+		} else {
+			managerNotified[id] = true
+		}
+		pause()
+	}
+
+	recorded := 0
+	for _, aging := range due {
+		level := aging.EscalationLevel
+		if !messaged[aging.UserID] && !managerNotified[level.ID] {
+			continue
+		}
+		notice := models.EscalationNotice{
+			UserID:          aging.UserID,
+			LevelID:         &level.ID,
+			LevelName:       level.Name,
+			Amount:          aging.Total,
+			AgeDays:         aging.AgeDays,
+			ManagerNotified: managerNotified[level.ID],
+			SentBy:          sentBy,
+		}
+		if err := h.DB.RecordEscalationNotice(&notice); err != nil {
+			log.Errorf("Failed to record escalation of %s: %v", aging.UserName, err)
+			continue
+		}
+		recorded++
+	}
+	log.Infof("Escalations complete: %d of %d users escalated", recorded, len(due))
+}
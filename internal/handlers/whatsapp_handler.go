@@ -23,7 +23,7 @@ import (
 )
 
 const (
-	defaultBalanceMessageTemplate = "**Balance Update** \n\nDear {name},\nYour current canteen balance is: *PKR {balance}*\n\nPlease pay online via Jazz Cash 03422949447 (Syed Kazim Raza) {duration} of Canteen bill for {month} {year}\n\nThis is an automated message from Maya Canteen Management System.\n\nAfter the payment share the screenshot.\n\n{transactions}"
+	defaultBalanceMessageTemplate = "**Balance Update** \n\nDear {name},\nYour current canteen balance is: *PKR {balance}*{installment}\n\nPlease pay online via Jazz Cash 03422949447 (Syed Kazim Raza) {duration} of Canteen bill for {month} {year}\n\nThis is an automated message from Maya Canteen Management System.\n\nAfter the payment share the screenshot. Please write your employee ID {employee_id} in the payment reference so that the payment is matched to your account.\n\n{transactions}"
 	csvHeader                     = "Date,Type,Amount,Description\n"
 	textTransactionHeader         = "Transaction History:\n"
 	textTransactionHeaderLine     = "Date | Type | Amount | Description\n"
@@ -134,11 +134,12 @@ func (h *WhatsAppHandler) SendWhatsAppMessage(phoneNumber, message string) error
 }
 
 // formatBalanceMessage formats the balance notification message with user details
-func (h *WhatsAppHandler) formatBalanceMessage(template string, name string, balance float64) string {
+func (h *WhatsAppHandler) formatBalanceMessage(template string, name, employeeID string, balance float64) string {
 	var builder strings.Builder
 	builder.WriteString(template)
 	message := builder.String()
 	message = strings.ReplaceAll(message, "{name}", name)
+	message = strings.ReplaceAll(message, "{employee_id}", employeeID)
 	message = strings.ReplaceAll(message, "{balance}", fmt.Sprintf("%.2f", balance))
 	return message
 }
@@ -189,7 +190,28 @@ func (h *WhatsAppHandler) sendBalanceNotification(user models.User, userBalance
 		"includeTransactions": includeTransactions,
 	}).Info("sendBalanceNotification called with params")
 
-	message := h.formatBalanceMessage(messageTemplate, user.Name, float64(userBalance.Balance))
+	// Users on an installment plan are asked for the installment due instead of the whole balance
+	balance := float64(userBalance.Balance)
+	installment := ""
+	plan, err := h.DB.GetActiveInstallmentPlan(user.ID)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"user_id": user.ID,
+			"error":   err,
+		}).Error("Failed to get installment plan for user in sendBalanceNotification")
+		return fmt.Errorf("failed to get installment plan: %v", err)
+	}
+	if notice := installmentNotice(plan); notice != "" {
+		balance = -plan.AmountDue()
+		installment = "\n" + notice
+	}
+
+	message := h.formatBalanceMessage(messageTemplate, user.Name, user.EmployeeId, balance)
+	if strings.Contains(message, "{installment}") {
+		message = strings.ReplaceAll(message, "{installment}", installment)
+	} else if installment != "" {
+		message = message + "\n" + installment
+	}
 
 	var combinedMessage string
 	var csvContent string
@@ -26,7 +26,8 @@ func DefaultZKPort() int {
 
 // SetupZKDevice initializes and manages the ZK device connection and event capture.
 // Accepts a broadcastFunc to decouple from routes and avoid import cycles.
-func SetupZKDevice(eventLogger *logStd.Logger, broadcastFunc func(event string, data map[string]any)) *gozk.ZK {
+// onAttendance, if not nil, is called with the employee ID of every punch.
+func SetupZKDevice(eventLogger *logStd.Logger, broadcastFunc func(event string, data map[string]any), onAttendance func(employeeID string, attendedAt time.Time)) *gozk.ZK {
 	portInt := DefaultZKPort()
 	ip := os.Getenv("ZK_IP")
 	if ip == "" {
@@ -75,6 +76,9 @@ func SetupZKDevice(eventLogger *logStd.Logger, broadcastFunc func(event string,
 						"user_id":   event.UserID,
 						"timestamp": event.AttendedAt.Format(time.RFC3339),
 					})
+					if onAttendance != nil {
+						onAttendance(event.UserID, event.AttendedAt)
+					}
 				}
 			}
 
@@ -31,7 +31,7 @@ func CORS() Middleware {
 			// CORS headers for regular HTTP requests
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token, Upgrade, Connection")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token, X-Actor, Upgrade, Connection")
 			w.Header().Set("Access-Control-Allow-Credentials", "false") // Set to "true" if credentials are needed
 
 			// Handle preflight OPTIONS requests
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// LedgerEntry is a transaction's effect on a user's balance: negative for charges and positive
+// for credits
+type LedgerEntry struct {
+	UserID    int64     `json:"user_id"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AgingBuckets splits an unpaid amount by how long ago it was charged
+type AgingBuckets struct {
+	Current    float64 `json:"current"` // Charged less than 30 days ago
+	Days30     float64 `json:"days_30"` // 30 to 59 days
+	Days60     float64 `json:"days_60"` // 60 to 89 days
+	Days90Plus float64 `json:"days_90_plus"`
+	Total      float64 `json:"total"`
+}
+
+// UserAging is the age of a user's unpaid amount. Credits are matched against the oldest
+// charges first, so what remains unpaid are the most recent charges.
+type UserAging struct {
+	UserID           int64            `json:"user_id"`
+	UserName         string           `json:"user_name"`
+	EmployeeID       string           `json:"employee_id"`
+	Department       string           `json:"user_department"`
+	Phone            string           `json:"user_phone"`
+	UserActive       bool             `json:"user_active"`
+	Balance          float64          `json:"balance"`
+	AgingBuckets                      // Unpaid amount by age, its total is what the user owes
+	OldestUnpaidDate *time.Time       `json:"oldest_unpaid_date"`
+	AgeDays          int              `json:"age_days"` // Days since the oldest unpaid charge
+	EscalationLevel  *EscalationLevel `json:"escalation_level,omitempty"`
+}
+
+// AgingReport is the aging of all users who owe money
+type AgingReport struct {
+	AsOf   time.Time    `json:"as_of"`
+	Users  []UserAging  `json:"users"`
+	Totals AgingBuckets `json:"totals"`
+}
+
+// EscalationLevel is a step of the escalation policy for overdue balances. A user reaches the
+// level once their oldest unpaid charge is MinAgeDays old and they owe at least MinAmount; of the
+// levels a user reaches, the one with the highest MinAgeDays applies.
+type EscalationLevel struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	MinAgeDays      int       `json:"min_age_days"`
+	MinAmount       float64   `json:"min_amount"`
+	MessageTemplate string    `json:"message_template"`        // Sent to the user, supports {name}, {employee_id}, {balance} and {age_days}
+	ManagerPhone    string    `json:"manager_phone,omitempty"` // Sent a summary of the users who reached the level
+	RepeatDays      int       `json:"repeat_days"`             // Days before a user is escalated at the same level again
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// EscalationNotice records an escalation sent to a user
+type EscalationNotice struct {
+	ID              int64     `json:"id"`
+	UserID          int64     `json:"user_id"`
+	UserName        string    `json:"user_name"`
+	LevelID         *int64    `json:"level_id"` // nil once the level is deleted
+	LevelName       string    `json:"level_name"`
+	Amount          float64   `json:"amount"`
+	AgeDays         int       `json:"age_days"`
+	ManagerNotified bool      `json:"manager_notified"`
+	SentBy          string    `json:"sent_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+)
+
+// Revenue series granularities
+const (
+	AnalyticsGranularityDay   = "day"
+	AnalyticsGranularityWeek  = "week" // Weeks start on Monday
+	AnalyticsGranularityMonth = "month"
+)
+
+// AnalyticsPeriod summarizes the sales of a period. Revenue is the total of purchases less
+// refunds, including walk-in guest sales and the company share.
+type AnalyticsPeriod struct {
+	StartDate             string  `json:"start_date"` // YYYY-MM-DD
+	EndDate               string  `json:"end_date"`
+	Revenue               float64 `json:"revenue"`
+	PurchaseCount         int     `json:"purchase_count"`
+	AverageBasket         float64 `json:"average_basket"`          // Revenue per purchase
+	AverageItems          float64 `json:"average_items"`           // Items sold per purchase
+	UniqueCustomers       int     `json:"unique_customers"`        // Users with at least one purchase
+	AverageDailyCustomers float64 `json:"average_daily_customers"` // Unique customers per day with sales
+}
+
+// AnalyticsSummary compares the sales of a period with the period of the same length before it.
+// Changes are percentages, nil when the previous period had none.
+type AnalyticsSummary struct {
+	Current               AnalyticsPeriod `json:"current"`
+	Previous              AnalyticsPeriod `json:"previous"`
+	RevenueChange         *float64        `json:"revenue_change"`
+	PurchaseCountChange   *float64        `json:"purchase_count_change"`
+	AverageBasketChange   *float64        `json:"average_basket_change"`
+	UniqueCustomersChange *float64        `json:"unique_customers_change"`
+	GeneratedAt           time.Time       `json:"generated_at"` // When the figures were computed, they are cached for a while
+}
+
+// RevenuePoint is the revenue of a day, week or month
+type RevenuePoint struct {
+	Period        string  `json:"period"` // Date the day, week or month starts on, YYYY-MM-DD
+	Revenue       float64 `json:"revenue"`
+	PurchaseCount int     `json:"purchase_count"`
+	RefundTotal   float64 `json:"refund_total"`
+}
+
+// RevenueSeries is the revenue of a period by day, week or month, compared with the period of
+// the same length before it
+type RevenueSeries struct {
+	Granularity   string         `json:"granularity"`
+	Points        []RevenuePoint `json:"points"`
+	Total         float64        `json:"total"`
+	PreviousTotal float64        `json:"previous_total"`
+	Change        *float64       `json:"change"` // Percentage, nil when the previous period had no revenue
+	GeneratedAt   time.Time      `json:"generated_at"`
+}
+
+// HeatmapCell is the sales made at an hour of a weekday
+type HeatmapCell struct {
+	Weekday       int     `json:"weekday"` // 0 for Sunday
+	Hour          int     `json:"hour"`    // 0 to 23, local time
+	PurchaseCount int     `json:"purchase_count"`
+	Revenue       float64 `json:"revenue"`
+}
+
+// DailyCustomers is the number of users who made purchases on a day
+type DailyCustomers struct {
+	Date            string `json:"date"` // YYYY-MM-DD
+	UniqueCustomers int    `json:"unique_customers"`
+	PurchaseCount   int    `json:"purchase_count"`
+}
+
+// TopProduct is one of the best selling products of a period
+type TopProduct struct {
+	Rank            int      `json:"rank"`
+	ProductID       int64    `json:"product_id"`
+	ProductName     string   `json:"product_name"`
+	Quantity        int      `json:"quantity"`
+	Revenue         float64  `json:"revenue"`
+	PreviousRevenue float64  `json:"previous_revenue"` // In the period of the same length before
+	Change          *float64 `json:"change"`
+}
+
+// TopUser is one of the highest spending users of a period
+type TopUser struct {
+	Rank          int      `json:"rank"`
+	UserID        int64    `json:"user_id"`
+	UserName      string   `json:"user_name"`
+	EmployeeID    string   `json:"employee_id"`
+	Spent         float64  `json:"spent"`
+	PurchaseCount int      `json:"purchase_count"`
+	PreviousSpent float64  `json:"previous_spent"` // In the period of the same length before
+	Change        *float64 `json:"change"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Audit log actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+// Audited entity types
+const (
+	AuditEntityUser            = "user"
+	AuditEntityProduct         = "product"
+	AuditEntityTransaction     = "transaction"
+	AuditEntityPricingRule     = "pricing_rule"
+	AuditEntityPaymentLine     = "payment_line"
+	AuditEntityBonusRule       = "top_up_bonus_rule"
+	AuditEntityInstallmentPlan = "installment_plan"
+	AuditEntityEscalationLevel = "escalation_level"
+	AuditEntityBackup          = "backup"
+)
+
+// AuditChange represents the before and after value of a changed field
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditLog represents a recorded create, update or delete of an entity
+type AuditLog struct {
+	ID         int64                  `json:"id"`
+	Actor      string                 `json:"actor"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   int64                  `json:"entity_id"`
+	Changes    map[string]AuditChange `json:"changes"`
+	IPAddress  string                 `json:"ip_address"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditLogFilter represents the filters of an audit log query. Zero values match everything.
+type AuditLogFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   int64
+	StartDate  time.Time
+	EndDate    time.Time
+	Limit      int
+	Offset     int
+}
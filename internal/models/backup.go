@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// BackupBundleVersion is the current version of the JSON backup bundle format
+const BackupBundleVersion = 1
+
+// BackupBundle represents a JSON export of the canteen database.
+// Tables maps a table name to its rows, each row mapping column names to values.
+type BackupBundle struct {
+	Version   int                         `json:"version"`
+	CreatedAt time.Time                   `json:"created_at"`
+	Tables    map[string][]map[string]any `json:"tables"`
+}
+
+// RestoreResult represents the number of rows restored per table
+type RestoreResult struct {
+	Tables map[string]int `json:"tables"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// Balance transfer statuses
+const (
+	BalanceTransferStatusPending   = "pending" // Awaiting approval by the payer
+	BalanceTransferStatusCompleted = "completed"
+	BalanceTransferStatusRejected  = "rejected"
+)
+
+// BalanceTransfer moves balance from one user to another, e.g. to settle a lunch a colleague
+// paid for. A completed transfer is recorded as a pair of linked transfer transactions.
+type BalanceTransfer struct {
+	ID                int64      `json:"id"`
+	FromUserID        int64      `json:"from_user_id"` // Payer, whose balance is debited
+	FromUserName      string     `json:"from_user_name"`
+	ToUserID          int64      `json:"to_user_id"` // Payee, whose balance is credited
+	ToUserName        string     `json:"to_user_name"`
+	Amount            float64    `json:"amount"`
+	Note              string     `json:"note,omitempty"`
+	Status            string     `json:"status"`
+	RequestedBy       string     `json:"requested_by"`
+	ReviewedBy        string     `json:"reviewed_by,omitempty"` // Who approved or rejected the transfer for the payer
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+	FromTransactionID *int64     `json:"from_transaction_id,omitempty"`
+	ToTransactionID   *int64     `json:"to_transaction_id,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Cash session statuses
+const (
+	CashSessionStatusOpen   = "open"
+	CashSessionStatusClosed = "closed"
+)
+
+// CashSession is a cashier's shift at the cash drawer, from opening with a float to closing
+// with a counted amount
+type CashSession struct {
+	ID             int64         `json:"id"`
+	Cashier        string        `json:"cashier"` // Actor who opened the session and records its transactions
+	Status         string        `json:"status"`
+	OpeningFloat   float64       `json:"opening_float"`
+	CashIn         float64       `json:"cash_in"`         // Cash deposits and wallet top-ups
+	CashSales      float64       `json:"cash_sales"`      // Purchases paid in cash
+	CashOut        float64       `json:"cash_out"`        // Refunds and final balances paid out in cash
+	ExpectedAmount float64       `json:"expected_amount"` // Float plus cash in and sales, less cash out
+	CountedAmount  *float64      `json:"counted_amount,omitempty"`
+	Difference     *float64      `json:"difference,omitempty"` // Counted less expected: over when positive, short when negative
+	Note           string        `json:"note,omitempty"`
+	OpenedAt       time.Time     `json:"opened_at"`
+	ClosedAt       *time.Time    `json:"closed_at,omitempty"`
+	ClosedBy       string        `json:"closed_by,omitempty"`
+	Transactions   []Transaction `json:"transactions,omitempty"` // Set on the session report
+}
+
+// CashSessionTotals is the cash a session's transactions moved through the drawer
+type CashSessionTotals struct {
+	CashIn    float64
+	CashSales float64
+	CashOut   float64
+}
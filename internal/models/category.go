@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// Category represents a menu category that groups products, e.g. beverages or snacks
+type Category struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	SortOrder int       `json:"sort_order"` // Position on the menu, lowest first
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProductImage represents an uploaded product image
+type ProductImage struct {
+	ProductID   int64     `json:"product_id"`
+	ContentType string    `json:"content_type"`
+	Data        []byte    `json:"-"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MenuCategory represents a category of the menu with its active products
+type MenuCategory struct {
+	CategoryID *int64    `json:"category_id"` // nil for products without a category
+	Name       string    `json:"name"`
+	SortOrder  int       `json:"sort_order"`
+	Products   []Product `json:"products"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+)
+
+// Pre-order statuses
+const (
+	PreOrderStatusPending   = "pending"
+	PreOrderStatusCollected = "collected"
+	PreOrderStatusCancelled = "cancelled"
+)
+
+// MenuItem is a product unit available on the menu of a given date
+type MenuItem struct {
+	ID            int64     `json:"id"`
+	MenuDate      string    `json:"menu_date"` // YYYY-MM-DD
+	ProductID     int64     `json:"product_id"`
+	ProductName   string    `json:"product_name"`
+	UnitID        int64     `json:"unit_id"`
+	UnitName      string    `json:"unit_name"`
+	Price         float64   `json:"price"`          // Current price of the unit
+	QuantityLimit *int      `json:"quantity_limit"` // Nil when the quantity is not limited
+	Ordered       int       `json:"ordered"`        // Quantity of pending and collected pre-orders
+	Remaining     *int      `json:"remaining"`      // Nil when the quantity is not limited
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DailyMenu is the menu published for a date
+type DailyMenu struct {
+	Date  string     `json:"date"`
+	Items []MenuItem `json:"items"`
+}
+
+// PreOrder is an employee's order of a menu item, placed before the menu date
+type PreOrder struct {
+	ID            int64      `json:"id"`
+	MenuItemID    int64      `json:"menu_item_id"`
+	MenuDate      string     `json:"menu_date"`
+	UserID        int64      `json:"user_id"`
+	UserName      string     `json:"user_name"`
+	EmployeeID    string     `json:"employee_id"`
+	ProductID     int64      `json:"product_id"`
+	ProductName   string     `json:"product_name"`
+	UnitID        int64      `json:"unit_id"`
+	UnitName      string     `json:"unit_name"`
+	Quantity      int        `json:"quantity"`
+	Status        string     `json:"status"`
+	TransactionID *int64     `json:"transaction_id,omitempty"` // Purchase created when the order was collected
+	CollectedAt   *time.Time `json:"collected_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// PreOrderLine is a requested quantity of a menu item. A quantity of zero cancels the order.
+type PreOrderLine struct {
+	MenuItemID int64 `json:"menu_item_id"`
+	Quantity   int   `json:"quantity"`
+}
+
+// MenuItemTally is the quantity of a menu item ordered for the kitchen
+type MenuItemTally struct {
+	MenuItemID    int64  `json:"menu_item_id"`
+	ProductID     int64  `json:"product_id"`
+	ProductName   string `json:"product_name"`
+	UnitName      string `json:"unit_name"`
+	QuantityLimit *int   `json:"quantity_limit"`
+	Pending       int    `json:"pending"`
+	Collected     int    `json:"collected"`
+	Remaining     *int   `json:"remaining"`
+}
+
+// MenuOrderTally is the kitchen's overview of the pre-orders of a date
+type MenuOrderTally struct {
+	Date          string          `json:"date"`
+	Items         []MenuItemTally `json:"items"`
+	Orders        []PreOrder      `json:"orders"`
+	TotalQuantity int             `json:"total_quantity"`
+}
+
+// PreOrderCollection is the purchase created when an employee collects their pre-orders
+type PreOrderCollection struct {
+	Transaction Transaction `json:"transaction"`
+	Orders      []PreOrder  `json:"orders"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+)
+
+// Department represents a billing department that users belong to
+type Department struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	CostCenter     string    `json:"cost_center"`
+	BillingContact string    `json:"billing_contact"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// DepartmentSpend represents the monthly spend of a department
+type DepartmentSpend struct {
+	Month            string  `json:"month"` // YYYY-MM
+	DepartmentID     int64   `json:"department_id"`
+	DepartmentName   string  `json:"department_name"`
+	CostCenter       string  `json:"cost_center"`
+	PurchaseTotal    float64 `json:"purchase_total"`
+	DepositTotal     float64 `json:"deposit_total"`
+	TransactionCount int     `json:"transaction_count"`
+	UserCount        int     `json:"user_count"`
+}
+
+// DepartmentTopConsumer represents one of the highest spending users of a department
+type DepartmentTopConsumer struct {
+	DepartmentID   int64   `json:"department_id"`
+	DepartmentName string  `json:"department_name"`
+	Rank           int     `json:"rank"`
+	UserID         int64   `json:"user_id"`
+	UserName       string  `json:"user_name"`
+	EmployeeID     string  `json:"employee_id"`
+	TotalSpent     float64 `json:"total_spent"`
+	PurchaseCount  int     `json:"purchase_count"`
+}
+
+// DepartmentProductConsumption represents how much of a product the users of a department consumed
+type DepartmentProductConsumption struct {
+	DepartmentID   int64   `json:"department_id"`
+	DepartmentName string  `json:"department_name"`
+	ProductID      int64   `json:"product_id"`
+	ProductName    string  `json:"product_name"`
+	Quantity       int     `json:"quantity"`
+	TotalSales     float64 `json:"total_sales"`   // After discounts, refunds taken off
+	CompanyShare   float64 `json:"company_share"` // Subsidies of the product lines
+}
+
+// DepartmentBalance represents the outstanding balance of a department
+type DepartmentBalance struct {
+	DepartmentID       int64   `json:"department_id"`
+	DepartmentName     string  `json:"department_name"`
+	CostCenter         string  `json:"cost_center"`
+	BillingContact     string  `json:"billing_contact"`
+	UserCount          int     `json:"user_count"`
+	UsersInDebt        int     `json:"users_in_debt"`
+	OutstandingBalance float64 `json:"outstanding_balance"` // Sum of negative balances, reported as a positive amount
+	NetBalance         float64 `json:"net_balance"`
+}
+
+// EmployerInvoice represents the company shares of a month, which the employer is billed for
+type EmployerInvoice struct {
+	Month         string                      `json:"month"` // YYYY-MM
+	Departments   []EmployerInvoiceDepartment `json:"departments"`
+	TotalAmount   float64                     `json:"total_amount"` // Total of the invoiced transactions
+	EmployeeShare float64                     `json:"employee_share"`
+	CompanyShare  float64                     `json:"company_share"` // Amount to invoice
+}
+
+// EmployerInvoiceDepartment represents the company shares of the users of a department
+type EmployerInvoiceDepartment struct {
+	DepartmentID     *int64                `json:"department_id"` // nil for users without a department
+	DepartmentName   string                `json:"department_name"`
+	CostCenter       string                `json:"cost_center"`
+	BillingContact   string                `json:"billing_contact"`
+	TransactionCount int                   `json:"transaction_count"`
+	TotalAmount      float64               `json:"total_amount"`
+	EmployeeShare    float64               `json:"employee_share"`
+	CompanyShare     float64               `json:"company_share"`
+	Employees        []EmployerInvoiceLine `json:"employees"`
+}
+
+// EmployerInvoiceLine represents the company shares of a single user
+type EmployerInvoiceLine struct {
+	UserID           int64   `json:"user_id"`
+	EmployeeID       string  `json:"employee_id"`
+	Name             string  `json:"name"`
+	TransactionCount int     `json:"transaction_count"`
+	TotalAmount      float64 `json:"total_amount"`
+	EmployeeShare    float64 `json:"employee_share"`
+	CompanyShare     float64 `json:"company_share"`
+}
+
+// GetID returns the department ID
+func (d *Department) GetID() int64 {
+	return d.ID
+}
+
+// SetID sets the department ID
+func (d *Department) SetID(id int64) {
+	d.ID = id
+}
+
+// SetCreatedAt sets the created timestamp
+func (d *Department) SetCreatedAt(timestamp any) {
+	if t, ok := timestamp.(time.Time); ok {
+		d.CreatedAt = t
+	}
+}
+
+// SetUpdatedAt sets the updated timestamp
+func (d *Department) SetUpdatedAt(timestamp any) {
+	if t, ok := timestamp.(time.Time); ok {
+		d.UpdatedAt = t
+	}
+}
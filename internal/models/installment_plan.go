@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// Installment plan statuses
+const (
+	InstallmentPlanStatusActive    = "active"
+	InstallmentPlanStatusCompleted = "completed" // Every installment has been paid
+	InstallmentPlanStatusCancelled = "cancelled"
+)
+
+// Installment statuses
+const (
+	InstallmentStatusPending = "pending"
+	InstallmentStatusPaid    = "paid"
+	InstallmentStatusMissed  = "missed" // Past its due date and not fully paid
+)
+
+// InstallmentPlan is an interest-free arrangement for a user to pay off an outstanding balance
+// in monthly installments. Payments towards the plan are deposits carrying its batch reference,
+// and are allocated to the installments in the order they fall due.
+type InstallmentPlan struct {
+	ID               int64         `json:"id"`
+	UserID           int64         `json:"user_id"`
+	UserName         string        `json:"user_name"`
+	EmployeeID       string        `json:"employee_id"`
+	TotalAmount      float64       `json:"total_amount"`
+	InstallmentCount int           `json:"installment_count"`
+	Status           string        `json:"status"`
+	Note             string        `json:"note,omitempty"`
+	CreatedBy        string        `json:"created_by"`
+	PaidAmount       float64       `json:"paid_amount"`
+	RemainingAmount  float64       `json:"remaining_amount"`
+	OverdueAmount    float64       `json:"overdue_amount"` // Unpaid amount of missed installments
+	MissedCount      int           `json:"missed_count"`
+	NextInstallment  *Installment  `json:"next_installment,omitempty"` // First installment not yet due and not paid
+	Installments     []Installment `json:"installments"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+}
+
+// Installment is one of the monthly payments of an installment plan
+type Installment struct {
+	ID         int64     `json:"id"`
+	PlanID     int64     `json:"plan_id"`
+	Sequence   int       `json:"sequence"` // 1 for the first installment
+	DueDate    time.Time `json:"due_date"`
+	Amount     float64   `json:"amount"`
+	PaidAmount float64   `json:"paid_amount"`
+	Status     string    `json:"status"`
+}
+
+// AmountDue returns what the user should pay now: the overdue installments and the next one
+func (p *InstallmentPlan) AmountDue() float64 {
+	amount := p.OverdueAmount
+	if p.NextInstallment != nil {
+		amount += p.NextInstallment.Amount - p.NextInstallment.PaidAmount
+	}
+	return amount
+}
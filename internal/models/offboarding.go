@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Settlement methods of an offboarded user's final balance
+const (
+	SettlementMethodPayroll  = "payroll"   // Deducted from or paid with the final salary
+	SettlementMethodCash     = "cash"      // Collected or paid out at the cash drawer
+	SettlementMethodWriteOff = "write-off" // Debt forgiven by the canteen
+)
+
+// Offboarding records how the final balance of a departing employee was settled. Offboarded
+// users are deactivated and cannot make new purchases.
+type Offboarding struct {
+	ID               int64     `json:"id"`
+	UserID           int64     `json:"user_id"`
+	UserName         string    `json:"user_name"`
+	EmployeeID       string    `json:"employee_id"`
+	FinalBalance     float64   `json:"final_balance"`               // Balance before settlement, negative when the user owes
+	SettlementMethod string    `json:"settlement_method,omitempty"` // Empty when there was nothing to settle
+	Reason           string    `json:"reason,omitempty"`
+	TransactionID    *int64    `json:"transaction_id,omitempty"` // Transaction that settled the final balance
+	ProcessedBy      string    `json:"processed_by"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// FinalStatement is the statement of a departing employee's account: every transaction and
+// the balance they add up to, with the offboarding once it has been processed
+type FinalStatement struct {
+	User         User          `json:"user"`
+	Balance      float64       `json:"balance"`
+	Transactions []Transaction `json:"transactions"`
+	Offboarding  *Offboarding  `json:"offboarding,omitempty"`
+	GeneratedAt  time.Time     `json:"generated_at"`
+}
+
+// WriteOff is a written off balance in the write-off report
+type WriteOff struct {
+	TransactionID int64     `json:"transaction_id"`
+	UserID        int64     `json:"user_id"`
+	UserName      string    `json:"user_name"`
+	EmployeeID    string    `json:"employee_id"`
+	Department    string    `json:"department"`
+	Amount        float64   `json:"amount"`
+	Description   string    `json:"description"`
+	Offboarding   bool      `json:"offboarding"` // Written off when the user was offboarded
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WriteOffReport lists the balances written off in a date range
+type WriteOffReport struct {
+	StartDate   string     `json:"start_date"`
+	EndDate     string     `json:"end_date"`
+	TotalAmount float64    `json:"total_amount"`
+	WriteOffs   []WriteOff `json:"write_offs"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// Payment line statuses
+const (
+	PaymentLineStatusMatched   = "matched"   // Matched to a user automatically, awaiting approval
+	PaymentLineStatusUnmatched = "unmatched" // Awaiting a user to be picked by a reviewer
+	PaymentLineStatusPosted    = "posted"    // Approved and posted as a deposit
+	PaymentLineStatusRejected  = "rejected"
+	PaymentLineStatusDuplicate = "duplicate" // Already imported from an earlier statement
+)
+
+// Payment line match methods
+const (
+	PaymentMatchPhone     = "phone"
+	PaymentMatchReference = "reference"
+	PaymentMatchAmount    = "amount"
+	PaymentMatchManual    = "manual"
+)
+
+// PaymentStatement is an uploaded bank or JazzCash statement
+type PaymentStatement struct {
+	ID         int64                `json:"id"`
+	Source     string               `json:"source"` // e.g. "jazzcash" or "bank"
+	FileName   string               `json:"file_name"`
+	UploadedBy string               `json:"uploaded_by"`
+	Lines      []PaymentLine        `json:"lines,omitempty"`
+	Summary    PaymentStatementSums `json:"summary"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+// PaymentStatementSums counts the lines of a statement by status
+type PaymentStatementSums struct {
+	Lines       int     `json:"lines"`
+	Matched     int     `json:"matched"`
+	Unmatched   int     `json:"unmatched"`
+	Posted      int     `json:"posted"`
+	Rejected    int     `json:"rejected"`
+	Duplicate   int     `json:"duplicate"`
+	TotalAmount float64 `json:"total_amount"`
+}
+
+// PaymentLine is an incoming payment on a statement
+type PaymentLine struct {
+	ID            int64      `json:"id"`
+	StatementID   int64      `json:"statement_id"`
+	Line          int        `json:"line"` // Line number in the uploaded file
+	PaidAt        time.Time  `json:"paid_at"`
+	Amount        float64    `json:"amount"`
+	Reference     string     `json:"reference"`
+	Phone         string     `json:"phone"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"`
+	MatchMethod   string     `json:"match_method,omitempty"`
+	UserID        *int64     `json:"user_id"`
+	UserName      string     `json:"user_name,omitempty"`
+	EmployeeID    string     `json:"employee_id,omitempty"`
+	TransactionID *int64     `json:"transaction_id,omitempty"` // Deposit posted when the line was approved
+	ReviewedBy    string     `json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	Note          string     `json:"note,omitempty"`
+	Fingerprint   string     `json:"-"` // Identifies the payment across statements to detect duplicates
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// PaymentApproval is the result of approving a payment line
+type PaymentApproval struct {
+	Line        PaymentLine `json:"line"`
+	Transaction Transaction `json:"transaction"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// PayrollDeduction represents the amount to deduct from an employee's salary for a payroll period
+type PayrollDeduction struct {
+	UserID         int64   `json:"user_id"`
+	EmployeeID     string  `json:"employee_id"`
+	Name           string  `json:"name"`
+	Department     string  `json:"department"`
+	Balance        float64 `json:"balance"`          // Balance at the end of the period, less everything paid since
+	CurrentBalance float64 `json:"current_balance"`  // Balance including transactions after the period
+	AmountToDeduct float64 `json:"amount_to_deduct"` // Outstanding amount, at most what the user currently owes
+}
+
+// PayrollSettlement represents a batch of deposits posted when payroll deductions are settled
+type PayrollSettlement struct {
+	Period         string        `json:"period"` // YYYY-MM
+	BatchReference string        `json:"batch_reference"`
+	UserCount      int           `json:"user_count"`
+	TotalAmount    float64       `json:"total_amount"`
+	Transactions   []Transaction `json:"transactions"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Pending deposit statuses
+const (
+	PendingDepositStatusPending  = "pending"
+	PendingDepositStatusApproved = "approved"
+	PendingDepositStatusRejected = "rejected"
+)
+
+// PendingDeposit is a payment receipt an employee sent over WhatsApp, awaiting a cashier
+// to check it and post the deposit
+type PendingDeposit struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	UserName      string     `json:"user_name"`
+	EmployeeID    string     `json:"employee_id"`
+	Phone         string     `json:"phone"`      // Number the receipt was sent from and the confirmation is sent to
+	MessageID     string     `json:"message_id"` // WhatsApp message ID, to ignore redelivered messages
+	MediaType     string     `json:"media_type"` // "image" or "document"
+	MimeType      string     `json:"mime_type"`
+	FileName      string     `json:"file_name"`
+	FilePath      string     `json:"-"`
+	Caption       string     `json:"caption"`
+	Status        string     `json:"status"`
+	Amount        *float64   `json:"amount,omitempty"`         // Deposited amount, set when approved
+	TransactionID *int64     `json:"transaction_id,omitempty"` // Deposit posted when the receipt was approved
+	ReviewedBy    string     `json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	Note          string     `json:"note,omitempty"`
+	ReceivedAt    time.Time  `json:"received_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// PendingDepositApproval is the result of approving a pending deposit
+type PendingDepositApproval struct {
+	PendingDeposit   PendingDeposit `json:"pending_deposit"`
+	Transaction      Transaction    `json:"transaction"`
+	ConfirmationSent bool           `json:"confirmation_sent"`
+}
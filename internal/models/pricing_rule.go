@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of pricing rule. Discounts are given by the canteen, while subsidies are paid by
+// the employer on the employee's behalf.
+const (
+	PricingRuleKindDiscount = "discount"
+	PricingRuleKindSubsidy  = "subsidy"
+)
+
+// Types of pricing rule
+const (
+	PricingRuleTypePercentage = "percentage"  // Value percent off the line
+	PricingRuleTypeFixed      = "fixed"       // Value off every unit of the line
+	PricingRuleTypeBuyXGetY   = "buy_x_get_y" // FreeQuantity of every BuyQuantity + FreeQuantity units are free
+)
+
+// PricingRule is a discount or subsidy applied to the lines of purchases. A rule applies to
+// every line unless it is narrowed down to a product, category, department or user, a
+// validity period, a daily time window or a minimum quantity.
+type PricingRule struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	Kind         string     `json:"kind"` // "discount" or "subsidy"
+	Type         string     `json:"type"` // "percentage", "fixed" or "buy_x_get_y"
+	Value        float64    `json:"value"`
+	BuyQuantity  int        `json:"buy_quantity"`
+	FreeQuantity int        `json:"free_quantity"`
+	MinQuantity  int        `json:"min_quantity"`
+	ProductID    *int64     `json:"product_id"`
+	CategoryID   *int64     `json:"category_id"`
+	DepartmentID *int64     `json:"department_id"`
+	UserID       *int64     `json:"user_id"`
+	ValidFrom    *time.Time `json:"valid_from"`
+	ValidUntil   *time.Time `json:"valid_until"`
+	StartTime    string     `json:"start_time"` // Daily window in local "HH:MM", e.g. a happy hour
+	EndTime      string     `json:"end_time"`
+	Active       bool       `json:"active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
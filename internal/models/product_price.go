@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// Statuses of a product price history entry
+const (
+	ProductPriceStatusPast      = "past"
+	ProductPriceStatusCurrent   = "current"
+	ProductPriceStatusScheduled = "scheduled"
+)
+
+// ProductPrice is an entry of the price history of a product unit. Entries effective in the
+// future are scheduled price changes, which are applied to the unit once they are due.
+type ProductPrice struct {
+	ID            int64      `json:"id"`
+	ProductID     int64      `json:"product_id"`
+	UnitID        *int64     `json:"unit_id"` // Cleared when the unit is deleted
+	UnitName      string     `json:"unit_name"`
+	Price         float64    `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	AppliedAt     *time.Time `json:"applied_at,omitempty"` // Set once the price is applied to the unit
+	Reason        string     `json:"reason"`
+	Status        string     `json:"status"` // "past", "current" or "scheduled"
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// PriceChangeImpact represents the revenue impact of a single price change, from the time it
+// took effect until the next change or the end of the report
+type PriceChangeImpact struct {
+	PriceID           int64     `json:"price_id"`
+	ProductID         int64     `json:"product_id"`
+	ProductName       string    `json:"product_name"`
+	UnitID            *int64    `json:"unit_id"`
+	UnitName          string    `json:"unit_name"`
+	OldPrice          float64   `json:"old_price"`
+	NewPrice          float64   `json:"new_price"`
+	EffectiveFrom     time.Time `json:"effective_from"`
+	EffectiveUntil    time.Time `json:"effective_until"`
+	QuantitySold      int       `json:"quantity_sold"`
+	Revenue           float64   `json:"revenue"`
+	RevenueAtOldPrice float64   `json:"revenue_at_old_price"`
+	Impact            float64   `json:"impact"` // Revenue minus the revenue at the old price
+}
+
+// PriceImpactReport represents the revenue impact of the price changes made in a date range
+type PriceImpactReport struct {
+	StartDate   time.Time           `json:"start_date"`
+	EndDate     time.Time           `json:"end_date"`
+	Changes     []PriceChangeImpact `json:"changes"`
+	TotalImpact float64             `json:"total_impact"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	// ProductUnitRegular is the name of the default unit of products sold in one size
+	ProductUnitRegular = "Regular"
+	// ProductUnitPack is the name of the default unit of products also sold as singles
+	ProductUnitPack = "Pack"
+	// ProductUnitSingle is the name of the unit that replaced single unit pricing
+	ProductUnitSingle = "Single"
+
+	// LegacyPackSize is the pack size assumed when single unit pricing is converted to units
+	LegacyPackSize = 20
+)
+
+// ProductUnit is a sellable unit of a product, such as a single, a pack of 20 or a half plate
+type ProductUnit struct {
+	ID          int64     `json:"id"`
+	ProductID   int64     `json:"product_id"`
+	Name        string    `json:"name"`
+	Price       float64   `json:"price"`
+	StockFactor float64   `json:"stock_factor"` // Stock units used by one sale, e.g. 20 for a pack of 20 singles
+	IsDefault   bool      `json:"is_default"`   // The default unit's price is the product price
+	SortOrder   int       `json:"sort_order"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ProductUnitSales represents the sales of a single unit of a product
+type ProductUnitSales struct {
+	UnitID        *int64  `json:"unit_id"`
+	UnitName      string  `json:"unit_name"`
+	Quantity      int     `json:"quantity"`
+	StockQuantity float64 `json:"stock_quantity"`
+	TotalSales    float64 `json:"total_sales"`
+}
+
+// DefaultProductUnits returns the units of a product created without any. Products with
+// a single unit price are sold as packs of LegacyPackSize and singles, others in one size.
+func DefaultProductUnits(product *Product) []ProductUnit {
+	if product.SingleUnitPrice > 0 {
+		return []ProductUnit{
+			{ProductID: product.ID, Name: ProductUnitPack, Price: product.Price, StockFactor: LegacyPackSize, IsDefault: true},
+			{ProductID: product.ID, Name: ProductUnitSingle, Price: product.SingleUnitPrice, StockFactor: 1, SortOrder: 1},
+		}
+	}
+	return []ProductUnit{
+		{ProductID: product.ID, Name: ProductUnitRegular, Price: product.Price, StockFactor: 1, IsDefault: true},
+	}
+}
@@ -14,16 +14,22 @@ const (
 
 // Product represents a product in the system
 type Product struct {
-	ID              int64       `json:"id"`
-	Name            string      `json:"name"`
-	Description     string      `json:"description"`
-	Price           float64     `json:"price"`
-	Type            ProductType `json:"type"`
-	Active          bool        `json:"active"`
-	IsSingleUnit    bool        `json:"is_single_unit"`    // For cigarettes: true if single, false if packet
-	SingleUnitPrice float64     `json:"single_unit_price"` // For cigarettes: true if single, false if packet
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
+	ID              int64         `json:"id"`
+	Name            string        `json:"name"`
+	Description     string        `json:"description"`
+	Price           float64       `json:"price"`
+	Type            ProductType   `json:"type"`
+	Active          bool          `json:"active"`
+	IsSingleUnit    bool          `json:"is_single_unit"`    // Deprecated: sold as singles, replaced by Units
+	SingleUnitPrice float64       `json:"single_unit_price"` // Deprecated: price of a single, replaced by Units
+	CategoryID      *int64        `json:"category_id"`
+	SortOrder       int           `json:"sort_order"` // Position within the category on the menu, lowest first
+	Barcode         string        `json:"barcode"`
+	ImageURL        string        `json:"image_url,omitempty"` // Set when the product has an uploaded image
+	Units           []ProductUnit `json:"units,omitempty"`     // Sellable units, the default unit first
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+	DeletedAt       *time.Time    `json:"deleted_at,omitempty"` // Set when the product is soft deleted
 }
 
 // GetID returns the product ID
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PurgeResult represents the soft deleted rows permanently removed by a purge
+type PurgeResult struct {
+	DeletedBefore time.Time `json:"deleted_before"`
+	Users         int64     `json:"users"`
+	Products      int64     `json:"products"`
+	Transactions  int64     `json:"transactions"`
+}
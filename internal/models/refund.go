@@ -0,0 +1,13 @@
+package models
+
+// RefundLine is a quantity of a purchase line that is returned
+type RefundLine struct {
+	TransactionProductID int64 `json:"transaction_product_id"`
+	Quantity             int   `json:"quantity"`
+}
+
+// Refund is a refund transaction together with the product lines it returned
+type Refund struct {
+	Transaction Transaction          `json:"transaction"`
+	Products    []TransactionProduct `json:"products"`
+}
@@ -4,15 +4,68 @@ import (
 	"time"
 )
 
+// Payment methods of a transaction
+const (
+	PaymentMethodAccount = "account" // Charged to or credited from the user's balance
+	PaymentMethodCash    = "cash"    // Paid in cash at the drawer; cash purchases are settled on the spot
+)
+
+// Transaction types
+const (
+	TransactionTypePurchase   = "purchase"
+	TransactionTypeDeposit    = "deposit"
+	TransactionTypeRefund     = "refund"
+	TransactionTypeAdjustment = "adjustment" // Correction charged to the user's balance
+	TransactionTypeWriteOff   = "write-off"  // Outstanding balance the canteen forgives
+	TransactionTypeTransfer   = "transfer"   // Balance moved between users, signed by its amount
+	TransactionTypeTopUp      = "top-up"     // Funds paid in advance into the user's wallet
+	TransactionTypeBonus      = "bonus"      // Extra credit granted by a top-up bonus rule
+)
+
+// TransactionTypes lists the valid transaction types
+var TransactionTypes = []string{
+	TransactionTypePurchase,
+	TransactionTypeDeposit,
+	TransactionTypeRefund,
+	TransactionTypeAdjustment,
+	TransactionTypeWriteOff,
+	TransactionTypeTransfer,
+	TransactionTypeTopUp,
+	TransactionTypeBonus,
+}
+
+// TransactionTypeSign returns the sign of the effect a transaction type has on the user's
+// balance: 1 for credits, -1 for debits and 0 for invalid types. Transfers credit their
+// amount, which is negative for the user the balance is moved from.
+func TransactionTypeSign(transactionType string) int {
+	switch transactionType {
+	case TransactionTypeDeposit, TransactionTypeRefund, TransactionTypeWriteOff, TransactionTypeTransfer,
+		TransactionTypeTopUp, TransactionTypeBonus:
+		return 1
+	case TransactionTypePurchase, TransactionTypeAdjustment:
+		return -1
+	default:
+		return 0
+	}
+}
+
 // Transaction represents a financial transaction in the system
 type Transaction struct {
-	ID              int64     `json:"id"`
-	UserID          int64     `json:"user_id"`
-	Amount          float64   `json:"amount"`
-	Description     string    `json:"description"`
-	TransactionType string    `json:"transaction_type"` // e.g., "deposit", "withdrawal", "purchase"
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"user_id"` // 0 for walk-in guest sales, which have no user
+	Amount          float64    `json:"amount"`  // Total of the employee and company shares
+	Description     string     `json:"description"`
+	TransactionType string     `json:"transaction_type"`          // One of TransactionTypes
+	BatchReference  string     `json:"batch_reference,omitempty"` // Shared by transactions posted together, e.g. a payroll settlement
+	DiscountAmount  float64    `json:"discount_amount"`           // Taken off the product lines by discount rules
+	EmployeeShare   float64    `json:"employee_share"`            // Charged to the user's balance
+	CompanyShare    float64    `json:"company_share"`             // Paid by the employer, e.g. through subsidy rules
+	PaymentMethod   string     `json:"payment_method"`            // PaymentMethodAccount or PaymentMethodCash
+	CashSessionID   *int64     `json:"cash_session_id,omitempty"` // Cash session of the cashier who recorded it
+	RefundOfID      *int64     `json:"refund_of_id,omitempty"`    // Purchase a refund returns products of
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"` // Set when the transaction is soft deleted
 }
 
 // EmployeeTransaction represents a financial transaction with user details
@@ -23,6 +76,8 @@ type EmployeeTransaction struct {
 	EmployeeID      string    `json:"employee_id"`
 	Department      string    `json:"department"`
 	Amount          float64   `json:"amount"`
+	EmployeeShare   float64   `json:"employee_share"`
+	CompanyShare    float64   `json:"company_share"`
 	Description     string    `json:"description"`
 	TransactionType string    `json:"transaction_type"`
 	CreatedAt       time.Time `json:"created_at"`
@@ -0,0 +1,29 @@
+package models
+
+// TransactionImportRow represents a validated row of a bulk transaction import
+type TransactionImportRow struct {
+	Line        int                  `json:"line"`
+	EmployeeID  string               `json:"employee_id"`
+	Transaction Transaction          `json:"transaction"`
+	Products    []TransactionProduct `json:"products,omitempty"`
+}
+
+// TransactionImportRowError represents the validation errors of a single import row
+type TransactionImportRowError struct {
+	Line       int      `json:"line"`
+	EmployeeID string   `json:"employee_id"`
+	Errors     []string `json:"errors"`
+}
+
+// TransactionImportResult represents the outcome of a bulk transaction import or dry run
+type TransactionImportResult struct {
+	DryRun         bool                        `json:"dry_run"`
+	TotalRows      int                         `json:"total_rows"`
+	ValidRows      int                         `json:"valid_rows"`
+	InvalidRows    int                         `json:"invalid_rows"`
+	Imported       int                         `json:"imported"`
+	TotalAmount    float64                     `json:"total_amount"`
+	BatchReference string                      `json:"batch_reference,omitempty"`
+	Errors         []TransactionImportRowError `json:"errors"`
+	Rows           []TransactionImportRow      `json:"rows,omitempty"`
+}
@@ -6,39 +6,55 @@ import (
 
 // TransactionProduct represents a product included in a transaction
 type TransactionProduct struct {
-	ID            int64     `json:"id"`
-	TransactionID int64     `json:"transaction_id"`
-	ProductID     int64     `json:"product_id"`
-	ProductName   string    `json:"product_name"`
-	Quantity      int       `json:"quantity"`
-	UnitPrice     float64   `json:"unit_price"`
-	IsSingleUnit  bool      `json:"is_single_unit"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             int64     `json:"id"`
+	TransactionID  int64     `json:"transaction_id"`
+	ProductID      int64     `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	Quantity       int       `json:"quantity"`
+	UnitPrice      float64   `json:"unit_price"`
+	IsSingleUnit   bool      `json:"is_single_unit"`
+	UnitID         *int64    `json:"unit_id"`
+	UnitName       string    `json:"unit_name"`
+	StockFactor    float64   `json:"stock_factor"` // Stock factor of the unit at the time of sale
+	DiscountRuleID *int64    `json:"discount_rule_id"`
+	DiscountName   string    `json:"discount_name"`
+	DiscountAmount float64   `json:"discount_amount"` // Taken off the line by the discount rule
+	SubsidyRuleID  *int64    `json:"subsidy_rule_id"`
+	SubsidyName    string    `json:"subsidy_name"`
+	SubsidyAmount  float64   `json:"subsidy_amount"`             // Paid by the employer through the subsidy rule
+	RefundedLineID *int64    `json:"refunded_line_id,omitempty"` // Purchase line a refund line returns
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // TransactionProductDetail represents transaction product with additional product details
 type TransactionProductDetail struct {
-	ID            int64     `json:"id"`
-	TransactionID int64     `json:"transaction_id"`
-	ProductID     int64     `json:"product_id"`
-	ProductName   string    `json:"product_name"`
-	ProductType   string    `json:"product_type"`
-	Quantity      int       `json:"quantity"`
-	UnitPrice     float64   `json:"unit_price"`
-	TotalPrice    float64   `json:"total_price"`
-	IsSingleUnit  bool      `json:"is_single_unit"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             int64     `json:"id"`
+	TransactionID  int64     `json:"transaction_id"`
+	ProductID      int64     `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	ProductType    string    `json:"product_type"`
+	Quantity       int       `json:"quantity"`
+	UnitPrice      float64   `json:"unit_price"`
+	TotalPrice     float64   `json:"total_price"`
+	IsSingleUnit   bool      `json:"is_single_unit"`
+	UnitID         *int64    `json:"unit_id"`
+	UnitName       string    `json:"unit_name"`
+	DiscountAmount float64   `json:"discount_amount"`
+	SubsidyAmount  float64   `json:"subsidy_amount"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // ProductSalesSummary represents summary statistics for product sales
 type ProductSalesSummary struct {
-	ProductID      int64   `json:"product_id"`
-	ProductName    string  `json:"product_name"`
-	ProductType    string  `json:"product_type"`
-	TotalQuantity  int     `json:"total_quantity"`
-	TotalSales     float64 `json:"total_sales"`
-	SingleUnitSold int     `json:"single_unit_sold"`
-	FullUnitSold   int     `json:"full_unit_sold"`
+	ProductID      int64              `json:"product_id"`
+	ProductName    string             `json:"product_name"`
+	ProductType    string             `json:"product_type"`
+	TotalQuantity  int                `json:"total_quantity"`
+	TotalSales     float64            `json:"total_sales"`
+	SingleUnitSold int                `json:"single_unit_sold"`
+	FullUnitSold   int                `json:"full_unit_sold"`
+	StockQuantity  float64            `json:"stock_quantity"` // Quantity sold in stock units
+	Variants       []ProductUnitSales `json:"variants"`
 }
@@ -10,11 +10,14 @@ type User struct {
 	Name             string     `json:"name"`
 	EmployeeId       string     `json:"employee_id"`
 	Department       string     `json:"department"`
+	DepartmentID     *int64     `json:"department_id"`
 	Phone            string     `json:"phone"`
 	Active           bool       `json:"active"`
+	CreditLimit      float64    `json:"credit_limit"` // Maximum amount purchases on account and balance transfers may take the user's debt to. 0 means no limit on purchases and no debt from transfers
 	LastNotification *time.Time `json:"last_notification"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"` // Set when the user is soft deleted
 }
 
 // GetID returns the user ID
@@ -12,4 +12,5 @@ type UserBalance struct {
 	LastNotification *time.Time `json:"last_notification"`
 	Phone            string     `json:"user_phone"`
 	Balance          float64    `json:"balance"`
+	Prepaid          bool       `json:"prepaid"` // Balance is positive: the user has funds paid in advance
 }
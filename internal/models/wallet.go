@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// TopUpBonusRule grants extra credit on wallet top-ups of at least MinAmount, e.g. "top up
+// 5000 get 100 extra". The bonus is BonusAmount plus BonusPercent of the top-up.
+type TopUpBonusRule struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	MinAmount    float64    `json:"min_amount"`
+	BonusAmount  float64    `json:"bonus_amount"`
+	BonusPercent float64    `json:"bonus_percent"`
+	ValidFrom    *time.Time `json:"valid_from"`
+	ValidUntil   *time.Time `json:"valid_until"`
+	Active       bool       `json:"active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Bonus returns the bonus the rule grants on a top-up of amount at the given time, or zero if
+// the rule does not apply
+func (r *TopUpBonusRule) Bonus(amount float64, at time.Time) float64 {
+	if !r.Active || amount < r.MinAmount {
+		return 0
+	}
+	if (r.ValidFrom != nil && at.Before(*r.ValidFrom)) || (r.ValidUntil != nil && !at.Before(*r.ValidUntil)) {
+		return 0
+	}
+	return r.BonusAmount + amount*r.BonusPercent/100
+}
+
+// TopUp is money paid in advance into a user's wallet, distinct from deposits settling what
+// the user owes. It is recorded as a top-up transaction, with a bonus transaction if a bonus
+// rule applied.
+type TopUp struct {
+	ID                 int64     `json:"id"`
+	UserID             int64     `json:"user_id"`
+	UserName           string    `json:"user_name"`
+	Amount             float64   `json:"amount"`
+	BonusAmount        float64   `json:"bonus_amount"`
+	BonusRuleID        *int64    `json:"bonus_rule_id,omitempty"`
+	BonusRuleName      string    `json:"bonus_rule_name,omitempty"` // Kept when the rule is deleted
+	PaymentMethod      string    `json:"payment_method"`
+	Note               string    `json:"note,omitempty"`
+	TransactionID      *int64    `json:"transaction_id,omitempty"`
+	BonusTransactionID *int64    `json:"bonus_transaction_id,omitempty"`
+	CreatedBy          string    `json:"created_by"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// PrepaidLiability is the money the canteen holds for users with a positive balance, which
+// it owes them in purchases
+type PrepaidLiability struct {
+	AsOf           time.Time     `json:"as_of"`
+	TotalLiability float64       `json:"total_liability"` // Sum of the positive balances
+	UserCount      int           `json:"user_count"`
+	TotalTopUps    float64       `json:"total_top_ups"` // All wallet top-ups ever paid in
+	TotalBonuses   float64       `json:"total_bonuses"` // All top-up bonuses ever granted
+	Users          []UserBalance `json:"users"`
+}
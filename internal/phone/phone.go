@@ -0,0 +1,74 @@
+// Package phone normalizes phone numbers to the E.164 format used for WhatsApp.
+package phone
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrInvalid is returned when a phone number cannot be normalized to E.164
+var ErrInvalid = errors.New("invalid phone number")
+
+// DefaultCountryCode returns the country calling code used for numbers written in
+// national format, configurable via the DEFAULT_PHONE_COUNTRY_CODE env var (default "92").
+func DefaultCountryCode() string {
+	code := strings.TrimPrefix(strings.TrimSpace(os.Getenv("DEFAULT_PHONE_COUNTRY_CODE")), "+")
+	if code == "" {
+		return "92"
+	}
+	return code
+}
+
+// Normalize converts a phone number to E.164 (e.g. "+923001234567").
+// Spaces, dashes, dots and parentheses are ignored. Numbers starting with "00" are
+// treated as international, and numbers starting with a single "0" as national
+// numbers of the default country. An empty number normalizes to "".
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+
+	international := strings.HasPrefix(raw, "+")
+	var digits strings.Builder
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalid
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		number = DefaultCountryCode() + number[1:]
+	case !strings.HasPrefix(number, DefaultCountryCode()):
+		// A bare national number without its leading zero
+		number = DefaultCountryCode() + number
+	}
+
+	// E.164 allows at most 15 digits; anything under 8 is not a subscriber number
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalid
+	}
+	return "+" + number, nil
+}
+
+// Digits returns only the digits of a phone number, e.g. for comparing numbers
+// stored in different formats
+func Digits(raw string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, raw)
+}
@@ -0,0 +1,44 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Setenv("DEFAULT_PHONE_COUNTRY_CODE", "")
+
+	valid := map[string]string{
+		"+923452324442":    "+923452324442",
+		"+92 345 232-4442": "+923452324442",
+		"03452324442":      "+923452324442",
+		"923452324442":     "+923452324442",
+		"3452324442":       "+923452324442",
+		"00447911123456":   "+447911123456",
+		"(0345) 232.44.42": "+923452324442",
+		"":                 "",
+	}
+	for raw, expected := range valid {
+		normalized, err := Normalize(raw)
+		assert.NoError(t, err, raw)
+		assert.Equal(t, expected, normalized, raw)
+	}
+
+	for _, raw := range []string{"12", "+0345232444", "0345-CANTEEN", "+1234567890123456", "92+3452324442"} {
+		_, err := Normalize(raw)
+		assert.ErrorIs(t, err, ErrInvalid, raw)
+	}
+}
+
+func TestNormalizeWithCountryCode(t *testing.T) {
+	t.Setenv("DEFAULT_PHONE_COUNTRY_CODE", "+44")
+
+	normalized, err := Normalize("07911 123456")
+	assert.NoError(t, err)
+	assert.Equal(t, "+447911123456", normalized)
+}
+
+func TestDigits(t *testing.T) {
+	assert.Equal(t, "923452324442", Digits("+92 345-2324442"))
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"maya-canteen/internal/database"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultAuditRetentionDays is how long audit log entries are kept, configurable via AUDIT_RETENTION_DAYS
+const defaultAuditRetentionDays = 365
+
+// StartAuditRetention removes audit log entries older than AUDIT_RETENTION_DAYS once at
+// startup and then daily. Setting AUDIT_RETENTION_DAYS to 0 keeps entries forever.
+func StartAuditRetention(db database.Service) {
+	days := defaultAuditRetentionDays
+	if value := os.Getenv("AUDIT_RETENTION_DAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Errorf("Invalid AUDIT_RETENTION_DAYS %q, keeping audit logs for %d days", value, days)
+		} else {
+			days = parsed
+		}
+	}
+	if days == 0 {
+		log.Info("Audit logs are kept forever")
+		return
+	}
+
+	purge := func() {
+		removed, err := db.PurgeAuditLogs(time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Errorf("Audit log retention failed: %v", err)
+			return
+		}
+		if removed > 0 {
+			log.Infof("Removed %d audit log entries older than %d days", removed, days)
+		}
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
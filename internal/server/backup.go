@@ -0,0 +1,70 @@
+package server
+
+import (
+	"maya-canteen/internal/database"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Scheduled backup defaults, configurable via BACKUP_INTERVAL, BACKUP_DIR and BACKUP_RETENTION
+const (
+	defaultBackupInterval  = 24 * time.Hour
+	defaultBackupRetention = 7
+)
+
+// BackupDir returns the scheduled backup directory, configurable via env var.
+// It defaults to a backups folder next to the database.
+func BackupDir() string {
+	if dir := os.Getenv("BACKUP_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(SetupDBPath()), "backups")
+}
+
+// StartBackupScheduler periodically backs up the database into BackupDir, keeping the
+// newest BACKUP_RETENTION files (0 keeps all). Setting BACKUP_INTERVAL to 0 disables it.
+func StartBackupScheduler(db database.Service) {
+	interval := defaultBackupInterval
+	if value := os.Getenv("BACKUP_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Errorf("Invalid BACKUP_INTERVAL %q, using %s: %v", value, interval, err)
+		} else {
+			interval = parsed
+		}
+	}
+	if interval <= 0 {
+		log.Info("Scheduled backups are disabled")
+		return
+	}
+
+	retention := defaultBackupRetention
+	if value := os.Getenv("BACKUP_RETENTION"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Errorf("Invalid BACKUP_RETENTION %q, keeping %d backups", value, retention)
+		} else {
+			retention = parsed
+		}
+	}
+
+	dir := BackupDir()
+	log.Infof("Backing up the database to %s every %s, keeping %d backups", dir, interval, retention)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			path, err := db.RunScheduledBackup(dir, retention)
+			if err != nil {
+				log.Errorf("Scheduled backup failed: %v", err)
+				continue
+			}
+			log.Infof("Database backed up to %s", path)
+		}
+	}()
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PreOrderPunchHook returns the ZK device attendance hook that collects the employee's
+// pre-orders of the day when they punch in. It returns nil, leaving punches alone, unless
+// PREORDER_COLLECT_ON_PUNCH is set to true.
+func PreOrderPunchHook() func(employeeID string, attendedAt time.Time) {
+	value := os.Getenv("PREORDER_COLLECT_ON_PUNCH")
+	if value == "" {
+		return nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Errorf("Invalid PREORDER_COLLECT_ON_PUNCH %q, not collecting pre-orders on punch", value)
+		return nil
+	}
+	if !enabled {
+		return nil
+	}
+
+	log.Info("Collecting pre-orders on ZK device punches")
+	return handlers.NewDailyMenuHandler(database.New()).CollectOnPunch
+}
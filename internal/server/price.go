@@ -0,0 +1,36 @@
+package server
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// priceSchedulerInterval is how often scheduled product prices are checked
+const priceSchedulerInterval = time.Minute
+
+// StartPriceScheduler applies scheduled product prices once they are due, checking once at
+// startup and then every priceSchedulerInterval
+func StartPriceScheduler(db database.Service) {
+	productHandler := handlers.NewProductHandler(db)
+	apply := func() {
+		applied, err := productHandler.ApplyDuePrices(time.Now())
+		if err != nil {
+			log.Errorf("Applying scheduled prices failed: %v", err)
+		}
+		if applied > 0 {
+			log.Infof("Applied %d scheduled product prices", applied)
+		}
+	}
+
+	go func() {
+		apply()
+		ticker := time.NewTicker(priceSchedulerInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			apply()
+		}
+	}()
+}
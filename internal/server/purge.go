@@ -0,0 +1,43 @@
+package server
+
+import (
+	"maya-canteen/internal/database"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StartPurgeScheduler permanently removes rows soft deleted more than PURGE_DELETED_AFTER_DAYS
+// ago, once at startup and then daily. It is disabled unless PURGE_DELETED_AFTER_DAYS is set.
+func StartPurgeScheduler(db database.Service) {
+	value := os.Getenv("PURGE_DELETED_AFTER_DAYS")
+	if value == "" {
+		return
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days <= 0 {
+		log.Errorf("Invalid PURGE_DELETED_AFTER_DAYS %q, scheduled purging is disabled", value)
+		return
+	}
+
+	purge := func() {
+		result, err := db.PurgeDeleted(time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Errorf("Scheduled purge failed: %v", err)
+			return
+		}
+		log.Infof("Purged %d users, %d products and %d transactions deleted more than %d days ago",
+			result.Users, result.Products, result.Transactions, days)
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+	"maya-canteen/internal/server/routes"
+	"os"
+	"path/filepath"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ReceiptDir returns the directory payment receipts received over WhatsApp are saved to,
+// configurable via the RECEIPT_DIR env var. It defaults to a receipts folder next to the database.
+func ReceiptDir() string {
+	if dir := os.Getenv("RECEIPT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(SetupDBPath()), "receipts")
+}
+
+// ReceiptMessageHook returns the WhatsApp message hook that saves payment receipts sent by
+// registered users as pending deposits
+func ReceiptMessageHook() func(*events.Message) {
+	handler := handlers.NewPendingDepositHandler(database.New(), routes.CurrentWhatsAppClient)
+	handler.ReceiptDir = ReceiptDir()
+	return handler.SaveReceipt
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterAgingRoutes registers all balance aging and escalation policy routes
+func RegisterAgingRoutes(router *mux.Router, db database.Service) {
+	// Create aging handler
+	agingHandler := handlers.NewAgingHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/reports/aging", agingHandler.GetAgingReport).Methods("GET")
+	router.HandleFunc("/api/escalation-levels", agingHandler.GetEscalationLevels).Methods("GET")
+	router.HandleFunc("/api/escalation-levels", agingHandler.CreateEscalationLevel).Methods("POST")
+	router.HandleFunc("/api/escalation-levels/{id}", agingHandler.GetEscalationLevel).Methods("GET")
+	router.HandleFunc("/api/escalation-levels/{id}", agingHandler.UpdateEscalationLevel).Methods("PUT")
+	router.HandleFunc("/api/escalation-levels/{id}", agingHandler.DeleteEscalationLevel).Methods("DELETE")
+	router.HandleFunc("/api/escalation-notices", agingHandler.GetEscalationNotices).Methods("GET")
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterAnalyticsRoutes registers all spending analytics routes
+func RegisterAnalyticsRoutes(router *mux.Router, db database.Service) {
+	// Create analytics handler
+	analyticsHandler := handlers.NewAnalyticsHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/analytics/summary", analyticsHandler.GetSummary).Methods("POST")
+	router.HandleFunc("/api/analytics/revenue", analyticsHandler.GetRevenue).Methods("POST")
+	router.HandleFunc("/api/analytics/heatmap", analyticsHandler.GetHeatmap).Methods("POST")
+	router.HandleFunc("/api/analytics/daily-customers", analyticsHandler.GetDailyCustomers).Methods("POST")
+	router.HandleFunc("/api/analytics/top-products", analyticsHandler.GetTopProducts).Methods("POST")
+	router.HandleFunc("/api/analytics/top-users", analyticsHandler.GetTopUsers).Methods("POST")
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterAuditRoutes registers all audit log routes
+func RegisterAuditRoutes(router *mux.Router, db database.Service) {
+	// Create audit handler
+	auditHandler := handlers.NewAuditHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/audit", auditHandler.GetAuditLogs).Methods("GET")
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterBackupRoutes registers all backup and restore routes
+func RegisterBackupRoutes(router *mux.Router, db database.Service) {
+	// Create backup handler
+	backupHandler := handlers.NewBackupHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/admin/backup", backupHandler.Backup).Methods("GET")
+	router.HandleFunc("/api/admin/restore", backupHandler.Restore).Methods("POST")
+}
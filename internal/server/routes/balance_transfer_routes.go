@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterBalanceTransferRoutes registers all balance transfer routes
+func RegisterBalanceTransferRoutes(router *mux.Router, db database.Service) {
+	// Create balance transfer handler
+	balanceTransferHandler := handlers.NewBalanceTransferHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/transfers", balanceTransferHandler.CreateBalanceTransfer).Methods("POST")
+	router.HandleFunc("/api/transfers", balanceTransferHandler.GetBalanceTransfers).Methods("GET")
+	router.HandleFunc("/api/transfers/{id}", balanceTransferHandler.GetBalanceTransfer).Methods("GET")
+	router.HandleFunc("/api/transfers/{id}/approve", balanceTransferHandler.ApproveBalanceTransfer).Methods("POST")
+	router.HandleFunc("/api/transfers/{id}/reject", balanceTransferHandler.RejectBalanceTransfer).Methods("POST")
+	router.HandleFunc("/api/users/{user_id}/transfers", balanceTransferHandler.GetUserBalanceTransfers).Methods("GET")
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterCashSessionRoutes registers all routes for cash drawer sessions
+func RegisterCashSessionRoutes(router *mux.Router, db database.Service) {
+	// Create cash session handler
+	cashSessionHandler := handlers.NewCashSessionHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/cash-sessions", cashSessionHandler.OpenCashSession).Methods("POST")
+	router.HandleFunc("/api/cash-sessions", cashSessionHandler.GetCashSessions).Methods("GET")
+	router.HandleFunc("/api/cash-sessions/current", cashSessionHandler.GetCurrentCashSession).Methods("GET")
+	router.HandleFunc("/api/cash-sessions/{id}", cashSessionHandler.GetCashSession).Methods("GET")
+	router.HandleFunc("/api/cash-sessions/{id}/close", cashSessionHandler.CloseCashSession).Methods("POST")
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterCategoryRoutes registers all product category and menu routes
+func RegisterCategoryRoutes(router *mux.Router, db database.Service) {
+	// Create category handler
+	categoryHandler := handlers.NewCategoryHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/categories", categoryHandler.GetAllCategories).Methods("GET")
+	router.HandleFunc("/api/categories", categoryHandler.CreateCategory).Methods("POST")
+	router.HandleFunc("/api/categories/{id}", categoryHandler.GetCategory).Methods("GET")
+	router.HandleFunc("/api/categories/{id}", categoryHandler.UpdateCategory).Methods("PUT")
+	router.HandleFunc("/api/categories/{id}", categoryHandler.DeleteCategory).Methods("DELETE")
+	router.HandleFunc("/api/menu", categoryHandler.GetMenu).Methods("GET")
+}
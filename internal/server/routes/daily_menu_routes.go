@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterDailyMenuRoutes registers all daily menu and pre-order routes
+func RegisterDailyMenuRoutes(router *mux.Router, db database.Service) {
+	// Create daily menu handler
+	dailyMenuHandler := handlers.NewDailyMenuHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/menu/{date}", dailyMenuHandler.GetDailyMenu).Methods("GET")
+	router.HandleFunc("/api/menu/{date}", dailyMenuHandler.PublishDailyMenu).Methods("PUT")
+	router.HandleFunc("/api/menu/{date}/orders", dailyMenuHandler.GetMenuOrders).Methods("GET")
+	router.HandleFunc("/api/menu/{date}/orders", dailyMenuHandler.PlacePreOrders).Methods("POST")
+	router.HandleFunc("/api/menu/{date}/orders/collect", dailyMenuHandler.CollectPreOrders).Methods("POST")
+	router.HandleFunc("/api/menu/{date}/orders/{orderId}", dailyMenuHandler.CancelPreOrder).Methods("DELETE")
+}
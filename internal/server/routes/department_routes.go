@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterDepartmentRoutes registers all department-related routes
+func RegisterDepartmentRoutes(router *mux.Router, db database.Service) {
+	// Create department handler
+	departmentHandler := handlers.NewDepartmentHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/departments", departmentHandler.GetAllDepartments).Methods("GET")
+	router.HandleFunc("/api/departments", departmentHandler.CreateDepartment).Methods("POST")
+	router.HandleFunc("/api/departments/{id}", departmentHandler.GetDepartment).Methods("GET")
+	router.HandleFunc("/api/departments/{id}", departmentHandler.UpdateDepartment).Methods("PUT")
+	router.HandleFunc("/api/departments/{id}", departmentHandler.DeleteDepartment).Methods("DELETE")
+
+	// Department reporting endpoints
+	router.HandleFunc("/api/reports/department-spend", departmentHandler.GetDepartmentSpend).Methods("POST")
+	router.HandleFunc("/api/reports/department-top-consumers", departmentHandler.GetDepartmentTopConsumers).Methods("POST")
+	router.HandleFunc("/api/reports/department-products", departmentHandler.GetDepartmentProducts).Methods("POST")
+	router.HandleFunc("/api/reports/department-balances", departmentHandler.GetDepartmentBalances).Methods("GET")
+	router.HandleFunc("/api/reports/employer-invoice", departmentHandler.GetEmployerInvoice).Methods("GET")
+}
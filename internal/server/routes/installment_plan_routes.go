@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterInstallmentPlanRoutes registers all installment plan routes
+func RegisterInstallmentPlanRoutes(router *mux.Router, db database.Service) {
+	// Create installment plan handler
+	installmentPlanHandler := handlers.NewInstallmentPlanHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/installment-plans", installmentPlanHandler.GetInstallmentPlans).Methods("GET")
+	router.HandleFunc("/api/installment-plans/{id}", installmentPlanHandler.GetInstallmentPlan).Methods("GET")
+	router.HandleFunc("/api/installment-plans/{id}/payments", installmentPlanHandler.PayInstallmentPlan).Methods("POST")
+	router.HandleFunc("/api/installment-plans/{id}/cancel", installmentPlanHandler.CancelInstallmentPlan).Methods("POST")
+	router.HandleFunc("/api/users/{user_id}/installment-plans", installmentPlanHandler.GetUserInstallmentPlans).Methods("GET")
+	router.HandleFunc("/api/users/{user_id}/installment-plans", installmentPlanHandler.CreateInstallmentPlan).Methods("POST")
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterOffboardingRoutes registers all routes for offboarding departing employees
+func RegisterOffboardingRoutes(router *mux.Router, db database.Service) {
+	// Create offboarding handler
+	offboardingHandler := handlers.NewOffboardingHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/offboardings", offboardingHandler.GetOffboardings).Methods("GET")
+	router.HandleFunc("/api/users/{id}/offboarding", offboardingHandler.GetFinalStatement).Methods("GET")
+	router.HandleFunc("/api/users/{id}/offboarding", offboardingHandler.OffboardUser).Methods("POST")
+	router.HandleFunc("/api/reports/write-offs", offboardingHandler.GetWriteOffReport).Methods("POST")
+}
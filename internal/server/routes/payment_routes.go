@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterPaymentRoutes registers all payment statement and reconciliation routes
+func RegisterPaymentRoutes(router *mux.Router, db database.Service) {
+	// Create payment handler
+	paymentHandler := handlers.NewPaymentHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/payments/statements", paymentHandler.UploadStatement).Methods("POST")
+	router.HandleFunc("/api/payments/statements", paymentHandler.GetStatements).Methods("GET")
+	router.HandleFunc("/api/payments/statements/{id}", paymentHandler.GetStatement).Methods("GET")
+	router.HandleFunc("/api/payments/review", paymentHandler.GetReviewQueue).Methods("GET")
+	router.HandleFunc("/api/payments/lines/{id}/approve", paymentHandler.ApproveLine).Methods("POST")
+	router.HandleFunc("/api/payments/lines/{id}/reject", paymentHandler.RejectLine).Methods("POST")
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterPayrollRoutes registers all payroll deduction routes
+func RegisterPayrollRoutes(router *mux.Router, db database.Service) {
+	// Create payroll handler
+	payrollHandler := handlers.NewPayrollHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/payroll/deductions", payrollHandler.ExportPayrollDeductions).Methods("GET")
+	router.HandleFunc("/api/payroll/settle", payrollHandler.SettlePayrollDeductions).Methods("POST")
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterPendingDepositRoutes registers all routes for receipts awaiting a deposit
+func RegisterPendingDepositRoutes(router *mux.Router, db database.Service) {
+	// Create pending deposit handler
+	pendingDepositHandler := handlers.NewPendingDepositHandler(db, CurrentWhatsAppClient)
+
+	// Register routes
+	router.HandleFunc("/api/deposits/pending", pendingDepositHandler.GetPendingDeposits).Methods("GET")
+	router.HandleFunc("/api/deposits/pending/{id}/receipt", pendingDepositHandler.GetReceipt).Methods("GET")
+	router.HandleFunc("/api/deposits/pending/{id}/approve", pendingDepositHandler.ApprovePendingDeposit).Methods("POST")
+	router.HandleFunc("/api/deposits/pending/{id}/reject", pendingDepositHandler.RejectPendingDeposit).Methods("POST")
+}
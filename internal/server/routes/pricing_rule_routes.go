@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterPricingRuleRoutes registers all discount and subsidy rule routes
+func RegisterPricingRuleRoutes(router *mux.Router, db database.Service) {
+	// Create pricing rule handler
+	pricingRuleHandler := handlers.NewPricingRuleHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/pricing-rules", pricingRuleHandler.GetAllPricingRules).Methods("GET")
+	router.HandleFunc("/api/pricing-rules", pricingRuleHandler.CreatePricingRule).Methods("POST")
+	router.HandleFunc("/api/pricing-rules/{id}", pricingRuleHandler.GetPricingRule).Methods("GET")
+	router.HandleFunc("/api/pricing-rules/{id}", pricingRuleHandler.UpdatePricingRule).Methods("PUT")
+	router.HandleFunc("/api/pricing-rules/{id}", pricingRuleHandler.DeletePricingRule).Methods("DELETE")
+}
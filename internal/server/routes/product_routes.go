@@ -15,7 +15,20 @@ func RegisterProductRoutes(router *mux.Router, db database.Service) {
 	// Register routes
 	router.HandleFunc("/api/products", productHandler.GetAllProducts).Methods("GET")
 	router.HandleFunc("/api/products", productHandler.CreateProduct).Methods("POST")
+	router.HandleFunc("/api/products/barcode/{barcode}", productHandler.GetProductByBarcode).Methods("GET")
 	router.HandleFunc("/api/products/{id}", productHandler.GetProduct).Methods("GET")
 	router.HandleFunc("/api/products/{id}", productHandler.UpdateProduct).Methods("PUT")
 	router.HandleFunc("/api/products/{id}", productHandler.DeleteProduct).Methods("DELETE")
+	router.HandleFunc("/api/products/{id}/restore", productHandler.RestoreProduct).Methods("POST")
+	router.HandleFunc("/api/products/{id}/image", productHandler.GetProductImage).Methods("GET")
+	router.HandleFunc("/api/products/{id}/image", productHandler.UploadProductImage).Methods("PUT")
+	router.HandleFunc("/api/products/{id}/image", productHandler.DeleteProductImage).Methods("DELETE")
+	router.HandleFunc("/api/products/{id}/units", productHandler.GetProductUnits).Methods("GET")
+	router.HandleFunc("/api/products/{id}/units", productHandler.CreateProductUnit).Methods("POST")
+	router.HandleFunc("/api/products/{id}/units/{unitId}", productHandler.UpdateProductUnit).Methods("PUT")
+	router.HandleFunc("/api/products/{id}/units/{unitId}", productHandler.DeleteProductUnit).Methods("DELETE")
+	router.HandleFunc("/api/products/{id}/prices", productHandler.GetProductPrices).Methods("GET")
+	router.HandleFunc("/api/products/{id}/prices", productHandler.ScheduleProductPrice).Methods("POST")
+	router.HandleFunc("/api/products/{id}/prices/{priceId}", productHandler.CancelProductPrice).Methods("DELETE")
+	router.HandleFunc("/api/reports/price-impact", productHandler.GetPriceImpactReport).Methods("POST")
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterPurgeRoutes registers the route for purging soft deleted rows
+func RegisterPurgeRoutes(router *mux.Router, db database.Service) {
+	// Create purge handler
+	purgeHandler := handlers.NewPurgeHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/admin/purge", purgeHandler.PurgeDeleted).Methods("POST")
+}
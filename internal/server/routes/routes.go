@@ -26,6 +26,23 @@ func RegisterRoutes(db database.Service, whatsappClient handlers.WhatsAppClient)
 	RegisterTransactionRoutes(router, db)
 	RegisterUserRoutes(router, db)
 	RegisterProductRoutes(router, db)
+	RegisterCategoryRoutes(router, db)
+	RegisterDailyMenuRoutes(router, db)
+	RegisterPricingRuleRoutes(router, db)
+	RegisterPaymentRoutes(router, db)
+	RegisterPendingDepositRoutes(router, db)
+	RegisterCashSessionRoutes(router, db)
+	RegisterBalanceTransferRoutes(router, db)
+	RegisterOffboardingRoutes(router, db)
+	RegisterWalletRoutes(router, db)
+	RegisterInstallmentPlanRoutes(router, db)
+	RegisterAgingRoutes(router, db)
+	RegisterAnalyticsRoutes(router, db)
+	RegisterDepartmentRoutes(router, db)
+	RegisterPayrollRoutes(router, db)
+	RegisterBackupRoutes(router, db)
+	RegisterAuditRoutes(router, db)
+	RegisterPurgeRoutes(router, db)
 	RegisterWhatsAppRoutes(router, db)
 
 	// Apply middleware to HTTP routes
@@ -41,11 +58,21 @@ func initDatabaseTables(db database.Service) {
 		log.Fatal(err)
 	}
 
+	// Initialize department table (links existing users, so it runs after the user table)
+	if err := db.InitDepartmentTable(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize transaction table
 	if err := db.InitTransactionTable(); err != nil {
 		log.Fatal(err)
 	}
 
+	// Initialize category table (referenced by products)
+	if err := db.InitCategoryTable(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize product table
 	if err := db.InitProductTable(); err != nil {
 		log.Fatal(err)
@@ -54,4 +81,69 @@ func initDatabaseTables(db database.Service) {
 	if err := db.InitTransactionProductTable(); err != nil {
 		log.Fatal(err)
 	}
+
+	// Initialize product units after the products and sales they convert
+	if err := db.InitProductUnitTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize price history after the product units it records
+	if err := db.InitProductPriceTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize pricing rules table
+	if err := db.InitPricingRuleTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize daily menu and pre-order tables
+	if err := db.InitDailyMenuTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize payment statement tables
+	if err := db.InitPaymentTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize pending deposits table
+	if err := db.InitPendingDepositTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize cash sessions table
+	if err := db.InitCashSessionTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize balance transfers table
+	if err := db.InitBalanceTransferTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize offboardings table
+	if err := db.InitOffboardingTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize wallet top-up and bonus rule tables
+	if err := db.InitWalletTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize installment plan tables
+	if err := db.InitInstallmentPlanTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize escalation level and notice tables
+	if err := db.InitEscalationTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize audit log table
+	if err := db.InitAuditTable(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -17,9 +17,12 @@ func RegisterTransactionRoutes(router *mux.Router, db database.Service) {
 	router.HandleFunc("/api/transactions", transactionHandler.GetAllTransactions).Methods("GET")
 	router.HandleFunc("/api/transactions/latest", transactionHandler.GetLatestTransactions).Methods("GET")
 	router.HandleFunc("/api/transactions/date-range", transactionHandler.GetTransactionsByDateRange).Methods("POST")
+	router.HandleFunc("/api/transactions/import", transactionHandler.ImportTransactions).Methods("POST")
 	router.HandleFunc("/api/transactions/{id}", transactionHandler.GetTransaction).Methods("GET")
 	router.HandleFunc("/api/transactions/{id}", transactionHandler.UpdateTransaction).Methods("PUT")
 	router.HandleFunc("/api/transactions/{id}", transactionHandler.DeleteTransaction).Methods("DELETE")
+	router.HandleFunc("/api/transactions/{id}/restore", transactionHandler.RestoreTransaction).Methods("POST")
+	router.HandleFunc("/api/transactions/{id}/refund", transactionHandler.RefundTransaction).Methods("POST")
 	router.HandleFunc("/api/transactions/{id}/products", transactionHandler.GetTransactionProducts).Methods("GET")
 	router.HandleFunc("/api/users/{user_id}/transactions", transactionHandler.GetTransactionsByUserID).Methods("GET")
 	router.HandleFunc("/api/users/{user_id}/balance", transactionHandler.GetUserBalanceByUserID).Methods("GET")
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"maya-canteen/internal/database"
+	"maya-canteen/internal/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterWalletRoutes registers all prepaid wallet routes
+func RegisterWalletRoutes(router *mux.Router, db database.Service) {
+	// Create wallet handler
+	walletHandler := handlers.NewWalletHandler(db)
+
+	// Register routes
+	router.HandleFunc("/api/wallet/top-ups", walletHandler.GetTopUps).Methods("GET")
+	router.HandleFunc("/api/wallet/top-ups", walletHandler.TopUpWallet).Methods("POST")
+	router.HandleFunc("/api/users/{user_id}/top-ups", walletHandler.GetUserTopUps).Methods("GET")
+	router.HandleFunc("/api/wallet/bonus-rules", walletHandler.GetBonusRules).Methods("GET")
+	router.HandleFunc("/api/wallet/bonus-rules", walletHandler.CreateBonusRule).Methods("POST")
+	router.HandleFunc("/api/wallet/bonus-rules/{id}", walletHandler.GetBonusRule).Methods("GET")
+	router.HandleFunc("/api/wallet/bonus-rules/{id}", walletHandler.UpdateBonusRule).Methods("PUT")
+	router.HandleFunc("/api/wallet/bonus-rules/{id}", walletHandler.DeleteBonusRule).Methods("DELETE")
+	router.HandleFunc("/api/reports/prepaid-liability", walletHandler.GetPrepaidLiability).Methods("GET")
+}
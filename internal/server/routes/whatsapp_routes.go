@@ -7,16 +7,23 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// CurrentWhatsAppClient returns the WhatsApp client of the global WebSocket handler, or nil
+// if there is none yet
+func CurrentWhatsAppClient() *handlers.Client {
+	if GlobalWebSocketHandler == nil {
+		return nil
+	}
+	wc := GlobalWebSocketHandler.GetWhatsAppClient()
+	if wc == nil {
+		return nil
+	}
+	return wc.GetClient()
+}
+
 // RegisterWhatsAppRoutes registers all routes for WhatsApp functionality
 func RegisterWhatsAppRoutes(router *mux.Router, db database.Service) {
 	// Create a WhatsApp handler using the global WhatsApp client getter (function, not instance)
-	whatsappHandler := handlers.NewWhatsAppHandler(db, func() *handlers.Client {
-		wc := GlobalWebSocketHandler.GetWhatsAppClient()
-		if wc == nil {
-			return nil
-		}
-		return wc.GetClient()
-	})
+	whatsappHandler := handlers.NewWhatsAppHandler(db, CurrentWhatsAppClient)
 
 	// Create a subrouter for WhatsApp routes
 	whatsappRouter := router.PathPrefix("/api/whatsapp").Subrouter()
@@ -24,4 +31,5 @@ func RegisterWhatsAppRoutes(router *mux.Router, db database.Service) {
 	// Register WhatsApp notification routes
 	whatsappRouter.HandleFunc("/notify/{id}", whatsappHandler.NotifyUserBalance).Methods("POST")
 	whatsappRouter.HandleFunc("/notify-all", whatsappHandler.NotifyAllUsersBalances).Methods("POST")
+	whatsappRouter.HandleFunc("/escalations", whatsappHandler.SendEscalations).Methods("POST")
 }
@@ -44,6 +44,11 @@ func NewServer(whatsappClient handlers.WhatsAppClient) *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	StartBackupScheduler(s.db)
+	StartAuditRetention(s.db)
+	StartPurgeScheduler(s.db)
+	StartPriceScheduler(s.db)
+
 	return server
 }
 